@@ -24,3 +24,7 @@ func Start(ctx context.Context, addr string, port int) error {
 func Close() {
 	defaultServer.Close()
 }
+
+func Waiting() map[string]string {
+	return defaultServer.Waiting()
+}
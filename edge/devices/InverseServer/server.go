@@ -143,6 +143,16 @@ func (server *Server) Wait() {
 	server.wg.Wait()
 }
 
+//Waiting 已连接到服务器，等待被激活的控制器
+func (server *Server) Waiting() map[string]string {
+	result := map[string]string{}
+	server.connMap.Range(func(key, value interface{}) bool {
+		result[key.(string)] = value.(net.Conn).RemoteAddr().String()
+		return true
+	})
+	return result
+}
+
 func (server *Server) Try(_ context.Context, mac string) (net.Conn, error) {
 	var conn net.Conn
 	server.connMap.Range(func(key, value interface{}) bool {
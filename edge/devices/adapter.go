@@ -1,6 +1,9 @@
 package devices
 
 import (
+	"context"
+	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,6 +13,9 @@ import (
 	"github.com/maritimusj/centrum/edge/devices/ep6v2"
 	"github.com/maritimusj/centrum/edge/devices/event"
 	"github.com/maritimusj/centrum/edge/devices/measure"
+	"github.com/maritimusj/centrum/edge/devices/schedule"
+	"github.com/maritimusj/centrum/edge/devices/totalizer"
+	"github.com/maritimusj/centrum/edge/devices/virtual"
 	"github.com/maritimusj/centrum/edge/lang"
 	"github.com/maritimusj/centrum/global"
 	"github.com/maritimusj/centrum/json_rpc"
@@ -19,6 +25,7 @@ import (
 
 type Adapter struct {
 	device *ep6v2.Device
+	driver Driver
 	conf   *json_rpc.Conf
 
 	measureDataCH chan *measure.Data
@@ -27,6 +34,25 @@ type Adapter struct {
 	loggerStore logStore.Store
 
 	lastActiveTime time.Time
+	//最近一次采集的用时
+	pollDuration time.Duration
+
+	virtual       []*virtual.Channel
+	virtualValues map[string]interface{}
+
+	totalizers      []*totalizer.Totalizer
+	totalizerValues map[string]float64
+	//最近一次保存累计器状态的时间
+	totalizerSaved time.Time
+
+	//设备身份不匹配，等待gate确认
+	mismatch bool
+
+	schedules      []*schedule.Schedule
+	scheduleStates map[string]bool
+	pulses         map[string]*time.Timer
+
+	mu sync.RWMutex
 
 	done chan struct{}
 	wg   sync.WaitGroup
@@ -37,7 +63,7 @@ func (adapter *Adapter) heartBeat() {
 }
 
 func (adapter *Adapter) IsAlive() bool {
-	if adapter == nil || adapter.device == nil {
+	if adapter == nil || (adapter.getDevice() == nil && adapter.driver == nil) {
 		return false
 	}
 
@@ -67,22 +93,404 @@ func (adapter *Adapter) Close() {
 			recover()
 		}()
 
-		if adapter.device != nil {
-			adapter.device.Close()
+		if device := adapter.getDevice(); device != nil {
+			device.Close()
+
+			adapter.mu.Lock()
 			adapter.device = nil
+			adapter.mu.Unlock()
+		}
+
+		if adapter.driver != nil {
+			adapter.driver.Close()
 		}
 
 		close(adapter.done)
 		adapter.wg.Wait()
 
+		adapter.stopPulses()
+
 		if adapter.loggerStore != nil {
 			adapter.loggerStore.Close()
 		}
 
+		adapter.saveTotalizers()
+
 		return nil
 	})
 }
 
+//getDevice adapter关闭时device会被置为nil，其它goroutine需要通过这个函数读取
+func (adapter *Adapter) getDevice() *ep6v2.Device {
+	adapter.mu.RLock()
+	defer adapter.mu.RUnlock()
+
+	return adapter.device
+}
+
+//connect device是Serve中读取到的控制器，使用驱动时为nil
+func (adapter *Adapter) connect(ctx context.Context, device *ep6v2.Device) error {
+	if adapter.driver != nil {
+		return adapter.driver.Connect(ctx, adapter.conf.Address)
+	}
+	return device.Connect(ctx, adapter.conf.Address)
+}
+
+func (adapter *Adapter) isConnected(device *ep6v2.Device) bool {
+	if adapter.driver != nil {
+		return adapter.driver.IsConnected()
+	}
+	return device.IsConnected()
+}
+
+func (adapter *Adapter) closeDevice(device *ep6v2.Device) {
+	if adapter.driver != nil {
+		adapter.driver.Close()
+	} else {
+		device.Close()
+	}
+}
+
+func (adapter *Adapter) getStatus() lang.StrIndex {
+	adapter.mu.RLock()
+	mismatch := adapter.mismatch
+	adapter.mu.RUnlock()
+
+	if mismatch {
+		return lang.IdentityMismatch
+	}
+
+	if adapter.driver != nil {
+		return adapter.driver.GetStatus()
+	}
+	if device := adapter.getDevice(); device != nil {
+		return device.GetStatus()
+	}
+	return lang.Disconnected
+}
+
+func (adapter *Adapter) getIdentity() *json_rpc.Identity {
+	adapter.mu.RLock()
+	defer adapter.mu.RUnlock()
+
+	return adapter.conf.Identity
+}
+
+func (adapter *Adapter) setIdentity(identity *json_rpc.Identity) {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	adapter.conf.Identity = identity
+}
+
+func (adapter *Adapter) setMismatch(mismatch bool) {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	adapter.mismatch = mismatch
+}
+
+//readIdentity 读取控制器型号、版本、MAC地址和通道数量
+func (adapter *Adapter) readIdentity(device *ep6v2.Device) (*json_rpc.Identity, error) {
+	model, err := device.GetModel()
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := device.GetAddr()
+	if err != nil {
+		return nil, err
+	}
+
+	chNum, err := device.GetCHNum(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &json_rpc.Identity{
+		Model:   model.ID,
+		Version: model.Version,
+		MAC:     addr.Mac.String(),
+		AI:      chNum.AI,
+		AO:      chNum.AO,
+		DI:      chNum.DI,
+		DO:      chNum.DO,
+		VO:      chNum.VO,
+	}, nil
+}
+
+//verifyIdentity 校验控制器身份，首次连接时记录身份，身份不匹配时返回false
+func (adapter *Adapter) verifyIdentity(device *ep6v2.Device) (bool, error) {
+	if adapter.driver != nil {
+		return true, nil
+	}
+
+	identity, err := adapter.readIdentity(device)
+	if err != nil {
+		return false, err
+	}
+
+	return adapter.matchIdentity(identity), nil
+}
+
+//matchIdentity 和记录的身份比较，没有记录时记录下来，不匹配时等待gate确认
+func (adapter *Adapter) matchIdentity(identity *json_rpc.Identity) bool {
+	expected := adapter.getIdentity()
+	if expected == nil {
+		adapter.setIdentity(identity)
+		event.Publish(event.IdentityChanged, adapter.conf, identity, false)
+		return true
+	}
+
+	if *expected != *identity {
+		adapter.setMismatch(true)
+		adapter.logger.Warnf("identity mismatch, expected: %+v, got: %+v", *expected, *identity)
+		event.Publish(event.IdentityChanged, adapter.conf, identity, true)
+		return false
+	}
+
+	adapter.setMismatch(false)
+	return true
+}
+
+func (adapter *Adapter) setVirtualChannels(channels []*virtual.Channel) {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	adapter.virtual = channels
+	adapter.virtualValues = nil
+}
+
+func (adapter *Adapter) getVirtualChannels() []*virtual.Channel {
+	adapter.mu.RLock()
+	defer adapter.mu.RUnlock()
+
+	return adapter.virtual
+}
+
+func (adapter *Adapter) setVirtualValues(values map[string]interface{}) {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	adapter.virtualValues = values
+}
+
+func (adapter *Adapter) setTotalizers(list []*totalizer.Totalizer) {
+	adapter.saveTotalizers()
+
+	//读取保存的状态不需要加锁，已经在运行的点位随后使用内存中的状态
+	for _, t := range list {
+		totalizer.Load(adapter.conf.UID, t)
+	}
+
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	old := make(map[string]*totalizer.Totalizer, len(adapter.totalizers))
+	for _, t := range adapter.totalizers {
+		old[t.Tag] = t
+	}
+
+	for _, t := range list {
+		if v, ok := old[t.Tag]; ok {
+			t.SetState(v.State())
+		}
+	}
+
+	adapter.totalizers = list
+	adapter.totalizerValues = nil
+}
+
+func (adapter *Adapter) saveTotalizers() {
+	adapter.mu.RLock()
+	states := totalizer.States(adapter.totalizers)
+	adapter.mu.RUnlock()
+
+	if err := totalizer.Save(adapter.conf.UID, states); err != nil {
+		adapter.logger.Errorln(err)
+	}
+}
+
+//resetTotalizer 累计点位清零，tag为空时清零全部
+func (adapter *Adapter) resetTotalizer(tag string) error {
+	adapter.mu.Lock()
+
+	found := false
+	for _, t := range adapter.totalizers {
+		if tag == "" || strings.EqualFold(tag, t.Tag) {
+			t.Reset()
+			if adapter.totalizerValues != nil {
+				adapter.totalizerValues[t.Tag] = 0
+			}
+			found = true
+		}
+	}
+
+	if !found {
+		adapter.mu.Unlock()
+		return lang.Error(lang.ErrCHNotExists)
+	}
+
+	states := totalizer.States(adapter.totalizers)
+	adapter.mu.Unlock()
+
+	//清零后立即保存，不等待下一次定期保存
+	return totalizer.Save(adapter.conf.UID, states)
+}
+
+//getExtraData 虚拟点位和累计点位的最近一次计算结果，tag为空时返回全部
+func (adapter *Adapter) getExtraData(tag string) []map[string]interface{} {
+	adapter.mu.RLock()
+	defer adapter.mu.RUnlock()
+
+	result := make([]map[string]interface{}, 0, len(adapter.virtual))
+	for _, ch := range adapter.virtual {
+		if tag != "" && !strings.EqualFold(tag, ch.Tag) {
+			continue
+		}
+
+		entry := map[string]interface{}{
+			"tag":     ch.Tag,
+			"title":   ch.Title,
+			"virtual": true,
+		}
+		if ch.Unit != "" {
+			entry["unit"] = ch.Unit
+		}
+		if v, ok := adapter.virtualValues[ch.Tag]; ok {
+			entry["value"] = v
+		}
+		result = append(result, entry)
+	}
+
+	for _, t := range adapter.totalizers {
+		if tag != "" && !strings.EqualFold(tag, t.Tag) {
+			continue
+		}
+
+		entry := map[string]interface{}{
+			"tag":       t.Tag,
+			"title":     t.Title,
+			"totalizer": true,
+		}
+		if t.Unit != "" {
+			entry["unit"] = t.Unit
+		}
+		if v, ok := adapter.totalizerValues[t.Tag]; ok {
+			entry["value"] = v
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+func (adapter *Adapter) setSchedules(list []*schedule.Schedule) {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	//配置没有变化的定时保留上次的状态，避免覆盖手动操作
+	old := make(map[string]*schedule.Schedule, len(adapter.schedules))
+	for _, s := range adapter.schedules {
+		old[s.Tag] = s
+	}
+
+	states := make(map[string]bool, len(list))
+	for _, s := range list {
+		if v, ok := old[s.Tag]; ok && reflect.DeepEqual(v.Schedule, s.Schedule) {
+			if state, ok := adapter.scheduleStates[s.Tag]; ok {
+				states[s.Tag] = state
+			}
+		}
+	}
+
+	adapter.schedules = list
+	adapter.scheduleStates = states
+}
+
+func (adapter *Adapter) getSchedules() []*schedule.Schedule {
+	adapter.mu.RLock()
+	defer adapter.mu.RUnlock()
+
+	return adapter.schedules
+}
+
+func (adapter *Adapter) getScheduleState(tag string) (bool, bool) {
+	adapter.mu.RLock()
+	defer adapter.mu.RUnlock()
+
+	v, ok := adapter.scheduleStates[tag]
+	return v, ok
+}
+
+func (adapter *Adapter) setScheduleState(tag string, v bool) {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	if adapter.scheduleStates == nil {
+		adapter.scheduleStates = map[string]bool{}
+	}
+	adapter.scheduleStates[tag] = v
+}
+
+//setPulse 记录脉冲输出关闭的定时器，同一个点位再次脉冲输出时替换原来的定时器
+func (adapter *Adapter) setPulse(tag string, timer *time.Timer) {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	if adapter.pulses == nil {
+		adapter.pulses = map[string]*time.Timer{}
+	}
+	if t, ok := adapter.pulses[tag]; ok {
+		t.Stop()
+	}
+	adapter.pulses[tag] = timer
+}
+
+func (adapter *Adapter) removePulse(tag string) {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	delete(adapter.pulses, tag)
+}
+
+func (adapter *Adapter) hasPulse(tag string) bool {
+	adapter.mu.RLock()
+	defer adapter.mu.RUnlock()
+
+	_, ok := adapter.pulses[tag]
+	return ok
+}
+
+//retryPulse 关闭失败时稍后重试
+func (adapter *Adapter) retryPulse(tag string, delay time.Duration) {
+	adapter.mu.RLock()
+	defer adapter.mu.RUnlock()
+
+	if t, ok := adapter.pulses[tag]; ok {
+		t.Reset(delay)
+	}
+}
+
+//stopPulses adapter关闭时停止定时器，未结束的脉冲输出由重新启动的adapter关闭
+func (adapter *Adapter) stopPulses() {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	for _, t := range adapter.pulses {
+		t.Stop()
+	}
+	adapter.pulses = nil
+}
+
+//OnSwitch 记录DO开关操作到设备日志
+func (adapter *Adapter) OnSwitch(tag string, on bool, source lang.StrIndex) {
+	state := lang.SwitchOff
+	if on {
+		state = lang.SwitchOn
+	}
+	event.Publish(event.DeviceLog, adapter.conf, "info", lang.Str(lang.SwitchLog, tag, lang.Str(state), lang.Str(source)))
+}
+
 func (adapter *Adapter) OnDeviceStatusChanged(index lang.StrIndex) {
 	event.Publish(event.DeviceStatusChanged, adapter.conf, index)
 }
@@ -0,0 +1,80 @@
+package devices
+
+import (
+	"github.com/maritimusj/centrum/edge/lang"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	deviceConnectedDesc = prometheus.NewDesc(
+		"edge_device_connected", "Whether the device is connected (1) or not (0).", []string{"uid"}, nil)
+	deviceStatusDesc = prometheus.NewDesc(
+		"edge_device_status", "Status index of the device.", []string{"uid"}, nil)
+	modbusRequestsDesc = prometheus.NewDesc(
+		"edge_modbus_requests_total", "Number of Modbus requests sent to the device.", []string{"uid"}, nil)
+	modbusErrorsDesc = prometheus.NewDesc(
+		"edge_modbus_errors_total", "Number of failed Modbus requests by kind.", []string{"uid", "kind"}, nil)
+	modbusLatencyDesc = prometheus.NewDesc(
+		"edge_modbus_latency_seconds", "Modbus request latency over recent requests.", []string{"uid", "quantile"}, nil)
+)
+
+//collector 采集时读取全部设备的连接状态和Modbus通讯统计
+type collector struct {
+	runner *Runner
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- deviceConnectedDesc
+	ch <- deviceStatusDesc
+	ch <- modbusRequestsDesc
+	ch <- modbusErrorsDesc
+	ch <- modbusLatencyDesc
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	c.runner.adapters.Range(func(key, v interface{}) bool {
+		var (
+			uid     = key.(string)
+			adapter = v.(*Adapter)
+			status  = adapter.getStatus()
+		)
+
+		connected := 0.0
+		if status == lang.Connected {
+			connected = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(deviceConnectedDesc, prometheus.GaugeValue, connected, uid)
+		ch <- prometheus.MustNewConstMetric(deviceStatusDesc, prometheus.GaugeValue, float64(status), uid)
+
+		device := adapter.getDevice()
+		if device == nil {
+			return true
+		}
+
+		stats := device.GetDiagnostics().Stats()
+
+		var exceptions uint64
+		for _, e := range stats.Exceptions {
+			exceptions += e.Count
+		}
+
+		ch <- prometheus.MustNewConstMetric(modbusRequestsDesc, prometheus.CounterValue, float64(stats.Requests), uid)
+		for kind, n := range map[string]uint64{
+			"timeout":   stats.Timeouts,
+			"protocol":  stats.ProtocolErrors,
+			"exception": exceptions,
+			"other":     stats.OtherErrors,
+		} {
+			ch <- prometheus.MustNewConstMetric(modbusErrorsDesc, prometheus.CounterValue, float64(n), uid, kind)
+		}
+		for quantile, ms := range map[string]float64{
+			"0.5":  stats.P50Latency,
+			"0.95": stats.P95Latency,
+			"0.99": stats.P99Latency,
+		} {
+			ch <- prometheus.MustNewConstMetric(modbusLatencyDesc, prometheus.GaugeValue, ms/1000, uid, quantile)
+		}
+		return true
+	})
+}
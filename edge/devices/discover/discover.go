@@ -0,0 +1,176 @@
+package discover
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maritimusj/centrum/edge/devices/InverseServer"
+	"github.com/maritimusj/centrum/edge/devices/ep6v2"
+	"github.com/maritimusj/centrum/edge/lang"
+	"github.com/maritimusj/centrum/json_rpc"
+	"github.com/maritimusj/modbus"
+)
+
+const (
+	//一次最多扫描的主机数量
+	MaxHosts = 1024
+	//同时扫描的连接数量
+	Concurrency = 64
+
+	DefaultPort    = 502
+	DefaultTimeout = 500 * time.Millisecond
+)
+
+//Hosts 解析扫描范围，支持CIDR和单个IPv4地址，不包括网络地址和广播地址
+func Hosts(cidr string) ([]net.IP, error) {
+	cidr = strings.TrimSpace(cidr)
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr).To4()
+		if ip == nil {
+			return nil, lang.Error(lang.ErrInvalidScanRange, cidr)
+		}
+		return []net.IP{ip}, nil
+	}
+
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() == nil {
+		return nil, lang.Error(lang.ErrInvalidScanRange, cidr)
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	total := uint32(1) << uint(bits-ones)
+	if total > MaxHosts {
+		return nil, lang.Error(lang.ErrInvalidScanRange, cidr)
+	}
+
+	begin := binary.BigEndian.Uint32(ipNet.IP.To4())
+	first, last := begin, begin+total-1
+	if total > 2 {
+		first, last = first+1, last-1
+	}
+
+	result := make([]net.IP, 0, total)
+	for n := first; n <= last; n++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, n)
+		result = append(result, ip)
+	}
+	return result, nil
+}
+
+//Scan 扫描指定范围内的Modbus TCP设备，并列出等待反向连接的控制器
+func Scan(ctx context.Context, conf *json_rpc.ScanConf) ([]*json_rpc.Discovered, error) {
+	var hosts []net.IP
+	if conf.CIDR != "" {
+		var err error
+		hosts, err = Hosts(conf.CIDR)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ports := conf.Ports
+	if len(ports) == 0 {
+		ports = []int{DefaultPort}
+	}
+
+	timeout := DefaultTimeout
+	if conf.Timeout > 0 {
+		timeout = time.Duration(conf.Timeout) * time.Millisecond
+	}
+
+	var (
+		result = make([]*json_rpc.Discovered, 0)
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, Concurrency)
+	)
+
+loop:
+	for _, ip := range hosts {
+		for _, port := range ports {
+			select {
+			case <-ctx.Done():
+				break loop
+			case sem <- struct{}{}:
+			}
+
+			wg.Add(1)
+			go func(address string) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				if v := probe(ctx, address, timeout); v != nil {
+					mu.Lock()
+					result = append(result, v)
+					mu.Unlock()
+				}
+			}(net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+		}
+	}
+
+	wg.Wait()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Address < result[j].Address
+	})
+
+	for mac, remote := range InverseServer.Waiting() {
+		result = append(result, &json_rpc.Discovered{
+			Address: mac,
+			MAC:     mac,
+			Remote:  remote,
+			Inverse: true,
+		})
+	}
+
+	return result, nil
+}
+
+//probe 连接并识别一个设备，不是Modbus TCP设备时返回nil
+func probe(ctx context.Context, address string, timeout time.Duration) *json_rpc.Discovered {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil
+	}
+
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	model, addr, err := ep6v2.Identify(conn, timeout)
+	if err != nil && model == nil {
+		//返回异常码的也是Modbus设备，只是无法识别型号
+		if _, ok := err.(*modbus.ModbusError); !ok {
+			return nil
+		}
+	}
+
+	result := &json_rpc.Discovered{
+		Address: address,
+	}
+
+	if model != nil {
+		result.Model = model.ID
+		result.Version = model.Version
+		result.Title = model.Title
+	}
+
+	if addr != nil {
+		result.IP = addr.Ip.String()
+		result.Mask = addr.Mask.String()
+		result.Gateway = addr.Gateway.String()
+		result.MAC = addr.Mac.String()
+	}
+
+	return result
+}
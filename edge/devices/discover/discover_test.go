@@ -0,0 +1,146 @@
+package discover
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/maritimusj/centrum/json_rpc"
+)
+
+func TestHosts(t *testing.T) {
+	cases := map[string][]string{
+		"192.168.1.10":     {"192.168.1.10"},
+		" 192.168.1.10 ":   {"192.168.1.10"},
+		"192.168.1.0/30":   {"192.168.1.1", "192.168.1.2"},
+		"192.168.1.5/31":   {"192.168.1.4", "192.168.1.5"},
+		"192.168.1.7/32":   {"192.168.1.7"},
+		"192.168.1.130/25": nil,
+	}
+	for cidr, expected := range cases {
+		hosts, err := Hosts(cidr)
+		if err != nil {
+			t.Fatalf("%s: %s", cidr, err)
+		}
+		if expected == nil {
+			if len(hosts) != 126 || hosts[0].String() != "192.168.1.129" || hosts[125].String() != "192.168.1.254" {
+				t.Fatalf("%s: unexpected hosts %v", cidr, hosts)
+			}
+			continue
+		}
+		if len(hosts) != len(expected) {
+			t.Fatalf("%s: expected %v, got %v", cidr, expected, hosts)
+		}
+		for i, ip := range hosts {
+			if ip.String() != expected[i] {
+				t.Fatalf("%s: expected %v, got %v", cidr, expected, hosts)
+			}
+		}
+	}
+
+	for _, cidr := range []string{"", "abc", "::1", "fe80::/120", "10.0.0.0/8", "192.168.1.0/33"} {
+		if _, err := Hosts(cidr); err == nil {
+			t.Fatalf("%q should be rejected", cidr)
+		}
+	}
+}
+
+//listen 启动一个本地服务，handle处理每个连接
+func listen(t *testing.T, handle func(conn net.Conn)) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+//exception 对每个Modbus请求回复异常码
+func exception(conn net.Conn) {
+	header := make([]byte, 7)
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		pdu := make([]byte, binary.BigEndian.Uint16(header[4:])-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+
+		resp := make([]byte, 9)
+		copy(resp, header[:4])
+		binary.BigEndian.PutUint16(resp[4:], 3)
+		resp[6] = header[6]
+		resp[7] = pdu[0] | 0x80
+		resp[8] = 0x02
+		if _, err := conn.Write(resp); err != nil {
+			return
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	modbusPort := listen(t, exception)
+	otherPort := listen(t, func(conn net.Conn) {})
+
+	result, err := Scan(context.Background(), &json_rpc.ScanConf{
+		CIDR:  "127.0.0.1",
+		Ports: []int{modbusPort, otherPort},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found []*json_rpc.Discovered
+	for _, v := range result {
+		if !v.Inverse {
+			found = append(found, v)
+		}
+	}
+
+	//返回异常码的是Modbus设备，其它服务不是
+	if len(found) != 1 || found[0].Address != net.JoinHostPort("127.0.0.1", strconv.Itoa(modbusPort)) {
+		t.Fatalf("only the modbus device should be found, got %v", found)
+	}
+	if found[0].Model != "" || found[0].MAC != "" {
+		t.Fatalf("unknown model expected, got %v", found[0])
+	}
+
+	if _, err := Scan(context.Background(), &json_rpc.ScanConf{CIDR: "10.0.0.0/8"}); err == nil {
+		t.Fatal("too large range should be rejected")
+	}
+}
+
+func TestScanCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := Scan(ctx, &json_rpc.ScanConf{CIDR: "127.0.0.0/24", Ports: []int{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range result {
+		if !v.Inverse {
+			t.Fatalf("canceled scan should not probe, got %v", v)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package dlt645
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goburrow/serial"
+)
+
+//SerialConf 串口参数，表计默认为 2400,8,E,1
+type SerialConf struct {
+	BaudRate int    `json:"baudRate"`
+	DataBits int    `json:"dataBits"`
+	StopBits int    `json:"stopBits"`
+	Parity   string `json:"parity"`
+}
+
+//IsSerialAddress 判断地址是否为本地串口（/dev/ttyXXX 或 COMx），否则视为TCP串口服务器地址
+func IsSerialAddress(address string) bool {
+	return strings.HasPrefix(address, "/dev/") || strings.HasPrefix(strings.ToUpper(address), "COM")
+}
+
+//Client 一条RS-485总线上的DL/T 645主站
+type Client struct {
+	conn    io.ReadWriteCloser
+	timeout time.Duration
+	mu      sync.Mutex
+}
+
+func Dial(ctx context.Context, address string, conf *SerialConf, timeout time.Duration) (*Client, error) {
+	var conn io.ReadWriteCloser
+	if IsSerialAddress(address) {
+		c := &serial.Config{
+			Address:  address,
+			BaudRate: 2400,
+			DataBits: 8,
+			StopBits: 1,
+			Parity:   "E",
+			Timeout:  timeout,
+		}
+		if conf != nil {
+			if conf.BaudRate > 0 {
+				c.BaudRate = conf.BaudRate
+			}
+			if conf.DataBits > 0 {
+				c.DataBits = conf.DataBits
+			}
+			if conf.StopBits > 0 {
+				c.StopBits = conf.StopBits
+			}
+			if conf.Parity != "" {
+				c.Parity = strings.ToUpper(conf.Parity)
+			}
+		}
+		port, err := serial.Open(c)
+		if err != nil {
+			return nil, err
+		}
+		conn = port
+	} else {
+		dialer := net.Dialer{Timeout: timeout}
+		c, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return nil, err
+		}
+		conn = c
+	}
+
+	return NewClient(conn, timeout), nil
+}
+
+func NewClient(conn io.ReadWriteCloser, timeout time.Duration) *Client {
+	return &Client{
+		conn:    conn,
+		timeout: timeout,
+	}
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) transact(req *Frame) (*Frame, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if conn, ok := c.conn.(net.Conn); ok {
+		_ = conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+
+	if _, err := c.conn.Write(req.Encode()); err != nil {
+		return nil, err
+	}
+
+	for {
+		resp, err := ReadFrame(c.conn)
+		if err != nil {
+			return nil, err
+		}
+
+		//半双工总线上可能读到自己发出的帧
+		if resp.Ctrl&ctrlSlaveResponse == 0 {
+			continue
+		}
+
+		if resp.Ctrl&0x1F != req.Ctrl {
+			return nil, ErrInvalidFrame
+		}
+
+		if req.Address != BroadcastAddress && resp.Address != req.Address {
+			return nil, ErrInvalidAddress
+		}
+
+		if resp.IsError() {
+			var code byte
+			if len(resp.Data) > 0 {
+				code = resp.Data[0]
+			}
+			return nil, fmt.Errorf("dlt645: meter %s responds error: 0x%02x", req.Address, code)
+		}
+
+		return resp, nil
+	}
+}
+
+//ReadAddress 通过广播地址读取表地址，总线上只能有一块表，有多块表应答时返回ErrMultipleMeters
+func (c *Client) ReadAddress() (Address, error) {
+	resp, err := c.transact(&Frame{
+		Address: BroadcastAddress,
+		Ctrl:    CtrlReadAddress,
+	})
+	if err != nil {
+		//多块表同时应答时数据帧会互相干扰
+		if err == ErrInvalidChecksum || err == ErrInvalidFrame {
+			return Address{}, ErrMultipleMeters
+		}
+		return Address{}, err
+	}
+
+	if c.answered() {
+		return Address{}, ErrMultipleMeters
+	}
+
+	var addr Address
+	if len(resp.Data) >= 6 {
+		copy(addr[:], resp.Data[:6])
+	} else {
+		addr = resp.Address
+	}
+
+	return addr, nil
+}
+
+//answered 在超时时间内是否还有其它表的应答
+func (c *Client) answered() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if conn, ok := c.conn.(net.Conn); ok {
+		_ = conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+
+	_, err := ReadFrame(c.conn)
+	return err == nil || err == ErrInvalidChecksum || err == ErrInvalidFrame
+}
+
+//Read 读取表的一个数据项
+func (c *Client) Read(addr Address, id *Identifier) (float64, error) {
+	resp, err := c.transact(&Frame{
+		Address: addr,
+		Ctrl:    CtrlReadData,
+		Data:    id.Bytes(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(resp.Data) < 4 || !bytes.Equal(resp.Data[:4], id.Bytes()) {
+		return 0, ErrInvalidFrame
+	}
+
+	return id.Decode(resp.Data[4:])
+}
@@ -0,0 +1,55 @@
+package dlt645
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+//bus 模拟总线，写入的请求直接丢弃，依次读出预先准备好的应答
+type bus struct {
+	bytes.Buffer
+}
+
+func (b *bus) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (b *bus) Close() error {
+	return nil
+}
+
+func answer(addr Address) []byte {
+	return (&Frame{Address: addr, Ctrl: CtrlReadAddress | ctrlSlaveResponse, Data: addr[:]}).Encode()
+}
+
+func TestReadAddress(t *testing.T) {
+	addr, err := ParseAddress("000012345678")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := &bus{}
+	conn.Buffer.Write(answer(addr))
+
+	v, err := NewClient(conn, time.Second).ReadAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != addr {
+		t.Fatal("invalid address:", v)
+	}
+
+	other, err := ParseAddress("000087654321")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn = &bus{}
+	conn.Buffer.Write(answer(addr))
+	conn.Buffer.Write(answer(other))
+
+	if _, err := NewClient(conn, time.Second).ReadAddress(); err != ErrMultipleMeters {
+		t.Fatal("expect ErrMultipleMeters, got:", err)
+	}
+}
@@ -0,0 +1,271 @@
+package dlt645
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maritimusj/centrum/edge/devices/measure"
+	"github.com/maritimusj/centrum/edge/lang"
+)
+
+const (
+	defaultTimeout = 2 * time.Second
+)
+
+//Conf 驱动参数，由设备的 params.options 传入
+type Conf struct {
+	SerialConf
+	//表地址列表，为空时通过广播地址自动读取（总线上只能有一块表），一条总线上有多块表时必须配置
+	Meters []string `json:"meters"`
+	//需要读取的数据标识，为空时使用 DefaultIdentifiers
+	Identifiers []string `json:"identifiers"`
+	//单次读取超时，毫秒
+	Timeout int `json:"timeout"`
+}
+
+type value struct {
+	tag   string
+	title string
+	unit  string
+	val   float64
+}
+
+type Device struct {
+	conf   *Conf
+	client *Client
+	status lang.StrIndex
+
+	meters      []Address
+	identifiers []*Identifier
+
+	values []*value
+	mu     sync.RWMutex
+}
+
+func New(options string) (*Device, error) {
+	conf := &Conf{}
+	if options != "" {
+		if err := json.Unmarshal([]byte(options), conf); err != nil {
+			return nil, err
+		}
+	}
+
+	device := &Device{
+		conf:   conf,
+		status: lang.Disconnected,
+	}
+
+	for _, str := range conf.Meters {
+		addr, err := ParseAddress(str)
+		if err != nil {
+			return nil, err
+		}
+		device.meters = append(device.meters, addr)
+	}
+
+	if len(conf.Identifiers) > 0 {
+		for _, str := range conf.Identifiers {
+			id, err := ParseIdentifier(str)
+			if err != nil {
+				return nil, err
+			}
+			device.identifiers = append(device.identifiers, id)
+		}
+	} else {
+		device.identifiers = DefaultIdentifiers
+	}
+
+	return device, nil
+}
+
+func (device *Device) timeout() time.Duration {
+	if device.conf.Timeout > 0 {
+		return time.Duration(device.conf.Timeout) * time.Millisecond
+	}
+	return defaultTimeout
+}
+
+func (device *Device) Connect(ctx context.Context, address string) error {
+	device.mu.Lock()
+	defer device.mu.Unlock()
+
+	device.status = lang.Connecting
+
+	client, err := Dial(ctx, address, &device.conf.SerialConf, device.timeout())
+	if err != nil {
+		device.status = lang.Disconnected
+		return err
+	}
+
+	//未配置表地址时自动读取
+	if len(device.conf.Meters) == 0 {
+		addr, err := client.ReadAddress()
+		if err != nil {
+			_ = client.Close()
+			device.status = lang.Disconnected
+			return err
+		}
+		device.meters = []Address{addr}
+	}
+
+	device.client = client
+	device.status = lang.Connected
+	return nil
+}
+
+func (device *Device) IsConnected() bool {
+	device.mu.RLock()
+	defer device.mu.RUnlock()
+
+	return device.client != nil && device.status == lang.Connected
+}
+
+func (device *Device) Close() {
+	device.mu.Lock()
+	defer device.mu.Unlock()
+
+	if device.client != nil {
+		_ = device.client.Close()
+		device.client = nil
+	}
+
+	device.status = lang.Disconnected
+}
+
+func (device *Device) Reset() {
+	device.mu.Lock()
+	defer device.mu.Unlock()
+
+	device.values = nil
+}
+
+func (device *Device) GetStatus() lang.StrIndex {
+	device.mu.RLock()
+	defer device.mu.RUnlock()
+
+	return device.status
+}
+
+func (device *Device) GetStatusTitle() string {
+	return lang.Str(device.GetStatus())
+}
+
+func (device *Device) GetBaseInfo() (map[string]interface{}, error) {
+	device.mu.RLock()
+	defer device.mu.RUnlock()
+
+	meters := make([]string, 0, len(device.meters))
+	for _, addr := range device.meters {
+		meters = append(meters, addr.String())
+	}
+
+	return map[string]interface{}{
+		"model":  "DL/T 645-2007",
+		"meters": meters,
+	}, nil
+}
+
+//Gather 依次读取每块表的数据项，只有全部读取失败时才返回错误
+func (device *Device) Gather(ctx context.Context) ([]*measure.Data, error) {
+	device.mu.RLock()
+	client, meters := device.client, device.meters
+	device.mu.RUnlock()
+
+	if client == nil {
+		return nil, lang.Error(lang.ErrDeviceNotConnected)
+	}
+
+	var (
+		result  []*measure.Data
+		values  []*value
+		lastErr error
+	)
+
+	for _, addr := range meters {
+		for _, id := range device.identifiers {
+			select {
+			case <-ctx.Done():
+				return result, ctx.Err()
+			default:
+			}
+
+			v, err := client.Read(addr, id)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+
+			val := &value{
+				tag:   TagName(addr, id),
+				title: fmt.Sprintf("%s(%s)", id.Title, addr),
+				unit:  id.Unit,
+				val:   v,
+			}
+			values = append(values, val)
+
+			data := measure.New(val.tag)
+			data.AddTag("tag", val.tag)
+			data.AddTag("title", val.title)
+			data.AddTag("unit", val.unit)
+			data.AddTag("meter", addr.String())
+			data.AddField("val", v)
+			result = append(result, data)
+		}
+	}
+
+	if len(values) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	device.mu.Lock()
+	device.values = values
+	device.mu.Unlock()
+
+	return result, nil
+}
+
+func (device *Device) GetValue(tag string) (interface{}, error) {
+	device.mu.RLock()
+	defer device.mu.RUnlock()
+
+	for _, v := range device.values {
+		if strings.EqualFold(v.tag, tag) {
+			return map[string]interface{}{
+				"title": v.title,
+				"tag":   v.tag,
+				"unit":  v.unit,
+				"value": v.val,
+			}, nil
+		}
+	}
+	return nil, lang.Error(lang.ErrCHNotExists)
+}
+
+func (device *Device) SetValue(string, interface{}) error {
+	return lang.Error(lang.ErrCHReadOnly)
+}
+
+func (device *Device) GetRealtimeData() ([]map[string]interface{}, error) {
+	device.mu.RLock()
+	defer device.mu.RUnlock()
+
+	values := make([]map[string]interface{}, 0, len(device.values))
+	for _, v := range device.values {
+		values = append(values, map[string]interface{}{
+			"tag":   v.tag,
+			"title": v.title,
+			"unit":  v.unit,
+			"value": v.val,
+		})
+	}
+	return values, nil
+}
+
+//TagName 点位名称，如 AI-000012345678-Ua
+func TagName(addr Address, id *Identifier) string {
+	return "AI-" + addr.String() + "-" + id.Name
+}
@@ -0,0 +1,207 @@
+package dlt645
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	frameStart = 0x68
+	frameEnd   = 0x16
+	preamble   = 0xFE
+
+	//控制码
+	CtrlReadData    = 0x11
+	CtrlReadAddress = 0x13
+
+	//从站应答标志位
+	ctrlSlaveResponse = 0x80
+	ctrlSlaveError    = 0x40
+	ctrlFollowUp      = 0x20
+
+	maxDataLen = 200
+)
+
+var (
+	//广播地址，用于总线上只有一块表时读取通信地址
+	BroadcastAddress = Address{0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA}
+
+	ErrInvalidFrame    = errors.New("dlt645: invalid frame")
+	ErrInvalidChecksum = errors.New("dlt645: invalid checksum")
+	ErrInvalidAddress  = errors.New("dlt645: invalid address")
+	ErrMultipleMeters  = errors.New("dlt645: more than one meter answers the broadcast address, meters must be configured")
+)
+
+//Address 表地址，A0在前（低位在前）
+type Address [6]byte
+
+//ParseAddress 解析12位十进制表地址，如 "000012345678"
+func ParseAddress(str string) (Address, error) {
+	var addr Address
+
+	str = strings.TrimSpace(str)
+	if len(str) > 12 {
+		return addr, ErrInvalidAddress
+	}
+
+	str = strings.Repeat("0", 12-len(str)) + str
+	for i := 0; i < 6; i++ {
+		b, err := encodeBCDByte(str[10-i*2 : 12-i*2])
+		if err != nil {
+			return addr, ErrInvalidAddress
+		}
+		addr[i] = b
+	}
+
+	return addr, nil
+}
+
+func (addr Address) String() string {
+	var sb strings.Builder
+	for i := 5; i >= 0; i-- {
+		sb.WriteString(fmt.Sprintf("%02x", addr[i]))
+	}
+	return sb.String()
+}
+
+//Frame 一个DL/T 645-2007数据帧
+type Frame struct {
+	Address Address
+	Ctrl    byte
+	Data    []byte
+}
+
+func (f *Frame) IsError() bool {
+	return f.Ctrl&ctrlSlaveError != 0
+}
+
+func (f *Frame) HasFollowUp() bool {
+	return f.Ctrl&ctrlSlaveResponse != 0 && f.Ctrl&ctrlFollowUp != 0
+}
+
+//Encode 编码数据帧，数据域自动加0x33
+func (f *Frame) Encode() []byte {
+	buf := make([]byte, 0, 4+12+len(f.Data))
+	buf = append(buf, preamble, preamble, preamble, preamble)
+
+	begin := len(buf)
+	buf = append(buf, frameStart)
+	buf = append(buf, f.Address[:]...)
+	buf = append(buf, frameStart, f.Ctrl, byte(len(f.Data)))
+	for _, b := range f.Data {
+		buf = append(buf, b+0x33)
+	}
+
+	buf = append(buf, checksum(buf[begin:]), frameEnd)
+	return buf
+}
+
+//ReadFrame 从数据流中读取一个数据帧，跳过前导字节，数据域自动减0x33
+func ReadFrame(r io.Reader) (*Frame, error) {
+	b := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		if b[0] == frameStart {
+			break
+		}
+		if b[0] != preamble {
+			return nil, ErrInvalidFrame
+		}
+	}
+
+	header := make([]byte, 9)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	if header[6] != frameStart {
+		return nil, ErrInvalidFrame
+	}
+
+	l := int(header[8])
+	if l > maxDataLen {
+		return nil, ErrInvalidFrame
+	}
+
+	tail := make([]byte, l+2)
+	if _, err := io.ReadFull(r, tail); err != nil {
+		return nil, err
+	}
+
+	if tail[l+1] != frameEnd {
+		return nil, ErrInvalidFrame
+	}
+
+	sum := checksum(append(append([]byte{frameStart}, header...), tail[:l]...))
+	if sum != tail[l] {
+		return nil, ErrInvalidChecksum
+	}
+
+	f := &Frame{
+		Ctrl: header[7],
+		Data: make([]byte, l),
+	}
+
+	copy(f.Address[:], header[:6])
+	for i := 0; i < l; i++ {
+		f.Data[i] = tail[i] - 0x33
+	}
+
+	return f, nil
+}
+
+func checksum(data []byte) byte {
+	var sum byte
+	for _, b := range data {
+		sum += b
+	}
+	return sum
+}
+
+func encodeBCDByte(str string) (byte, error) {
+	if len(str) != 2 || str[0] < '0' || str[0] > '9' || str[1] < '0' || str[1] > '9' {
+		return 0, ErrInvalidAddress
+	}
+	return (str[0]-'0')<<4 | (str[1] - '0'), nil
+}
+
+//DecodeBCD 解码低位在前的BCD数据，signed为true时最高字节的最高位为符号位
+func DecodeBCD(data []byte, decimals int, signed bool) (float64, error) {
+	if len(data) == 0 {
+		return 0, ErrInvalidFrame
+	}
+
+	var (
+		v        float64
+		negative bool
+	)
+
+	for i := len(data) - 1; i >= 0; i-- {
+		b := data[i]
+		if i == len(data)-1 && signed {
+			negative = b&0x80 != 0
+			b &= 0x7F
+		}
+
+		hi, lo := b>>4, b&0x0F
+		if hi > 9 || lo > 9 {
+			return 0, fmt.Errorf("dlt645: invalid bcd data: % x", data)
+		}
+
+		v = v*100 + float64(hi*10+lo)
+	}
+
+	for i := 0; i < decimals; i++ {
+		v /= 10
+	}
+
+	if negative {
+		v = -v
+	}
+
+	return v, nil
+}
@@ -0,0 +1,53 @@
+package dlt645
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFrame(t *testing.T) {
+	addr, err := ParseAddress("000012345678")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addr.String() != "000012345678" {
+		t.Fatal("invalid address:", addr.String())
+	}
+
+	id, err := ParseIdentifier("02010100")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := (&Frame{Address: addr, Ctrl: CtrlReadData, Data: id.Bytes()}).Encode()
+	expected := []byte{0xFE, 0xFE, 0xFE, 0xFE, 0x68, 0x78, 0x56, 0x34, 0x12, 0x00, 0x00, 0x68, 0x11, 0x04, 0x33, 0x34, 0x34, 0x35, 0xC9, 0x16}
+	if !bytes.Equal(req, expected) {
+		t.Fatalf("invalid frame: % x", req)
+	}
+
+	//A相电压 220.1V
+	resp := (&Frame{Address: addr, Ctrl: CtrlReadData | ctrlSlaveResponse, Data: append(id.Bytes(), 0x01, 0x22)}).Encode()
+	f, err := ReadFrame(bytes.NewReader(resp))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := id.Decode(f.Data[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != 220.1 {
+		t.Fatal("invalid value:", v)
+	}
+
+	v, err = DecodeBCD([]byte{0x00, 0x50, 0x80}, 4, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v != -0.5 {
+		t.Fatal("invalid signed value:", v)
+	}
+}
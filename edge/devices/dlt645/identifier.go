@@ -0,0 +1,81 @@
+package dlt645
+
+import (
+	"fmt"
+	"strconv"
+)
+
+//Identifier 数据标识（DI3 DI2 DI1 DI0）及其数据格式
+type Identifier struct {
+	DI       uint32
+	Name     string
+	Title    string
+	Unit     string
+	Length   int
+	Decimals int
+	Signed   bool
+}
+
+var (
+	//默认读取的数据项：电能、功率、电压、电流、功率因数
+	DefaultIdentifiers = []*Identifier{
+		{DI: 0x00000000, Name: "Ep", Title: "组合有功总电能", Unit: "kWh", Length: 4, Decimals: 2},
+		{DI: 0x00010000, Name: "EpImp", Title: "正向有功总电能", Unit: "kWh", Length: 4, Decimals: 2},
+		{DI: 0x00020000, Name: "EpExp", Title: "反向有功总电能", Unit: "kWh", Length: 4, Decimals: 2},
+
+		{DI: 0x02010100, Name: "Ua", Title: "A相电压", Unit: "V", Length: 2, Decimals: 1},
+		{DI: 0x02010200, Name: "Ub", Title: "B相电压", Unit: "V", Length: 2, Decimals: 1},
+		{DI: 0x02010300, Name: "Uc", Title: "C相电压", Unit: "V", Length: 2, Decimals: 1},
+
+		{DI: 0x02020100, Name: "Ia", Title: "A相电流", Unit: "A", Length: 3, Decimals: 3, Signed: true},
+		{DI: 0x02020200, Name: "Ib", Title: "B相电流", Unit: "A", Length: 3, Decimals: 3, Signed: true},
+		{DI: 0x02020300, Name: "Ic", Title: "C相电流", Unit: "A", Length: 3, Decimals: 3, Signed: true},
+
+		{DI: 0x02030000, Name: "P", Title: "总有功功率", Unit: "kW", Length: 3, Decimals: 4, Signed: true},
+		{DI: 0x02040000, Name: "Q", Title: "总无功功率", Unit: "kvar", Length: 3, Decimals: 4, Signed: true},
+		{DI: 0x02050000, Name: "S", Title: "总视在功率", Unit: "kVA", Length: 3, Decimals: 4, Signed: true},
+
+		{DI: 0x02060000, Name: "PF", Title: "总功率因数", Length: 2, Decimals: 3, Signed: true},
+	}
+
+	identifierMap = map[uint32]*Identifier{}
+)
+
+func init() {
+	for _, id := range DefaultIdentifiers {
+		identifierMap[id.DI] = id
+	}
+}
+
+//ParseIdentifier 解析数据标识，支持十六进制字符串如 "02010100" 或数据项名称如 "Ua"
+func ParseIdentifier(str string) (*Identifier, error) {
+	for _, id := range DefaultIdentifiers {
+		if id.Name == str {
+			return id, nil
+		}
+	}
+
+	v, err := strconv.ParseUint(str, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("dlt645: invalid data identifier: %s", str)
+	}
+
+	if id, ok := identifierMap[uint32(v)]; ok {
+		return id, nil
+	}
+
+	return nil, fmt.Errorf("dlt645: unsupported data identifier: %s", str)
+}
+
+//Bytes 返回DI0在前的数据标识
+func (id *Identifier) Bytes() []byte {
+	return []byte{byte(id.DI), byte(id.DI >> 8), byte(id.DI >> 16), byte(id.DI >> 24)}
+}
+
+//Decode 解码应答数据域（不含数据标识）
+func (id *Identifier) Decode(data []byte) (float64, error) {
+	if len(data) < id.Length {
+		return 0, ErrInvalidFrame
+	}
+	return DecodeBCD(data[:id.Length], id.Decimals, id.Signed)
+}
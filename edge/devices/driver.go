@@ -0,0 +1,41 @@
+package devices
+
+import (
+	"context"
+
+	"github.com/maritimusj/centrum/edge/devices/dlt645"
+	"github.com/maritimusj/centrum/edge/devices/measure"
+	"github.com/maritimusj/centrum/edge/devices/snmp"
+	"github.com/maritimusj/centrum/edge/lang"
+	"github.com/maritimusj/centrum/json_rpc"
+)
+
+//Driver ep6v2以外的设备驱动
+type Driver interface {
+	Connect(ctx context.Context, address string) error
+	IsConnected() bool
+	Close()
+	Reset()
+
+	GetStatus() lang.StrIndex
+	GetStatusTitle() string
+	GetBaseInfo() (map[string]interface{}, error)
+
+	//Gather 读取一次全部点位数据
+	Gather(ctx context.Context) ([]*measure.Data, error)
+	GetRealtimeData() ([]map[string]interface{}, error)
+
+	GetValue(tag string) (interface{}, error)
+	SetValue(tag string, v interface{}) error
+}
+
+func NewDriver(conf *json_rpc.Conf) (Driver, error) {
+	switch conf.Driver {
+	case json_rpc.DriverDLT645:
+		return dlt645.New(conf.Options)
+	case json_rpc.DriverSNMP:
+		return snmp.New(conf.Options)
+	default:
+		return nil, lang.Error(lang.ErrUnknownDriver, conf.Driver)
+	}
+}
@@ -42,12 +42,23 @@ type Device struct {
 
 	chNum        *CHNum.Data
 	readTimeData *realtime.Data
+
+	diag *modbus.Diagnostics
 }
 
 func New() *Device {
 	return &Device{
 		status: lang.Disconnected,
+		diag:   modbus.NewDiagnostics(),
+	}
+}
+
+//GetDiagnostics 设备的通讯诊断数据
+func (device *Device) GetDiagnostics() *modbus.Diagnostics {
+	if device != nil {
+		return device.diag
 	}
+	return nil
 }
 
 func (device *Device) SetConnector(connector Connector) {
@@ -104,10 +115,10 @@ func (device *Device) Connect(ctx context.Context, address string) error {
 
 	device.Reset(func() {
 		device.status = lang.Connected
-		handler := rawModbus.NewTCPClientHandlerFrom(conn)
+		handler := rawModbus.NewTCPClientHandlerFrom(device.diag.Conn(conn))
 		client := rawModbus.NewClient(handler)
 		device.handler = handler
-		device.client = &modbusWrapper{client: client}
+		device.client = &modbusWrapper{client: client, diag: device.diag}
 	})
 
 	return nil
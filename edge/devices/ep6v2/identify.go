@@ -0,0 +1,63 @@
+package ep6v2
+
+import (
+	"net"
+	"time"
+
+	rawModbus "github.com/maritimusj/modbus"
+)
+
+//probeClient 识别设备时使用，不重试，错误原样返回
+type probeClient struct {
+	client rawModbus.Client
+}
+
+func (c *probeClient) ReadCoils(address, quantity uint16) ([]byte, time.Duration, error) {
+	begin := time.Now()
+	result, err := c.client.ReadCoils(address, quantity)
+	return result, time.Now().Sub(begin), err
+}
+
+func (c *probeClient) ReadDiscreteInputs(address, quantity uint16) ([]byte, time.Duration, error) {
+	begin := time.Now()
+	result, err := c.client.ReadDiscreteInputs(address, quantity)
+	return result, time.Now().Sub(begin), err
+}
+
+func (c *probeClient) WriteSingleCoil(address, value uint16) ([]byte, time.Duration, error) {
+	begin := time.Now()
+	result, err := c.client.WriteSingleCoil(address, value)
+	return result, time.Now().Sub(begin), err
+}
+
+func (c *probeClient) ReadInputRegisters(address, quantity uint16) ([]byte, time.Duration, error) {
+	begin := time.Now()
+	result, err := c.client.ReadInputRegisters(address, quantity)
+	return result, time.Now().Sub(begin), err
+}
+
+func (c *probeClient) ReadHoldingRegisters(address, quantity uint16) ([]byte, time.Duration, error) {
+	begin := time.Now()
+	result, err := c.client.ReadHoldingRegisters(address, quantity)
+	return result, time.Now().Sub(begin), err
+}
+
+//Identify 读取连接上控制器的型号和网络地址，连接由调用者关闭
+func Identify(conn net.Conn, timeout time.Duration) (*Model, *Addr, error) {
+	handler := rawModbus.NewTCPClientHandlerFrom(conn)
+	handler.Timeout = timeout
+
+	client := &probeClient{client: rawModbus.NewClient(handler)}
+
+	model := &Model{}
+	if err := model.fetchData(client); err != nil {
+		return nil, nil, err
+	}
+
+	addr := &Addr{}
+	if err := addr.fetchData(client); err != nil {
+		return model, nil, err
+	}
+
+	return model, addr, nil
+}
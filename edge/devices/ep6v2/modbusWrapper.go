@@ -5,32 +5,38 @@ import (
 	"sync"
 	"time"
 
+	"github.com/maritimusj/centrum/edge/devices/modbus"
 	"github.com/maritimusj/centrum/util"
 
-	"github.com/maritimusj/modbus"
+	rawModbus "github.com/maritimusj/modbus"
 )
 
 type modbusWrapper struct {
-	client modbus.Client
+	client rawModbus.Client
+	diag   *modbus.Diagnostics
 	sync.Mutex
 }
 
-func (w *modbusWrapper) retry(fn func() ([]byte, error)) (result []byte, used time.Duration, err error) {
+func (w *modbusWrapper) retry(function byte, address uint16, fn func() ([]byte, error)) (result []byte, used time.Duration, err error) {
 	for i := 0; i < 3; i++ {
-		begin := time.Now()
+		w.diag.Begin()
 		result, err = fn()
+		used = w.diag.End(function, address, err)
 		if err != nil {
 			if e, ok := err.(net.Error); ok && (e.Temporary() || e.Timeout()) {
 				time.Sleep(time.Duration(util.Exponent(10, uint64(i+1))) * time.Millisecond)
 				continue
 			}
 
-			if e, ok := err.(modbus.Error); ok && e.Temporary() {
+			if e, ok := err.(rawModbus.Error); ok && e.Temporary() {
 				time.Sleep(time.Duration(util.Exponent(10, uint64(i+1))) * time.Millisecond)
 				continue
 			}
+
+			//其它错误不再重试
+			return nil, used, err
 		}
-		return result, time.Now().Sub(begin), nil
+		return result, used, nil
 	}
 	used = -1
 	return
@@ -39,7 +45,7 @@ func (w *modbusWrapper) retry(fn func() ([]byte, error)) (result []byte, used ti
 func (w *modbusWrapper) ReadCoils(address, quantity uint16) (results []byte, duration time.Duration, err error) {
 	w.Lock()
 	defer w.Unlock()
-	return w.retry(func() (bytes []byte, err error) {
+	return w.retry(rawModbus.FuncCodeReadCoils, address, func() (bytes []byte, err error) {
 		return w.client.ReadCoils(address, quantity)
 	})
 }
@@ -47,7 +53,7 @@ func (w *modbusWrapper) ReadCoils(address, quantity uint16) (results []byte, dur
 func (w *modbusWrapper) ReadDiscreteInputs(address, quantity uint16) (results []byte, duration time.Duration, err error) {
 	w.Lock()
 	defer w.Unlock()
-	return w.retry(func() (bytes []byte, err error) {
+	return w.retry(rawModbus.FuncCodeReadDiscreteInputs, address, func() (bytes []byte, err error) {
 		return w.client.ReadDiscreteInputs(address, quantity)
 	})
 }
@@ -55,7 +61,7 @@ func (w *modbusWrapper) ReadDiscreteInputs(address, quantity uint16) (results []
 func (w *modbusWrapper) WriteSingleCoil(address, value uint16) (results []byte, duration time.Duration, err error) {
 	w.Lock()
 	defer w.Unlock()
-	return w.retry(func() (bytes []byte, err error) {
+	return w.retry(rawModbus.FuncCodeWriteSingleCoil, address, func() (bytes []byte, err error) {
 		return w.client.WriteSingleCoil(address, value)
 	})
 }
@@ -63,7 +69,7 @@ func (w *modbusWrapper) WriteSingleCoil(address, value uint16) (results []byte,
 func (w *modbusWrapper) ReadInputRegisters(address, quantity uint16) (results []byte, duration time.Duration, err error) {
 	w.Lock()
 	defer w.Unlock()
-	return w.retry(func() (bytes []byte, err error) {
+	return w.retry(rawModbus.FuncCodeReadInputRegisters, address, func() (bytes []byte, err error) {
 		return w.client.ReadInputRegisters(address, quantity)
 	})
 }
@@ -71,7 +77,7 @@ func (w *modbusWrapper) ReadInputRegisters(address, quantity uint16) (results []
 func (w *modbusWrapper) ReadHoldingRegisters(address, quantity uint16) (results []byte, duration time.Duration, err error) {
 	w.Lock()
 	defer w.Unlock()
-	return w.retry(func() (bytes []byte, err error) {
+	return w.retry(rawModbus.FuncCodeReadHoldingRegisters, address, func() (bytes []byte, err error) {
 		return w.client.ReadHoldingRegisters(address, quantity)
 	})
 }
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/asaskevich/EventBus"
 	"github.com/maritimusj/centrum/edge/devices/measure"
@@ -33,18 +34,27 @@ const (
 	DevicePerfChanged   = "device:perf::changed"
 	MeasureDiscovered   = "measure::discovered"
 	MeasureAlarm        = "measure::alarm"
+	IdentityChanged     = "device:identity::changed"
+	DeviceLog           = "device::log"
 )
 
 func isHttpTooBusy() bool {
 	return len(__httpRequestCH) > 600
 }
 
+//QueueLength 等待发送到gate的回调请求数量
+func QueueLength() int {
+	return len(__httpRequestCH)
+}
+
 func Init(ctx context.Context) {
 	eventsMap := map[string]interface{}{
 		DeviceStatusChanged: OnDeviceStatusChanged,
 		DevicePerfChanged:   OnDevicePerfChanged,
 		MeasureDiscovered:   OnMeasureDiscovered,
 		MeasureAlarm:        OnMeasureAlarm,
+		IdentityChanged:     OnIdentityChanged,
+		DeviceLog:           OnDeviceLog,
 	}
 
 	for e, fn := range eventsMap {
@@ -154,3 +164,37 @@ func OnMeasureAlarm(conf *json_rpc.Conf, measureData *measure.Data) {
 		})
 	}
 }
+
+//OnIdentityChanged 首次记录设备身份或者发现身份不匹配时通知gate
+func OnIdentityChanged(conf *json_rpc.Conf, identity *json_rpc.Identity, mismatch bool) {
+	if conf.CallbackURL != "" {
+		HttpPost(conf.CallbackURL, map[string]interface{}{
+			"identity": map[string]interface{}{
+				"uid":      conf.UID,
+				"model":    identity.Model,
+				"version":  identity.Version,
+				"mac":      identity.MAC,
+				"ai":       identity.AI,
+				"ao":       identity.AO,
+				"di":       identity.DI,
+				"do":       identity.DO,
+				"vo":       identity.VO,
+				"mismatch": mismatch,
+			},
+		})
+	}
+}
+
+//OnDeviceLog 直接写入gate的设备日志，不受edge日志级别的限制
+func OnDeviceLog(conf *json_rpc.Conf, level string, msg string) {
+	if conf.CallbackURL != "" {
+		HttpPost(conf.CallbackURL, map[string]interface{}{
+			"log": map[string]interface{}{
+				"uid":   conf.UID,
+				"level": level,
+				"msg":   msg,
+				"time":  time.Now(),
+			},
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package devices
+
+import (
+	"testing"
+
+	"github.com/maritimusj/centrum/edge/devices/event"
+	"github.com/maritimusj/centrum/edge/lang"
+	"github.com/maritimusj/centrum/json_rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestMatchIdentity(t *testing.T) {
+	var published []bool
+	onChanged := func(conf *json_rpc.Conf, identity *json_rpc.Identity, mismatch bool) {
+		published = append(published, mismatch)
+	}
+	if err := event.Event.Subscribe(event.IdentityChanged, onChanged); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = event.Event.Unsubscribe(event.IdentityChanged, onChanged)
+	}()
+
+	adapter := &Adapter{
+		conf:   &json_rpc.Conf{UID: "1"},
+		logger: log.New(),
+	}
+
+	original := &json_rpc.Identity{Model: "EP6", Version: "2.0", MAC: "00:11:22:33:44:55", AI: 8, DO: 4}
+	replaced := &json_rpc.Identity{Model: "EP6", Version: "2.0", MAC: "00:11:22:33:44:66", AI: 8, DO: 4}
+
+	//首次连接时记录身份
+	if !adapter.matchIdentity(original) || adapter.getIdentity() == nil || *adapter.getIdentity() != *original {
+		t.Fatal("identity should be recorded on first connection")
+	}
+	if !adapter.matchIdentity(&json_rpc.Identity{Model: "EP6", Version: "2.0", MAC: "00:11:22:33:44:55", AI: 8, DO: 4}) {
+		t.Fatal("same identity should match")
+	}
+
+	//更换控制器后等待确认
+	if adapter.matchIdentity(replaced) {
+		t.Fatal("replaced controller should not match")
+	}
+	if adapter.getStatus() != lang.IdentityMismatch {
+		t.Fatal("status should be identity mismatch")
+	}
+	if *adapter.getIdentity() != *original {
+		t.Fatal("recorded identity should not be changed before acknowledgement")
+	}
+
+	//gate确认后下发新的身份
+	adapter.setIdentity(&json_rpc.Identity{Model: "EP6", Version: "2.0", MAC: "00:11:22:33:44:66", AI: 8, DO: 4})
+	if !adapter.matchIdentity(replaced) {
+		t.Fatal("acknowledged identity should match")
+	}
+	if adapter.getStatus() == lang.IdentityMismatch {
+		t.Fatal("mismatch should be cleared after acknowledgement")
+	}
+
+	if len(published) != 2 || published[0] || !published[1] {
+		t.Fatalf("unexpected identity events: %v", published)
+	}
+}
@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/maritimusj/centrum/edge/devices/InverseServer"
+	"github.com/maritimusj/centrum/edge/devices/event"
+	"github.com/maritimusj/centrum/edge/devices/poller"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "edge"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	//每次采集的用时
+	PollDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "poll_duration_seconds",
+		Help:      "Duration of a device poll.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"uid"})
+
+	//写入InfluxDB的数据点
+	PointsWritten = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "points_written_total",
+		Help:      "Number of points written to InfluxDB.",
+	}, []string{"uid"})
+
+	//没有写入InfluxDB而丢弃的数据点
+	PointsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "points_dropped_total",
+		Help:      "Number of points dropped before being written to InfluxDB.",
+	}, []string{"uid"})
+
+	InfluxDBWriteErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "influxdb_write_errors_total",
+		Help:      "Number of failed InfluxDB batch writes.",
+	}, []string{"uid"})
+)
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		PollDuration,
+		PointsWritten,
+		PointsDropped,
+		InfluxDBWriteErrors,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "callback_queue_length",
+			Help:      "Number of pending callback requests to the gate.",
+		}, func() float64 {
+			return float64(event.QueueLength())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "inverse_connections",
+			Help:      "Number of controllers connected to the inverse server and waiting to be activated.",
+		}, func() float64 {
+			return float64(len(InverseServer.Waiting()))
+		}),
+		&pollerCollector{},
+	)
+}
+
+//Register 注册其它的统计数据
+func Register(c prometheus.Collector) error {
+	return registry.Register(c)
+}
+
+//Remove 设备移除后删除相关的统计数据
+func Remove(uid string) {
+	PollDuration.DeleteLabelValues(uid)
+	PointsWritten.DeleteLabelValues(uid)
+	PointsDropped.DeleteLabelValues(uid)
+	InfluxDBWriteErrors.DeleteLabelValues(uid)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+var (
+	pollerWorkersDesc = prometheus.NewDesc(
+		namespace+"_poller_workers", "Number of poller workers.", nil, nil)
+	pollerInFlightDesc = prometheus.NewDesc(
+		namespace+"_poller_in_flight", "Number of device requests in flight.", nil, nil)
+	pollerQueuedDesc = prometheus.NewDesc(
+		namespace+"_poller_queued", "Number of device requests waiting in the queue.", []string{"priority"}, nil)
+	pollerRequestsDesc = prometheus.NewDesc(
+		namespace+"_poller_requests_total", "Number of device requests started.", []string{"priority"}, nil)
+	pollerDroppedDesc = prometheus.NewDesc(
+		namespace+"_poller_dropped_total", "Number of device requests dropped from the queue.", []string{"priority"}, nil)
+	pollerWaitDesc = prometheus.NewDesc(
+		namespace+"_poller_wait_seconds_total", "Total time device requests waited in the queue.", []string{"priority"}, nil)
+)
+
+//pollerCollector 设备访问队列的统计数据
+type pollerCollector struct{}
+
+func (c *pollerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pollerWorkersDesc
+	ch <- pollerInFlightDesc
+	ch <- pollerQueuedDesc
+	ch <- pollerRequestsDesc
+	ch <- pollerDroppedDesc
+	ch <- pollerWaitDesc
+}
+
+func (c *pollerCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := poller.GetStats()
+
+	ch <- prometheus.MustNewConstMetric(pollerWorkersDesc, prometheus.GaugeValue, float64(stats.Workers))
+	ch <- prometheus.MustNewConstMetric(pollerInFlightDesc, prometheus.GaugeValue, float64(stats.InFlight))
+
+	for priority, s := range stats.Wait {
+		ch <- prometheus.MustNewConstMetric(pollerQueuedDesc, prometheus.GaugeValue, float64(s.Queued), priority)
+		ch <- prometheus.MustNewConstMetric(pollerRequestsDesc, prometheus.CounterValue, float64(s.Count), priority)
+		ch <- prometheus.MustNewConstMetric(pollerDroppedDesc, prometheus.CounterValue, float64(s.Dropped), priority)
+		ch <- prometheus.MustNewConstMetric(pollerWaitDesc, prometheus.CounterValue, s.Total.Seconds(), priority)
+	}
+}
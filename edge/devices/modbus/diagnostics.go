@@ -0,0 +1,281 @@
+package modbus
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	rawModbus "github.com/maritimusj/modbus"
+)
+
+const (
+	//用于计算延时百分位的样本数量
+	latencySamples = 256
+	//一次最多抓取的帧数量
+	MaxCaptureFrames = 100
+)
+
+//Frame 一次请求和应答的原始数据
+type Frame struct {
+	Time     time.Time `json:"time"`
+	Function byte      `json:"function"`
+	Address  uint16    `json:"address"`
+	Request  string    `json:"request"`
+	Response string    `json:"response"`
+	Latency  float64   `json:"latency"`
+	Error    string    `json:"error,omitempty"`
+}
+
+//Exception 设备返回的Modbus异常码统计
+type Exception struct {
+	Function byte   `json:"function"`
+	Address  uint16 `json:"address"`
+	Code     byte   `json:"code"`
+	Count    uint64 `json:"count"`
+}
+
+type exceptionKey struct {
+	function byte
+	address  uint16
+	code     byte
+}
+
+//Stats 通讯统计，延时单位为毫秒
+type Stats struct {
+	Connects       uint64       `json:"connects"`
+	Requests       uint64       `json:"requests"`
+	Success        uint64       `json:"success"`
+	Timeouts       uint64       `json:"timeouts"`
+	ProtocolErrors uint64       `json:"protocolErrors"`
+	Exceptions     []*Exception `json:"exceptions"`
+	OtherErrors    uint64       `json:"otherErrors"`
+	LastError      string       `json:"lastError,omitempty"`
+	LastErrorAt    *time.Time   `json:"lastErrorAt,omitempty"`
+	AvgLatency     float64      `json:"avgLatency"`
+	P50Latency     float64      `json:"p50Latency"`
+	P95Latency     float64      `json:"p95Latency"`
+	P99Latency     float64      `json:"p99Latency"`
+	Capturing      int          `json:"capturing"`
+	Frames         []*Frame     `json:"frames"`
+}
+
+//Diagnostics 设备的通讯诊断数据，设备重新连接后继续累计
+type Diagnostics struct {
+	connects       uint64
+	requests       uint64
+	success        uint64
+	timeouts       uint64
+	protocolErrors uint64
+	otherErrors    uint64
+	exceptions     map[exceptionKey]uint64
+	lastError      string
+	lastErrorAt    time.Time
+
+	totalLatency time.Duration
+	latencies    []time.Duration
+	pos          int
+
+	//剩余需要抓取的帧数量
+	capture int
+	frames  []*Frame
+	begin   time.Time
+	tx      bytes.Buffer
+	rx      bytes.Buffer
+
+	mu sync.Mutex
+}
+
+func NewDiagnostics() *Diagnostics {
+	return &Diagnostics{
+		exceptions: map[exceptionKey]uint64{},
+		latencies:  make([]time.Duration, 0, latencySamples),
+	}
+}
+
+//Conn 包装设备连接，抓包时记录收发的原始数据
+func (d *Diagnostics) Conn(conn net.Conn) net.Conn {
+	d.mu.Lock()
+	d.connects++
+	d.mu.Unlock()
+
+	return &traceConn{Conn: conn, diag: d}
+}
+
+//Capture 抓取接下来n次请求的原始数据，之前抓取的数据会被清除
+func (d *Diagnostics) Capture(n int) {
+	if n > MaxCaptureFrames {
+		n = MaxCaptureFrames
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.capture = n
+	d.frames = nil
+}
+
+//Begin 开始一次请求
+func (d *Diagnostics) Begin() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.begin = time.Now()
+	d.tx.Reset()
+	d.rx.Reset()
+}
+
+//End 结束一次请求，统计结果并返回用时
+func (d *Diagnostics) End(function byte, address uint16, err error) time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	used := time.Now().Sub(d.begin)
+
+	d.requests++
+	if err == nil {
+		d.success++
+		d.totalLatency += used
+		if len(d.latencies) < latencySamples {
+			d.latencies = append(d.latencies, used)
+		} else {
+			d.latencies[d.pos] = used
+		}
+		d.pos = (d.pos + 1) % latencySamples
+	} else {
+		switch e := err.(type) {
+		case *rawModbus.ModbusError:
+			d.exceptions[exceptionKey{
+				function: e.FunctionCode &^ 0x80,
+				address:  address,
+				code:     e.ExceptionCode,
+			}]++
+		case net.Error:
+			if e.Timeout() {
+				d.timeouts++
+			} else {
+				d.otherErrors++
+			}
+		case rawModbus.Error:
+			d.protocolErrors++
+		default:
+			d.otherErrors++
+		}
+		d.lastError = err.Error()
+		d.lastErrorAt = time.Now()
+	}
+
+	if d.capture > 0 {
+		d.capture--
+		frame := &Frame{
+			Time:     d.begin,
+			Function: function,
+			Address:  address,
+			Request:  hex.EncodeToString(d.tx.Bytes()),
+			Response: hex.EncodeToString(d.rx.Bytes()),
+			Latency:  milliseconds(used),
+		}
+		if err != nil {
+			frame.Error = err.Error()
+		}
+		d.frames = append(d.frames, frame)
+	}
+
+	return used
+}
+
+func (d *Diagnostics) Stats() *Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := &Stats{
+		Connects:       d.connects,
+		Requests:       d.requests,
+		Success:        d.success,
+		Timeouts:       d.timeouts,
+		ProtocolErrors: d.protocolErrors,
+		Exceptions:     make([]*Exception, 0, len(d.exceptions)),
+		OtherErrors:    d.otherErrors,
+		LastError:      d.lastError,
+		Capturing:      d.capture,
+		Frames:         make([]*Frame, len(d.frames)),
+	}
+
+	if !d.lastErrorAt.IsZero() {
+		t := d.lastErrorAt
+		stats.LastErrorAt = &t
+	}
+
+	for key, n := range d.exceptions {
+		stats.Exceptions = append(stats.Exceptions, &Exception{
+			Function: key.function,
+			Address:  key.address,
+			Code:     key.code,
+			Count:    n,
+		})
+	}
+	sort.Slice(stats.Exceptions, func(i, j int) bool {
+		return stats.Exceptions[i].Count > stats.Exceptions[j].Count
+	})
+
+	if d.success > 0 {
+		stats.AvgLatency = milliseconds(d.totalLatency / time.Duration(d.success))
+	}
+
+	if len(d.latencies) > 0 {
+		samples := append([]time.Duration{}, d.latencies...)
+		sort.Slice(samples, func(i, j int) bool {
+			return samples[i] < samples[j]
+		})
+		stats.P50Latency = milliseconds(percentile(samples, 50))
+		stats.P95Latency = milliseconds(percentile(samples, 95))
+		stats.P99Latency = milliseconds(percentile(samples, 99))
+	}
+
+	copy(stats.Frames, d.frames)
+	return stats
+}
+
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+//traceConn 抓包时记录连接上收发的数据
+type traceConn struct {
+	net.Conn
+	diag *Diagnostics
+}
+
+func (c *traceConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.diag.mu.Lock()
+		if c.diag.capture > 0 {
+			c.diag.tx.Write(b[:n])
+		}
+		c.diag.mu.Unlock()
+	}
+	return n, err
+}
+
+func (c *traceConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.diag.mu.Lock()
+		if c.diag.capture > 0 {
+			c.diag.rx.Write(b[:n])
+		}
+		c.diag.mu.Unlock()
+	}
+	return n, err
+}
@@ -0,0 +1,48 @@
+package modbus
+
+import (
+	"net"
+	"testing"
+
+	rawModbus "github.com/maritimusj/modbus"
+)
+
+func TestDiagnostics(t *testing.T) {
+	d := NewDiagnostics()
+
+	client, server := net.Pipe()
+	defer server.Close()
+
+	conn := d.Conn(client)
+	go func() {
+		buf := make([]byte, 4)
+		_, _ = server.Read(buf)
+		_, _ = server.Write([]byte{0x01, 0x02})
+	}()
+
+	d.Capture(1)
+
+	d.Begin()
+	_, _ = conn.Write([]byte{0x0a, 0x0b, 0x0c, 0x0d})
+	_, _ = conn.Read(make([]byte, 2))
+	d.End(rawModbus.FuncCodeReadCoils, 10, nil)
+
+	d.Begin()
+	d.End(rawModbus.FuncCodeReadCoils, 10, &rawModbus.ModbusError{FunctionCode: 0x81, ExceptionCode: 2})
+
+	d.Begin()
+	d.End(rawModbus.FuncCodeReadCoils, 10, rawModbus.NewModbusProtocolError("bad length"))
+
+	stats := d.Stats()
+	if stats.Connects != 1 || stats.Requests != 3 || stats.Success != 1 || stats.ProtocolErrors != 1 {
+		t.Fatal("invalid counters:", stats)
+	}
+
+	if len(stats.Exceptions) != 1 || stats.Exceptions[0].Function != 1 || stats.Exceptions[0].Code != 2 {
+		t.Fatal("invalid exceptions:", stats.Exceptions)
+	}
+
+	if len(stats.Frames) != 1 || stats.Frames[0].Request != "0a0b0c0d" || stats.Frames[0].Response != "0102" {
+		t.Fatal("invalid frames:", stats.Frames)
+	}
+}
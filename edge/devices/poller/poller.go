@@ -0,0 +1,242 @@
+package poller
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type Priority int
+
+const (
+	//后台定时采集
+	Background Priority = iota
+	//gate发起的读写请求，优先于后台采集
+	Interactive
+)
+
+const (
+	//默认同时访问设备的数量
+	DefaultWorkers = 16
+	//默认每个设备同时进行的请求数量
+	DefaultMaxInFlight = 1
+)
+
+var (
+	ErrCancelled = errors.New("poll request cancelled")
+
+	defaultPoller *Poller
+	once          sync.Once
+)
+
+type task struct {
+	key      string
+	fn       func() error
+	enqueued time.Time
+	done     <-chan struct{}
+	result   chan error
+}
+
+//WaitStats 请求在队列中的等待时间
+type WaitStats struct {
+	Count   uint64        `json:"count"`
+	Total   time.Duration `json:"total"`
+	Max     time.Duration `json:"max"`
+	Last    time.Duration `json:"last"`
+	Queued  int           `json:"queued"`
+	Dropped uint64        `json:"dropped"`
+}
+
+type Stats struct {
+	Workers     int                   `json:"workers"`
+	MaxInFlight int                   `json:"maxInFlight"`
+	InFlight    int                   `json:"inFlight"`
+	Wait        map[string]*WaitStats `json:"wait"`
+	Devices     map[string]int        `json:"devices"`
+}
+
+//Poller 所有设备共享的访问队列，限制同时访问设备的总数量和每个设备的请求数量
+type Poller struct {
+	workers     int
+	maxInFlight int
+
+	queues   [2][]*task
+	inFlight map[string]int
+	total    int
+	stats    [2]WaitStats
+
+	mu   sync.Mutex
+	cond *sync.Cond
+}
+
+func New(workers, maxInFlight int) *Poller {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if maxInFlight <= 0 {
+		maxInFlight = DefaultMaxInFlight
+	}
+
+	p := &Poller{
+		workers:     workers,
+		maxInFlight: maxInFlight,
+		inFlight:    map[string]int{},
+	}
+	p.cond = sync.NewCond(&p.mu)
+
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+//Init 按配置创建默认的访问队列，需要在启动设备之前调用，否则使用默认配置
+func Init(workers, maxInFlight int) {
+	once.Do(func() {
+		defaultPoller = New(workers, maxInFlight)
+	})
+}
+
+func Default() *Poller {
+	Init(DefaultWorkers, DefaultMaxInFlight)
+	return defaultPoller
+}
+
+func Do(done <-chan struct{}, key string, priority Priority, fn func() error) error {
+	return Default().Do(done, key, priority, fn)
+}
+
+func GetStats() *Stats {
+	return Default().Stats()
+}
+
+//Jitter 返回一个随机的启动延时，避免所有设备同时开始采集
+func Jitter(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(interval)))
+}
+
+//Do 排队执行fn并等待结果，done关闭时放弃排队中的请求
+func (p *Poller) Do(done <-chan struct{}, key string, priority Priority, fn func() error) error {
+	t := &task{
+		key:      key,
+		fn:       fn,
+		enqueued: time.Now(),
+		done:     done,
+		result:   make(chan error, 1),
+	}
+
+	p.mu.Lock()
+	p.queues[priority] = append(p.queues[priority], t)
+	p.mu.Unlock()
+	p.cond.Signal()
+
+	select {
+	case err := <-t.result:
+		return err
+	case <-done:
+		return ErrCancelled
+	}
+}
+
+//next 取出下一个可以执行的请求，交互请求优先，同一个设备的请求按顺序执行
+func (p *Poller) next() *task {
+	for {
+		for priority := Interactive; priority >= Background; priority-- {
+			queue := p.queues[priority]
+			for i, t := range queue {
+				select {
+				case <-t.done:
+					//请求方已经放弃
+					p.queues[priority] = append(queue[:i:i], queue[i+1:]...)
+					p.stats[priority].Dropped++
+					return nil
+				default:
+				}
+
+				if p.inFlight[t.key] >= p.maxInFlight {
+					continue
+				}
+
+				p.queues[priority] = append(queue[:i:i], queue[i+1:]...)
+
+				wait := time.Since(t.enqueued)
+				s := &p.stats[priority]
+				s.Count++
+				s.Total += wait
+				s.Last = wait
+				if wait > s.Max {
+					s.Max = wait
+				}
+
+				p.inFlight[t.key]++
+				p.total++
+				return t
+			}
+		}
+		p.cond.Wait()
+	}
+}
+
+func (p *Poller) work() {
+	for {
+		p.mu.Lock()
+		t := p.next()
+		p.mu.Unlock()
+
+		if t == nil {
+			continue
+		}
+
+		t.result <- run(t.fn)
+
+		p.mu.Lock()
+		p.inFlight[t.key]--
+		if p.inFlight[t.key] <= 0 {
+			delete(p.inFlight, t.key)
+		}
+		p.total--
+		p.mu.Unlock()
+
+		//同一个设备的其它请求可能在等待
+		p.cond.Broadcast()
+	}
+}
+
+func run(fn func() error) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("poll panic: %v", e)
+		}
+	}()
+	return fn()
+}
+
+func (p *Poller) Stats() *Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := &Stats{
+		Workers:     p.workers,
+		MaxInFlight: p.maxInFlight,
+		InFlight:    p.total,
+		Wait:        map[string]*WaitStats{},
+		Devices:     map[string]int{},
+	}
+
+	for priority, name := range []string{"background", "interactive"} {
+		s := p.stats[priority]
+		s.Queued = len(p.queues[priority])
+		stats.Wait[name] = &s
+	}
+
+	for key, n := range p.inFlight {
+		stats.Devices[key] = n
+	}
+
+	return stats
+}
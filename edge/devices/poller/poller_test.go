@@ -0,0 +1,86 @@
+package poller
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPriority(t *testing.T) {
+	p := New(1, 1)
+	done := make(chan struct{})
+
+	//占住唯一的worker，让后面的请求排队
+	block := make(chan struct{})
+	go p.Do(done, "a", Background, func() error {
+		<-block
+		return nil
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	var (
+		mu    sync.Mutex
+		order []Priority
+		wg    sync.WaitGroup
+	)
+
+	for _, priority := range []Priority{Background, Interactive} {
+		wg.Add(1)
+		go func(priority Priority) {
+			defer wg.Done()
+			_ = p.Do(done, "b", priority, func() error {
+				mu.Lock()
+				order = append(order, priority)
+				mu.Unlock()
+				return nil
+			})
+		}(priority)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(block)
+	wg.Wait()
+
+	if len(order) != 2 || order[0] != Interactive {
+		t.Fatal("expected interactive first, got:", order)
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	p := New(4, 1)
+	done := make(chan struct{})
+
+	var (
+		mu       sync.Mutex
+		running  int
+		maxValue int
+		wg       sync.WaitGroup
+	)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = p.Do(done, "a", Background, func() error {
+				mu.Lock()
+				running++
+				if running > maxValue {
+					maxValue = running
+				}
+				mu.Unlock()
+
+				time.Sleep(time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	if maxValue != 1 {
+		t.Fatal("expected 1 request in flight, got:", maxValue)
+	}
+}
@@ -0,0 +1,85 @@
+package devices
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/maritimusj/centrum/json_rpc"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	//向gate注册并上报负载的间隔
+	registerInterval = 10 * time.Second
+	registerTimeout  = 5 * time.Second
+)
+
+//StartRegister 定时向gate注册，url为gate访问当前edge的rpc地址
+func (runner *Runner) StartRegister(gateURL, url, token string) {
+	client := &http.Client{
+		Timeout: registerTimeout,
+	}
+
+	go func() {
+		ticker := time.NewTicker(registerInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := runner.register(client, gateURL, url, token); err != nil {
+				log.Warningln("[register]", gateURL, err)
+			}
+
+			select {
+			case <-runner.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (runner *Runner) register(client *http.Client, gateURL, url, token string) error {
+	load, err := runner.GetLoad()
+	if err != nil {
+		return err
+	}
+
+	data, err := jsoniter.Marshal(&json_rpc.Registration{
+		URL:   url,
+		Token: token,
+		Load:  load,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(gateURL+"/v1/web/edge/register", "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+
+	res := gjson.ParseBytes(body)
+	if !res.Get("status").Bool() {
+		return errors.New(res.Get("data.msg").Str)
+	}
+
+	return nil
+}
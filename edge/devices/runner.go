@@ -12,16 +12,32 @@ import (
 	"github.com/maritimusj/centrum/edge/logStore"
 
 	"github.com/maritimusj/centrum/edge/devices/InverseServer"
+	"github.com/maritimusj/centrum/edge/devices/discover"
 	"github.com/maritimusj/centrum/edge/devices/ep6v2"
 	"github.com/maritimusj/centrum/edge/devices/measure"
+	"github.com/maritimusj/centrum/edge/devices/metrics"
+	"github.com/maritimusj/centrum/edge/devices/modbus"
+	"github.com/maritimusj/centrum/edge/devices/poller"
+	"github.com/maritimusj/centrum/edge/devices/realtime"
+	"github.com/maritimusj/centrum/edge/devices/schedule"
+	"github.com/maritimusj/centrum/edge/devices/totalizer"
+	"github.com/maritimusj/centrum/edge/devices/virtual"
 	"github.com/maritimusj/centrum/edge/lang"
 	"github.com/maritimusj/centrum/json_rpc"
+	"github.com/shirou/gopsutil/cpu"
 	log "github.com/sirupsen/logrus"
 
 	influx "github.com/influxdata/influxdb1-client/v2"
 	httpLogStore "github.com/maritimusj/centrum/edge/logStore/http"
 )
 
+const (
+	//关闭脉冲输出失败后重试的间隔
+	pulseRetryDelay = 5 * time.Second
+	//保存累计器状态的间隔，edge异常退出时最多丢失这段时间的累计值
+	totalizerSaveInterval = time.Minute
+)
+
 type Runner struct {
 	ctx      context.Context
 	adapters sync.Map
@@ -33,6 +49,8 @@ func New() *Runner {
 	runner := &Runner{
 		ctx: context.Background(),
 	}
+
+	_ = metrics.Register(&collector{runner: runner})
 	return runner
 }
 
@@ -48,38 +66,72 @@ func (runner *Runner) StartInverseServer(conf *json_rpc.InverseConf) error {
 	return InverseServer.Start(runner.ctx, conf.Address, conf.Port)
 }
 
+//do 通过共享的访问队列访问设备，adapter关闭时放弃排队
+func (runner *Runner) do(adapter *Adapter, priority poller.Priority, fn func() error) error {
+	return poller.Do(adapter.done, adapter.conf.UID, priority, fn)
+}
+
 func (runner *Runner) GetBaseInfo(uid string) (map[string]interface{}, error) {
 	if v, ok := runner.adapters.Load(uid); ok {
 		adapter := v.(*Adapter)
 
-		baseInfo := make(map[string]interface{})
+		var baseInfo map[string]interface{}
+		err := runner.do(adapter, poller.Interactive, func() (err error) {
+			baseInfo, err = runner.getBaseInfo(adapter)
+			return
+		})
 
-		model, err := adapter.device.GetModel()
-		if err != nil {
-			return nil, err
-		}
+		return baseInfo, err
+	}
 
-		baseInfo["model"] = model.ID
-		baseInfo["version"] = model.Version
-		baseInfo["title"] = model.Title
+	return nil, lang.Error(lang.ErrDeviceNotExists)
+}
 
-		addr, err := adapter.device.GetAddr()
+func (runner *Runner) getBaseInfo(adapter *Adapter) (map[string]interface{}, error) {
+	if adapter.driver != nil {
+		baseInfo, err := adapter.driver.GetBaseInfo()
 		if err != nil {
 			return nil, err
 		}
 
-		baseInfo["addr"] = addr.Ip.String() + "/" + addr.Mask.String()
-		baseInfo["mac"] = addr.Mac.String()
-
 		baseInfo["status"] = map[string]interface{}{
-			"index": adapter.device.GetStatus(),
-			"title": adapter.device.GetStatusTitle(),
+			"index": adapter.driver.GetStatus(),
+			"title": adapter.driver.GetStatusTitle(),
 		}
 
 		return baseInfo, nil
 	}
 
-	return nil, lang.Error(lang.ErrDeviceNotExists)
+	device := adapter.getDevice()
+	if device == nil {
+		return nil, lang.Error(lang.ErrDeviceNotExists)
+	}
+
+	baseInfo := make(map[string]interface{})
+
+	model, err := device.GetModel()
+	if err != nil {
+		return nil, err
+	}
+
+	baseInfo["model"] = model.ID
+	baseInfo["version"] = model.Version
+	baseInfo["title"] = model.Title
+
+	addr, err := device.GetAddr()
+	if err != nil {
+		return nil, err
+	}
+
+	baseInfo["addr"] = addr.Ip.String() + "/" + addr.Mask.String()
+	baseInfo["mac"] = addr.Mac.String()
+
+	baseInfo["status"] = map[string]interface{}{
+		"index": device.GetStatus(),
+		"title": device.GetStatusTitle(),
+	}
+
+	return baseInfo, nil
 }
 
 func (runner *Runner) needRestartAdapter(conf *json_rpc.Conf, newConf *json_rpc.Conf) bool {
@@ -88,7 +140,9 @@ func (runner *Runner) needRestartAdapter(conf *json_rpc.Conf, newConf *json_rpc.
 		conf.InfluxDBUserName != newConf.InfluxDBUserName ||
 		conf.InfluxDBPassword != newConf.InfluxDBPassword ||
 		conf.DB != newConf.DB ||
-		conf.CallbackURL != newConf.CallbackURL
+		conf.CallbackURL != newConf.CallbackURL ||
+		conf.Driver != newConf.Driver ||
+		conf.Options != newConf.Options
 }
 
 func (runner *Runner) Restart() {
@@ -100,6 +154,25 @@ func (runner *Runner) Restart() {
 func (runner *Runner) Active(conf *json_rpc.Conf) error {
 	log.Traceln("active:", conf.UID, conf.Address)
 
+	channels, err := virtual.Compile(conf.Virtual)
+	if err != nil {
+		return err
+	}
+
+	totalizers := make([]*totalizer.Totalizer, 0, len(conf.Totalizers))
+	for _, ch := range conf.Totalizers {
+		t, err := totalizer.New(ch)
+		if err != nil {
+			return err
+		}
+		totalizers = append(totalizers, t)
+	}
+
+	schedules, err := schedule.Compile(conf.Schedules)
+	if err != nil {
+		return err
+	}
+
 	select {
 	case <-runner.ctx.Done():
 		return runner.ctx.Err()
@@ -114,6 +187,20 @@ func (runner *Runner) Active(conf *json_rpc.Conf) error {
 			adapter.Close()
 		} else {
 			adapter.conf.Interval = conf.Interval
+			adapter.conf.Virtual = conf.Virtual
+			adapter.setVirtualChannels(channels)
+
+			adapter.conf.Totalizers = conf.Totalizers
+			adapter.setTotalizers(totalizers)
+
+			adapter.conf.Schedules = conf.Schedules
+			adapter.setSchedules(schedules)
+
+			//gate确认了新的设备身份
+			if conf.Identity != nil {
+				adapter.setIdentity(conf.Identity)
+			}
+
 			if adapter.conf.LogLevel != conf.LogLevel {
 				adapter.conf.LogLevel = conf.LogLevel
 
@@ -124,7 +211,7 @@ func (runner *Runner) Active(conf *json_rpc.Conf) error {
 				adapter.logger.SetLevel(level)
 			}
 
-			adapter.OnDeviceStatusChanged(adapter.device.GetStatus())
+			adapter.OnDeviceStatusChanged(adapter.getStatus())
 			return nil
 		}
 	}
@@ -157,7 +244,6 @@ func (runner *Runner) Active(conf *json_rpc.Conf) error {
 	}
 
 	adapter := &Adapter{
-		device:         ep6v2.New(),
 		conf:           conf,
 		logger:         logger,
 		loggerStore:    loggerHook,
@@ -166,7 +252,24 @@ func (runner *Runner) Active(conf *json_rpc.Conf) error {
 		done:           make(chan struct{}),
 	}
 
-	err := runner.Serve(adapter)
+	if conf.Driver == json_rpc.DriverEP6v2 {
+		adapter.device = ep6v2.New()
+	} else {
+		driver, err := NewDriver(conf)
+		if err != nil {
+			if loggerHook != nil {
+				loggerHook.Close()
+			}
+			return err
+		}
+		adapter.driver = driver
+	}
+
+	adapter.setVirtualChannels(channels)
+	adapter.setTotalizers(totalizers)
+	adapter.setSchedules(schedules)
+
+	err = runner.Serve(adapter)
 	if err != nil {
 		return err
 	}
@@ -181,7 +284,20 @@ func (runner *Runner) Active(conf *json_rpc.Conf) error {
 func (runner *Runner) GetValue(ch *json_rpc.CH) (retVal interface{}, err error) {
 	if v, ok := runner.adapters.Load(ch.UID); ok {
 		adapter := v.(*Adapter)
-		return adapter.device.GetCHValue(ch.Tag)
+		if values := adapter.getExtraData(ch.Tag); len(values) > 0 {
+			return values[0], nil
+		}
+		err = runner.do(adapter, poller.Interactive, func() (err error) {
+			if adapter.driver != nil {
+				retVal, err = adapter.driver.GetValue(ch.Tag)
+			} else if device := adapter.getDevice(); device != nil {
+				retVal, err = device.GetCHValue(ch.Tag)
+			} else {
+				err = lang.Error(lang.ErrDeviceNotExists)
+			}
+			return
+		})
+		return retVal, err
 	}
 	return nil, lang.Error(lang.ErrDeviceNotExists)
 }
@@ -189,149 +305,367 @@ func (runner *Runner) GetValue(ch *json_rpc.CH) (retVal interface{}, err error)
 func (runner *Runner) SetValue(val *json_rpc.Value) error {
 	if v, ok := runner.adapters.Load(val.UID); ok {
 		adapter := v.(*Adapter)
-		return adapter.device.SetCHValue(val.Tag, val.V)
+		return runner.do(adapter, poller.Interactive, func() error {
+			return runner.setCHValue(adapter, val.Tag, val.V)
+		})
 	}
 	return lang.Error(lang.ErrDeviceNotExists)
 }
 
-func (runner *Runner) GetRealtimeData(uid string) ([]map[string]interface{}, error) {
+func (runner *Runner) setCHValue(adapter *Adapter, tag string, val interface{}) error {
+	if len(adapter.getExtraData(tag)) > 0 {
+		return lang.Error(lang.ErrCHReadOnly)
+	}
+	if adapter.driver != nil {
+		return adapter.driver.SetValue(tag, val)
+	}
+	device := adapter.getDevice()
+	if device == nil {
+		return lang.Error(lang.ErrDeviceNotExists)
+	}
+	return device.SetCHValue(tag, val)
+}
+
+//Pulse 开启DO，到时间后由edge关闭
+func (runner *Runner) Pulse(pulse *json_rpc.Pulse) error {
+	if v, ok := runner.adapters.Load(pulse.UID); ok {
+		adapter := v.(*Adapter)
+		if pulse.Seconds <= 0 {
+			return lang.Error(lang.ErrInvalidPulse, pulse.Seconds)
+		}
+
+		err := runner.do(adapter, poller.Interactive, func() error {
+			return runner.setCHValue(adapter, pulse.Tag, true)
+		})
+		if err != nil {
+			return err
+		}
+
+		runner.startPulse(adapter, pulse.Tag, time.Now().Add(time.Duration(pulse.Seconds)*time.Second))
+		adapter.OnSwitch(pulse.Tag, true, lang.SwitchByPulse)
+		return nil
+	}
+	return lang.Error(lang.ErrDeviceNotExists)
+}
+
+//GetDiagnostics 获取设备的通讯诊断数据和抓取的原始帧
+func (runner *Runner) GetDiagnostics(uid string) (interface{}, error) {
 	if v, ok := runner.adapters.Load(uid); ok {
 		adapter := v.(*Adapter)
-		r, err := adapter.device.GetRealTimeData()
+		device := adapter.getDevice()
+		if adapter.driver != nil || device == nil {
+			return nil, lang.Error(lang.ErrDiagnosticsNotSupported)
+		}
+		return device.GetDiagnostics().Stats(), nil
+	}
+	return nil, lang.Error(lang.ErrDeviceNotExists)
+}
+
+//Capture 抓取设备接下来指定数量的请求和应答
+func (runner *Runner) Capture(capture *json_rpc.Capture) error {
+	if v, ok := runner.adapters.Load(capture.UID); ok {
+		adapter := v.(*Adapter)
+		device := adapter.getDevice()
+		if adapter.driver != nil || device == nil {
+			return lang.Error(lang.ErrDiagnosticsNotSupported)
+		}
+		if capture.Num <= 0 || capture.Num > modbus.MaxCaptureFrames {
+			return lang.Error(lang.ErrInvalidCaptureNum, capture.Num)
+		}
+		device.GetDiagnostics().Capture(capture.Num)
+		return nil
+	}
+	return lang.Error(lang.ErrDeviceNotExists)
+}
+
+//GetLoad 当前的设备数量、平均采集用时和CPU占用，gate根据负载分配设备
+func (runner *Runner) GetLoad() (*json_rpc.Load, error) {
+	var (
+		total   int
+		polled  int
+		elapsed time.Duration
+	)
+
+	runner.adapters.Range(func(key, v interface{}) bool {
+		adapter := v.(*Adapter)
+		total++
+
+		adapter.mu.RLock()
+		if adapter.pollDuration > 0 {
+			polled++
+			elapsed += adapter.pollDuration
+		}
+		adapter.mu.RUnlock()
+		return true
+	})
+
+	load := &json_rpc.Load{
+		Adapters: total,
+	}
+
+	if polled > 0 {
+		load.PollLatency = float64(elapsed.Milliseconds()) / float64(polled)
+	}
+
+	if percent, err := cpu.Percent(0, false); err == nil && len(percent) > 0 {
+		load.CPU = percent[0]
+	}
+
+	return load, nil
+}
+
+//startPulse 到时间后关闭脉冲输出，结束时间保存下来，edge重启后继续
+func (runner *Runner) startPulse(adapter *Adapter, tag string, deadline time.Time) {
+	if err := schedule.SavePulse(adapter.conf.UID, tag, deadline); err != nil {
+		adapter.logger.Errorln(err)
+	}
+
+	adapter.setPulse(tag, time.AfterFunc(time.Until(deadline), func() {
+		runner.endPulse(adapter, tag)
+	}))
+}
+
+//endPulse 关闭脉冲输出，失败时一直重试到成功为止
+func (runner *Runner) endPulse(adapter *Adapter, tag string) {
+	if adapter.IsDone() {
+		return
+	}
+
+	err := runner.do(adapter, poller.Background, func() error {
+		return runner.setCHValue(adapter, tag, false)
+	})
+	if err != nil {
+		adapter.logger.Errorln(err)
+		adapter.retryPulse(tag, pulseRetryDelay)
+		return
+	}
+
+	adapter.removePulse(tag)
+	if err := schedule.RemovePulse(adapter.conf.UID, tag); err != nil {
+		adapter.logger.Errorln(err)
+	}
+	adapter.OnSwitch(tag, false, lang.SwitchByPulse)
+}
+
+//resumePulses 继续adapter重新启动前没有结束的脉冲输出，已经到期的立即关闭
+func (runner *Runner) resumePulses(adapter *Adapter) {
+	for tag, deadline := range schedule.LoadPulses(adapter.conf.UID) {
+		if !adapter.hasPulse(tag) {
+			runner.startPulse(adapter, tag, deadline)
+		}
+	}
+}
+
+//runSchedules 执行定时开关
+func (runner *Runner) runSchedules(adapter *Adapter) {
+	now := time.Now()
+
+	for _, s := range adapter.getSchedules() {
+		//脉冲输出期间不执行定时
+		if adapter.hasPulse(s.Tag) {
+			continue
+		}
+
+		//只在状态改变时输出，不覆盖两次改变之间的手动操作
+		state := s.State(now)
+		if last, ok := adapter.getScheduleState(s.Tag); ok && last == state {
+			continue
+		}
+
+		err := runner.do(adapter, poller.Background, func() error {
+			return runner.setCHValue(adapter, s.Tag, state)
+		})
 		if err != nil {
-			return nil, err
+			adapter.logger.Errorln(err)
+			continue
 		}
 
-		defer r.Release()
+		adapter.setScheduleState(s.Tag, state)
+		adapter.OnSwitch(s.Tag, state, lang.SwitchBySchedule)
+	}
+}
 
-		values := make([]map[string]interface{}, 0)
-		for i := 0; i < r.AINum(); i++ {
-			ai, err := adapter.device.GetAI(i)
-			if err != nil {
-				return values, err
-			}
-			//空浮风机点位分析
-			if TurboBlower.Match(ai.GetConfig().Title) {
-				v, ok := r.GetAIValue(i, 0)
-				if ok {
-					for _, p := range TurboBlower.Analysis(ai.GetConfig().Title, int(v)) {
-						entry := map[string]interface{}{
-							"tag":   "AI-" + p.Name,
-							"title": p.Name,
-						}
+func (runner *Runner) GetRealtimeData(uid string) ([]map[string]interface{}, error) {
+	if v, ok := runner.adapters.Load(uid); ok {
+		adapter := v.(*Adapter)
 
-						val, _ := p.GetField("val")
-						entry["value"] = val
+		var values []map[string]interface{}
+		err := runner.do(adapter, poller.Interactive, func() (err error) {
+			values, err = runner.getRealtimeData(adapter)
+			return
+		})
 
-						if v, exists := p.GetTag("alarm"); exists && v.(string) != "" {
-							entry["alarm"] = v
-						}
+		return values, err
+	}
 
-						values = append(values, entry)
-						adapter.OnMeasureDiscovered("AI-"+p.Name, p.Name)
-					}
-				}
-			} else {
-				entry := map[string]interface{}{
-					"tag":   ai.GetConfig().TagName,
-					"title": ai.GetConfig().Title,
-					"unit":  ai.GetConfig().Uint,
-				}
+	return nil, lang.Error(lang.ErrDeviceNotExists)
+}
 
-				if v, ok := r.GetAIValue(i, ai.GetConfig().Point); ok {
-					av, x := ai.CheckAlarm(v)
+func (runner *Runner) getRealtimeData(adapter *Adapter) ([]map[string]interface{}, error) {
+	if adapter.driver != nil {
+		values, err := adapter.driver.GetRealtimeData()
+		if err != nil {
+			return nil, err
+		}
+		return append(values, adapter.getExtraData("")...), nil
+	}
 
-					entry["alarm"] = ep6v2.AlarmDesc(av)
-					entry["value"] = v
+	device := adapter.getDevice()
+	if device == nil {
+		return nil, lang.Error(lang.ErrDeviceNotExists)
+	}
 
-					if av != ep6v2.AlarmNormal {
-						entry["threshold"] = x
-					}
-				}
+	r, err := device.GetRealTimeData()
+	if err != nil {
+		return nil, err
+	}
 
-				values = append(values, entry)
-				adapter.OnMeasureDiscovered(ai.GetConfig().TagName, ai.GetConfig().Title)
-			}
+	defer r.Release()
+
+	values := make([]map[string]interface{}, 0)
+	for i := 0; i < r.AINum(); i++ {
+		ai, err := device.GetAI(i)
+		if err != nil {
+			return values, err
 		}
+		//空浮风机点位分析
+		if TurboBlower.Match(ai.GetConfig().Title) {
+			v, ok := r.GetAIValue(i, 0)
+			if ok {
+				for _, p := range TurboBlower.Analysis(ai.GetConfig().Title, int(v)) {
+					entry := map[string]interface{}{
+						"tag":   "AI-" + p.Name,
+						"title": p.Name,
+					}
 
-		for i := 0; i < r.AONum(); i++ {
-			ao, err := adapter.device.GetAO(i)
-			if err != nil {
-				return values, err
-			}
+					val, _ := p.GetField("val")
+					entry["value"] = val
+
+					if v, exists := p.GetTag("alarm"); exists && v.(string) != "" {
+						entry["alarm"] = v
+					}
 
+					values = append(values, entry)
+					adapter.OnMeasureDiscovered("AI-"+p.Name, p.Name)
+				}
+			}
+		} else {
 			entry := map[string]interface{}{
-				"tag":   ao.GetConfig().TagName,
-				"title": ao.GetConfig().Title,
-				"unit":  ao.GetConfig().Uint,
+				"tag":   ai.GetConfig().TagName,
+				"title": ai.GetConfig().Title,
+				"unit":  ai.GetConfig().Uint,
 			}
 
-			if v, ok := r.GetAOValue(i); ok {
+			if v, ok := r.GetAIValue(i, ai.GetConfig().Point); ok {
+				av, x := ai.CheckAlarm(v)
+
+				entry["alarm"] = ep6v2.AlarmDesc(av)
 				entry["value"] = v
+
+				if av != ep6v2.AlarmNormal {
+					entry["threshold"] = x
+				}
 			}
 
 			values = append(values, entry)
-			adapter.OnMeasureDiscovered(ao.GetConfig().TagName, ao.GetConfig().Title)
+			adapter.OnMeasureDiscovered(ai.GetConfig().TagName, ai.GetConfig().Title)
 		}
+	}
 
-		for i := 0; i < r.DINum(); i++ {
-			di, err := adapter.device.GetDI(i)
-			if err != nil {
-				return values, err
-			}
+	for i := 0; i < r.AONum(); i++ {
+		ao, err := device.GetAO(i)
+		if err != nil {
+			return values, err
+		}
 
-			entry := map[string]interface{}{
-				"tag":   di.GetConfig().TagName,
-				"title": di.GetConfig().Title,
-			}
+		entry := map[string]interface{}{
+			"tag":   ao.GetConfig().TagName,
+			"title": ao.GetConfig().Title,
+			"unit":  ao.GetConfig().Uint,
+		}
 
-			if v, ok := r.GetDIValue(i); ok {
-				entry["value"] = v
-			}
+		if v, ok := r.GetAOValue(i); ok {
+			entry["value"] = v
+		}
 
-			values = append(values, entry)
-			adapter.OnMeasureDiscovered(di.GetConfig().TagName, di.GetConfig().Title)
+		values = append(values, entry)
+		adapter.OnMeasureDiscovered(ao.GetConfig().TagName, ao.GetConfig().Title)
+	}
+
+	for i := 0; i < r.DINum(); i++ {
+		di, err := device.GetDI(i)
+		if err != nil {
+			return values, err
 		}
 
-		for i := 0; i < r.DONum(); i++ {
-			do, err := adapter.device.GetDO(i)
-			if err != nil {
-				return values, err
-			}
+		entry := map[string]interface{}{
+			"tag":   di.GetConfig().TagName,
+			"title": di.GetConfig().Title,
+		}
 
-			entry := map[string]interface{}{
-				"tag":   do.GetConfig().TagName,
-				"title": do.GetConfig().Title,
-				"ctrl":  do.GetConfig().IsManual,
-			}
+		if v, ok := r.GetDIValue(i); ok {
+			entry["value"] = v
+		}
 
-			if v, ok := r.GetDOValue(i); ok {
-				entry["value"] = v
-			}
+		values = append(values, entry)
+		adapter.OnMeasureDiscovered(di.GetConfig().TagName, di.GetConfig().Title)
+	}
 
-			values = append(values, entry)
-			adapter.OnMeasureDiscovered(do.GetConfig().TagName, do.GetConfig().Title)
+	for i := 0; i < r.DONum(); i++ {
+		do, err := device.GetDO(i)
+		if err != nil {
+			return values, err
+		}
+
+		entry := map[string]interface{}{
+			"tag":   do.GetConfig().TagName,
+			"title": do.GetConfig().Title,
+			"ctrl":  do.GetConfig().IsManual,
 		}
 
-		return values, nil
+		if v, ok := r.GetDOValue(i); ok {
+			entry["value"] = v
+		}
+
+		values = append(values, entry)
+		adapter.OnMeasureDiscovered(do.GetConfig().TagName, do.GetConfig().Title)
 	}
 
-	return nil, lang.Error(lang.ErrDeviceNotExists)
+	return append(values, adapter.getExtraData("")...), nil
 }
 
 func (runner *Runner) Reset(uid string) {
 	if v, ok := runner.adapters.Load(uid); ok {
 		adapter := v.(*Adapter)
-		adapter.device.Reset()
+		if adapter.driver != nil {
+			adapter.driver.Reset()
+		} else if device := adapter.getDevice(); device != nil {
+			device.Reset()
+		}
 	}
 }
 
+func (runner *Runner) ResetTotalizer(ch *json_rpc.CH) error {
+	if v, ok := runner.adapters.Load(ch.UID); ok {
+		adapter := v.(*Adapter)
+		return adapter.resetTotalizer(ch.Tag)
+	}
+	return lang.Error(lang.ErrDeviceNotExists)
+}
+
+//Discover 扫描网络中的控制器
+func (runner *Runner) Discover(conf *json_rpc.ScanConf) ([]*json_rpc.Discovered, error) {
+	return discover.Scan(runner.ctx, conf)
+}
+
 func (runner *Runner) Remove(uid string) {
 	if v, ok := runner.adapters.Load(uid); ok {
 		adapter := v.(*Adapter)
 
 		runner.adapters.Delete(uid)
 		adapter.Close()
+
+		metrics.Remove(uid)
 	}
 }
 
@@ -360,7 +694,7 @@ func (runner *Runner) InitInfluxDB(conf *json_rpc.Conf) (influx.Client, error) {
 	return c, nil
 }
 
-func (runner *Runner) getMeasureData(client influx.Client, db string, ch <-chan *measure.Data) error {
+func (runner *Runner) getMeasureData(client influx.Client, uid, db string, ch <-chan *measure.Data) error {
 	bp, _ := influx.NewBatchPoints(influx.BatchPointsConfig{
 		Precision: "ns",
 		Database:  db,
@@ -378,17 +712,21 @@ func (runner *Runner) getMeasureData(client influx.Client, db string, ch <-chan
 
 			if err != nil {
 				log.Errorln(err)
+				metrics.PointsDropped.WithLabelValues(uid).Inc()
 				continue
 			} else {
 				bp.AddPoint(point)
 			}
 
 		case <-time.After(1 * time.Second):
-			if len(bp.Points()) > 0 {
+			if n := len(bp.Points()); n > 0 {
 				err := client.Write(bp)
 				if err != nil {
+					metrics.InfluxDBWriteErrors.WithLabelValues(uid).Inc()
+					metrics.PointsDropped.WithLabelValues(uid).Add(float64(n))
 					return err
 				} else {
+					metrics.PointsWritten.WithLabelValues(uid).Add(float64(n))
 					return err
 				}
 			}
@@ -436,7 +774,7 @@ func (runner *Runner) Serve(adapter *Adapter) (err error) {
 			case <-adapter.done:
 				return
 			default:
-				err := runner.getMeasureData(c, adapter.conf.DB, adapter.measureDataCH)
+				err := runner.getMeasureData(c, adapter.conf.UID, adapter.conf.DB, adapter.measureDataCH)
 				if err != nil {
 					adapter.logger.Error(err)
 					//return
@@ -454,13 +792,16 @@ func (runner *Runner) Serve(adapter *Adapter) (err error) {
 		const delay = 10 * time.Second
 
 	tryConnectToDevice:
-		device := adapter.device
+		client := adapter.getDevice()
+		if client == nil && adapter.driver == nil {
+			return
+		}
 		for {
 			adapter.heartBeat()
 
 			adapter.OnDeviceStatusChanged(lang.Connecting)
 
-			err := device.Connect(runner.ctx, adapter.conf.Address)
+			err := adapter.connect(runner.ctx, client)
 			if err != nil {
 				if err == runner.ctx.Err() {
 					return
@@ -475,27 +816,60 @@ func (runner *Runner) Serve(adapter *Adapter) (err error) {
 					continue
 				}
 			} else {
-				if device.IsConnected() {
+				if adapter.isConnected(client) {
 					break
 				}
 			}
 		}
 
+		//身份不匹配时不再采集数据，等待gate确认新的设备身份
+		if ok, err := adapter.verifyIdentity(client); !ok {
+			adapter.closeDevice(client)
+			if err != nil {
+				adapter.logger.Errorln(err)
+				adapter.OnDeviceStatusChanged(lang.Disconnected)
+			} else {
+				adapter.OnDeviceStatusChanged(lang.IdentityMismatch)
+			}
+
+			select {
+			case <-runner.ctx.Done():
+				return
+			case <-adapter.done:
+				return
+			case <-time.After(delay):
+				goto tryConnectToDevice
+			}
+		}
+
 		adapter.OnDeviceStatusChanged(lang.Connected)
 
+		runner.resumePulses(adapter)
+
+		//随机延时开始第一次采集，避免大量设备同时访问
+		wait := poller.Jitter(adapter.conf.Interval)
+
 		for {
 			select {
 			case <-runner.ctx.Done():
 				return
 			case <-adapter.done:
 				return
-			case <-time.After(adapter.conf.Interval):
+			case <-time.After(wait):
+				wait = adapter.conf.Interval
 				adapter.heartBeat()
 
+				begin := time.Now()
 				err := runner.gatherData(adapter)
+				elapsed := time.Now().Sub(begin)
+				metrics.PollDuration.WithLabelValues(adapter.conf.UID).Observe(elapsed.Seconds())
+
+				adapter.mu.Lock()
+				adapter.pollDuration = elapsed
+				adapter.mu.Unlock()
 				if err != nil {
 					adapter.logger.Errorln(err)
-					device.Close()
+					adapter.closeDevice(client)
 
 					go adapter.OnDeviceStatusChanged(lang.Disconnected)
 					go adapter.OnDevicePerfChanged(map[string]interface{}{
@@ -503,6 +877,8 @@ func (runner *Runner) Serve(adapter *Adapter) (err error) {
 					})
 					goto tryConnectToDevice
 				}
+
+				runner.runSchedules(adapter)
 			}
 		}
 	}()
@@ -511,9 +887,215 @@ func (runner *Runner) Serve(adapter *Adapter) (err error) {
 }
 
 func (runner *Runner) gatherData(adapter *Adapter) error {
-	client := adapter.device
+	snapshot := make(map[string]interface{})
+
+	var err error
+	if adapter.driver != nil {
+		err = runner.gatherDriverData(adapter, snapshot)
+	} else {
+		err = runner.gatherDeviceData(adapter, snapshot)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	runner.gatherVirtualData(adapter, snapshot)
+	runner.gatherTotalizerData(adapter, snapshot)
+	return nil
+}
+
+//gatherTotalizerData 更新累计点位并保存状态
+func (runner *Runner) gatherTotalizerData(adapter *Adapter, snapshot map[string]interface{}) {
+	adapter.mu.Lock()
+
+	if len(adapter.totalizers) == 0 {
+		adapter.mu.Unlock()
+		return
+	}
 
-	data, err := client.GetRealTimeData()
+	var (
+		now    = time.Now()
+		values = make(map[string]float64, len(adapter.totalizers))
+		result = make([]*measure.Data, 0, len(adapter.totalizers))
+	)
+
+	for _, t := range adapter.totalizers {
+		v, ok := snapshot[t.Source]
+		if !ok {
+			continue
+		}
+
+		total := t.Update(v, now)
+		values[t.Tag] = total
+
+		data := measure.New(t.Tag)
+		data.AddTag("uid", adapter.conf.UID)
+		data.AddTag("address", adapter.conf.Address)
+		data.AddTag("tag", t.Tag)
+		data.AddTag("title", t.Title)
+		if t.Unit != "" {
+			data.AddTag("unit", t.Unit)
+		}
+		data.AddField("val", total)
+		result = append(result, data)
+	}
+
+	adapter.totalizerValues = values
+
+	//定期保存状态，adapter关闭时也会保存
+	var states map[string]totalizer.State
+	if now.Sub(adapter.totalizerSaved) >= totalizerSaveInterval {
+		states = totalizer.States(adapter.totalizers)
+		adapter.totalizerSaved = now
+	}
+	adapter.mu.Unlock()
+
+	if states != nil {
+		if err := totalizer.Save(adapter.conf.UID, states); err != nil {
+			adapter.logger.Errorln(err)
+		}
+	}
+
+	for _, data := range result {
+		tag, _ := data.GetTag("tag")
+		title, _ := data.GetTag("title")
+
+		select {
+		case <-runner.ctx.Done():
+			data.Release()
+		case <-adapter.done:
+			data.Release()
+		case adapter.measureDataCH <- data:
+		}
+
+		adapter.OnMeasureDiscovered(tag.(string), title.(string))
+	}
+}
+
+//gatherVirtualData 使用本周期的数据快照计算虚拟点位
+func (runner *Runner) gatherVirtualData(adapter *Adapter, snapshot map[string]interface{}) {
+	channels := adapter.getVirtualChannels()
+	if len(channels) == 0 {
+		return
+	}
+
+	values := make(map[string]interface{}, len(channels))
+	for _, ch := range channels {
+		v, err := ch.Eval(snapshot)
+		if err != nil {
+			adapter.logger.Debugln(ch.Tag, err)
+			continue
+		}
+
+		//后面的虚拟点位可以引用前面的结果
+		snapshot[ch.Tag] = v
+		values[ch.Tag] = v
+
+		data := measure.New(ch.Tag)
+		data.AddTag("uid", adapter.conf.UID)
+		data.AddTag("address", adapter.conf.Address)
+		data.AddTag("tag", ch.Tag)
+		data.AddTag("title", ch.Title)
+		if ch.Unit != "" {
+			data.AddTag("unit", ch.Unit)
+		}
+		data.AddField("val", v)
+
+		select {
+		case <-runner.ctx.Done():
+			data.Release()
+		case <-adapter.done:
+			data.Release()
+		case adapter.measureDataCH <- data:
+		}
+
+		adapter.OnMeasureDiscovered(ch.Tag, ch.Title)
+	}
+
+	adapter.setVirtualValues(values)
+}
+
+func (runner *Runner) gatherDriverData(adapter *Adapter, snapshot map[string]interface{}) error {
+	start := time.Now()
+
+	var values []*measure.Data
+	err := runner.do(adapter, poller.Background, func() (err error) {
+		values, err = adapter.driver.Gather(runner.ctx)
+		return
+	})
+	if err != nil {
+		return err
+	}
+
+	adapter.OnDevicePerfChanged(map[string]interface{}{
+		"delay": time.Now().Sub(start),
+	})
+
+	for _, data := range values {
+		v, _ := data.GetTag("tag")
+		tag, ok := v.(string)
+		if !ok || tag == "" {
+			adapter.logger.Warningln(lang.Error(lang.ErrDataWithoutTag))
+			data.Release()
+			continue
+		}
+
+		v, _ = data.GetTag("title")
+		title, ok := v.(string)
+		if !ok || title == "" {
+			title = tag
+		}
+
+		data.AddTag("uid", adapter.conf.UID)
+		data.AddTag("address", adapter.conf.Address)
+
+		if v, ok := data.GetField("val"); ok {
+			snapshot[tag] = virtual.Value(v)
+		}
+
+		if v, exists := data.GetTag("alarm"); exists {
+			if alarm, ok := v.(string); ok && alarm != "" {
+				adapter.OnMeasureAlarm(data.Clone())
+			}
+		}
+
+		select {
+		case <-runner.ctx.Done():
+			data.Release()
+		case <-adapter.done:
+			data.Release()
+		case adapter.measureDataCH <- data:
+		}
+
+		adapter.OnMeasureDiscovered(tag, title)
+	}
+
+	return nil
+}
+
+//sendMeasureData 把采集到的数据交给保存队列，adapter关闭后不再等待队列
+func (runner *Runner) sendMeasureData(adapter *Adapter, data *measure.Data) {
+	select {
+	case <-runner.ctx.Done():
+		data.Release()
+	case <-adapter.done:
+		data.Release()
+	case adapter.measureDataCH <- data:
+	}
+}
+
+func (runner *Runner) gatherDeviceData(adapter *Adapter, snapshot map[string]interface{}) error {
+	client := adapter.getDevice()
+	if client == nil {
+		return lang.Error(lang.ErrDeviceNotExists)
+	}
+
+	var data *realtime.Data
+	err := runner.do(adapter, poller.Background, func() (err error) {
+		data, err = client.GetRealTimeData()
+		return
+	})
 	if err != nil {
 		return err
 	}
@@ -534,11 +1116,9 @@ func (runner *Runner) gatherData(adapter *Adapter) error {
 		case <-adapter.done:
 			return errors.New("adapter closed")
 		default:
-			if err := fn(); err != nil {
-				return err
-			}
+			//每个通道单独排队，gate的读写请求可以在通道之间插入
+			return runner.do(adapter, poller.Background, fn)
 		}
-		return nil
 	}
 
 	for i := 0; i < data.AINum(); i++ {
@@ -571,16 +1151,17 @@ func (runner *Runner) gatherData(adapter *Adapter) error {
 					data.AddTag("title", ai.GetConfig().Title)
 					data.AddTag("alarm", ep6v2.AlarmDesc(av))
 					data.AddField("val", v)
+					snapshot[ai.GetConfig().TagName] = virtual.Value(v)
 
 					if av != ep6v2.AlarmNormal {
 						data.AddTag("unit", ai.GetConfig().Uint)
 						data.AddField("threshold", x)
 					}
 
-					adapter.measureDataCH <- data
 					if av != 0 {
 						adapter.OnMeasureAlarm(data.Clone())
 					}
+					runner.sendMeasureData(adapter, data)
 				}
 
 				adapter.OnMeasureDiscovered(ai.GetConfig().TagName, ai.GetConfig().Title)
@@ -606,7 +1187,8 @@ func (runner *Runner) gatherData(adapter *Adapter) error {
 				data.AddTag("tag", di.GetConfig().TagName)
 				data.AddTag("title", di.GetConfig().Title)
 				data.AddField("val", v)
-				adapter.measureDataCH <- data
+				snapshot[di.GetConfig().TagName] = v
+				runner.sendMeasureData(adapter, data)
 			}
 			adapter.OnMeasureDiscovered(di.GetConfig().TagName, di.GetConfig().Title)
 			return nil
@@ -630,7 +1212,8 @@ func (runner *Runner) gatherData(adapter *Adapter) error {
 				data.AddTag("tag", ao.GetConfig().TagName)
 				data.AddTag("title", ao.GetConfig().Title)
 				data.AddField("val", v)
-				adapter.measureDataCH <- data
+				snapshot[ao.GetConfig().TagName] = virtual.Value(v)
+				runner.sendMeasureData(adapter, data)
 			}
 			adapter.OnMeasureDiscovered(ao.GetConfig().TagName, ao.GetConfig().Title)
 			return nil
@@ -654,7 +1237,8 @@ func (runner *Runner) gatherData(adapter *Adapter) error {
 				data.AddTag("tag", do.GetConfig().TagName)
 				data.AddTag("title", do.GetConfig().Title)
 				data.AddField("val", v)
-				adapter.measureDataCH <- data
+				snapshot[do.GetConfig().TagName] = v
+				runner.sendMeasureData(adapter, data)
 			}
 			adapter.OnMeasureDiscovered(do.GetConfig().TagName, do.GetConfig().Title)
 			return nil
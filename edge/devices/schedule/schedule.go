@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/maritimusj/centrum/json_rpc"
+	"github.com/maritimusj/centrum/util/calendar"
+)
+
+const (
+	//循环开关
+	KindCycle = "cycle"
+	//按星期和时间段开关
+	KindCalendar = "calendar"
+)
+
+type Schedule struct {
+	json_rpc.Schedule
+	calendar *calendar.Calendar
+}
+
+//Compile 检查并编译DO定时配置
+func Compile(list []json_rpc.Schedule) ([]*Schedule, error) {
+	result := make([]*Schedule, 0, len(list))
+	for _, conf := range list {
+		if !strings.HasPrefix(strings.ToUpper(conf.Tag), "DO-") {
+			return nil, fmt.Errorf("invalid schedule tag: %s", conf.Tag)
+		}
+
+		s := &Schedule{
+			Schedule: conf,
+		}
+
+		switch conf.Kind {
+		case KindCycle:
+			if conf.OnTime <= 0 || conf.OffTime <= 0 {
+				return nil, fmt.Errorf("invalid cycle of %s: %d/%d", conf.Tag, conf.OnTime, conf.OffTime)
+			}
+		case KindCalendar:
+			c, err := calendar.Compile(conf.Windows, conf.Holidays)
+			if err != nil {
+				return nil, fmt.Errorf("invalid calendar of %s: %s", conf.Tag, err)
+			}
+			s.calendar = c
+		default:
+			return nil, fmt.Errorf("invalid schedule kind of %s: %s", conf.Tag, conf.Kind)
+		}
+
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+//State 指定时间DO应有的状态
+func (s *Schedule) State(now time.Time) bool {
+	if s.Kind == KindCycle {
+		period := int64(s.OnTime + s.OffTime)
+		return now.Unix()%period < int64(s.OnTime)
+	}
+
+	return s.calendar.Contains(now)
+}
@@ -0,0 +1,56 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maritimusj/centrum/json_rpc"
+)
+
+func TestState(t *testing.T) {
+	list, err := Compile([]json_rpc.Schedule{
+		{
+			Tag:  "DO-1",
+			Kind: KindCalendar,
+			Windows: []json_rpc.TimeWindow{
+				{Weekdays: []int{1, 2, 3, 4, 5}, Start: "08:00", End: "17:30"},
+				{Weekdays: []int{5}, Start: "22:00", End: "02:00"},
+			},
+			Holidays: []string{"2020-01-01"},
+		},
+		{Tag: "DO-2", Kind: KindCycle, OnTime: 10, OffTime: 50},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calendar, cycle := list[0], list[1]
+
+	cases := []struct {
+		time     string
+		expected bool
+	}{
+		{"2020-01-06 08:00", true},  //星期一
+		{"2020-01-06 17:30", false}, //结束时间不包括在内
+		{"2020-01-05 10:00", false}, //星期日
+		{"2020-01-10 23:00", true},  //星期五晚上
+		{"2020-01-11 01:00", true},  //跨越午夜
+		{"2020-01-11 03:00", false},
+		{"2020-01-01 10:00", false}, //节假日
+	}
+
+	for _, c := range cases {
+		now, _ := time.ParseInLocation("2006-01-02 15:04", c.time, time.Local)
+		if v := calendar.State(now); v != c.expected {
+			t.Fatal(c.time, "expected:", c.expected, "got:", v)
+		}
+	}
+
+	if !cycle.State(time.Unix(60, 0)) || cycle.State(time.Unix(70, 0)) {
+		t.Fatal("invalid cycle state")
+	}
+
+	if _, err := Compile([]json_rpc.Schedule{{Tag: "AI-1", Kind: KindCycle, OnTime: 1, OffTime: 1}}); err == nil {
+		t.Fatal("expect invalid tag error")
+	}
+}
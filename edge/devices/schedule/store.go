@@ -0,0 +1,101 @@
+package schedule
+
+import (
+	"bytes"
+	"time"
+
+	bolt "github.com/etcd-io/bbolt"
+)
+
+const (
+	bucketName = "pulse"
+)
+
+var (
+	db *bolt.DB
+)
+
+//Open 打开脉冲输出的存储，没有打开时edge重启后不会关闭重启前未结束的脉冲输出
+func Open(filename string) error {
+	d, err := bolt.Open(filename, 0666, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return err
+	}
+
+	err = d.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketName))
+		return err
+	})
+	if err != nil {
+		_ = d.Close()
+		return err
+	}
+
+	db = d
+	return nil
+}
+
+func Close() {
+	if db != nil {
+		_ = db.Close()
+		db = nil
+	}
+}
+
+func prefix(uid string) []byte {
+	return []byte(uid + ".")
+}
+
+func key(uid, tag string) []byte {
+	return append(prefix(uid), tag...)
+}
+
+//SavePulse 保存一个未结束的脉冲输出和它的结束时间
+func SavePulse(uid, tag string, deadline time.Time) error {
+	if db == nil {
+		return nil
+	}
+
+	data, err := deadline.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucketName)).Put(key(uid, tag), data)
+	})
+}
+
+//RemovePulse 脉冲输出已经关闭
+func RemovePulse(uid, tag string) error {
+	if db == nil {
+		return nil
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucketName)).Delete(key(uid, tag))
+	})
+}
+
+//LoadPulses 读取设备未结束的脉冲输出
+func LoadPulses(uid string) map[string]time.Time {
+	result := map[string]time.Time{}
+	if db == nil {
+		return result
+	}
+
+	_ = db.View(func(tx *bolt.Tx) error {
+		p := prefix(uid)
+		c := tx.Bucket([]byte(bucketName)).Cursor()
+		for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+			var deadline time.Time
+			if err := deadline.UnmarshalBinary(v); err != nil {
+				continue
+			}
+			result[string(k[len(p):])] = deadline
+		}
+		return nil
+	})
+
+	return result
+}
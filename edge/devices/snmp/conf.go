@@ -0,0 +1,219 @@
+package snmp
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	TypeAI = "AI"
+	TypeDI = "DI"
+
+	defaultTimeout = 3 * time.Second
+	defaultRetries = 2
+)
+
+//V3Conf SNMPv3 USM参数
+type V3Conf struct {
+	User      string `json:"user"`
+	AuthProto string `json:"authProto"` //MD5, SHA, SHA224, SHA256, SHA384, SHA512
+	AuthPass  string `json:"authPass"`
+	PrivProto string `json:"privProto"` //DES, AES, AES192, AES256
+	PrivPass  string `json:"privPass"`
+}
+
+//OIDConf 一个轮询的OID
+type OIDConf struct {
+	OID   string `json:"oid"`
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Unit  string `json:"unit"`
+	//AI或者DI，默认为AI
+	Type string `json:"type"`
+	//AI: val = raw * scale + offset
+	Scale  float64 `json:"scale"`
+	Offset float64 `json:"offset"`
+	//AI报警上下限
+	Hi *float64 `json:"hi"`
+	Lo *float64 `json:"lo"`
+	//DI: 指定时，原始值等于On为true，否则非0为true
+	On *float64 `json:"on"`
+	//DI: 为true时产生的报警等级
+	Alarm string `json:"alarm"`
+}
+
+//TrapConf 一个trap的映射，收到trap后对应的DI点位置为true并产生报警
+type TrapConf struct {
+	OID   string `json:"oid"`
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	//恢复trap，收到后点位置为false；为空时点位只在收到trap的周期内为true
+	Clear string `json:"clear"`
+	//报警等级，默认为HF
+	Alarm string `json:"alarm"`
+}
+
+//Conf 驱动参数，由设备的 params.options 传入
+type Conf struct {
+	Version   string      `json:"version"` //1, 2c, 3
+	Community string      `json:"community"`
+	V3        *V3Conf     `json:"v3"`
+	Timeout   int         `json:"timeout"` //毫秒
+	Retries   int         `json:"retries"`
+	OIDs      []*OIDConf  `json:"oids"`
+	Traps     []*TrapConf `json:"traps"`
+}
+
+func parseConf(options string) (*Conf, error) {
+	conf := &Conf{}
+	if options != "" {
+		if err := json.Unmarshal([]byte(options), conf); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Community == "" {
+		conf.Community = "public"
+	}
+
+	for _, oid := range conf.OIDs {
+		if oid.OID == "" || oid.Name == "" {
+			return nil, errors.New("snmp: oid and name are required")
+		}
+		oid.OID = normalizeOID(oid.OID)
+		oid.Type = strings.ToUpper(oid.Type)
+		if oid.Type == "" {
+			oid.Type = TypeAI
+		}
+		if oid.Type != TypeAI && oid.Type != TypeDI {
+			return nil, errors.New("snmp: invalid type: " + oid.Type)
+		}
+		if oid.Scale == 0 {
+			oid.Scale = 1
+		}
+		if oid.Title == "" {
+			oid.Title = oid.Name
+		}
+	}
+
+	for _, trap := range conf.Traps {
+		if trap.OID == "" || trap.Name == "" {
+			return nil, errors.New("snmp: trap oid and name are required")
+		}
+		trap.OID = normalizeOID(trap.OID)
+		if trap.Clear != "" {
+			trap.Clear = normalizeOID(trap.Clear)
+		}
+		if trap.Alarm == "" {
+			trap.Alarm = "HF"
+		}
+		if trap.Title == "" {
+			trap.Title = trap.Name
+		}
+	}
+
+	return conf, nil
+}
+
+func (conf *Conf) timeout() time.Duration {
+	if conf.Timeout > 0 {
+		return time.Duration(conf.Timeout) * time.Millisecond
+	}
+	return defaultTimeout
+}
+
+func (conf *Conf) retries() int {
+	if conf.Retries > 0 {
+		return conf.Retries
+	}
+	return defaultRetries
+}
+
+func (conf *Conf) apply(client *gosnmp.GoSNMP) error {
+	switch conf.Version {
+	case "1":
+		client.Version = gosnmp.Version1
+		client.Community = conf.Community
+	case "", "2c", "2":
+		client.Version = gosnmp.Version2c
+		client.Community = conf.Community
+	case "3":
+		params, flags, err := conf.V3.usm()
+		if err != nil {
+			return err
+		}
+
+		client.Version = gosnmp.Version3
+		client.SecurityModel = gosnmp.UserSecurityModel
+		client.MsgFlags = flags
+		client.SecurityParameters = params
+	default:
+		return errors.New("snmp: unknown version: " + conf.Version)
+	}
+	return nil
+}
+
+//usm 生成USM安全参数和对应的安全级别
+func (v3 *V3Conf) usm() (*gosnmp.UsmSecurityParameters, gosnmp.SnmpV3MsgFlags, error) {
+	if v3 == nil || v3.User == "" {
+		return nil, 0, errors.New("snmp: v3 user is required")
+	}
+
+	params := &gosnmp.UsmSecurityParameters{
+		UserName:                 v3.User,
+		AuthenticationProtocol:   gosnmp.NoAuth,
+		PrivacyProtocol:          gosnmp.NoPriv,
+		AuthenticationPassphrase: v3.AuthPass,
+		PrivacyPassphrase:        v3.PrivPass,
+	}
+
+	flags := gosnmp.NoAuthNoPriv
+	if v3.AuthProto != "" {
+		v, ok := authProtocols[strings.ToUpper(v3.AuthProto)]
+		if !ok {
+			return nil, 0, errors.New("snmp: unknown auth protocol: " + v3.AuthProto)
+		}
+		params.AuthenticationProtocol = v
+		flags = gosnmp.AuthNoPriv
+
+		if v3.PrivProto != "" {
+			v, ok := privProtocols[strings.ToUpper(v3.PrivProto)]
+			if !ok {
+				return nil, 0, errors.New("snmp: unknown priv protocol: " + v3.PrivProto)
+			}
+			params.PrivacyProtocol = v
+			flags = gosnmp.AuthPriv
+		}
+	}
+
+	return params, flags, nil
+}
+
+var (
+	authProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+		"MD5":    gosnmp.MD5,
+		"SHA":    gosnmp.SHA,
+		"SHA224": gosnmp.SHA224,
+		"SHA256": gosnmp.SHA256,
+		"SHA384": gosnmp.SHA384,
+		"SHA512": gosnmp.SHA512,
+	}
+	privProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+		"DES":    gosnmp.DES,
+		"AES":    gosnmp.AES,
+		"AES192": gosnmp.AES192,
+		"AES256": gosnmp.AES256,
+	}
+)
+
+func normalizeOID(oid string) string {
+	oid = strings.TrimSpace(oid)
+	if !strings.HasPrefix(oid, ".") {
+		oid = "." + oid
+	}
+	return oid
+}
@@ -0,0 +1,413 @@
+package snmp
+
+import (
+	"context"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/maritimusj/centrum/edge/devices/measure"
+	"github.com/maritimusj/centrum/edge/lang"
+)
+
+const (
+	sysDescrOID  = ".1.3.6.1.2.1.1.1.0"
+	sysUpTimeOID = ".1.3.6.1.2.1.1.3.0"
+	sysNameOID   = ".1.3.6.1.2.1.1.5.0"
+)
+
+type value struct {
+	tag   string
+	title string
+	unit  string
+	val   interface{}
+}
+
+type trapState struct {
+	active  bool
+	pending bool
+}
+
+type Device struct {
+	conf   *Conf
+	client *gosnmp.GoSNMP
+	ip     string
+	status lang.StrIndex
+
+	sysDescr string
+	sysName  string
+
+	traps  map[string]*trapState
+	values []*value
+	mu     sync.RWMutex
+}
+
+func New(options string) (*Device, error) {
+	conf, err := parseConf(options)
+	if err != nil {
+		return nil, err
+	}
+
+	device := &Device{
+		conf:   conf,
+		status: lang.Disconnected,
+		traps:  make(map[string]*trapState),
+	}
+
+	for _, trap := range conf.Traps {
+		device.traps[trap.Name] = &trapState{}
+	}
+
+	return device, nil
+}
+
+func (device *Device) Connect(ctx context.Context, address string) error {
+	device.mu.Lock()
+	defer device.mu.Unlock()
+
+	device.status = lang.Connecting
+
+	host, port := address, uint16(161)
+	if h, p, err := net.SplitHostPort(address); err == nil {
+		v, err := strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			device.status = lang.Disconnected
+			return err
+		}
+		host, port = h, uint16(v)
+	}
+
+	client := &gosnmp.GoSNMP{
+		Context:            ctx,
+		Target:             host,
+		Port:               port,
+		Transport:          "udp",
+		Timeout:            device.conf.timeout(),
+		Retries:            device.conf.retries(),
+		ExponentialTimeout: true,
+		MaxOids:            gosnmp.MaxOids,
+	}
+
+	if err := device.conf.apply(client); err != nil {
+		device.status = lang.Disconnected
+		return err
+	}
+
+	if err := client.Connect(); err != nil {
+		device.status = lang.Disconnected
+		return err
+	}
+
+	//udp没有连接过程，读取一次系统信息确认设备在线
+	result, err := client.Get([]string{sysDescrOID, sysNameOID, sysUpTimeOID})
+	if err != nil {
+		_ = client.Conn.Close()
+		device.status = lang.Disconnected
+		return err
+	}
+
+	for _, v := range result.Variables {
+		switch v.Name {
+		case sysDescrOID:
+			device.sysDescr = toString(v.Value)
+		case sysNameOID:
+			device.sysName = toString(v.Value)
+		}
+	}
+
+	device.client = client
+	device.ip = client.Conn.RemoteAddr().(*net.UDPAddr).IP.String()
+	device.status = lang.Connected
+
+	if len(device.conf.Traps) > 0 {
+		register(device.ip, device)
+	}
+
+	return nil
+}
+
+func (device *Device) IsConnected() bool {
+	device.mu.RLock()
+	defer device.mu.RUnlock()
+
+	return device.client != nil && device.status == lang.Connected
+}
+
+func (device *Device) Close() {
+	device.mu.Lock()
+	defer device.mu.Unlock()
+
+	if device.client != nil {
+		unregister(device.ip, device)
+		_ = device.client.Conn.Close()
+		device.client = nil
+	}
+
+	device.status = lang.Disconnected
+}
+
+func (device *Device) Reset() {
+	device.mu.Lock()
+	defer device.mu.Unlock()
+
+	device.values = nil
+	for _, state := range device.traps {
+		state.active = false
+		state.pending = false
+	}
+}
+
+func (device *Device) GetStatus() lang.StrIndex {
+	device.mu.RLock()
+	defer device.mu.RUnlock()
+
+	return device.status
+}
+
+func (device *Device) GetStatusTitle() string {
+	return lang.Str(device.GetStatus())
+}
+
+func (device *Device) GetBaseInfo() (map[string]interface{}, error) {
+	device.mu.RLock()
+	defer device.mu.RUnlock()
+
+	return map[string]interface{}{
+		"model": "SNMP",
+		"title": device.sysName,
+		"desc":  device.sysDescr,
+		"addr":  device.ip,
+	}, nil
+}
+
+func (device *Device) onTrap(version gosnmp.SnmpVersion, community, oid string) {
+	device.mu.Lock()
+	defer device.mu.Unlock()
+
+	//v3设备只接受通过认证的v3 trap，其它设备按community检查
+	if device.conf.Version == "3" {
+		if version != gosnmp.Version3 {
+			return
+		}
+	} else if version == gosnmp.Version3 || community != device.conf.Community {
+		return
+	}
+
+	for _, trap := range device.conf.Traps {
+		if trap.OID == oid {
+			state := device.traps[trap.Name]
+			state.active = true
+			state.pending = true
+		} else if trap.Clear == oid {
+			device.traps[trap.Name].active = false
+		}
+	}
+}
+
+func (device *Device) Gather(ctx context.Context) ([]*measure.Data, error) {
+	device.mu.RLock()
+	client := device.client
+	device.mu.RUnlock()
+
+	if client == nil {
+		return nil, lang.Error(lang.ErrDeviceNotConnected)
+	}
+
+	var (
+		result []*measure.Data
+		values []*value
+	)
+
+	oids := device.conf.OIDs
+	for i := 0; i < len(oids); i += client.MaxOids {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		end := i + client.MaxOids
+		if end > len(oids) {
+			end = len(oids)
+		}
+
+		names := make([]string, 0, end-i)
+		for _, oid := range oids[i:end] {
+			names = append(names, oid.OID)
+		}
+
+		packet, err := client.Get(names)
+		if err != nil {
+			return result, err
+		}
+
+		for _, v := range packet.Variables {
+			for _, oid := range oids[i:end] {
+				if oid.OID != normalizeOID(v.Name) {
+					continue
+				}
+				if data, val := device.convert(oid, v); data != nil {
+					result = append(result, data)
+					values = append(values, val)
+				}
+			}
+		}
+	}
+
+	device.mu.Lock()
+	defer device.mu.Unlock()
+
+	for _, trap := range device.conf.Traps {
+		state := device.traps[trap.Name]
+
+		val := &value{
+			tag:   TypeDI + "-" + trap.Name,
+			title: trap.Title,
+			val:   state.active,
+		}
+		values = append(values, val)
+
+		data := measure.New(val.tag)
+		data.AddTag("tag", val.tag)
+		data.AddTag("title", val.title)
+		data.AddField("val", state.active)
+
+		if state.pending {
+			data.AddTag("alarm", trap.Alarm)
+			state.pending = false
+		}
+
+		//没有恢复trap的，报告一次后复位
+		if trap.Clear == "" {
+			state.active = false
+		}
+
+		result = append(result, data)
+	}
+
+	device.values = values
+	return result, nil
+}
+
+func (device *Device) convert(oid *OIDConf, pdu gosnmp.SnmpPDU) (*measure.Data, *value) {
+	raw, ok := toFloat(pdu)
+	if !ok {
+		return nil, nil
+	}
+
+	tag := oid.Type + "-" + oid.Name
+	data := measure.New(tag)
+	data.AddTag("tag", tag)
+	data.AddTag("title", oid.Title)
+
+	val := &value{
+		tag:   tag,
+		title: oid.Title,
+		unit:  oid.Unit,
+	}
+
+	if oid.Type == TypeDI {
+		var on bool
+		if oid.On != nil {
+			on = raw == *oid.On
+		} else {
+			on = raw != 0
+		}
+
+		val.val = on
+		data.AddField("val", on)
+
+		if on && oid.Alarm != "" {
+			data.AddTag("alarm", oid.Alarm)
+		}
+	} else {
+		v := raw*oid.Scale + oid.Offset
+
+		val.val = v
+		data.AddTag("unit", oid.Unit)
+		data.AddField("val", v)
+
+		if oid.Hi != nil && v > *oid.Hi {
+			data.AddTag("alarm", "HI")
+			data.AddField("threshold", *oid.Hi)
+		} else if oid.Lo != nil && v < *oid.Lo {
+			data.AddTag("alarm", "LO")
+			data.AddField("threshold", *oid.Lo)
+		}
+	}
+
+	return data, val
+}
+
+func (device *Device) GetValue(tag string) (interface{}, error) {
+	device.mu.RLock()
+	defer device.mu.RUnlock()
+
+	for _, v := range device.values {
+		if strings.EqualFold(v.tag, tag) {
+			return map[string]interface{}{
+				"title": v.title,
+				"tag":   v.tag,
+				"unit":  v.unit,
+				"value": v.val,
+			}, nil
+		}
+	}
+	return nil, lang.Error(lang.ErrCHNotExists)
+}
+
+func (device *Device) SetValue(string, interface{}) error {
+	return lang.Error(lang.ErrCHReadOnly)
+}
+
+func (device *Device) GetRealtimeData() ([]map[string]interface{}, error) {
+	device.mu.RLock()
+	defer device.mu.RUnlock()
+
+	values := make([]map[string]interface{}, 0, len(device.values))
+	for _, v := range device.values {
+		entry := map[string]interface{}{
+			"tag":   v.tag,
+			"title": v.title,
+			"value": v.val,
+		}
+		if v.unit != "" {
+			entry["unit"] = v.unit
+		}
+		values = append(values, entry)
+	}
+	return values, nil
+}
+
+func toFloat(pdu gosnmp.SnmpPDU) (float64, bool) {
+	switch pdu.Type {
+	case gosnmp.Integer, gosnmp.Counter32, gosnmp.Gauge32, gosnmp.TimeTicks, gosnmp.Counter64, gosnmp.Uinteger32:
+		v, _ := new(big.Float).SetInt(gosnmp.ToBigInt(pdu.Value)).Float64()
+		return v, true
+	case gosnmp.OctetString:
+		v, err := strconv.ParseFloat(strings.TrimSpace(toString(pdu.Value)), 64)
+		return v, err == nil
+	case gosnmp.OpaqueFloat:
+		v, ok := pdu.Value.(float32)
+		return float64(v), ok
+	case gosnmp.OpaqueDouble:
+		v, ok := pdu.Value.(float64)
+		return v, ok
+	default:
+		return 0, false
+	}
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return ""
+	}
+}
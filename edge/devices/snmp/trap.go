@@ -0,0 +1,154 @@
+package snmp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	snmpTrapOID = ".1.3.6.1.6.3.1.1.4.1.0"
+	//v1 generic trap 对应的v2 trap OID前缀，RFC 3584
+	genericTrapPrefix = ".1.3.6.1.6.3.1.1.5."
+)
+
+var (
+	trapServer *gosnmp.TrapListener
+	//v3 trap的用户名和最低安全级别，trap服务只支持一个USM用户
+	trapUser  string
+	trapFlags gosnmp.SnmpV3MsgFlags
+	//按设备IP注册的trap接收者
+	receivers sync.Map
+)
+
+//StartTrapServer 启动trap服务，支持v1/v2c trap，v3不为空时同时接收这个USM用户的v3 trap
+func StartTrapServer(addr string, port int, v3 *V3Conf) error {
+	CloseTrapServer()
+
+	params := &gosnmp.GoSNMP{
+		Transport: "udp",
+		Version:   gosnmp.Version2c,
+		Timeout:   defaultTimeout,
+		Retries:   defaultRetries,
+		MaxOids:   gosnmp.MaxOids,
+	}
+
+	trapUser, trapFlags = "", gosnmp.NoAuthNoPriv
+	if v3 != nil && v3.User != "" {
+		usm, flags, err := v3.usm()
+		if err != nil {
+			return err
+		}
+
+		params.Version = gosnmp.Version3
+		params.SecurityModel = gosnmp.UserSecurityModel
+		params.MsgFlags = flags
+		params.SecurityParameters = usm
+
+		trapUser, trapFlags = v3.User, flags
+	}
+
+	server := gosnmp.NewTrapListener()
+	server.Params = params
+	server.OnNewTrap = onNewTrap
+
+	errCH := make(chan error, 1)
+	go func() {
+		errCH <- server.Listen(fmt.Sprintf("%s:%d", addr, port))
+	}()
+
+	select {
+	case err := <-errCH:
+		return err
+	case <-server.Listening():
+	case <-time.After(3 * time.Second):
+		return fmt.Errorf("snmp: start trap server timeout")
+	}
+
+	trapServer = server
+	log.Println("snmp trap server listen on port: ", port)
+	return nil
+}
+
+func CloseTrapServer() {
+	if trapServer != nil {
+		trapServer.Close()
+		trapServer = nil
+	}
+}
+
+func register(ip string, device *Device) {
+	v, _ := receivers.LoadOrStore(ip, &sync.Map{})
+	v.(*sync.Map).Store(device, struct{}{})
+}
+
+func unregister(ip string, device *Device) {
+	if v, ok := receivers.Load(ip); ok {
+		v.(*sync.Map).Delete(device)
+	}
+}
+
+func onNewTrap(packet *gosnmp.SnmpPacket, addr *net.UDPAddr) {
+	oid := trapOID(packet)
+	if oid == "" {
+		return
+	}
+
+	ip := addr.IP.String()
+	if packet.Version == gosnmp.Version1 && packet.AgentAddress != "" {
+		ip = packet.AgentAddress
+	}
+
+	if packet.Version == gosnmp.Version3 && !allowV3(packet) {
+		log.Traceln("snmp: unauthorized v3 trap from", ip, oid)
+		return
+	}
+
+	v, ok := receivers.Load(ip)
+	if !ok {
+		log.Traceln("snmp: unexpected trap from", ip, oid)
+		return
+	}
+
+	v.(*sync.Map).Range(func(key, _ interface{}) bool {
+		key.(*Device).onTrap(packet.Version, packet.Community, oid)
+		return true
+	})
+}
+
+//allowV3 认证和解密已经由trap服务完成，这里检查用户名和安全级别，拒绝没有认证的v3 trap
+func allowV3(packet *gosnmp.SnmpPacket) bool {
+	if trapUser == "" {
+		return false
+	}
+
+	usm, ok := packet.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if !ok || usm.UserName != trapUser {
+		return false
+	}
+
+	return packet.MsgFlags&gosnmp.AuthPriv >= trapFlags
+}
+
+func trapOID(packet *gosnmp.SnmpPacket) string {
+	if packet.Version == gosnmp.Version1 {
+		if packet.GenericTrap == 6 {
+			return normalizeOID(packet.Enterprise) + ".0." + strconv.Itoa(packet.SpecificTrap)
+		}
+		return genericTrapPrefix + strconv.Itoa(packet.GenericTrap+1)
+	}
+
+	for _, v := range packet.Variables {
+		if normalizeOID(v.Name) == snmpTrapOID {
+			if str, ok := v.Value.(string); ok {
+				return normalizeOID(str)
+			}
+		}
+	}
+	return ""
+}
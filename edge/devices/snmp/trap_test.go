@@ -0,0 +1,82 @@
+package snmp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+func sendTrap(t *testing.T, port int, conf *Conf) {
+	client := &gosnmp.GoSNMP{
+		Target:    "127.0.0.1",
+		Port:      uint16(port),
+		Transport: "udp",
+		Timeout:   time.Second,
+		MaxOids:   gosnmp.MaxOids,
+	}
+	if err := conf.apply(client); err != nil {
+		t.Fatal(err)
+	}
+	if client.Version == gosnmp.Version3 {
+		//trap由发送方作为权威引擎
+		client.SecurityParameters.(*gosnmp.UsmSecurityParameters).AuthoritativeEngineID = "8000000001020304"
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Conn.Close()
+
+	_, err := client.SendTrap(gosnmp.SnmpTrap{
+		Variables: []gosnmp.SnmpPDU{
+			{Name: snmpTrapOID, Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1.9999.1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTrapV3(t *testing.T) {
+	const port = 19162
+
+	v3 := &V3Conf{User: "edge", AuthProto: "SHA", AuthPass: "authpassword", PrivProto: "AES", PrivPass: "privpassword"}
+	if err := StartTrapServer("127.0.0.1", port, v3); err != nil {
+		t.Fatal(err)
+	}
+	defer CloseTrapServer()
+
+	device, err := New(`{"version": "3", "v3": {"user": "edge"}, "traps": [{"oid": ".1.3.6.1.4.1.9999.1", "name": "DI-1"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	register("127.0.0.1", device)
+	defer unregister("127.0.0.1", device)
+
+	received := func() bool {
+		time.Sleep(200 * time.Millisecond)
+		device.mu.Lock()
+		defer device.mu.Unlock()
+
+		state := device.traps["DI-1"]
+		v := state.pending
+		state.pending = false
+		return v
+	}
+
+	//没有认证的v2c和v3 trap都不能触发v3设备的点位
+	sendTrap(t, port, &Conf{Version: "2c", Community: "public"})
+	if received() {
+		t.Fatal("v2c trap accepted by v3 device")
+	}
+
+	sendTrap(t, port, &Conf{Version: "3", V3: &V3Conf{User: "edge"}})
+	if received() {
+		t.Fatal("noAuthNoPriv trap accepted")
+	}
+
+	sendTrap(t, port, &Conf{Version: "3", V3: v3})
+	if !received() {
+		t.Fatal("authPriv trap rejected")
+	}
+}
@@ -0,0 +1,100 @@
+package totalizer
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+
+	bolt "github.com/etcd-io/bbolt"
+)
+
+const (
+	bucketName = "totalizer"
+)
+
+var (
+	db *bolt.DB
+)
+
+//Open 打开累计器状态存储，没有打开时累计值在edge重启后丢失
+func Open(filename string) error {
+	d, err := bolt.Open(filename, 0666, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return err
+	}
+
+	err = d.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(bucketName))
+		return err
+	})
+	if err != nil {
+		_ = d.Close()
+		return err
+	}
+
+	db = d
+	return nil
+}
+
+func Close() {
+	if db != nil {
+		_ = db.Close()
+		db = nil
+	}
+}
+
+func key(uid, tag string) []byte {
+	return []byte(uid + "." + tag)
+}
+
+//Load 读取已保存的状态
+func Load(uid string, t *Totalizer) {
+	if db == nil {
+		return
+	}
+
+	_ = db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte(bucketName)).Get(key(uid, t.Tag))
+		if data == nil {
+			return nil
+		}
+
+		var state State
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&state); err != nil {
+			return err
+		}
+
+		t.SetState(state)
+		return nil
+	})
+}
+
+//States 复制累计器的状态，调用者持有锁时复制，保存时不需要再持有锁
+func States(list []*Totalizer) map[string]State {
+	states := make(map[string]State, len(list))
+	for _, t := range list {
+		states[t.Tag] = t.State()
+	}
+	return states
+}
+
+//Save 保存一个设备全部累计器的状态
+func Save(uid string, states map[string]State) error {
+	if db == nil || len(states) == 0 {
+		return nil
+	}
+
+	return db.Batch(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		for tag, state := range states {
+			var b bytes.Buffer
+			if err := gob.NewEncoder(&b).Encode(state); err != nil {
+				return err
+			}
+			if err := bucket.Put(key(uid, tag), b.Bytes()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,131 @@
+package totalizer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/maritimusj/centrum/json_rpc"
+)
+
+const (
+	//AI流量累计，梯形积分
+	KindFlow = "flow"
+	//DI/DO运行时间，小时
+	KindRuntime = "runtime"
+	//DI/DO启动次数，上升沿计数
+	KindStarts = "starts"
+
+	//超过这个间隔没有数据时不做积分，避免设备离线期间的数据被计入
+	defaultMaxGap = 5 * time.Minute
+)
+
+//State 累计器状态，需要持久化
+type State struct {
+	Value    float64
+	Last     float64
+	LastTime time.Time
+}
+
+type Totalizer struct {
+	json_rpc.TotalizerCH
+	state State
+}
+
+func New(conf json_rpc.TotalizerCH) (*Totalizer, error) {
+	if !strings.HasPrefix(strings.ToUpper(conf.Tag), "AI-") {
+		return nil, fmt.Errorf("invalid totalizer tag: %s", conf.Tag)
+	}
+
+	if conf.Source == "" {
+		return nil, fmt.Errorf("invalid totalizer source: %s", conf.Tag)
+	}
+
+	switch conf.Kind {
+	case KindFlow:
+		//默认流量单位为每小时
+		if conf.Scale == 0 {
+			conf.Scale = 1.0 / 3600
+		}
+	case KindRuntime, KindStarts:
+		if conf.Scale == 0 {
+			conf.Scale = 1
+		}
+	default:
+		return nil, fmt.Errorf("invalid totalizer kind: %s", conf.Kind)
+	}
+
+	if conf.Title == "" {
+		conf.Title = conf.Tag
+	}
+
+	return &Totalizer{
+		TotalizerCH: conf,
+	}, nil
+}
+
+func (t *Totalizer) State() State {
+	return t.state
+}
+
+func (t *Totalizer) SetState(state State) {
+	t.state = state
+}
+
+func (t *Totalizer) Reset() {
+	t.state.Value = 0
+}
+
+func (t *Totalizer) maxGap() time.Duration {
+	if t.MaxGap > 0 {
+		return time.Duration(t.MaxGap) * time.Second
+	}
+	return defaultMaxGap
+}
+
+//Update 使用本周期的源点位值更新累计值
+func (t *Totalizer) Update(v interface{}, now time.Time) float64 {
+	var x float64
+	switch val := v.(type) {
+	case float64:
+		x = val
+	case float32:
+		x = float64(val)
+	case bool:
+		if val {
+			x = 1
+		}
+	default:
+		return t.state.Value
+	}
+
+	last, lastTime := t.state.Last, t.state.LastTime
+	t.state.Last, t.state.LastTime = x, now
+
+	if lastTime.IsZero() {
+		return t.state.Value
+	}
+
+	if t.Kind == KindStarts {
+		if last == 0 && x != 0 {
+			t.state.Value += t.Scale
+		}
+		return t.state.Value
+	}
+
+	dt := now.Sub(lastTime)
+	if dt <= 0 || dt > t.maxGap() {
+		return t.state.Value
+	}
+
+	switch t.Kind {
+	case KindFlow:
+		t.state.Value += (last + x) / 2 * dt.Seconds() * t.Scale
+	case KindRuntime:
+		if last != 0 {
+			t.state.Value += dt.Hours() * t.Scale
+		}
+	}
+
+	return t.state.Value
+}
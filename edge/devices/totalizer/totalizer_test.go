@@ -0,0 +1,73 @@
+package totalizer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/maritimusj/centrum/json_rpc"
+)
+
+func TestUpdate(t *testing.T) {
+	type sample struct {
+		seconds int
+		v       interface{}
+	}
+
+	cases := []struct {
+		name     string
+		conf     json_rpc.TotalizerCH
+		samples  []sample
+		expected float64
+	}{
+		{
+			//每小时3600，梯形积分 (0+3600)/2*1s + (3600+3600)/2*1s
+			name:     "flow",
+			conf:     json_rpc.TotalizerCH{Tag: "AI-10", Source: "AI-1", Kind: KindFlow},
+			samples:  []sample{{0, 0.0}, {1, 3600.0}, {2, float32(3600)}},
+			expected: 1.5,
+		},
+		{
+			name:     "flow gap",
+			conf:     json_rpc.TotalizerCH{Tag: "AI-10", Source: "AI-1", Kind: KindFlow, MaxGap: 10},
+			samples:  []sample{{0, 3600.0}, {1, 3600.0}, {100, 3600.0}, {101, 3600.0}},
+			expected: 2,
+		},
+		{
+			//只计算上一个周期处于运行状态的时间
+			name:     "runtime",
+			conf:     json_rpc.TotalizerCH{Tag: "AI-11", Source: "DI-1", Kind: KindRuntime},
+			samples:  []sample{{0, true}, {60, true}, {120, false}, {180, true}},
+			expected: 120.0 / 3600,
+		},
+		{
+			name:     "starts",
+			conf:     json_rpc.TotalizerCH{Tag: "AI-12", Source: "DI-1", Kind: KindStarts},
+			samples:  []sample{{0, true}, {1, false}, {2, true}, {3, true}, {4, false}, {5, true}},
+			expected: 2,
+		},
+		{
+			//无法识别的数值不影响累计
+			name:     "invalid value",
+			conf:     json_rpc.TotalizerCH{Tag: "AI-12", Source: "DI-1", Kind: KindStarts},
+			samples:  []sample{{0, false}, {1, "on"}, {2, true}},
+			expected: 1,
+		},
+	}
+
+	for _, c := range cases {
+		total, err := New(c.conf)
+		if err != nil {
+			t.Fatal(c.name, err)
+		}
+
+		var v float64
+		for _, s := range c.samples {
+			v = total.Update(s.v, time.Unix(int64(s.seconds), 0))
+		}
+
+		if math.Abs(v-c.expected) > 1e-9 {
+			t.Fatal(c.name, "expected:", c.expected, "got:", v)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package virtual
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Knetic/govaluate"
+	"github.com/maritimusj/centrum/json_rpc"
+)
+
+var (
+	ErrInvalidArgs = errors.New("invalid arguments")
+
+	//表达式中可用的函数
+	functions = map[string]govaluate.ExpressionFunction{
+		"min": func(args ...interface{}) (interface{}, error) {
+			values, err := numbers(args)
+			if err != nil {
+				return nil, err
+			}
+			v := values[0]
+			for _, x := range values[1:] {
+				v = math.Min(v, x)
+			}
+			return v, nil
+		},
+		"max": func(args ...interface{}) (interface{}, error) {
+			values, err := numbers(args)
+			if err != nil {
+				return nil, err
+			}
+			v := values[0]
+			for _, x := range values[1:] {
+				v = math.Max(v, x)
+			}
+			return v, nil
+		},
+		"avg": func(args ...interface{}) (interface{}, error) {
+			values, err := numbers(args)
+			if err != nil {
+				return nil, err
+			}
+			var sum float64
+			for _, x := range values {
+				sum += x
+			}
+			return sum / float64(len(values)), nil
+		},
+		"abs": func(args ...interface{}) (interface{}, error) {
+			values, err := numbers(args)
+			if err != nil || len(values) != 1 {
+				return nil, ErrInvalidArgs
+			}
+			return math.Abs(values[0]), nil
+		},
+		"round": func(args ...interface{}) (interface{}, error) {
+			values, err := numbers(args)
+			if err != nil || len(values) > 2 {
+				return nil, ErrInvalidArgs
+			}
+			n := 0.0
+			if len(values) == 2 {
+				n = values[1]
+			}
+			p := math.Pow(10, n)
+			return math.Round(values[0]*p) / p, nil
+		},
+		"sqrt": func(args ...interface{}) (interface{}, error) {
+			values, err := numbers(args)
+			if err != nil || len(values) != 1 {
+				return nil, ErrInvalidArgs
+			}
+			return math.Sqrt(values[0]), nil
+		},
+		"any": func(args ...interface{}) (interface{}, error) {
+			for _, arg := range args {
+				if isTrue(arg) {
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+		"all": func(args ...interface{}) (interface{}, error) {
+			for _, arg := range args {
+				if !isTrue(arg) {
+					return false, nil
+				}
+			}
+			return len(args) > 0, nil
+		},
+		"count": func(args ...interface{}) (interface{}, error) {
+			var n float64
+			for _, arg := range args {
+				if isTrue(arg) {
+					n++
+				}
+			}
+			return n, nil
+		},
+		//时间函数，使用edge本地时间
+		"now": func(args ...interface{}) (interface{}, error) {
+			return float64(time.Now().Unix()), nil
+		},
+		"hour": func(args ...interface{}) (interface{}, error) {
+			return float64(time.Now().Hour()), nil
+		},
+		"minute": func(args ...interface{}) (interface{}, error) {
+			return float64(time.Now().Minute()), nil
+		},
+		"weekday": func(args ...interface{}) (interface{}, error) {
+			return float64(time.Now().Weekday()), nil
+		},
+		"day": func(args ...interface{}) (interface{}, error) {
+			return float64(time.Now().Day()), nil
+		},
+		"month": func(args ...interface{}) (interface{}, error) {
+			return float64(time.Now().Month()), nil
+		},
+	}
+)
+
+//Channel 由表达式计算得到的虚拟点位
+type Channel struct {
+	json_rpc.VirtualCH
+	expr *govaluate.EvaluableExpression
+}
+
+//Compile 编译虚拟点位列表，点位名称必须以AI-或DI-开头
+func Compile(list []json_rpc.VirtualCH) ([]*Channel, error) {
+	result := make([]*Channel, 0, len(list))
+	for _, conf := range list {
+		kind := strings.ToUpper(strings.SplitN(conf.Tag, "-", 2)[0])
+		if kind != "AI" && kind != "DI" {
+			return nil, fmt.Errorf("invalid virtual ch tag: %s", conf.Tag)
+		}
+
+		expr, err := govaluate.NewEvaluableExpressionWithFunctions(conf.Expr, functions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression of %s: %s", conf.Tag, err)
+		}
+
+		if conf.Title == "" {
+			conf.Title = conf.Tag
+		}
+
+		result = append(result, &Channel{
+			VirtualCH: conf,
+			expr:      expr,
+		})
+	}
+	return result, nil
+}
+
+//Eval 使用当前周期的点位快照计算，AI点位结果为float64，DI点位结果为bool
+func (ch *Channel) Eval(snapshot map[string]interface{}) (interface{}, error) {
+	v, err := ch.expr.Evaluate(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(strings.ToUpper(ch.Tag), "DI") {
+		return isTrue(v), nil
+	}
+
+	switch x := v.(type) {
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, fmt.Errorf("invalid result of %s: %v", ch.Tag, x)
+		}
+		return x, nil
+	case bool:
+		if x {
+			return 1.0, nil
+		}
+		return 0.0, nil
+	default:
+		return nil, fmt.Errorf("invalid result of %s: %v", ch.Tag, v)
+	}
+}
+
+//Value 把点位值转换为表达式可用的类型
+func Value(v interface{}) interface{} {
+	switch x := v.(type) {
+	case float32:
+		return float64(x)
+	case int:
+		return float64(x)
+	case int64:
+		return float64(x)
+	default:
+		return v
+	}
+}
+
+func numbers(args []interface{}) ([]float64, error) {
+	if len(args) == 0 {
+		return nil, ErrInvalidArgs
+	}
+
+	values := make([]float64, 0, len(args))
+	for _, arg := range args {
+		switch x := arg.(type) {
+		case float64:
+			values = append(values, x)
+		case bool:
+			if x {
+				values = append(values, 1)
+			} else {
+				values = append(values, 0)
+			}
+		default:
+			return nil, ErrInvalidArgs
+		}
+	}
+	return values, nil
+}
+
+func isTrue(v interface{}) bool {
+	switch x := v.(type) {
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,42 @@
+package virtual
+
+import (
+	"testing"
+
+	"github.com/maritimusj/centrum/json_rpc"
+)
+
+func TestEval(t *testing.T) {
+	channels, err := Compile([]json_rpc.VirtualCH{
+		{Tag: "AI-DP", Expr: "[AI-1] - [AI-2]"},
+		{Tag: "AI-AVG", Expr: "round(avg([AI-1], [AI-2], [AI-DP]), 1)"},
+		{Tag: "DI-RUN", Expr: "any([DI-1], [DI-2]) && hour() >= 0"},
+		{Tag: "AI-RUNNING", Expr: "count([DI-1], [DI-2])"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := map[string]interface{}{
+		"AI-1": Value(float32(12.5)),
+		"AI-2": Value(float32(2.5)),
+		"DI-1": false,
+		"DI-2": true,
+	}
+
+	expected := []interface{}{10.0, 8.3, true, 1.0}
+	for i, ch := range channels {
+		v, err := ch.Eval(snapshot)
+		if err != nil {
+			t.Fatal(ch.Tag, err)
+		}
+		if v != expected[i] {
+			t.Fatal(ch.Tag, "expected:", expected[i], "got:", v)
+		}
+		snapshot[ch.Tag] = v
+	}
+
+	if _, err := Compile([]json_rpc.VirtualCH{{Tag: "X-1", Expr: "1"}}); err == nil {
+		t.Fatal("expect invalid tag error")
+	}
+}
@@ -15,11 +15,25 @@ var (
 		lang.Disconnected:        "Disconnected",
 		lang.MalFunctioned:       "MalFunctioned",
 		lang.InfluxDBError:       "InfluxDBError",
+		lang.IdentityMismatch:    "IdentityMismatch",
+		lang.SwitchOn:            "on",
+		lang.SwitchOff:           "off",
+		lang.SwitchByPulse:       "pulse",
+		lang.SwitchBySchedule:    "schedule",
+		lang.SwitchLog:           "%s %s (%s)",
 	}
 
 	errStrMap = map[lang.ErrIndex]string{
-		lang.Ok:                    "Ok",
-		lang.ErrDeviceNotExists:    "device does not exists!",
-		lang.ErrDeviceNotConnected: "device does not connected！",
+		lang.Ok:                         "Ok",
+		lang.ErrDeviceNotExists:         "device does not exists!",
+		lang.ErrDeviceNotConnected:      "device does not connected！",
+		lang.ErrCHNotExists:             "ch does not exists!",
+		lang.ErrDataWithoutTag:          "driver returned data without a tag!",
+		lang.ErrCHReadOnly:              "ch is read only!",
+		lang.ErrUnknownDriver:           "unknown device driver: %s",
+		lang.ErrInvalidScanRange:        "invalid scan range: %s",
+		lang.ErrInvalidPulse:            "invalid pulse time: %d seconds",
+		lang.ErrDiagnosticsNotSupported: "diagnostics is not supported by the device driver",
+		lang.ErrInvalidCaptureNum:       "invalid number of frames to capture: %d",
 	}
 )
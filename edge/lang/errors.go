@@ -31,6 +31,14 @@ const (
 
 	ErrDeviceNotExists
 	ErrDeviceNotConnected
+	ErrCHNotExists
+	ErrDataWithoutTag
+	ErrCHReadOnly
+	ErrUnknownDriver
+	ErrInvalidScanRange
+	ErrInvalidPulse
+	ErrDiagnosticsNotSupported
+	ErrInvalidCaptureNum
 )
 
 func ErrorStr(index ErrIndex, params ...interface{}) string {
@@ -14,4 +14,11 @@ const (
 	Disconnected
 	MalFunctioned
 	InfluxDBError
+	IdentityMismatch
+
+	SwitchOn
+	SwitchOff
+	SwitchByPulse
+	SwitchBySchedule
+	SwitchLog
 )
@@ -15,11 +15,25 @@ var (
 		lang.Disconnected:        "已断开",
 		lang.MalFunctioned:       "故障",
 		lang.InfluxDBError:       "数据库错误",
+		lang.IdentityMismatch:    "身份不匹配",
+		lang.SwitchOn:            "开启",
+		lang.SwitchOff:           "关闭",
+		lang.SwitchByPulse:       "脉冲",
+		lang.SwitchBySchedule:    "定时",
+		lang.SwitchLog:           "%s %s（%s）",
 	}
 
 	errStrMap = map[lang.ErrIndex]string{
-		lang.Ok:                    "成功！",
-		lang.ErrDeviceNotExists:    "设备不存在！",
-		lang.ErrDeviceNotConnected: "设备没有连接！",
+		lang.Ok:                         "成功！",
+		lang.ErrDeviceNotExists:         "设备不存在！",
+		lang.ErrDeviceNotConnected:      "设备没有连接！",
+		lang.ErrCHNotExists:             "点位不存在！",
+		lang.ErrDataWithoutTag:          "驱动返回的数据缺少点位名称！",
+		lang.ErrCHReadOnly:              "点位不支持写入！",
+		lang.ErrUnknownDriver:           "不支持的设备驱动：%s",
+		lang.ErrInvalidScanRange:        "无效的扫描范围：%s",
+		lang.ErrInvalidPulse:            "无效的脉冲时间：%d秒",
+		lang.ErrDiagnosticsNotSupported: "设备驱动不支持通讯诊断",
+		lang.ErrInvalidCaptureNum:       "无效的抓包数量：%d",
 	}
 )
@@ -5,10 +5,16 @@ import (
 	"io/ioutil"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/maritimusj/centrum/edge/devices/InverseServer"
 	"github.com/maritimusj/centrum/edge/devices/event"
+	"github.com/maritimusj/centrum/edge/devices/metrics"
+	"github.com/maritimusj/centrum/edge/devices/poller"
+	"github.com/maritimusj/centrum/edge/devices/schedule"
+	"github.com/maritimusj/centrum/edge/devices/snmp"
+	"github.com/maritimusj/centrum/edge/devices/totalizer"
 
 	"github.com/maritimusj/centrum/edge/lang"
 	_ "github.com/maritimusj/centrum/edge/lang/enUS"
@@ -53,6 +59,29 @@ func main() {
 	viper.SetDefault("inverse.addr", "")
 	viper.SetDefault("inverse.port", 10502)
 
+	//snmp trap server，默认关闭
+	viper.SetDefault("snmp.trap.enable", false)
+	viper.SetDefault("snmp.trap.addr", "")
+	viper.SetDefault("snmp.trap.port", 162)
+	//v3 trap的USM用户，为空时只接收v1/v2c trap
+	viper.SetDefault("snmp.trap.v3.user", "")
+
+	//累计点位状态文件
+	viper.SetDefault("totalizer.db", "totalizer.db")
+
+	//未结束的脉冲输出
+	viper.SetDefault("pulse.db", "pulse.db")
+
+	//同时访问设备的数量和每个设备同时进行的请求数量
+	viper.SetDefault("poller.workers", poller.DefaultWorkers)
+	viper.SetDefault("poller.inflight", poller.DefaultMaxInFlight)
+
+	//gate地址，设置后定时向gate注册并上报负载
+	viper.SetDefault("gate.url", "")
+	viper.SetDefault("gate.token", "")
+	//gate访问当前edge的rpc地址，默认为http://<edge.addr>:<edge.port>/rpc
+	viper.SetDefault("edge.url", "")
+
 	viper.SetDefault("error.level", "error")
 
 	var l log.Level
@@ -88,11 +117,55 @@ func main() {
 		}
 	}
 
+	var (
+		trapEnable = viper.GetBool("snmp.trap.enable")
+		trapAddr   = viper.GetString("snmp.trap.addr")
+		trapPort   = viper.GetInt("snmp.trap.port")
+	)
+	if trapEnable {
+		//初始化snmp trap server
+		err = snmp.StartTrapServer(trapAddr, trapPort, &snmp.V3Conf{
+			User:      viper.GetString("snmp.trap.v3.user"),
+			AuthProto: viper.GetString("snmp.trap.v3.authProto"),
+			AuthPass:  viper.GetString("snmp.trap.v3.authPass"),
+			PrivProto: viper.GetString("snmp.trap.v3.privProto"),
+			PrivPass:  viper.GetString("snmp.trap.v3.privPass"),
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	//累计点位状态存储
+	err = totalizer.Open(viper.GetString("totalizer.db"))
+	if err != nil {
+		log.Error(err)
+	}
+
+	//脉冲输出状态存储
+	err = schedule.Open(viper.GetString("pulse.db"))
+	if err != nil {
+		log.Error(err)
+	}
+
 	//初始化rpc服务
 	server := rpc.NewServer()
 	server.RegisterCodec(json.NewCodec(), "application/json")
 	server.RegisterCodec(json.NewCodec(), "application/json;charset=UTF-8")
 
+	//记录gate的请求ID，方便对照两边的日志
+	server.RegisterAfterFunc(func(i *rpc.RequestInfo) {
+		id := i.Request.Header.Get(json_rpc.RequestIDHeader)
+		if i.Error != nil {
+			log.Warningf("[rpc] %s %s: %s", id, i.Method, i.Error)
+		} else {
+			log.Debugf("[rpc] %s %s", id, i.Method)
+		}
+	})
+
+	//设备访问队列
+	poller.Init(viper.GetInt("poller.workers"), viper.GetInt("poller.inflight"))
+
 	//初始化runner
 	runner := devices.New()
 	edge := json_rpc.New(runner)
@@ -103,6 +176,7 @@ func main() {
 
 	r := mux.NewRouter()
 	r.Handle("/rpc", server)
+	r.Handle("/metrics", metrics.Handler())
 
 	go func() {
 		var (
@@ -116,6 +190,18 @@ func main() {
 		}
 	}()
 
+	if gateURL := viper.GetString("gate.url"); gateURL != "" {
+		url := viper.GetString("edge.url")
+		if url == "" {
+			addr := viper.GetString("edge.addr")
+			if addr == "" {
+				addr = "localhost"
+			}
+			url = fmt.Sprintf("http://%s:%d/rpc", addr, viper.GetInt("edge.port"))
+		}
+		runner.StartRegister(strings.TrimRight(gateURL, "/"), url, viper.GetString("gate.token"))
+	}
+
 	pidFile := viper.GetString("pid.file")
 	if pidFile != "" {
 		pid := fmt.Sprintf("%d", os.Getpid())
@@ -152,5 +238,12 @@ func main() {
 		InverseServer.Close()
 	}
 
+	if trapEnable {
+		snmp.CloseTrapServer()
+	}
+
 	runner.Close()
+
+	totalizer.Close()
+	schedule.Close()
 }
@@ -30,14 +30,17 @@ func Register(clientID string, user model.User) error {
 }
 
 /**
-向指定用户推送消息
+向指定用户推送消息，返回最后一个失败的错误
 */
-func SendTo(user model.User, title, content string) {
+func SendTo(user model.User, title, content string) error {
+	var lastErr error
 	result := properties.LoadAllString("user", user.Name())
 	for clientID := range result {
 		err := defaultClient.toSingle(clientID, title, content)
 		if err != nil {
 			user.Logger().Warnln(lang.ErrGeTuiSendMessageFailed.Str(err))
+			lastErr = err
 		}
 	}
+	return lastErr
 }
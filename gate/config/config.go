@@ -31,6 +31,10 @@ const (
 	LogFileNamePath            = "log.filename"
 	StreamURLsPath             = "stream.urls"
 	WebViewURLsPath            = "web.urls"
+	AlarmRoutingPath           = "alarm.routing"
+	AlarmShelvesPath           = "alarm.shelves"
+	AlarmPrioritiesPath        = "alarm.priorities"
+	InboxRetentionPath         = "inbox.retention"
 	GeTuiAppIDPath             = "getui.app.id"
 	GeTuiAppKeyPath            = "getui.app.key"
 	GeTuiAppSecretPath         = "getui.app.secret"
@@ -137,6 +141,58 @@ func (c *Config) SaveWebViewUrls(urls interface{}) error {
 	return c.ExtraConfig.Save()
 }
 
+//AlarmRouting 警报通知的路由和升级设置
+func (c *Config) AlarmRouting() string {
+	return c.ExtraConfig.GetOption(AlarmRoutingPath).Raw
+}
+
+func (c *Config) SaveAlarmRouting(routing interface{}) error {
+	err := c.ExtraConfig.SetOption(AlarmRoutingPath, routing)
+	if err != nil {
+		return err
+	}
+	return c.ExtraConfig.Save()
+}
+
+//AlarmShelves 搁置的点位和维护中的设备
+func (c *Config) AlarmShelves() string {
+	return c.ExtraConfig.GetOption(AlarmShelvesPath).Raw
+}
+
+func (c *Config) SaveAlarmShelves(shelves interface{}) error {
+	err := c.ExtraConfig.SetOption(AlarmShelvesPath, shelves)
+	if err != nil {
+		return err
+	}
+	return c.ExtraConfig.Save()
+}
+
+//AlarmPriorities 警报等级和点位的优先级设置
+func (c *Config) AlarmPriorities() string {
+	return c.ExtraConfig.GetOption(AlarmPrioritiesPath).Raw
+}
+
+func (c *Config) SaveAlarmPriorities(priorities interface{}) error {
+	err := c.ExtraConfig.SetOption(AlarmPrioritiesPath, priorities)
+	if err != nil {
+		return err
+	}
+	return c.ExtraConfig.Save()
+}
+
+//InboxRetention 站内消息的保留设置
+func (c *Config) InboxRetention() string {
+	return c.ExtraConfig.GetOption(InboxRetentionPath).Raw
+}
+
+func (c *Config) SaveInboxRetention(retention interface{}) error {
+	err := c.ExtraConfig.SetOption(InboxRetentionPath, retention)
+	if err != nil {
+		return err
+	}
+	return c.ExtraConfig.Save()
+}
+
 func (c *Config) RegCode() string {
 	code := c.BaseConfig.GetOption(SysRegCodePath)
 	if code.Exists() {
@@ -14,4 +14,10 @@ const (
 	EquipmentCreated = "equipment::created"
 	EquipmentUpdated = "equipment::updated"
 	EquipmentDeleted = "equipment::deleted"
+
+	DeviceStatusChanged = "device:status::changed"
+
+	AlarmRaised    = "alarm::raised"
+	AlarmConfirmed = "alarm::confirmed"
+	AlarmCleared   = "alarm::cleared"
 )
@@ -41,6 +41,12 @@ var (
 		lang.AlarmUnconfirmed: "unconfirmed",
 		lang.AlarmConfirmed:   "confirmed",
 
+		lang.AlarmPriorityAdvisory: "advisory",
+		lang.AlarmPriorityLow:      "low",
+		lang.AlarmPriorityMedium:   "medium",
+		lang.AlarmPriorityHigh:     "high",
+		lang.AlarmPriorityCritical: "critical",
+
 		lang.RoleSystemAdminTitle:       "sysadmin",
 		lang.RoleOrganizationAdminTitle: "admin",
 		lang.RoleGuestTitle:             "guest",
@@ -271,6 +277,31 @@ var (
 		lang.CommentDeleteTitle: "",
 		lang.CommentDeleteDesc:  "",
 
+		lang.RuleListTitle:            "",
+		lang.RuleListDesc:             "",
+		lang.RuleCreateTitle:          "",
+		lang.RuleCreateDesc:           "",
+		lang.RuleDetailTitle:          "",
+		lang.RuleDetailDesc:           "",
+		lang.RuleUpdateTitle:          "",
+		lang.RuleUpdateDesc:           "",
+		lang.RuleDeleteTitle:          "",
+		lang.RuleDeleteDesc:           "",
+		lang.RuleLogListTitle:         "",
+		lang.RuleLogListDesc:          "",
+		lang.WebhookListTitle:         "",
+		lang.WebhookListDesc:          "",
+		lang.WebhookCreateTitle:       "",
+		lang.WebhookCreateDesc:        "",
+		lang.WebhookDetailTitle:       "",
+		lang.WebhookDetailDesc:        "",
+		lang.WebhookUpdateTitle:       "",
+		lang.WebhookUpdateDesc:        "",
+		lang.WebhookDeleteTitle:       "",
+		lang.WebhookDeleteDesc:        "",
+		lang.WebhookDeliveryListTitle: "",
+		lang.WebhookDeliveryListDesc:  "",
+
 		lang.ResourceLogListTitle: "",
 		lang.ResourceLogListDesc:  "",
 
@@ -329,7 +360,58 @@ var (
 		lang.CVSHeaderUser:        "user",
 		lang.CVSHeaderConfirmedBy: "confirmedBy",
 
-		lang.DeviceConnected: "device connected.",
+		lang.DeviceConnected:        "device connected.",
+		lang.DeviceIdentityMismatch: "device identity mismatch, please check if the controller was replaced.",
+		lang.SwitchOn:               "on",
+		lang.SwitchOff:              "off",
+		lang.DeviceCtrlOk:           "%s set %s to %s",
+		lang.DeviceCtrlBlocked:      "%s set %s to %s was rejected: %s",
+		lang.InterlockAlarm:         "%s has unconfirmed alarm",
+		lang.InterlockValue:         "%s %s %v",
+		lang.InterlockNoValue:       "unable to read value of %s",
+		lang.InterlockMinOffTime:    "less than %d seconds since last stop",
+		lang.InterlockMaxStarts:     "at most %[2]d starts within %[1]d seconds",
+		lang.InterlockInvalid:       "invalid interlock condition",
+
+		lang.UserCreateRuleOk:     "%s created rule %s",
+		lang.UserUpdateRuleOk:     "%s updated rule %s",
+		lang.UserDeleteRuleOk:     "%s deleted rule %s",
+		lang.RuleExecuted:         "rule %s executed (%s)",
+		lang.RuleDryRun:           "rule %s dry run, no action taken (%s)",
+		lang.RuleFailed:           "rule %s failed (%s): %s",
+		lang.EdgeRegistered:       "edge %s registered",
+		lang.EdgeRegisterDisabled: "edge.token is not set, edge registration is disabled",
+		lang.EdgeOnline:           "edge %s is back online",
+		lang.EdgeOffline:          "edge %s is not responding: %s",
+		lang.DeviceFailover:       "device %s moved to edge %s",
+		lang.DeviceFailoverFailed: "failed to move device %s: %s",
+		lang.UserPinDeviceOk:      "%s pinned device %s to edge %s",
+		lang.UserUnpinDeviceOk:    "%s unpinned device %s",
+		lang.AlarmNotifyDetail:    "alarm %[3]s on %[1]s/%[2]s, current value: %[4]v",
+		lang.NotifyDelivered:      "notification sent to %[2]s via %[1]s",
+		lang.NotifyFailed:         "failed to send notification to %[2]s via %[1]s: %[3]s",
+
+		lang.AlarmEscalationTitle:     "alarm not confirmed",
+		lang.AlarmEscalated:           "alarm %d is not confirmed in time, tier %d has been notified",
+		lang.UserUpdateAlarmRoutingOk: "%s updated the alarm routing",
+		lang.UserShelveMeasureOk:      "%s shelved measure %s for %d minutes, reason: %s",
+		lang.UserUnshelveMeasureOk:    "%s unshelved measure %s",
+		lang.UserStartMaintenanceOk:   "%s put %s into maintenance for %d minutes, reason: %s",
+		lang.UserEndMaintenanceOk:     "%s ended maintenance of %s",
+		lang.ShelveExpired:            "shelve or maintenance of %s expired",
+		lang.AlarmSuppressed:          "%s alarm %s (%s) suppressed: %s",
+		lang.AlarmFloodDetail:         "alarm flood on %s, %d alarms collapsed",
+		lang.AlarmFloodEnded:          "alarm flood on %s ended, %d alarms collapsed",
+		lang.AlarmFloodMeasure:        "alarm flood",
+		lang.AlarmChattering:          "alarm of %s is chattering, shelved for %d minutes",
+		lang.AlarmChatteringReason:    "chattering",
+
+		lang.UserUpdateAlarmPriorityOk:  "%s updated the alarm priorities",
+		lang.UserUpdateInboxRetentionOk: "%s updated the message retention",
+		lang.UserCreateWebhookOk:        "%s created webhook %s",
+		lang.UserUpdateWebhookOk:        "%s updated webhook %s",
+		lang.UserDeleteWebhookOk:        "%s deleted webhook %s",
+		lang.WebhookDeliveryFailed:      "webhook %s failed to deliver event %s after %d attempts: %v",
 	}
 
 	errStrMap = map[lang.ErrIndex]string{
@@ -387,6 +469,25 @@ var (
 		lang.ErrInvalidRegCode:                  "Invalid registry code.",
 		lang.ErrDeviceDisconnected:              "Device disconnected.",
 		lang.ErrNoEdgeAvailable:                 "No edge is available",
+		lang.ErrNoPendingIdentity:               "No pending device identity.",
+		lang.ErrInterlockBlocked:                "blocked by interlock: %s",
+		lang.ErrInvalidInterlock:                "invalid interlock: %s",
+		lang.ErrInvalidCHValue:                  "no valid value of %s.",
+		lang.ErrRuleNotFound:                    "Rule does not exists.",
+		lang.ErrInvalidRule:                     "invalid rule: %s",
+		lang.ErrEdgeNotFound:                    "edge not found: %s",
+		lang.ErrInvalidEdgeToken:                "invalid edge register token.",
+		lang.ErrEdgeUnavailable:                 "edge %s is temporarily unavailable, please try again later.",
+		lang.ErrInvalidAlarmRouting:             "invalid alarm routing: %v",
+		lang.ErrInvalidShelveDuration:           "invalid duration, must be between 1 and %d minutes",
+		lang.ErrShelveNotFound:                  "not shelved or in maintenance",
+		lang.ErrInvalidAlarmPriority:            "invalid alarm priority: %v",
+		lang.ErrMessageNotFound:                 "message not found",
+		lang.ErrInvalidInboxRetention:           "invalid message retention, days and messages per user must be greater than 0",
+		lang.ErrWebhookNotFound:                 "webhook not found",
+		lang.ErrWebhookDisabled:                 "webhook is disabled",
+		lang.ErrInvalidWebhook:                  "invalid webhook: %v",
+		lang.ErrDeliveryNotFound:                "delivery not found",
 		lang.ErrGeTuiRegisterUserFailed:         "GeTui register user %s failed！",
 		lang.ErrGeTuiSendMessageFailed:          "GeTui send message failed: %s",
 		lang.ErrGeTuiNotInitialized:             "GeTui was not initialized properly",
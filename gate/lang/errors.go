@@ -84,6 +84,30 @@ const (
 	ErrDeviceNotExistsOrActive
 
 	ErrNoEdgeAvailable
+	ErrNoPendingIdentity
+	ErrInterlockBlocked
+	ErrInvalidInterlock
+	ErrInvalidCHValue
+
+	ErrRuleNotFound
+	ErrInvalidRule
+
+	ErrEdgeNotFound
+	ErrInvalidEdgeToken
+	ErrEdgeUnavailable
+
+	ErrInvalidAlarmRouting
+	ErrInvalidShelveDuration
+	ErrShelveNotFound
+	ErrInvalidAlarmPriority
+
+	ErrMessageNotFound
+	ErrInvalidInboxRetention
+
+	ErrWebhookNotFound
+	ErrWebhookDisabled
+	ErrInvalidWebhook
+	ErrDeliveryNotFound
 
 	ErrGeTuiRegisterUserFailed
 	ErrGeTuiSendMessageFailed
@@ -248,6 +248,34 @@ const (
 	CommentCreateDesc
 	CommentDeleteDesc
 
+	RuleListTitle
+	RuleCreateTitle
+	RuleDetailTitle
+	RuleUpdateTitle
+	RuleDeleteTitle
+	RuleLogListTitle
+
+	RuleListDesc
+	RuleCreateDesc
+	RuleDetailDesc
+	RuleUpdateDesc
+	RuleDeleteDesc
+	RuleLogListDesc
+
+	WebhookListTitle
+	WebhookCreateTitle
+	WebhookDetailTitle
+	WebhookUpdateTitle
+	WebhookDeleteTitle
+	WebhookDeliveryListTitle
+
+	WebhookListDesc
+	WebhookCreateDesc
+	WebhookDetailDesc
+	WebhookUpdateDesc
+	WebhookDeleteDesc
+	WebhookDeliveryListDesc
+
 	ResourceLogListTitle
 	ResourceLogListDesc
 	ResourceLogDeleteTitle
@@ -282,6 +310,12 @@ const (
 	AlarmUnconfirmed
 	AlarmConfirmed
 
+	AlarmPriorityAdvisory
+	AlarmPriorityLow
+	AlarmPriorityMedium
+	AlarmPriorityHigh
+	AlarmPriorityCritical
+
 	SysBriefTitle
 	SysBriefDesc
 
@@ -310,11 +344,67 @@ const (
 	CVSHeaderConfirmedBy
 
 	DeviceConnected
+	DeviceIdentityMismatch
+
+	SwitchOn
+	SwitchOff
+	DeviceCtrlOk
+	DeviceCtrlBlocked
+	InterlockAlarm
+	InterlockValue
+	InterlockNoValue
+	InterlockMinOffTime
+	InterlockMaxStarts
+	InterlockInvalid
+
+	UserCreateRuleOk
+	UserUpdateRuleOk
+	UserDeleteRuleOk
+	RuleExecuted
+	RuleDryRun
+	RuleFailed
+
+	EdgeRegistered
+	EdgeRegisterDisabled
+	EdgeOnline
+	EdgeOffline
+	DeviceFailover
+	DeviceFailoverFailed
+	UserPinDeviceOk
+	UserUnpinDeviceOk
+
+	AlarmNotifyDetail
+	NotifyDelivered
+	NotifyFailed
+	AlarmEscalationTitle
+	AlarmEscalated
+	UserUpdateAlarmRoutingOk
+
+	UserShelveMeasureOk
+	UserUnshelveMeasureOk
+	UserStartMaintenanceOk
+	UserEndMaintenanceOk
+	ShelveExpired
+	AlarmSuppressed
+	AlarmFloodDetail
+	AlarmFloodEnded
+	AlarmFloodMeasure
+	AlarmChattering
+	AlarmChatteringReason
+
+	UserUpdateAlarmPriorityOk
+	UserUpdateInboxRetentionOk
+
+	UserCreateWebhookOk
+	UserUpdateWebhookOk
+	UserDeleteWebhookOk
+	WebhookDeliveryFailed
 )
 
 var (
 	resourceGroupsMap map[resource.Class]string
 	alarmStatusDesc   map[int]string
+	alarmPriorityDesc map[int]string
 )
 
 func load() {
@@ -332,6 +422,13 @@ func load() {
 		status.Unconfirmed: Str(AlarmUnconfirmed),
 		status.Confirmed:   Str(AlarmConfirmed),
 	}
+	alarmPriorityDesc = map[int]string{
+		status.PriorityAdvisory: Str(AlarmPriorityAdvisory),
+		status.PriorityLow:      Str(AlarmPriorityLow),
+		status.PriorityMedium:   Str(AlarmPriorityMedium),
+		status.PriorityHigh:     Str(AlarmPriorityHigh),
+		status.PriorityCritical: Str(AlarmPriorityCritical),
+	}
 }
 
 var (
@@ -470,6 +567,20 @@ func ApiResourcesMap() [][3]string {
 		{resource.CommentCreate, Str(CommentCreateTitle), Str(CommentCreateDesc)},
 		{resource.CommentDelete, Str(CommentDeleteTitle), Str(CommentDeleteDesc)},
 
+		{resource.RuleList, Str(RuleListTitle), Str(RuleListDesc)},
+		{resource.RuleCreate, Str(RuleCreateTitle), Str(RuleCreateDesc)},
+		{resource.RuleDetail, Str(RuleDetailTitle), Str(RuleDetailDesc)},
+		{resource.RuleUpdate, Str(RuleUpdateTitle), Str(RuleUpdateDesc)},
+		{resource.RuleDelete, Str(RuleDeleteTitle), Str(RuleDeleteDesc)},
+		{resource.RuleLogList, Str(RuleLogListTitle), Str(RuleLogListDesc)},
+
+		{resource.WebhookList, Str(WebhookListTitle), Str(WebhookListDesc)},
+		{resource.WebhookCreate, Str(WebhookCreateTitle), Str(WebhookCreateDesc)},
+		{resource.WebhookDetail, Str(WebhookDetailTitle), Str(WebhookDetailDesc)},
+		{resource.WebhookUpdate, Str(WebhookUpdateTitle), Str(WebhookUpdateDesc)},
+		{resource.WebhookDelete, Str(WebhookDeleteTitle), Str(WebhookDeleteDesc)},
+		{resource.WebhookDeliveryList, Str(WebhookDeliveryListTitle), Str(WebhookDeliveryListDesc)},
+
 		{resource.LogList, Str(ResourceLogListTitle), Str(ResourceLogListDesc)},
 		{resource.LogDelete, Str(ResourceLogDeleteTitle), Str(ResourceLogDeleteDesc)},
 
@@ -499,3 +610,10 @@ func AlarmStatusDesc(stats int) string {
 	}
 	panic(errors.New("unknown alarm status"))
 }
+
+func AlarmPriorityDesc(priority int) string {
+	if len(alarmPriorityDesc) == 0 {
+		load()
+	}
+	return alarmPriorityDesc[priority]
+}
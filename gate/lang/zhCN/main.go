@@ -41,6 +41,12 @@ var (
 		lang.AlarmUnconfirmed: "未确认",
 		lang.AlarmConfirmed:   "已确认",
 
+		lang.AlarmPriorityAdvisory: "提示",
+		lang.AlarmPriorityLow:      "低",
+		lang.AlarmPriorityMedium:   "中",
+		lang.AlarmPriorityHigh:     "高",
+		lang.AlarmPriorityCritical: "紧急",
+
 		lang.RoleSystemAdminTitle:       "系统管理员",
 		lang.RoleOrganizationAdminTitle: "管理员",
 		lang.RoleGuestTitle:             "普通用户",
@@ -287,6 +293,31 @@ var (
 		lang.CommentDeleteTitle: "",
 		lang.CommentDeleteDesc:  "",
 
+		lang.RuleListTitle:            "",
+		lang.RuleListDesc:             "",
+		lang.RuleCreateTitle:          "",
+		lang.RuleCreateDesc:           "",
+		lang.RuleDetailTitle:          "",
+		lang.RuleDetailDesc:           "",
+		lang.RuleUpdateTitle:          "",
+		lang.RuleUpdateDesc:           "",
+		lang.RuleDeleteTitle:          "",
+		lang.RuleDeleteDesc:           "",
+		lang.RuleLogListTitle:         "",
+		lang.RuleLogListDesc:          "",
+		lang.WebhookListTitle:         "",
+		lang.WebhookListDesc:          "",
+		lang.WebhookCreateTitle:       "",
+		lang.WebhookCreateDesc:        "",
+		lang.WebhookDetailTitle:       "",
+		lang.WebhookDetailDesc:        "",
+		lang.WebhookUpdateTitle:       "",
+		lang.WebhookUpdateDesc:        "",
+		lang.WebhookDeleteTitle:       "",
+		lang.WebhookDeleteDesc:        "",
+		lang.WebhookDeliveryListTitle: "",
+		lang.WebhookDeliveryListDesc:  "",
+
 		lang.ResourceLogListTitle: "",
 		lang.ResourceLogListDesc:  "",
 
@@ -345,7 +376,58 @@ var (
 		lang.CVSHeaderUser:        "用户",
 		lang.CVSHeaderConfirmedBy: "确认人",
 
-		lang.DeviceConnected: "设备已连接！",
+		lang.DeviceConnected:        "设备已连接！",
+		lang.DeviceIdentityMismatch: "设备身份不匹配，请确认是否更换了控制器！",
+		lang.SwitchOn:               "开启",
+		lang.SwitchOff:              "关闭",
+		lang.DeviceCtrlOk:           "%s 将 %s 设置为%s",
+		lang.DeviceCtrlBlocked:      "%s 将 %s 设置为%s 被拒绝：%s",
+		lang.InterlockAlarm:         "%s存在未确认的警报",
+		lang.InterlockValue:         "%s %s %v",
+		lang.InterlockNoValue:       "无法读取%s的值",
+		lang.InterlockMinOffTime:    "距离上次停止不足%d秒",
+		lang.InterlockMaxStarts:     "%d秒内最多启动%d次",
+		lang.InterlockInvalid:       "联锁条件无效",
+
+		lang.UserCreateRuleOk:     "%s 创建了规则 %s",
+		lang.UserUpdateRuleOk:     "%s 修改了规则 %s",
+		lang.UserDeleteRuleOk:     "%s 删除了规则 %s",
+		lang.RuleExecuted:         "规则 %s 已执行（%s）",
+		lang.RuleDryRun:           "规则 %s 试运行，未执行任何动作（%s）",
+		lang.RuleFailed:           "规则 %s 执行失败（%s）：%s",
+		lang.EdgeRegistered:       "edge %s 已注册",
+		lang.EdgeRegisterDisabled: "没有设置edge.token，edge动态注册已禁用",
+		lang.EdgeOnline:           "edge %s 已恢复",
+		lang.EdgeOffline:          "edge %s 没有响应：%s",
+		lang.DeviceFailover:       "设备 %s 已转移到 edge %s",
+		lang.DeviceFailoverFailed: "设备 %s 转移失败：%s",
+		lang.UserPinDeviceOk:      "%s 将设备 %s 固定到 edge %s",
+		lang.UserUnpinDeviceOk:    "%s 取消了设备 %s 的固定 edge",
+		lang.AlarmNotifyDetail:    "设备 %s 的点位 %s 发生 %s 警报，当前值：%v",
+		lang.NotifyDelivered:      "通过 %s 向用户 %s 发送通知成功",
+		lang.NotifyFailed:         "通过 %s 向用户 %s 发送通知失败：%s",
+
+		lang.AlarmEscalationTitle:     "警报未确认",
+		lang.AlarmEscalated:           "警报 %d 未及时确认，已通知第 %d 级",
+		lang.UserUpdateAlarmRoutingOk: "%s 更新了警报通知设置",
+		lang.UserShelveMeasureOk:      "%s 搁置了点位 %s %d 分钟，原因：%s",
+		lang.UserUnshelveMeasureOk:    "%s 取消了点位 %s 的搁置",
+		lang.UserStartMaintenanceOk:   "%s 将 %s 设置为维护模式 %d 分钟，原因：%s",
+		lang.UserEndMaintenanceOk:     "%s 结束了 %s 的维护模式",
+		lang.ShelveExpired:            "%s 的搁置或者维护已到期，自动恢复",
+		lang.AlarmSuppressed:          "%s 发生警报 %s（%s），已被抑制：%s",
+		lang.AlarmFloodDetail:         "%s 警报泛滥，已合并 %d 个警报",
+		lang.AlarmFloodEnded:          "%s 警报泛滥已结束，共合并 %d 个警报",
+		lang.AlarmFloodMeasure:        "警报泛滥",
+		lang.AlarmChattering:          "点位 %s 的警报反复出现，自动搁置 %d 分钟",
+		lang.AlarmChatteringReason:    "警报反复出现",
+
+		lang.UserUpdateAlarmPriorityOk:  "%s 更新了警报优先级设置",
+		lang.UserUpdateInboxRetentionOk: "%s 更新了消息保留设置",
+		lang.UserCreateWebhookOk:        "%s 创建了webhook %s",
+		lang.UserUpdateWebhookOk:        "%s 修改了webhook %s",
+		lang.UserDeleteWebhookOk:        "%s 删除了webhook %s",
+		lang.WebhookDeliveryFailed:      "webhook %s 投递事件 %s 失败，已尝试%d次：%v",
 	}
 
 	errStrMap = map[lang.ErrIndex]string{
@@ -403,6 +485,25 @@ var (
 		lang.ErrInvalidRegCode:                  "无效的注册码！",
 		lang.ErrDeviceDisconnected:              "断开连接！",
 		lang.ErrNoEdgeAvailable:                 "没有可用的edge程序，请重启系统！",
+		lang.ErrNoPendingIdentity:               "没有需要确认的设备身份！",
+		lang.ErrInterlockBlocked:                "联锁禁止：%s",
+		lang.ErrInvalidInterlock:                "联锁条件配置错误：%s",
+		lang.ErrInvalidCHValue:                  "点位%s没有有效的数值！",
+		lang.ErrRuleNotFound:                    "没有找到这个规则！",
+		lang.ErrInvalidRule:                     "规则配置错误：%s",
+		lang.ErrEdgeNotFound:                    "edge不存在：%s",
+		lang.ErrInvalidEdgeToken:                "edge注册令牌无效！",
+		lang.ErrEdgeUnavailable:                 "edge %s 暂时不可用，请稍后再试！",
+		lang.ErrInvalidAlarmRouting:             "警报通知设置不正确：%v",
+		lang.ErrInvalidShelveDuration:           "时长不正确，应在1到%d分钟之间",
+		lang.ErrShelveNotFound:                  "没有搁置或者维护记录",
+		lang.ErrInvalidAlarmPriority:            "警报优先级设置不正确：%v",
+		lang.ErrMessageNotFound:                 "没有找到这条消息！",
+		lang.ErrInvalidInboxRetention:           "消息保留设置不正确，保留天数和每个用户的消息数量都应大于0",
+		lang.ErrWebhookNotFound:                 "没有找到这个webhook！",
+		lang.ErrWebhookDisabled:                 "webhook已禁用！",
+		lang.ErrInvalidWebhook:                  "webhook设置不正确：%v",
+		lang.ErrDeliveryNotFound:                "没有找到这条投递记录！",
 		lang.ErrGeTuiRegisterUserFailed:         "个推注册用户%s失败！",
 		lang.ErrGeTuiSendMessageFailed:          "无法推送警报消息：%s",
 		lang.ErrGeTuiNotInitialized:             "个推没有正确配置！",
@@ -41,6 +41,12 @@ var (
 		lang.AlarmUnconfirmed: "未確認",
 		lang.AlarmConfirmed:   "已確認",
 
+		lang.AlarmPriorityAdvisory: "提示",
+		lang.AlarmPriorityLow:      "低",
+		lang.AlarmPriorityMedium:   "中",
+		lang.AlarmPriorityHigh:     "高",
+		lang.AlarmPriorityCritical: "緊急",
+
 		lang.RoleSystemAdminTitle:       "系統管理員",
 		lang.RoleOrganizationAdminTitle: "管理員",
 		lang.RoleGuestTitle:             "普通用戶",
@@ -287,6 +293,31 @@ var (
 		lang.CommentDeleteTitle: "",
 		lang.CommentDeleteDesc:  "",
 
+		lang.RuleListTitle:            "",
+		lang.RuleListDesc:             "",
+		lang.RuleCreateTitle:          "",
+		lang.RuleCreateDesc:           "",
+		lang.RuleDetailTitle:          "",
+		lang.RuleDetailDesc:           "",
+		lang.RuleUpdateTitle:          "",
+		lang.RuleUpdateDesc:           "",
+		lang.RuleDeleteTitle:          "",
+		lang.RuleDeleteDesc:           "",
+		lang.RuleLogListTitle:         "",
+		lang.RuleLogListDesc:          "",
+		lang.WebhookListTitle:         "",
+		lang.WebhookListDesc:          "",
+		lang.WebhookCreateTitle:       "",
+		lang.WebhookCreateDesc:        "",
+		lang.WebhookDetailTitle:       "",
+		lang.WebhookDetailDesc:        "",
+		lang.WebhookUpdateTitle:       "",
+		lang.WebhookUpdateDesc:        "",
+		lang.WebhookDeleteTitle:       "",
+		lang.WebhookDeleteDesc:        "",
+		lang.WebhookDeliveryListTitle: "",
+		lang.WebhookDeliveryListDesc:  "",
+
 		lang.ResourceLogListTitle: "",
 		lang.ResourceLogListDesc:  "",
 
@@ -345,7 +376,58 @@ var (
 		lang.CVSHeaderUser:        "用戶",
 		lang.CVSHeaderConfirmedBy: "確認人",
 
-		lang.DeviceConnected: "設備已連接！",
+		lang.DeviceConnected:        "設備已連接！",
+		lang.DeviceIdentityMismatch: "設備身份不匹配，請確認是否更換了控制器！",
+		lang.SwitchOn:               "開啟",
+		lang.SwitchOff:              "關閉",
+		lang.DeviceCtrlOk:           "%s 將 %s 設置為%s",
+		lang.DeviceCtrlBlocked:      "%s 將 %s 設置為%s 被拒絕：%s",
+		lang.InterlockAlarm:         "%s存在未確認的警報",
+		lang.InterlockValue:         "%s %s %v",
+		lang.InterlockNoValue:       "無法讀取%s的值",
+		lang.InterlockMinOffTime:    "距離上次停止不足%d秒",
+		lang.InterlockMaxStarts:     "%d秒內最多啟動%d次",
+		lang.InterlockInvalid:       "聯鎖條件無效",
+
+		lang.UserCreateRuleOk:     "%s 創建了規則 %s",
+		lang.UserUpdateRuleOk:     "%s 修改了規則 %s",
+		lang.UserDeleteRuleOk:     "%s 刪除了規則 %s",
+		lang.RuleExecuted:         "規則 %s 已執行（%s）",
+		lang.RuleDryRun:           "規則 %s 試運行，未執行任何動作（%s）",
+		lang.RuleFailed:           "規則 %s 執行失敗（%s）：%s",
+		lang.EdgeRegistered:       "edge %s 已註冊",
+		lang.EdgeRegisterDisabled: "沒有設置edge.token，edge動態註冊已禁用",
+		lang.EdgeOnline:           "edge %s 已恢復",
+		lang.EdgeOffline:          "edge %s 沒有響應：%s",
+		lang.DeviceFailover:       "設備 %s 已轉移到 edge %s",
+		lang.DeviceFailoverFailed: "設備 %s 轉移失敗：%s",
+		lang.UserPinDeviceOk:      "%s 將設備 %s 固定到 edge %s",
+		lang.UserUnpinDeviceOk:    "%s 取消了設備 %s 的固定 edge",
+		lang.AlarmNotifyDetail:    "設備 %s 的點位 %s 發生 %s 警報，當前值：%v",
+		lang.NotifyDelivered:      "通過 %s 向用戶 %s 發送通知成功",
+		lang.NotifyFailed:         "通過 %s 向用戶 %s 發送通知失敗：%s",
+
+		lang.AlarmEscalationTitle:     "警報未確認",
+		lang.AlarmEscalated:           "警報 %d 未及時確認，已通知第 %d 級",
+		lang.UserUpdateAlarmRoutingOk: "%s 更新了警報通知設置",
+		lang.UserShelveMeasureOk:      "%s 擱置了點位 %s %d 分鐘，原因：%s",
+		lang.UserUnshelveMeasureOk:    "%s 取消了點位 %s 的擱置",
+		lang.UserStartMaintenanceOk:   "%s 將 %s 設置為維護模式 %d 分鐘，原因：%s",
+		lang.UserEndMaintenanceOk:     "%s 結束了 %s 的維護模式",
+		lang.ShelveExpired:            "%s 的擱置或者維護已到期，自動恢復",
+		lang.AlarmSuppressed:          "%s 發生警報 %s（%s），已被抑制：%s",
+		lang.AlarmFloodDetail:         "%s 警報氾濫，已合併 %d 個警報",
+		lang.AlarmFloodEnded:          "%s 警報氾濫已結束，共合併 %d 個警報",
+		lang.AlarmFloodMeasure:        "警報氾濫",
+		lang.AlarmChattering:          "點位 %s 的警報反覆出現，自動擱置 %d 分鐘",
+		lang.AlarmChatteringReason:    "警報反覆出現",
+
+		lang.UserUpdateAlarmPriorityOk:  "%s 更新了警報優先級設置",
+		lang.UserUpdateInboxRetentionOk: "%s 更新了消息保留設置",
+		lang.UserCreateWebhookOk:        "%s 創建了webhook %s",
+		lang.UserUpdateWebhookOk:        "%s 修改了webhook %s",
+		lang.UserDeleteWebhookOk:        "%s 刪除了webhook %s",
+		lang.WebhookDeliveryFailed:      "webhook %s 投遞事件 %s 失敗，已嘗試%d次：%v",
 	}
 
 	errStrMap = map[lang.ErrIndex]string{
@@ -403,6 +485,25 @@ var (
 		lang.ErrInvalidRegCode:                  "無效的註冊碼！",
 		lang.ErrDeviceDisconnected:              "斷開連接！",
 		lang.ErrNoEdgeAvailable:                 "沒有可用的edge程序，請重啟系統！",
+		lang.ErrNoPendingIdentity:               "沒有需要確認的設備身份！",
+		lang.ErrInterlockBlocked:                "聯鎖禁止：%s",
+		lang.ErrInvalidInterlock:                "聯鎖條件配置錯誤：%s",
+		lang.ErrInvalidCHValue:                  "點位%s沒有有效的數值！",
+		lang.ErrRuleNotFound:                    "沒有找到這個規則！",
+		lang.ErrInvalidRule:                     "規則配置錯誤：%s",
+		lang.ErrEdgeNotFound:                    "edge不存在：%s",
+		lang.ErrInvalidEdgeToken:                "edge註冊令牌無效！",
+		lang.ErrEdgeUnavailable:                 "edge %s 暫時不可用，請稍後再試！",
+		lang.ErrInvalidAlarmRouting:             "警報通知設置不正確：%v",
+		lang.ErrInvalidShelveDuration:           "時長不正確，應在1到%d分鐘之間",
+		lang.ErrShelveNotFound:                  "沒有擱置或者維護記錄",
+		lang.ErrInvalidAlarmPriority:            "警報優先級設置不正確：%v",
+		lang.ErrMessageNotFound:                 "沒有找到這條消息！",
+		lang.ErrInvalidInboxRetention:           "消息保留設置不正確，保留天數和每個用戶的消息數量都應大於0",
+		lang.ErrWebhookNotFound:                 "沒有找到這個webhook！",
+		lang.ErrWebhookDisabled:                 "webhook已禁用！",
+		lang.ErrInvalidWebhook:                  "webhook設置不正確：%v",
+		lang.ErrDeliveryNotFound:                "沒有找到這條投遞記錄！",
 		lang.ErrGeTuiRegisterUserFailed:         "個推註冊用戶%s失敗！",
 		lang.ErrGeTuiSendMessageFailed:          "無法推送警報消息：%s",
 		lang.ErrGeTuiNotInitialized:             "個推沒有正確配置！",
@@ -282,6 +282,10 @@ func (store *store) write(db *bolt.DB, entry *logStore.Entry) error {
 	})
 }
 
+func (store *store) QueueLength() int {
+	return len(store.cache)
+}
+
 func (store *store) Stats(orgID int64) map[string]interface{} {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
@@ -11,6 +11,8 @@ import (
 const (
 	SystemLog = "system"
 	DebugLog  = "debug"
+	//通知的发送记录
+	NotifyLog = "notify"
 )
 
 type Data struct {
@@ -41,6 +43,8 @@ type Store interface {
 	GetList(orgID int64, src, level string, start *uint64, offset, limit uint64) (result []*Data, total uint64, err error)
 	Delete(orgID int64, src string) error
 	Stats(orgID int64) map[string]interface{}
+	//等待写入的日志数量
+	QueueLength() int
 
 	//interface for logrus hook
 	Levels() []logrus.Level
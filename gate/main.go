@@ -17,7 +17,12 @@ import (
 
 	"github.com/maritimusj/durafmt"
 
+	"github.com/maritimusj/centrum/gate/web/dispatch"
 	"github.com/maritimusj/centrum/gate/web/edge"
+	"github.com/maritimusj/centrum/gate/web/inbox"
+	"github.com/maritimusj/centrum/gate/web/interlock"
+	"github.com/maritimusj/centrum/gate/web/push"
+	"github.com/maritimusj/centrum/gate/web/webhook"
 
 	"github.com/spf13/viper"
 
@@ -31,6 +36,10 @@ import (
 
 	webAPI "github.com/maritimusj/centrum/gate/web/api"
 	webApp "github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/notify"
+	"github.com/maritimusj/centrum/gate/web/rule"
+	"github.com/maritimusj/centrum/gate/web/shelve"
+	"github.com/maritimusj/centrum/gate/web/suppress"
 	"github.com/maritimusj/centrum/util"
 	log "github.com/sirupsen/logrus"
 )
@@ -115,6 +124,38 @@ func main() {
 		fmt.Println(err)
 	}
 
+	//调用edge的超时、重试和熔断，时间格式如10s, 200ms
+	viper.SetDefault("edge.rpc.timeout", edge.DefaultOptions.Timeout)
+	viper.SetDefault("edge.rpc.retries", edge.DefaultOptions.Retries)
+	viper.SetDefault("edge.rpc.backoff", edge.DefaultOptions.Backoff)
+	viper.SetDefault("edge.rpc.breaker.failures", edge.DefaultOptions.BreakerFailures)
+	viper.SetDefault("edge.rpc.breaker.cooldown", edge.DefaultOptions.BreakerCooldown)
+
+	edge.SetOptions(edge.Options{
+		Timeout:         viper.GetDuration("edge.rpc.timeout"),
+		Retries:         viper.GetInt("edge.rpc.retries"),
+		Backoff:         viper.GetDuration("edge.rpc.backoff"),
+		BreakerFailures: viper.GetInt("edge.rpc.breaker.failures"),
+		BreakerCooldown: viper.GetDuration("edge.rpc.breaker.cooldown"),
+	})
+
+	//警报泛滥和反复出现的判断条件
+	viper.SetDefault("alarm.flood.device", suppress.DefaultOptions.FloodDevice)
+	viper.SetDefault("alarm.flood.site", suppress.DefaultOptions.FloodSite)
+	viper.SetDefault("alarm.flood.window", suppress.DefaultOptions.FloodWindow)
+	viper.SetDefault("alarm.chatter.count", suppress.DefaultOptions.ChatterCount)
+	viper.SetDefault("alarm.chatter.window", suppress.DefaultOptions.ChatterWindow)
+	viper.SetDefault("alarm.chatter.shelve", suppress.DefaultOptions.ChatterShelve)
+
+	suppress.SetOptions(suppress.Options{
+		FloodDevice:   viper.GetInt("alarm.flood.device"),
+		FloodSite:     viper.GetInt("alarm.flood.site"),
+		FloodWindow:   viper.GetDuration("alarm.flood.window"),
+		ChatterCount:  viper.GetInt("alarm.chatter.count"),
+		ChatterWindow: viper.GetDuration("alarm.chatter.window"),
+		ChatterShelve: viper.GetDuration("alarm.chatter.shelve"),
+	})
+
 	var edges []string
 	if viper.IsSet("edges") {
 		edges = viper.GetStringSlice("edges")
@@ -126,6 +167,13 @@ func main() {
 		edge.Add(url)
 	}
 
+	//edge自动注册时使用的令牌，没有设置时不允许注册
+	token := viper.GetString("edge.token")
+	if token == "" {
+		log.WithField("src", logStore.SystemLog).Warnln(lang.Str(lang.EdgeRegisterDisabled))
+	}
+	edge.SetRegisterToken(token)
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -134,6 +182,41 @@ func main() {
 	}
 	defer webApp.Close()
 
+	//警报优先级
+	if err := webApp.LoadAlarmPriorities(); err != nil {
+		log.Errorln("load alarm priorities: ", err)
+	}
+
+	//通知渠道
+	notify.Init()
+
+	//自动控制规则
+	rule.Start(ctx)
+
+	//联锁条件，跟踪DO的实际开关状态
+	interlock.Start(ctx)
+
+	//警报泛滥结束和过期记录清理
+	suppress.Start(ctx)
+
+	//edge健康检查和设备转移
+	edge.Start(ctx)
+
+	//点位搁置和维护模式
+	shelve.Start(ctx)
+
+	//警报通知路由和升级
+	dispatch.Start(ctx)
+
+	//站内消息
+	inbox.Start(ctx)
+
+	//实时推送
+	push.Start(ctx)
+
+	//webhook
+	webhook.Start(ctx)
+
 	//API服务
 	webAPI.Start(ctx, *webDir, webApp.Config)
 	defer webAPI.Wait()
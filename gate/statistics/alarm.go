@@ -0,0 +1,129 @@
+package statistics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb1-client/models"
+	"github.com/maritimusj/centrum/gate/lang"
+)
+
+//AlarmStats 点位的警报历史，edge在警报期间每次采集的数据都带有alarm标签
+type AlarmStats struct {
+	TagName string
+	//各警报等级的采集次数和持续时间
+	Samples  map[string]int64
+	Duration map[string]time.Duration
+	//第一次和最后一次处于警报状态的时间
+	First time.Time
+	Last  time.Time
+}
+
+//Total 处于警报状态的总时间
+func (stats *AlarmStats) Total() time.Duration {
+	var total time.Duration
+	for _, d := range stats.Duration {
+		total += d
+	}
+	return total
+}
+
+//GetAlarmStats 设备点位在指定时间内的警报历史，interval为设备的采集间隔，用于估算警报的持续时间，
+//tagNames为空时查询设备的全部点位
+func (client *Client) GetAlarmStats(dbName string, deviceID int64, start, end time.Time, interval time.Duration, tagNames ...string) (map[string]*AlarmStats, error) {
+	from := "/.*/"
+	if len(tagNames) > 0 {
+		names := make([]string, 0, len(tagNames))
+		for _, tag := range tagNames {
+			names = append(names, fmt.Sprintf(`"%s"`, tag))
+		}
+		from = strings.Join(names, ",")
+	}
+
+	where := fmt.Sprintf(`WHERE "uid"='%d' AND "alarm"<>'' AND "time">='%s' AND "time"<'%s' GROUP BY "alarm"`,
+		deviceID, start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
+
+	var SQL strings.Builder
+	for i, fn := range []string{"count", "first", "last"} {
+		if i > 0 {
+			SQL.WriteString(";")
+		}
+		SQL.WriteString(fmt.Sprintf(`SELECT %s("val") FROM %s %s`, fn, from, where))
+	}
+
+	res, err := client.queryData(dbName, SQL.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res) < 3 {
+		return nil, lang.ErrNoStatisticsData.Error()
+	}
+
+	for _, r := range res {
+		if r.Err != "" {
+			return nil, lang.InternalError(errors.New(r.Err))
+		}
+	}
+
+	result := map[string]*AlarmStats{}
+	get := func(row models.Row) *AlarmStats {
+		stats, ok := result[row.Name]
+		if !ok {
+			stats = &AlarmStats{
+				TagName:  row.Name,
+				Samples:  map[string]int64{},
+				Duration: map[string]time.Duration{},
+			}
+			result[row.Name] = stats
+		}
+		return stats
+	}
+
+	for _, row := range res[0].Series {
+		level := row.Tags["alarm"]
+		for _, v := range row.Values {
+			if len(v) < 2 {
+				continue
+			}
+			n, err := v[1].(json.Number).Int64()
+			if err != nil {
+				continue
+			}
+			stats := get(row)
+			stats.Samples[level] += n
+			stats.Duration[level] += time.Duration(n) * interval
+		}
+	}
+
+	for i, r := range res[1:3] {
+		for _, row := range r.Series {
+			for _, v := range row.Values {
+				if len(v) < 1 {
+					continue
+				}
+				sec, err := v[0].(json.Number).Int64()
+				if err != nil {
+					continue
+				}
+				t := time.Unix(sec, 0)
+				stats := get(row)
+				if i == 0 && (stats.First.IsZero() || t.Before(stats.First)) {
+					stats.First = t
+				}
+				if i == 1 && t.After(stats.Last) {
+					stats.Last = t
+				}
+			}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, lang.ErrNoStatisticsData.Error()
+	}
+
+	return result, nil
+}
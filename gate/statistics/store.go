@@ -1,6 +1,8 @@
 package statistics
 
 import (
+	"time"
+
 	_ "github.com/influxdata/influxdb1-client"
 	db "github.com/influxdata/influxdb1-client/v2"
 	"github.com/maritimusj/centrum/gate/lang"
@@ -34,6 +36,15 @@ func (client *Client) Open(option map[string]interface{}) error {
 	return lang.ErrInvalidDBConnStr.Error()
 }
 
+//Ping 检查InfluxDB是否可以访问
+func (client *Client) Ping(timeout time.Duration) error {
+	if client.db == nil {
+		return lang.ErrInvalidDBConnStr.Error()
+	}
+	_, _, err := client.db.Ping(timeout)
+	return err
+}
+
 func (client *Client) queryData(dbName string, cmd string) ([]db.Result, error) {
 	q := db.NewQuery(cmd, dbName, "s")
 	response, err := client.db.Query(q)
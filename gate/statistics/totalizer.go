@@ -0,0 +1,108 @@
+package statistics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maritimusj/centrum/gate/lang"
+)
+
+const (
+	PeriodDay   = "day"
+	PeriodMonth = "month"
+)
+
+//GetTotalizerStats 累计点位按天或者按月的增量，累计值被清零时从零开始计算
+func (client *Client) GetTotalizerStats(dbName string, deviceID int64, tagName string, start, end time.Time, period string) ([]map[string]interface{}, error) {
+	start = start.In(time.Local)
+	begin := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
+
+	//按本地时间的零点分组
+	_, offset := begin.Zone()
+
+	SQL := fmt.Sprintf(`SELECT first("val"),last("val") FROM "%s" WHERE "uid"='%d' AND "time">='%s' AND "time"<'%s' GROUP BY time(1d,%ds) fill(none)`,
+		tagName, deviceID, begin.AddDate(0, 0, -1).UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339), -offset)
+
+	res, err := client.queryData(dbName, SQL)
+	if err != nil {
+		return nil, err
+	}
+
+	if res[0].Err != "" {
+		return nil, lang.InternalError(errors.New(res[0].Err))
+	}
+
+	if len(res[0].Series) == 0 {
+		return nil, lang.ErrNoStatisticsData.Error()
+	}
+
+	layout := "2006-01-02"
+	if period == PeriodMonth {
+		layout = "2006-01"
+	}
+
+	var (
+		result = make([]map[string]interface{}, 0)
+		prev   *float64
+	)
+
+	for _, row := range res[0].Series[0].Values {
+		if len(row) < 3 {
+			continue
+		}
+
+		sec, _ := row[0].(json.Number).Int64()
+		first, err1 := toFloat(row[1])
+		last, err2 := toFloat(row[2])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+
+		var delta float64
+		if prev != nil {
+			delta = last - *prev
+		} else {
+			delta = last - first
+		}
+
+		//累计值被清零
+		if delta < 0 {
+			delta = last
+		}
+
+		prev = &last
+
+		//前一天的数据只用于计算第一天的增量
+		day := time.Unix(sec, 0).In(time.Local)
+		if day.Before(begin) {
+			continue
+		}
+
+		key := day.Format(layout)
+		if n := len(result); n > 0 && result[n-1]["time"] == key {
+			result[n-1]["value"] = result[n-1]["value"].(float64) + delta
+			result[n-1]["total"] = last
+		} else {
+			result = append(result, map[string]interface{}{
+				"time":  key,
+				"value": delta,
+				"total": last,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch x := v.(type) {
+	case json.Number:
+		return x.Float64()
+	case float64:
+		return x, nil
+	default:
+		return 0, errors.New("invalid value")
+	}
+}
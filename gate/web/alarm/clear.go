@@ -0,0 +1,30 @@
+package alarm
+
+import (
+	"time"
+
+	"github.com/maritimusj/centrum/gate/web/model"
+)
+
+const (
+	//MinClearTimeout 判断警报消失的最短时间
+	MinClearTimeout = 30 * time.Second
+	//超过几个采集周期没有上报认为警报已经消失
+	clearIntervals = 3
+)
+
+//ClearTimeout edge在警报持续期间每次采集都会上报，超过设备采集间隔的3倍(最少MinClearTimeout)没有上报认为警报已经消失
+func ClearTimeout(device model.Device) time.Duration {
+	if device == nil {
+		return MinClearTimeout
+	}
+	return ClearTimeoutOf(time.Duration(device.GetOption("params.interval").Int()) * time.Second)
+}
+
+//ClearTimeoutOf 按采集间隔计算警报消失的时间
+func ClearTimeoutOf(interval time.Duration) time.Duration {
+	if d := interval * clearIntervals; d > MinClearTimeout {
+		return d
+	}
+	return MinClearTimeout
+}
@@ -0,0 +1,172 @@
+package alarm
+
+import (
+	"sort"
+	"time"
+)
+
+//Record 用于统计的警报记录
+type Record struct {
+	MeasureID int64
+	//合并的警报数量，汇总警报大于1
+	Count int
+	//警报出现、确认和消失的时间，没有确认或者还没有消失时为零值
+	CreatedAt time.Time
+	AckedAt   time.Time
+	ClearedAt time.Time
+}
+
+//KPIOptions 统计的时间范围和判断条件
+type KPIOptions struct {
+	Start time.Time
+	End   time.Time
+	//值班操作员数量
+	Operators int
+	//每个操作员10分钟内的警报超过FloodRate时认为处于警报泛滥状态
+	FloodRate int
+	//持续时间超过StaleAfter的警报为陈旧警报
+	StaleAfter time.Duration
+	//最频繁警报的数量
+	Top int
+}
+
+var DefaultKPIOptions = KPIOptions{
+	Operators:  1,
+	FloodRate:  10,
+	StaleAfter: 24 * time.Hour,
+	Top:        10,
+}
+
+//BadActor 最频繁出现警报的点位
+type BadActor struct {
+	MeasureID int64   `json:"measure_id"`
+	Count     int     `json:"count"`
+	Percent   float64 `json:"percent"`
+}
+
+//KPI 警报系统的性能指标，时间的单位为秒
+type KPI struct {
+	Total int `json:"total"`
+	//每个操作员每小时的平均警报数
+	PerOperatorHour float64 `json:"per_operator_hour"`
+	//10分钟内最多的警报数和开始时间
+	Peak10Min   int       `json:"peak_10min"`
+	Peak10MinAt time.Time `json:"peak_10min_at"`
+	//处于警报泛滥状态的时间比例
+	FloodPercent float64 `json:"flood_percent"`
+
+	BadActors []BadActor `json:"bad_actors"`
+
+	//统计结束时仍然存在的警报，以及其中的陈旧警报
+	Standing int `json:"standing"`
+	Stale    int `json:"stale"`
+
+	//平均确认时间和平均消失时间
+	MeanTimeToAck   float64 `json:"mtta"`
+	MeanTimeToClear float64 `json:"mttc"`
+}
+
+const kpiInterval = 10 * time.Minute
+
+//Calculate 统计指定时间范围内出现的警报的性能指标，
+//records还应该包括统计开始前出现、开始时还没有消失的警报，用于统计结束时仍然存在的警报
+func Calculate(records []Record, options KPIOptions) *KPI {
+	kpi := &KPI{
+		BadActors: []BadActor{},
+	}
+
+	if !options.End.After(options.Start) {
+		return kpi
+	}
+	if options.Operators <= 0 {
+		options.Operators = 1
+	}
+
+	var (
+		buckets = make([]int, int((options.End.Sub(options.Start)+kpiInterval-1)/kpiInterval))
+		counts  = map[int64]int{}
+
+		acked, cleared         int
+		ackTotal, clearedTotal time.Duration
+	)
+
+	for _, r := range records {
+		if !r.CreatedAt.Before(options.End) {
+			continue
+		}
+
+		if r.ClearedAt.IsZero() || r.ClearedAt.After(options.End) {
+			kpi.Standing++
+			if options.End.Sub(r.CreatedAt) > options.StaleAfter {
+				kpi.Stale++
+			}
+		}
+
+		if r.CreatedAt.Before(options.Start) {
+			continue
+		}
+
+		n := r.Count
+		if n <= 0 {
+			n = 1
+		}
+		kpi.Total += n
+		buckets[int(r.CreatedAt.Sub(options.Start)/kpiInterval)] += n
+
+		//汇总警报不计入最频繁的点位
+		if r.Count <= 1 {
+			counts[r.MeasureID]++
+		}
+
+		if !r.AckedAt.IsZero() && !r.AckedAt.Before(r.CreatedAt) {
+			acked++
+			ackTotal += r.AckedAt.Sub(r.CreatedAt)
+		}
+
+		if !r.ClearedAt.IsZero() && !r.ClearedAt.After(options.End) && !r.ClearedAt.Before(r.CreatedAt) {
+			cleared++
+			clearedTotal += r.ClearedAt.Sub(r.CreatedAt)
+		}
+	}
+
+	hours := options.End.Sub(options.Start).Hours()
+	kpi.PerOperatorHour = float64(kpi.Total) / hours / float64(options.Operators)
+
+	var flood int
+	for i, n := range buckets {
+		if n > kpi.Peak10Min {
+			kpi.Peak10Min = n
+			kpi.Peak10MinAt = options.Start.Add(time.Duration(i) * kpiInterval)
+		}
+		if options.FloodRate > 0 && n > options.FloodRate*options.Operators {
+			flood++
+		}
+	}
+	if len(buckets) > 0 {
+		kpi.FloodPercent = float64(flood) * 100 / float64(len(buckets))
+	}
+
+	for id, n := range counts {
+		kpi.BadActors = append(kpi.BadActors, BadActor{
+			MeasureID: id,
+			Count:     n,
+			Percent:   float64(n) * 100 / float64(kpi.Total),
+		})
+	}
+	sort.Slice(kpi.BadActors, func(i, j int) bool {
+		a, b := kpi.BadActors[i], kpi.BadActors[j]
+		return a.Count > b.Count || (a.Count == b.Count && a.MeasureID < b.MeasureID)
+	})
+	if options.Top > 0 && len(kpi.BadActors) > options.Top {
+		kpi.BadActors = kpi.BadActors[:options.Top]
+	}
+
+	if acked > 0 {
+		kpi.MeanTimeToAck = (ackTotal / time.Duration(acked)).Seconds()
+	}
+	if cleared > 0 {
+		kpi.MeanTimeToClear = (clearedTotal / time.Duration(cleared)).Seconds()
+	}
+
+	return kpi
+}
@@ -0,0 +1,73 @@
+package alarm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalculate(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	var records []Record
+	//前10分钟内同一个点位出现12次警报，每次1分钟后消失，2分钟后确认
+	for i := 0; i < 12; i++ {
+		created := start.Add(time.Duration(i) * 30 * time.Second)
+		records = append(records, Record{
+			MeasureID: 1,
+			CreatedAt: created,
+			AckedAt:   created.Add(2 * time.Minute),
+			ClearedAt: created.Add(time.Minute),
+		})
+	}
+	//持续到统计结束的警报
+	records = append(records, Record{
+		MeasureID: 2,
+		CreatedAt: start.Add(30 * time.Minute),
+		AckedAt:   start.Add(32 * time.Minute),
+	})
+	//统计开始前出现的警报，一个持续到统计结束，一个在统计期间消失
+	records = append(records, Record{MeasureID: 4, CreatedAt: start.Add(-48 * time.Hour)})
+	records = append(records, Record{MeasureID: 5, CreatedAt: start.Add(-time.Hour), ClearedAt: start.Add(time.Minute)})
+	//统计范围以外的警报
+	records = append(records, Record{MeasureID: 3, CreatedAt: end.Add(time.Minute)})
+
+	options := DefaultKPIOptions
+	options.Start, options.End = start, end
+
+	kpi := Calculate(records, options)
+	if kpi.Total != 13 || kpi.PerOperatorHour != 13 {
+		t.Fatalf("unexpected total: %d, %v", kpi.Total, kpi.PerOperatorHour)
+	}
+	if kpi.Peak10Min != 12 || !kpi.Peak10MinAt.Equal(start) {
+		t.Fatalf("unexpected peak: %d at %v", kpi.Peak10Min, kpi.Peak10MinAt)
+	}
+	if kpi.FloodPercent < 16 || kpi.FloodPercent > 17 {
+		t.Fatalf("unexpected flood percent: %v", kpi.FloodPercent)
+	}
+	if len(kpi.BadActors) != 2 || kpi.BadActors[0].MeasureID != 1 || kpi.BadActors[0].Count != 12 {
+		t.Fatalf("unexpected bad actors: %+v", kpi.BadActors)
+	}
+	if kpi.Standing != 2 || kpi.Stale != 1 {
+		t.Fatalf("unexpected standing: %d, stale: %d", kpi.Standing, kpi.Stale)
+	}
+	if kpi.MeanTimeToAck != 120 || kpi.MeanTimeToClear != 60 {
+		t.Fatalf("unexpected mtta: %v, mttc: %v", kpi.MeanTimeToAck, kpi.MeanTimeToClear)
+	}
+}
+
+func TestClearTimeoutOf(t *testing.T) {
+	for _, c := range []struct {
+		interval time.Duration
+		expected time.Duration
+	}{
+		{0, MinClearTimeout},
+		{time.Second, MinClearTimeout},
+		{10 * time.Second, MinClearTimeout},
+		{time.Minute, 3 * time.Minute},
+	} {
+		if d := ClearTimeoutOf(c.interval); d != c.expected {
+			t.Fatalf("interval %v: expected %v, got %v", c.interval, c.expected, d)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package alarm
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/web/status"
+)
+
+var priorityNames = map[string]int{
+	"advisory": status.PriorityAdvisory,
+	"low":      status.PriorityLow,
+	"medium":   status.PriorityMedium,
+	"high":     status.PriorityHigh,
+	"critical": status.PriorityCritical,
+}
+
+//ParsePriority 优先级名称转换为数值
+func ParsePriority(name string) (int, bool) {
+	p, ok := priorityNames[strings.ToLower(name)]
+	return p, ok
+}
+
+//PriorityName 优先级的名称，未设置时返回空字符串
+func PriorityName(priority int) string {
+	for name, p := range priorityNames {
+		if p == priority {
+			return name
+		}
+	}
+	return ""
+}
+
+//DefaultPriority 根据警报等级得到的默认优先级
+func DefaultPriority(level string) int {
+	switch strings.ToUpper(level) {
+	case HF, LF:
+		return status.PriorityCritical
+	case HH, LL:
+		return status.PriorityHigh
+	case HI, LO:
+		return status.PriorityMedium
+	default:
+		return status.PriorityLow
+	}
+}
+
+//Priorities 警报优先级设置，点位的设置优先于警报等级的设置
+type Priorities struct {
+	//警报等级 -> 优先级名称
+	Levels map[string]string `json:"levels"`
+	//点位ID -> 优先级名称
+	Measures map[int64]string `json:"measures"`
+}
+
+var (
+	priorities = &Priorities{}
+	mu         sync.RWMutex
+)
+
+//ParsePriorities 解析并检查优先级设置
+func ParsePriorities(data []byte) (*Priorities, error) {
+	p := &Priorities{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, p); err != nil {
+			return nil, lang.ErrInvalidAlarmPriority.Error(err)
+		}
+	}
+
+	levels := make(map[string]string, len(p.Levels))
+	for level, name := range p.Levels {
+		if _, ok := ParsePriority(name); !ok {
+			return nil, lang.ErrInvalidAlarmPriority.Error(name)
+		}
+		levels[strings.ToUpper(level)] = strings.ToLower(name)
+	}
+	p.Levels = levels
+
+	for id, name := range p.Measures {
+		if _, ok := ParsePriority(name); !ok {
+			return nil, lang.ErrInvalidAlarmPriority.Error(name)
+		}
+		p.Measures[id] = strings.ToLower(name)
+	}
+
+	return p, nil
+}
+
+func SetPriorities(p *Priorities) {
+	mu.Lock()
+	priorities = p
+	mu.Unlock()
+}
+
+//Priority 警报的优先级，依次使用点位的设置、虚拟点位的设置、警报等级的设置和默认优先级，
+//statePriorities为点位关联的虚拟点位设置的优先级，取最高的一个
+func Priority(level string, measureID int64, statePriorities ...int) int {
+	mu.RLock()
+	p := priorities
+	mu.RUnlock()
+
+	if v, ok := ParsePriority(p.Measures[measureID]); ok {
+		return v
+	}
+
+	var highest int
+	for _, v := range statePriorities {
+		if v > highest {
+			highest = v
+		}
+	}
+	if highest > status.PriorityNone {
+		return highest
+	}
+
+	if v, ok := ParsePriority(p.Levels[strings.ToUpper(level)]); ok {
+		return v
+	}
+
+	return DefaultPriority(level)
+}
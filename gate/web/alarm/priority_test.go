@@ -0,0 +1,33 @@
+package alarm
+
+import (
+	"testing"
+
+	"github.com/maritimusj/centrum/gate/web/status"
+)
+
+func TestPriority(t *testing.T) {
+	p, err := ParsePriorities([]byte(`{"levels":{"hi":"High"},"measures":{"7":"advisory"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetPriorities(p)
+	defer SetPriorities(&Priorities{})
+
+	if v := Priority(HI, 7, status.PriorityCritical); v != status.PriorityAdvisory {
+		t.Fatalf("measure setting should be used first, got %d", v)
+	}
+	if v := Priority(HI, 1, status.PriorityLow, status.PriorityCritical); v != status.PriorityCritical {
+		t.Fatalf("highest state priority should be used, got %d", v)
+	}
+	if v := Priority(HI, 1); v != status.PriorityHigh {
+		t.Fatalf("level setting should be used, got %d", v)
+	}
+	if v := Priority(LL, 1); v != status.PriorityHigh {
+		t.Fatalf("default priority expected, got %d", v)
+	}
+
+	if _, err := ParsePriorities([]byte(`{"levels":{"HH":"urgent"}}`)); err == nil {
+		t.Fatal("invalid priority name should be rejected")
+	}
+}
@@ -10,16 +10,20 @@ import (
 
 	"github.com/influxdata/influxdb1-client/models"
 
+	alarmDef "github.com/maritimusj/centrum/gate/web/alarm"
 	"github.com/maritimusj/centrum/gate/web/app"
 
 	"github.com/kataras/iris"
 	"github.com/kataras/iris/hero"
+	"github.com/maritimusj/centrum/gate/event"
 	"github.com/maritimusj/centrum/gate/lang"
 
 	"github.com/maritimusj/centrum/gate/web/helper"
 	"github.com/maritimusj/centrum/gate/web/model"
 	"github.com/maritimusj/centrum/gate/web/resource"
 	"github.com/maritimusj/centrum/gate/web/response"
+	"github.com/maritimusj/centrum/gate/web/shelve"
+	"github.com/maritimusj/centrum/gate/web/suppress"
 )
 
 func List(ctx iris.Context) hero.Result {
@@ -72,6 +76,30 @@ func List(ctx iris.Context) hero.Result {
 			params = append(params, helper.Equipment(equipmentID))
 		}
 
+		//按优先级过滤，多个优先级用逗号分隔
+		if ctx.URLParamExists("priority") {
+			var priorities []int
+			for _, name := range strings.Split(ctx.URLParam("priority"), ",") {
+				p, ok := alarmDef.ParsePriority(strings.TrimSpace(name))
+				if !ok {
+					return lang.ErrInvalidAlarmPriority.Error(name)
+				}
+				priorities = append(priorities, p)
+			}
+			params = append(params, helper.Priority(priorities...))
+		}
+
+		if ctx.URLParam("sort") == "priority" {
+			params = append(params, helper.OrderBy("a.priority DESC, a.updated_at DESC"))
+		}
+
+		//设备断开期间点位的警报是连带警报，默认不显示
+		if !ctx.URLParamExists("consequential") {
+			if devices := suppress.DisconnectedDevices(); len(devices) > 0 {
+				params = append(params, helper.ExcludeDevices(devices...))
+			}
+		}
+
 		var (
 			start *time.Time
 			end   *time.Time
@@ -111,6 +139,11 @@ func List(ctx iris.Context) hero.Result {
 					"view": true,
 					"ctrl": app.Allow(admin, measure, resource.Ctrl),
 				}
+				if device, err := alarm.Device(); err == nil {
+					if entry := shelve.Suppressed(device, measure); entry != nil {
+						brief["shelve"] = entry.Brief()
+					}
+				}
 			}
 			lastID := alarm.GetOption(fmt.Sprintf("read.%d", admin.GetID())).Int()
 			_, total, err := s.GetCommentList(alarm, lastID, helper.Limit(1))
@@ -130,6 +163,29 @@ func List(ctx iris.Context) hero.Result {
 	})
 }
 
+//Shelves 有效的点位搁置和设备维护记录
+func Shelves(ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		admin := app.Store().MustGetUserFromContext(ctx)
+
+		var orgID int64
+		if !app.IsDefaultAdminUser(admin) {
+			orgID = admin.OrganizationID()
+		}
+
+		list := shelve.List(orgID)
+		result := make([]model.Map, 0, len(list))
+		for _, entry := range list {
+			result = append(result, entry.Brief())
+		}
+
+		return iris.Map{
+			"total": len(result),
+			"list":  result,
+		}
+	})
+}
+
 func Detail(alarmID int64, ctx iris.Context) hero.Result {
 	return response.Wrap(func() interface{} {
 		s := app.Store()
@@ -199,6 +255,10 @@ func Confirm(alarmID int64, ctx iris.Context) hero.Result {
 				return err
 			}
 
+			//汇总警报被确认后结束警报泛滥状态
+			suppress.OnConfirmed(alarm)
+			app.Event.Publish(event.AlarmConfirmed, admin.GetID(), alarm.GetID())
+
 			return lang.Ok
 		}
 
@@ -0,0 +1,172 @@
+package alarm
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/hero"
+	"github.com/maritimusj/centrum/gate/lang"
+	alarmDef "github.com/maritimusj/centrum/gate/web/alarm"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/dispatch"
+	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	"github.com/maritimusj/centrum/gate/web/response"
+)
+
+//KPI 警报系统的性能指标，默认统计最近24小时内出现的警报
+func KPI(ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var (
+			s       = app.Store()
+			admin   = s.MustGetUserFromContext(ctx)
+			params  []helper.OptionFN
+			options = alarmDef.DefaultKPIOptions
+		)
+
+		if !app.IsDefaultAdminUser(admin) {
+			params = append(params, helper.DefaultEffect(app.Config.DefaultEffect()))
+			params = append(params, helper.User(admin.GetID()))
+		}
+
+		if ctx.URLParamExists("group") {
+			groupID, err := strconv.ParseInt(ctx.URLParam("group"), 10, 0)
+			if err != nil {
+				return lang.ErrInvalidRequestData
+			}
+			group, err := s.GetGroup(groupID)
+			if err != nil {
+				return err
+			}
+			if !app.Allow(admin, group, resource.View) {
+				return lang.ErrNoPermission
+			}
+			params = append(params, helper.Group(groupID))
+		}
+
+		if ctx.URLParamExists("equipment") {
+			equipmentID, err := strconv.ParseInt(ctx.URLParam("equipment"), 10, 0)
+			if err != nil {
+				return lang.ErrInvalidRequestData
+			}
+			equipment, err := s.GetEquipment(equipmentID)
+			if err != nil {
+				return err
+			}
+			if !app.Allow(admin, equipment, resource.View) {
+				return lang.ErrNoPermission
+			}
+			params = append(params, helper.Equipment(equipmentID))
+		}
+
+		options.End = time.Now()
+		if ctx.URLParamExists("end") {
+			end, err := time.Parse("2006-01-02_15:04:05", ctx.URLParam("end"))
+			if err != nil {
+				return lang.ErrInvalidRequestData
+			}
+			options.End = end
+		}
+
+		options.Start = options.End.Add(-24 * time.Hour)
+		if ctx.URLParamExists("start") {
+			start, err := time.Parse("2006-01-02_15:04:05", ctx.URLParam("start"))
+			if err != nil {
+				return lang.ErrInvalidRequestData
+			}
+			options.Start = start
+		}
+
+		if !options.End.After(options.Start) {
+			return lang.ErrInvalidRequestData
+		}
+
+		options.Operators = ctx.URLParamIntDefault("operators", options.Operators)
+		options.Top = ctx.URLParamIntDefault("top", options.Top)
+		if hours := ctx.URLParamIntDefault("stale", 0); hours > 0 {
+			options.StaleAfter = time.Duration(hours) * time.Hour
+		}
+
+		alarms, _, err := s.GetAlarmList(&options.Start, &options.End, params...)
+		if err != nil {
+			return err
+		}
+
+		//统计开始前出现、开始时还没有消失的警报，用于统计结束时仍然存在的警报
+		standing, _, err := s.GetAlarmList(nil, &options.Start, append(params, helper.ClearedAfter(options.Start))...)
+		if err != nil {
+			return err
+		}
+		alarms = append(alarms, standing...)
+
+		records := make([]alarmDef.Record, 0, len(alarms))
+		for _, alarm := range alarms {
+			records = append(records, record(alarm))
+		}
+
+		kpi := alarmDef.Calculate(records, options)
+
+		badActors := make([]model.Map, 0, len(kpi.BadActors))
+		for _, actor := range kpi.BadActors {
+			entry := model.Map{
+				"count":   actor.Count,
+				"percent": actor.Percent,
+			}
+			if measure, err := s.GetMeasure(actor.MeasureID); err == nil {
+				entry["measure"] = measure.Brief()
+				if device := measure.Device(); device != nil {
+					entry["device"] = device.Brief()
+				}
+			} else {
+				entry["measure"] = model.Map{
+					"id": actor.MeasureID,
+				}
+			}
+			badActors = append(badActors, entry)
+		}
+
+		result := iris.Map{
+			"start":             options.Start.Format(lang.DatetimeFormatterStr.Str()),
+			"end":               options.End.Format(lang.DatetimeFormatterStr.Str()),
+			"operators":         options.Operators,
+			"total":             kpi.Total,
+			"per_operator_hour": kpi.PerOperatorHour,
+			"peak_10min":        kpi.Peak10Min,
+			"flood_percent":     kpi.FloodPercent,
+			"bad_actors":        badActors,
+			"standing":          kpi.Standing,
+			"stale":             kpi.Stale,
+			"mtta":              kpi.MeanTimeToAck,
+			"mttc":              kpi.MeanTimeToClear,
+		}
+		if kpi.Peak10Min > 0 {
+			result["peak_10min_at"] = kpi.Peak10MinAt.Format(lang.DatetimeFormatterStr.Str())
+		}
+
+		return result
+	})
+}
+
+func record(alarm model.Alarm) alarmDef.Record {
+	r := alarmDef.Record{
+		MeasureID: alarm.MeasureID(),
+		Count:     1,
+		CreatedAt: alarm.CreatedAt(),
+		ClearedAt: alarm.ClearedAt(),
+	}
+
+	//警报泛滥时的汇总警报
+	if count := int(alarm.GetOption("fields.count").Int()); count > 1 && alarm.GetOption("tags.alarm").String() == dispatch.FloodAlarm {
+		r.Count = count
+	}
+
+	if str := alarm.GetOption("confirm.time").String(); str != "" {
+		if t, err := time.ParseInLocation(lang.DatetimeFormatterStr.Str(), str, time.Local); err == nil {
+			r.AckedAt = t
+		}
+	}
+
+	return r
+}
@@ -3,8 +3,10 @@ package brief
 import (
 	"github.com/kataras/iris"
 	"github.com/kataras/iris/hero"
+	"github.com/maritimusj/centrum/gate/web/alarm"
 	"github.com/maritimusj/centrum/gate/web/app"
 	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/inbox"
 	"github.com/maritimusj/centrum/gate/web/response"
 	"github.com/maritimusj/centrum/gate/web/status"
 	"github.com/maritimusj/centrum/global"
@@ -54,8 +56,19 @@ func Simple(ctx iris.Context) hero.Result {
 			return err
 		}
 
+		//各优先级未确认的警报数量
+		priorities := iris.Map{}
+		for _, p := range []int{status.PriorityCritical, status.PriorityHigh, status.PriorityMedium, status.PriorityLow, status.PriorityAdvisory} {
+			_, n, err := app.Store().GetAlarmList(nil, nil, append(opts, helper.Priority(p))...)
+			if err != nil {
+				return err
+			}
+			priorities[alarm.PriorityName(p)] = n
+		}
+
 		result["alarm"] = iris.Map{
-			"total": total,
+			"total":    total,
+			"priority": priorities,
 		}
 
 		result["version"] = iris.Map{
@@ -81,15 +94,23 @@ func Simple(ctx iris.Context) hero.Result {
 			}
 		}
 
-		messages := global.GetAllMessage(ctx.GetHeader("token"), admin.GetID())
+		messages := inbox.Popup(admin)
 		if len(messages) > 0 {
 			var formattedMsg []interface{}
 			for _, msg := range messages {
-				formattedMsg = append(formattedMsg, msg.Data)
+				formattedMsg = append(formattedMsg, msg.Simple())
 			}
 			result["messages"] = formattedMsg
 		}
 
+		_, unread, err := s.GetMessageList(helper.User(admin.GetID()), helper.Status(status.Unread), helper.Limit(1))
+		if err != nil {
+			return err
+		}
+		result["inbox"] = iris.Map{
+			"unread": unread,
+		}
+
 		return result
 	})
 }
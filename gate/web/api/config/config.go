@@ -1,12 +1,19 @@
 package config
 
 import (
+	"io/ioutil"
+
 	"github.com/kataras/iris"
 	"github.com/kataras/iris/hero"
 	"github.com/maritimusj/centrum/gate/config"
 	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/logStore"
+	"github.com/maritimusj/centrum/gate/web/alarm"
 	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/dispatch"
+	"github.com/maritimusj/centrum/gate/web/inbox"
 	"github.com/maritimusj/centrum/gate/web/response"
+	log "github.com/sirupsen/logrus"
 )
 
 type SysConfig struct {
@@ -90,6 +97,116 @@ func UpdateStreamView(ctx iris.Context) hero.Result {
 	})
 }
 
+//AlarmRouting 警报通知的路由和升级设置
+func AlarmRouting() hero.Result {
+	return response.Wrap(func() interface{} {
+		routing, err := dispatch.Parse([]byte(app.Config.AlarmRouting()))
+		if err != nil {
+			return err
+		}
+		return routing
+	})
+}
+
+func UpdateAlarmRouting(ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		data, err := ioutil.ReadAll(ctx.Request().Body)
+		if err != nil {
+			return lang.ErrInvalidRequestData
+		}
+
+		routing, err := dispatch.Parse(data)
+		if err != nil {
+			return err
+		}
+
+		if err = app.Config.SaveAlarmRouting(routing); err != nil {
+			return err
+		}
+
+		if err = dispatch.Load(); err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		log.WithField("src", logStore.SystemLog).Info(lang.UserUpdateAlarmRoutingOk.Str(admin.Name()))
+
+		return lang.Ok
+	})
+}
+
+//AlarmPriorities 警报等级和点位的优先级设置
+func AlarmPriorities() hero.Result {
+	return response.Wrap(func() interface{} {
+		priorities, err := alarm.ParsePriorities([]byte(app.Config.AlarmPriorities()))
+		if err != nil {
+			return err
+		}
+		return priorities
+	})
+}
+
+func UpdateAlarmPriorities(ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		data, err := ioutil.ReadAll(ctx.Request().Body)
+		if err != nil {
+			return lang.ErrInvalidRequestData
+		}
+
+		priorities, err := alarm.ParsePriorities(data)
+		if err != nil {
+			return err
+		}
+
+		if err = app.Config.SaveAlarmPriorities(priorities); err != nil {
+			return err
+		}
+
+		if err = app.LoadAlarmPriorities(); err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		log.WithField("src", logStore.SystemLog).Info(lang.UserUpdateAlarmPriorityOk.Str(admin.Name()))
+
+		return lang.Ok
+	})
+}
+
+//InboxRetention 站内消息的保留天数和每个用户的消息数量
+func InboxRetention() hero.Result {
+	return response.Wrap(func() interface{} {
+		retention, err := inbox.ParseRetention([]byte(app.Config.InboxRetention()))
+		if err != nil {
+			return err
+		}
+		return retention
+	})
+}
+
+func UpdateInboxRetention(ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		data, err := ioutil.ReadAll(ctx.Request().Body)
+		if err != nil {
+			return lang.ErrInvalidRequestData
+		}
+
+		retention, err := inbox.ParseRetention(data)
+		if err != nil {
+			return err
+		}
+
+		if err = app.Config.SaveInboxRetention(retention); err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		log.WithField("src", logStore.SystemLog).Info(lang.UserUpdateInboxRetentionOk.Str(admin.Name()))
+
+		return lang.Ok
+	})
+}
+
 func Base() hero.Result {
 	return response.Wrap(&Form{
 		Sys: &SysConfig{
@@ -13,15 +13,18 @@ import (
 	"github.com/maritimusj/centrum/gate/lang"
 	"github.com/maritimusj/centrum/gate/web/app"
 	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/interlock"
 	"github.com/maritimusj/centrum/gate/web/model"
 	"github.com/maritimusj/centrum/gate/web/resource"
 	"github.com/maritimusj/centrum/gate/web/response"
+	"github.com/maritimusj/centrum/gate/web/shelve"
 	"github.com/maritimusj/centrum/gate/web/store"
 
 	"github.com/asaskevich/govalidator"
 	"github.com/kataras/iris"
 	"github.com/kataras/iris/hero"
 	"github.com/maritimusj/centrum/global"
+	"github.com/maritimusj/centrum/json_rpc"
 )
 
 func List(ctx iris.Context) hero.Result {
@@ -78,6 +81,9 @@ func List(ctx iris.Context) hero.Result {
 
 		for _, device := range devices {
 			brief := device.Brief()
+			if entry := shelve.Get(shelve.Device, device.GetID()); entry != nil {
+				brief["maintenance"] = entry.Brief()
+			}
 
 			groups, err := device.Groups()
 			if err != nil {
@@ -147,11 +153,17 @@ func Create(ctx iris.Context) hero.Result {
 	}
 
 	var form struct {
-		OrgID    int64   `json:"org"`
-		Title    string  `json:"title" valid:"required"`
-		Groups   []int64 `json:"groups"`
-		ConnStr  string  `json:"params.connStr" valid:"required"`
-		Interval int64   `json:"params.interval"`
+		OrgID      int64                  `json:"org"`
+		Title      string                 `json:"title" valid:"required"`
+		Groups     []int64                `json:"groups"`
+		ConnStr    string                 `json:"params.connStr" valid:"required"`
+		Interval   int64                  `json:"params.interval"`
+		Driver     string                 `json:"params.driver"`
+		Options    map[string]interface{} `json:"params.options"`
+		Virtual    []iris.Map             `json:"params.virtual"`
+		Totalizers []iris.Map             `json:"params.totalizers"`
+		Schedules  []iris.Map             `json:"params.schedules"`
+		Interlocks []*interlock.Rule      `json:"params.interlocks"`
 	}
 
 	if err := ctx.ReadJSON(&form); err != nil {
@@ -162,12 +174,39 @@ func Create(ctx iris.Context) hero.Result {
 		return response.Wrap(lang.ErrInvalidRequestData)
 	}
 
-	if govalidator.IsIPv4(form.ConnStr) {
-		form.ConnStr += ":502"
-	} else if govalidator.IsIPv6(form.ConnStr) {
-		form.ConnStr = fmt.Sprintf("[%s]:502", form.ConnStr)
-	} else if govalidator.IsMAC(form.ConnStr) {
-		form.ConnStr = strings.ToLower(form.ConnStr)
+	//其它驱动的连接地址由驱动自己解析
+	if form.Driver == json_rpc.DriverEP6v2 {
+		if govalidator.IsIPv4(form.ConnStr) {
+			form.ConnStr += ":502"
+		} else if govalidator.IsIPv6(form.ConnStr) {
+			form.ConnStr = fmt.Sprintf("[%s]:502", form.ConnStr)
+		} else if govalidator.IsMAC(form.ConnStr) {
+			form.ConnStr = strings.ToLower(form.ConnStr)
+		}
+	}
+
+	if form.Options == nil {
+		form.Options = map[string]interface{}{}
+	}
+
+	if form.Virtual == nil {
+		form.Virtual = []iris.Map{}
+	}
+
+	if form.Totalizers == nil {
+		form.Totalizers = []iris.Map{}
+	}
+
+	if form.Schedules == nil {
+		form.Schedules = []iris.Map{}
+	}
+
+	if form.Interlocks == nil {
+		form.Interlocks = []*interlock.Rule{}
+	}
+
+	if err := interlock.Validate(form.Interlocks); err != nil {
+		return response.Wrap(err)
 	}
 
 	if form.Interval < 1 {
@@ -191,8 +230,14 @@ func Create(ctx iris.Context) hero.Result {
 
 			device, err := s.CreateDevice(org, form.Title, map[string]interface{}{
 				"params": map[string]interface{}{
-					"connStr":  form.ConnStr,
-					"interval": form.Interval,
+					"connStr":    form.ConnStr,
+					"interval":   form.Interval,
+					"driver":     form.Driver,
+					"options":    form.Options,
+					"virtual":    form.Virtual,
+					"totalizers": form.Totalizers,
+					"schedules":  form.Schedules,
+					"interlocks": form.Interlocks,
 				},
 			})
 
@@ -308,10 +353,16 @@ func Detail(deviceID int64, ctx iris.Context) hero.Result {
 func Update(deviceID int64, ctx iris.Context) hero.Result {
 	return response.Wrap(func() interface{} {
 		var form struct {
-			Title    *string  `json:"title"`
-			ConnStr  *string  `json:"params.connStr"`
-			Interval *int64   `json:"params.interval"`
-			Groups   *[]int64 `json:"groups"`
+			Title      *string                 `json:"title"`
+			ConnStr    *string                 `json:"params.connStr"`
+			Interval   *int64                  `json:"params.interval"`
+			Driver     *string                 `json:"params.driver"`
+			Options    *map[string]interface{} `json:"params.options"`
+			Virtual    *[]iris.Map             `json:"params.virtual"`
+			Totalizers *[]iris.Map             `json:"params.totalizers"`
+			Schedules  *[]iris.Map             `json:"params.schedules"`
+			Interlocks *[]*interlock.Rule      `json:"params.interlocks"`
+			Groups     *[]int64                `json:"groups"`
 		}
 
 		if err := ctx.ReadJSON(&form); err != nil {
@@ -336,13 +387,20 @@ func Update(deviceID int64, ctx iris.Context) hero.Result {
 				logFields["title"] = form.Title
 			}
 
+			driver := device.GetOption("params.driver").Str
+			if form.Driver != nil {
+				driver = *form.Driver
+			}
+
 			if form.ConnStr != nil {
-				if govalidator.IsIPv4(*form.ConnStr) {
-					*form.ConnStr += ":502"
-				} else if govalidator.IsIPv6(*form.ConnStr) {
-					*form.ConnStr = fmt.Sprintf("[%s]:502", *form.ConnStr)
-				} else if govalidator.IsMAC(*form.ConnStr) {
-					*form.ConnStr = strings.ToLower(*form.ConnStr)
+				if driver == json_rpc.DriverEP6v2 {
+					if govalidator.IsIPv4(*form.ConnStr) {
+						*form.ConnStr += ":502"
+					} else if govalidator.IsIPv6(*form.ConnStr) {
+						*form.ConnStr = fmt.Sprintf("[%s]:502", *form.ConnStr)
+					} else if govalidator.IsMAC(*form.ConnStr) {
+						*form.ConnStr = strings.ToLower(*form.ConnStr)
+					}
 				}
 
 				err = device.SetOption("params.connStr", form.ConnStr)
@@ -363,6 +421,57 @@ func Update(deviceID int64, ctx iris.Context) hero.Result {
 				logFields["Interval"] = form.Interval
 			}
 
+			if form.Driver != nil {
+				err = device.SetOption("params.driver", form.Driver)
+				if err != nil {
+					return err
+				}
+				logFields["driver"] = form.Driver
+			}
+
+			if form.Options != nil {
+				err = device.SetOption("params.options", form.Options)
+				if err != nil {
+					return err
+				}
+				logFields["options"] = form.Options
+			}
+
+			if form.Virtual != nil {
+				err = device.SetOption("params.virtual", form.Virtual)
+				if err != nil {
+					return err
+				}
+				logFields["virtual"] = form.Virtual
+			}
+
+			if form.Totalizers != nil {
+				err = device.SetOption("params.totalizers", form.Totalizers)
+				if err != nil {
+					return err
+				}
+				logFields["totalizers"] = form.Totalizers
+			}
+
+			if form.Schedules != nil {
+				err = device.SetOption("params.schedules", form.Schedules)
+				if err != nil {
+					return err
+				}
+				logFields["schedules"] = form.Schedules
+			}
+
+			if form.Interlocks != nil {
+				if err = interlock.Validate(*form.Interlocks); err != nil {
+					return err
+				}
+				err = device.SetOption("params.interlocks", form.Interlocks)
+				if err != nil {
+					return err
+				}
+				logFields["interlocks"] = form.Interlocks
+			}
+
 			if form.Groups != nil {
 				var groups []interface{}
 				for _, g := range *form.Groups {
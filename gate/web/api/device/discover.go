@@ -0,0 +1,189 @@
+package device
+
+import (
+	"strings"
+
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/hero"
+	"github.com/maritimusj/centrum/gate/event"
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/edge"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	"github.com/maritimusj/centrum/gate/web/response"
+	"github.com/maritimusj/centrum/gate/web/store"
+	"github.com/maritimusj/centrum/json_rpc"
+)
+
+//Discover 扫描网络，返回已发现但还没有注册的控制器
+func Discover(ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var form struct {
+			CIDR    string `json:"cidr"`
+			Ports   []int  `json:"ports"`
+			Timeout int    `json:"timeout"`
+		}
+
+		if err := ctx.ReadJSON(&form); err != nil {
+			return lang.ErrInvalidRequestData
+		}
+
+		list, err := edge.Discover(&json_rpc.ScanConf{
+			CIDR:    form.CIDR,
+			Ports:   form.Ports,
+			Timeout: form.Timeout,
+		})
+		if err != nil {
+			return err
+		}
+
+		devices, _, err := app.Store().GetDeviceList()
+		if err != nil {
+			return err
+		}
+
+		registered := map[string]struct{}{}
+		for _, device := range devices {
+			registered[strings.ToLower(device.GetOption("params.connStr").Str)] = struct{}{}
+		}
+
+		isRegistered := func(v *json_rpc.Discovered) bool {
+			for _, addr := range []string{v.Address, v.MAC} {
+				if addr == "" {
+					continue
+				}
+				if _, ok := registered[strings.ToLower(addr)]; ok {
+					return true
+				}
+			}
+			return false
+		}
+
+		result := make([]iris.Map, 0, len(list))
+		for _, v := range list {
+			if isRegistered(v) {
+				continue
+			}
+			result = append(result, iris.Map{
+				"address": v.Address,
+				"model":   v.Model,
+				"version": v.Version,
+				"title":   v.Title,
+				"ip":      v.IP,
+				"mask":    v.Mask,
+				"gateway": v.Gateway,
+				"mac":     v.MAC,
+				"remote":  v.Remote,
+				"inverse": v.Inverse,
+			})
+		}
+
+		return result
+	})
+}
+
+//CreateFromDiscovered 使用扫描结果批量创建设备
+func CreateFromDiscovered(ctx iris.Context) hero.Result {
+	if !app.IsRegistered() {
+		return response.Wrap(lang.ErrRegFirst)
+	}
+
+	var form struct {
+		OrgID    int64   `json:"org"`
+		Groups   []int64 `json:"groups"`
+		Interval int64   `json:"params.interval"`
+		Devices  []struct {
+			Address string `json:"address"`
+			Title   string `json:"title"`
+		} `json:"devices"`
+	}
+
+	if err := ctx.ReadJSON(&form); err != nil || len(form.Devices) == 0 {
+		return response.Wrap(lang.ErrInvalidRequestData)
+	}
+
+	for _, v := range form.Devices {
+		if v.Address == "" {
+			return response.Wrap(lang.ErrInvalidRequestData)
+		}
+	}
+
+	if form.Interval < 1 {
+		form.Interval = 1
+	}
+
+	result := app.TransactionDo(func(s store.Store) interface{} {
+		var org interface{}
+
+		admin := s.MustGetUserFromContext(ctx)
+		if app.IsDefaultAdminUser(admin) {
+			if form.OrgID > 0 {
+				org = form.OrgID
+			} else {
+				org = app.Config.DefaultOrganization()
+			}
+		} else {
+			org = admin.OrganizationID()
+		}
+
+		var groups []interface{}
+		for _, g := range form.Groups {
+			groups = append(groups, g)
+		}
+
+		devices := make([]model.Device, 0, len(form.Devices))
+		for _, v := range form.Devices {
+			title := v.Title
+			if title == "" {
+				title = v.Address
+			}
+
+			device, err := s.CreateDevice(org, title, map[string]interface{}{
+				"params": map[string]interface{}{
+					"connStr":    strings.ToLower(v.Address),
+					"interval":   form.Interval,
+					"driver":     json_rpc.DriverEP6v2,
+					"options":    map[string]interface{}{},
+					"virtual":    []iris.Map{},
+					"totalizers": []iris.Map{},
+					"schedules":  []iris.Map{},
+					"interlocks": []iris.Map{},
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			if len(groups) > 0 {
+				err = device.SetGroups(groups...)
+				if err != nil {
+					return err
+				}
+			}
+
+			err = app.SetAllow(admin, device, resource.View, resource.Ctrl)
+			if err != nil {
+				return err
+			}
+
+			devices = append(devices, device)
+		}
+
+		return event.Data{
+			"userID":  admin.GetID(),
+			"devices": devices,
+		}
+	})
+
+	if data, ok := result.(event.Data); ok {
+		list := make([]model.Map, 0)
+		for _, device := range data.Get("devices").([]model.Device) {
+			app.Event.Publish(event.DeviceCreated, data.Get("userID"), device.GetID())
+			list = append(list, device.Simple())
+		}
+		return response.Wrap(list)
+	}
+
+	return response.Wrap(result)
+}
@@ -5,13 +5,17 @@ import (
 
 	"github.com/kataras/iris"
 	"github.com/kataras/iris/hero"
+	"github.com/maritimusj/centrum/gate/event"
 	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/logStore"
 	"github.com/maritimusj/centrum/gate/web/app"
 	"github.com/maritimusj/centrum/gate/web/edge"
+	"github.com/maritimusj/centrum/gate/web/interlock"
 	"github.com/maritimusj/centrum/gate/web/model"
 	"github.com/maritimusj/centrum/gate/web/resource"
 	"github.com/maritimusj/centrum/gate/web/response"
 	"github.com/maritimusj/centrum/global"
+	log "github.com/sirupsen/logrus"
 )
 
 func Reset(deviceID int64, ctx iris.Context) hero.Result {
@@ -141,7 +145,49 @@ func Ctrl(deviceID int64, chTagName string, ctx iris.Context) hero.Result {
 			return lang.ErrNoPermission
 		}
 
-		err = edge.SetCHValue(device, chTagName, form.Val)
+		err = interlock.SetCHValue(admin, device, measure, form.Val)
+		if err != nil {
+			return err
+		}
+
+		val, err := edge.GetCHValue(device, chTagName)
+		if err != nil {
+			return err
+		}
+
+		return val
+	})
+}
+
+//Pulse 脉冲输出，开启DO指定的秒数后由edge关闭
+func Pulse(deviceID int64, chTagName string, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var form struct {
+			Seconds int `form:"seconds" json:"seconds"`
+		}
+
+		if err := ctx.ReadJSON(&form); err != nil || form.Seconds <= 0 {
+			return lang.ErrInvalidRequestData
+		}
+
+		device, err := app.Store().GetDevice(deviceID)
+		if err != nil {
+			return err
+		}
+
+		measure, err := app.Store().GetMeasureFromTagName(device.GetID(), chTagName)
+		if err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		if !app.Allow(admin, measure, resource.Ctrl) {
+			return lang.ErrNoPermission
+		}
+
+		err = interlock.Do(admin, device, measure, true, func() error {
+			return edge.PulseCH(device, chTagName, form.Seconds)
+		})
 		if err != nil {
 			return err
 		}
@@ -179,3 +225,200 @@ func GetCHValue(deviceID int64, chTagName string, ctx iris.Context) hero.Result
 		return val
 	})
 }
+
+//ResetTotal 清零累计点位
+func ResetTotal(measureID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		measure, err := app.Store().GetMeasure(measureID)
+		if err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		if !app.Allow(admin, measure, resource.Ctrl) {
+			return lang.ErrNoPermission
+		}
+
+		device := measure.Device()
+		if device == nil {
+			return lang.ErrDeviceNotFound
+		}
+
+		err = edge.ResetTotalizerValue(device, measure.TagName())
+		if err != nil {
+			return err
+		}
+
+		return lang.Ok
+	})
+}
+
+//AcceptIdentity 确认更换后的控制器身份，edge恢复数据采集
+func AcceptIdentity(deviceID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		device, err := app.Store().GetDevice(deviceID)
+		if err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		if !app.Allow(admin, device, resource.Ctrl) {
+			return lang.ErrNoPermission
+		}
+
+		pending := device.GetOption("params.pendingIdentity")
+		if !pending.IsObject() {
+			return lang.ErrNoPendingIdentity
+		}
+
+		err = device.SetOption("params.identity", pending.Value())
+		if err != nil {
+			return err
+		}
+
+		err = device.SetOption("params.pendingIdentity", nil)
+		if err != nil {
+			return err
+		}
+
+		err = device.Save()
+		if err != nil {
+			return err
+		}
+
+		device.Logger().Infoln("accept device identity:", pending.Raw)
+
+		app.Event.Publish(event.DeviceUpdated, admin.GetID(), device.GetID())
+		return lang.Ok
+	})
+}
+
+//Diagnostics 设备的通讯诊断数据和抓取的原始帧
+func Diagnostics(deviceID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		device, err := app.Store().GetDevice(deviceID)
+		if err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		if !app.Allow(admin, device, resource.View) {
+			return lang.ErrNoPermission
+		}
+
+		data, err := edge.GetDeviceDiagnostics(device)
+		if err != nil {
+			return err
+		}
+		return data
+	})
+}
+
+//Capture 抓取设备接下来指定数量的请求和应答，结果通过Diagnostics查看
+func Capture(deviceID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var form struct {
+			Num int `form:"num" json:"num"`
+		}
+
+		if err := ctx.ReadJSON(&form); err != nil || form.Num <= 0 {
+			return lang.ErrInvalidRequestData
+		}
+
+		device, err := app.Store().GetDevice(deviceID)
+		if err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		if !app.Allow(admin, device, resource.Ctrl) {
+			return lang.ErrNoPermission
+		}
+
+		err = edge.CaptureFrames(device, form.Num)
+		if err != nil {
+			return err
+		}
+
+		return lang.Ok
+	})
+}
+
+//PinEdge 把设备固定到指定的edge
+func PinEdge(deviceID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var form struct {
+			URL string `form:"url" json:"url"`
+		}
+
+		if err := ctx.ReadJSON(&form); err != nil || form.URL == "" {
+			return lang.ErrInvalidRequestData
+		}
+
+		device, err := app.Store().GetDevice(deviceID)
+		if err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		if !app.Allow(admin, device, resource.Ctrl) {
+			return lang.ErrNoPermission
+		}
+
+		err = edge.PinDevice(device, form.URL)
+		if err != nil {
+			return err
+		}
+
+		err = device.SetOption("params.edge", form.URL)
+		if err != nil {
+			return err
+		}
+
+		err = device.Save()
+		if err != nil {
+			return err
+		}
+
+		msg := lang.UserPinDeviceOk.Str(admin.Name(), device.Title(), form.URL)
+		log.WithField("src", logStore.SystemLog).Info(msg)
+		admin.Logger().Info(msg)
+		device.Logger().Info(msg)
+
+		return lang.Ok
+	})
+}
+
+//UnpinEdge 取消设备固定的edge，设备仍然运行在当前edge上
+func UnpinEdge(deviceID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		device, err := app.Store().GetDevice(deviceID)
+		if err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		if !app.Allow(admin, device, resource.Ctrl) {
+			return lang.ErrNoPermission
+		}
+
+		err = device.SetOption("params.edge", nil)
+		if err != nil {
+			return err
+		}
+
+		err = device.Save()
+		if err != nil {
+			return err
+		}
+
+		edge.UnpinDevice(device)
+
+		msg := lang.UserUnpinDeviceOk.Str(admin.Name(), device.Title())
+		log.WithField("src", logStore.SystemLog).Info(msg)
+		admin.Logger().Info(msg)
+		device.Logger().Info(msg)
+
+		return lang.Ok
+	})
+}
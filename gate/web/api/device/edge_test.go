@@ -0,0 +1,132 @@
+package device
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/hero"
+	"github.com/maritimusj/centrum/gate/config"
+	"github.com/maritimusj/centrum/gate/web/app"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestAcceptIdentity(t *testing.T) {
+	app.Ctx = context.Background()
+	if err := app.InitDB(map[string]interface{}{
+		"connStr": filepath.Join(t.TempDir(), "test.db"),
+		"initDB":  true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	app.Config = config.New(app.Store())
+	if err := app.Config.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := app.Store()
+	org, err := s.CreateOrganization("test", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := s.CreateUser(org, app.Config.DefaultUserName(), []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	role, err := s.CreateRole(org, "guest", "guest", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	guest, err := s.CreateUser(org, "guest", []byte("password"), role)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	device, err := s.CreateDevice(org, "device", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := map[string]interface{}{"model": "EP6", "version": "2.0", "mac": "00:11:22:33:44:55", "ai": 8}
+	if err := device.SetOption("params.identity", identity); err != nil {
+		t.Fatal(err)
+	}
+	pending := map[string]interface{}{"model": "EP6", "version": "2.1", "mac": "00:11:22:33:44:66", "ai": 8}
+	if err := device.SetOption("params.pendingIdentity", pending); err != nil {
+		t.Fatal(err)
+	}
+	if err := device.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	var userID int64
+
+	a := iris.New()
+	a.Use(func(ctx iris.Context) {
+		ctx.Values().Set("__userID__", userID)
+		ctx.Next()
+	})
+	a.Post("/device/{id:int64}/identity", hero.Handler(AcceptIdentity))
+	if err := a.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(a)
+	defer srv.Close()
+
+	accept := func() bool {
+		resp, err := http.Post(srv.URL+"/device/"+strconv.FormatInt(device.GetID(), 10)+"/identity", "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var result struct {
+			Status bool `json:"status"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		return result.Status
+	}
+
+	reload := func() {
+		device, err = s.GetDevice(device.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//没有控制权限的用户不能确认
+	userID = guest.GetID()
+	if accept() {
+		t.Fatal("user without permission should not accept identity")
+	}
+	reload()
+	if device.GetOption("params.identity.version").Str != "2.0" || !device.GetOption("params.pendingIdentity").IsObject() {
+		t.Fatal("identity should not be changed")
+	}
+
+	//确认后新身份替换原来的身份
+	userID = admin.GetID()
+	if !accept() {
+		t.Fatal("admin should accept identity")
+	}
+	reload()
+	if device.GetOption("params.identity.version").Str != "2.1" || device.GetOption("params.identity.mac").Str != "00:11:22:33:44:66" {
+		t.Fatal("pending identity should be accepted, got", device.GetOption("params.identity").Raw)
+	}
+	if device.GetOption("params.pendingIdentity").IsObject() {
+		t.Fatal("pending identity should be removed")
+	}
+
+	//没有等待确认的身份
+	if accept() {
+		t.Fatal("nothing to accept")
+	}
+}
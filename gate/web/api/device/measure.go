@@ -10,6 +10,7 @@ import (
 	"github.com/maritimusj/centrum/gate/web/model"
 	"github.com/maritimusj/centrum/gate/web/resource"
 	"github.com/maritimusj/centrum/gate/web/response"
+	"github.com/maritimusj/centrum/gate/web/shelve"
 	"github.com/maritimusj/centrum/gate/web/store"
 )
 
@@ -59,6 +60,9 @@ func MeasureList(deviceID int64, ctx iris.Context) hero.Result {
 				"view": true,
 				"ctrl": app.Allow(admin, measure, resource.Ctrl),
 			}
+			if entry := shelve.Suppressed(device, measure); entry != nil {
+				brief["shelve"] = entry.Brief()
+			}
 			result = append(result, brief)
 		}
 
@@ -0,0 +1,138 @@
+package device
+
+import (
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/hero"
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/logStore"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	"github.com/maritimusj/centrum/gate/web/response"
+	"github.com/maritimusj/centrum/gate/web/shelve"
+	log "github.com/sirupsen/logrus"
+)
+
+type shelveForm struct {
+	Minutes int    `json:"minutes"`
+	Reason  string `json:"reason"`
+}
+
+//Shelve 搁置点位，到期前不产生警报和通知
+func Shelve(measureID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var form shelveForm
+		if err := ctx.ReadJSON(&form); err != nil {
+			return lang.ErrInvalidRequestData
+		}
+
+		measure, err := app.Store().GetMeasure(measureID)
+		if err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		if !app.Allow(admin, measure, resource.Ctrl) {
+			return lang.ErrNoPermission
+		}
+
+		entry, err := shelve.Set(shelve.Measure, measure.GetID(), measure.OrganizationID(), measure.Title(), admin, form.Minutes, form.Reason)
+		if err != nil {
+			return err
+		}
+
+		msg := lang.UserShelveMeasureOk.Str(admin.Name(), measure.Title(), form.Minutes, form.Reason)
+		log.WithField("src", logStore.SystemLog).Info(msg)
+		admin.Logger().Info(msg)
+		if device := measure.Device(); device != nil {
+			device.Logger().Info(msg)
+		}
+
+		return entry.Brief()
+	})
+}
+
+//Unshelve 取消点位的搁置
+func Unshelve(measureID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		measure, err := app.Store().GetMeasure(measureID)
+		if err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		if !app.Allow(admin, measure, resource.Ctrl) {
+			return lang.ErrNoPermission
+		}
+
+		if err = shelve.Remove(shelve.Measure, measure.GetID()); err != nil {
+			return err
+		}
+
+		msg := lang.UserUnshelveMeasureOk.Str(admin.Name(), measure.Title())
+		log.WithField("src", logStore.SystemLog).Info(msg)
+		admin.Logger().Info(msg)
+		if device := measure.Device(); device != nil {
+			device.Logger().Info(msg)
+		}
+
+		return lang.Ok
+	})
+}
+
+//StartMaintenance 设备进入维护模式，到期前设备的全部点位不产生警报和通知
+func StartMaintenance(deviceID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var form shelveForm
+		if err := ctx.ReadJSON(&form); err != nil {
+			return lang.ErrInvalidRequestData
+		}
+
+		device, err := app.Store().GetDevice(deviceID)
+		if err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		if !app.Allow(admin, device, resource.Ctrl) {
+			return lang.ErrNoPermission
+		}
+
+		entry, err := shelve.Set(shelve.Device, device.GetID(), device.OrganizationID(), device.Title(), admin, form.Minutes, form.Reason)
+		if err != nil {
+			return err
+		}
+
+		msg := lang.UserStartMaintenanceOk.Str(admin.Name(), device.Title(), form.Minutes, form.Reason)
+		log.WithField("src", logStore.SystemLog).Info(msg)
+		admin.Logger().Info(msg)
+		device.Logger().Info(msg)
+
+		return entry.Brief()
+	})
+}
+
+//EndMaintenance 设备退出维护模式
+func EndMaintenance(deviceID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		device, err := app.Store().GetDevice(deviceID)
+		if err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		if !app.Allow(admin, device, resource.Ctrl) {
+			return lang.ErrNoPermission
+		}
+
+		if err = shelve.Remove(shelve.Device, device.GetID()); err != nil {
+			return err
+		}
+
+		msg := lang.UserEndMaintenanceOk.Str(admin.Name(), device.Title())
+		log.WithField("src", logStore.SystemLog).Info(msg)
+		admin.Logger().Info(msg)
+		device.Logger().Info(msg)
+
+		return lang.Ok
+	})
+}
@@ -5,22 +5,29 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/maritimusj/centrum/gate/Getui"
-
-	"github.com/maritimusj/centrum/gate/web/model"
-
 	"github.com/maritimusj/centrum/gate/web/app"
 
+	"github.com/maritimusj/centrum/gate/event"
 	"github.com/maritimusj/centrum/gate/lang"
 
+	"github.com/maritimusj/centrum/gate/web/dispatch"
 	"github.com/maritimusj/centrum/gate/web/edge"
 	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/inbox"
+	"github.com/maritimusj/centrum/gate/web/push"
 	"github.com/maritimusj/centrum/gate/web/resource"
+	"github.com/maritimusj/centrum/gate/web/response"
+	"github.com/maritimusj/centrum/gate/web/rule"
+	"github.com/maritimusj/centrum/gate/web/shelve"
+	"github.com/maritimusj/centrum/gate/web/suppress"
 
 	"github.com/kataras/iris"
+	"github.com/kataras/iris/hero"
 	edgeLang "github.com/maritimusj/centrum/edge/lang"
 	"github.com/maritimusj/centrum/global"
+	"github.com/maritimusj/centrum/json_rpc"
 	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
 )
 
 type Log struct {
@@ -50,62 +57,63 @@ type Perf struct {
 	Delay int `json:"delay"`
 }
 
-func createMsg(res model.Resource, data interface{}) {
-	store := app.Store()
-	global.AddMessage(data, func(uid string, userId int64) bool {
-		user, err := store.GetUser(userId)
-		if err != nil {
-			global.Close(uid, userId)
-			return false
-		}
-
-		return app.Allow(user, res, resource.View)
-	})
+type Identity struct {
+	Model    string `json:"model"`
+	Version  string `json:"version"`
+	MAC      string `json:"mac"`
+	AI       int    `json:"ai"`
+	AO       int    `json:"ao"`
+	DI       int    `json:"di"`
+	DO       int    `json:"do"`
+	VO       int    `json:"vo"`
+	Mismatch bool   `json:"mismatch"`
 }
 
-//将新创建的点位授权给对设备有相应权限的用户
-//暂时采用循环遍历用户来判断
-func AssignPermissionsToUsers(device model.Device, measure model.Measure) error {
-	if app.Config.DefaultEffect() == resource.Allow {
-		return nil
+func (identity *Identity) Map() iris.Map {
+	return iris.Map{
+		"model":   identity.Model,
+		"version": identity.Version,
+		"mac":     identity.MAC,
+		"ai":      identity.AI,
+		"ao":      identity.AO,
+		"di":      identity.DI,
+		"do":      identity.DO,
+		"vo":      identity.VO,
 	}
+}
 
-	users, _, err := app.Store().GetUserList()
-	if err != nil {
-		return err
-	}
+func (identity *Identity) Equal(v gjson.Result) bool {
+	return v.Get("model").Str == identity.Model &&
+		v.Get("version").Str == identity.Version &&
+		v.Get("mac").Str == identity.MAC &&
+		int(v.Get("ai").Int()) == identity.AI &&
+		int(v.Get("ao").Int()) == identity.AO &&
+		int(v.Get("di").Int()) == identity.DI &&
+		int(v.Get("do").Int()) == identity.DO &&
+		int(v.Get("vo").Int()) == identity.VO
+}
 
-	for _, user := range users {
-		if app.IsDefaultAdminUser(user) {
-			continue
+//Register edge定时注册并上报负载
+func Register(ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var reg json_rpc.Registration
+		if err := ctx.ReadJSON(&reg); err != nil {
+			return lang.ErrInvalidRequestData
 		}
 
-		if app.Allow(user, device, resource.Ctrl) {
-			_ = app.SetAllow(user, measure, resource.View, resource.Ctrl)
-		} else if app.Allow(user, device, resource.View) {
-			_ = app.SetAllow(user, measure, resource.View)
+		err := edge.Register(&reg)
+		if err != nil {
+			return err
 		}
-	}
-
-	return nil
+		return lang.Ok
+	})
 }
 
-func NotifyAllUsers(alarm model.Alarm) error {
-	measure, err := alarm.Measure()
-	if err != nil {
-		return err
-	}
-
-	users, _, err := app.Store().GetUserList()
-	if err != nil {
-		return err
-	}
-	for _, user := range users {
-		if app.Allow(user, measure, resource.View) {
-			Getui.SendTo(user, lang.AlarmNotifyTitle.Str(), lang.AlarmNotifyContent.Str())
-		}
-	}
-	return nil
+//Edges 全部edge的状态和负载
+func Edges() hero.Result {
+	return response.Wrap(func() interface{} {
+		return edge.Edges()
+	})
 }
 
 func Feedback(deviceID int64, ctx iris.Context) {
@@ -120,11 +128,12 @@ func Feedback(deviceID int64, ctx iris.Context) {
 	}
 
 	var form struct {
-		Log     *Log     `json:"log"`
-		Status  *Status  `json:"status"`
-		Measure *Measure `json:"measure"`
-		Alarm   *Alarm   `json:"alarm"`
-		Perf    *Perf    `json:"perf"`
+		Log      *Log      `json:"log"`
+		Status   *Status   `json:"status"`
+		Measure  *Measure  `json:"measure"`
+		Alarm    *Alarm    `json:"alarm"`
+		Perf     *Perf     `json:"perf"`
+		Identity *Identity `json:"identity"`
 	}
 
 	if err := ctx.ReadJSON(&form); err != nil {
@@ -138,25 +147,21 @@ func Feedback(deviceID int64, ctx iris.Context) {
 		if form.Status.Index == int(edgeLang.Disconnected) {
 			global.UpdateDevicePerf(device, iris.Map{})
 			if org == int(edgeLang.Connected) {
-				createMsg(device, iris.Map{
-					"level":      "warning",
-					"title":      device.Title(),
-					"message":    lang.ErrDeviceDisconnected.Str(),
-					"created_at": time.Now().Format(lang.DatetimeFormatterStr.Str()),
-				})
+				inbox.Post(device, inbox.LevelWarning, device.Title(), lang.ErrDeviceDisconnected.Str(), nil)
 				device.Logger().Warningln(lang.ErrDeviceDisconnected.Str())
 			}
 		} else if form.Status.Index == int(edgeLang.Connected) {
 			if org != int(edgeLang.Connected) {
-				createMsg(device, iris.Map{
-					"level":      "success",
-					"title":      device.Title(),
-					"message":    lang.DeviceConnected.Str(),
-					"created_at": time.Now().Format(lang.DatetimeFormatterStr.Str()),
-				})
+				inbox.Post(device, inbox.LevelSuccess, device.Title(), lang.DeviceConnected.Str(), nil)
 			}
 		}
 		global.UpdateDeviceStatus(device, form.Status.Index, form.Status.Title)
+		push.OnDeviceStatus(device, form.Status.Index, form.Status.Title)
+		if org != form.Status.Index {
+			app.Event.Publish(event.DeviceStatusChanged, device.GetID(), form.Status.Index, form.Status.Title)
+		}
+		rule.OnDeviceStatus(device, org, form.Status.Index)
+		suppress.OnDeviceStatus(device, form.Status.Index)
 	}
 
 	if form.Measure != nil {
@@ -180,7 +185,7 @@ func Feedback(deviceID int64, ctx iris.Context) {
 			}
 
 			//将新创建的点位授权给对设备有相应权限的用户
-			err = AssignPermissionsToUsers(device, measure)
+			err = app.AssignPermissionsToUsers(device, measure)
 			if err != nil {
 				log.Debugln("[Feedback 7]", err)
 			}
@@ -203,30 +208,54 @@ func Feedback(deviceID int64, ctx iris.Context) {
 			return
 		}
 
+		//记录警报出现的时间，反复出现的点位会被自动搁置
+		raised := suppress.Observe(device, measure)
+
 		alarm, _, err := app.Store().GetLastUnconfirmedAlarm(helper.Device(device.GetID()), helper.Measure(measure.GetID()))
 		if err != nil {
 			if err != lang.ErrAlarmNotFound.Error() {
 				log.Debugln("[Feedback 10]", err)
 				return
 			}
-			alarm, err = app.Store().CreateAlarm(device, measure.GetID(), map[string]interface{}{
-				"name":   form.Alarm.Name,
-				"tags":   form.Alarm.Tags,
-				"fields": form.Alarm.Fields,
-				"time":   form.Alarm.Time,
-			})
-			if err != nil {
-				log.Debugln("[Feedback 11]", err)
-			} else {
-				go func() {
-					_ = NotifyAllUsers(alarm)
-				}()
+
+			switch entry := shelve.Suppressed(device, measure); {
+			case entry != nil:
+				//点位被搁置或者设备处于维护模式时只记录，不产生警报和通知
+				if raised {
+					val := fmt.Sprintf("%v%v", form.Alarm.Fields["val"], form.Alarm.Tags["unit"])
+					device.Logger().Warningln(lang.AlarmSuppressed.Str(measure.Title(), form.Alarm.Tags["alarm"], val, entry.Reason))
+				}
+			case suppress.Disconnected(device):
+				//设备断开期间点位的警报是连带警报，不处理
+			case suppress.Flood(device, measure):
+				//警报泛滥时合并到汇总警报，不单独创建和通知
+			default:
+				priority := app.AlarmPriority(measure, form.Alarm.Tags["alarm"])
+				alarm, err = app.Store().CreateAlarm(device, measure.GetID(), priority, map[string]interface{}{
+					"name":   form.Alarm.Name,
+					"tags":   form.Alarm.Tags,
+					"fields": form.Alarm.Fields,
+					"time":   form.Alarm.Time,
+				})
+				if err != nil {
+					log.Debugln("[Feedback 11]", err)
+				} else {
+					go func() {
+						_ = dispatch.OnAlarm(alarm)
+					}()
+					rule.OnAlarm(device, measure)
+				}
 			}
 		} else {
+			//已经消失的警报再次出现
+			reopened := !alarm.ClearedAt().IsZero()
 			alarm.Updated()
 			if err = alarm.Save(); err != nil {
 				log.Debugln("[Feedback 11]", err)
 			}
+			if reopened {
+				rule.OnAlarm(device, measure)
+			}
 		}
 	}
 
@@ -251,6 +280,28 @@ func Feedback(deviceID int64, ctx iris.Context) {
 		}
 		data["level"] = level
 		global.UpdateDevicePerf(device, data)
+		push.OnDevicePerf(device, data)
+	}
+
+	if form.Identity != nil {
+		if form.Identity.Mismatch {
+			//同一个新身份只提醒一次，等待用户确认
+			if !form.Identity.Equal(device.GetOption("params.pendingIdentity")) {
+				if err := device.SetOption("params.pendingIdentity", form.Identity.Map()); err != nil {
+					log.Debugln("[Feedback 12]", err)
+				} else if err = device.Save(); err != nil {
+					log.Debugln("[Feedback 13]", err)
+				}
+				inbox.Post(device, inbox.LevelWarning, device.Title(), lang.DeviceIdentityMismatch.Str(), nil)
+				device.Logger().Warningln(lang.DeviceIdentityMismatch.Str(), form.Identity.Map())
+			}
+		} else if !device.GetOption("params.identity").Exists() {
+			if err := device.SetOption("params.identity", form.Identity.Map()); err != nil {
+				log.Debugln("[Feedback 14]", err)
+			} else if err = device.Save(); err != nil {
+				log.Debugln("[Feedback 15]", err)
+			}
+		}
 	}
 
 	if form.Log != nil {
@@ -13,6 +13,7 @@ import (
 	"github.com/maritimusj/centrum/gate/web/app"
 	"github.com/maritimusj/centrum/gate/web/edge"
 	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/interlock"
 	"github.com/maritimusj/centrum/gate/web/model"
 	"github.com/maritimusj/centrum/gate/web/resource"
 	"github.com/maritimusj/centrum/gate/web/response"
@@ -242,7 +243,7 @@ func Ctrl(ctx iris.Context) hero.Result {
 			return lang.ErrDeviceNotFound.Error()
 		}
 
-		err = edge.SetCHValue(device, measure.TagName(), form.Val)
+		err = interlock.SetCHValue(admin, device, measure, form.Val)
 		if err != nil {
 			return err
 		}
@@ -11,6 +11,7 @@ import (
 	"github.com/maritimusj/centrum/gate/web/model"
 	"github.com/maritimusj/centrum/gate/web/resource"
 	"github.com/maritimusj/centrum/gate/web/response"
+	"github.com/maritimusj/centrum/gate/web/shelve"
 	"github.com/maritimusj/centrum/gate/web/store"
 )
 
@@ -68,6 +69,9 @@ func List(ctx iris.Context) hero.Result {
 
 		for _, equipment := range equipments {
 			brief := equipment.Brief()
+			if entry := shelve.Get(shelve.Equipment, equipment.GetID()); entry != nil {
+				brief["maintenance"] = entry.Brief()
+			}
 
 			groups, err := equipment.Groups()
 			if err != nil {
@@ -0,0 +1,74 @@
+package equipment
+
+import (
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/hero"
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/logStore"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	"github.com/maritimusj/centrum/gate/web/response"
+	"github.com/maritimusj/centrum/gate/web/shelve"
+	log "github.com/sirupsen/logrus"
+)
+
+//StartMaintenance 虚拟设备进入维护模式，到期前关联的点位不产生警报和通知
+func StartMaintenance(equipmentID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var form struct {
+			Minutes int    `json:"minutes"`
+			Reason  string `json:"reason"`
+		}
+		if err := ctx.ReadJSON(&form); err != nil {
+			return lang.ErrInvalidRequestData
+		}
+
+		equipment, err := app.Store().GetEquipment(equipmentID)
+		if err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		if !app.Allow(admin, equipment, resource.Ctrl) {
+			return lang.ErrNoPermission
+		}
+
+		entry, err := shelve.Set(shelve.Equipment, equipment.GetID(), equipment.OrganizationID(), equipment.Title(), admin, form.Minutes, form.Reason)
+		if err != nil {
+			return err
+		}
+
+		msg := lang.UserStartMaintenanceOk.Str(admin.Name(), equipment.Title(), form.Minutes, form.Reason)
+		log.WithField("src", logStore.SystemLog).Info(msg)
+		admin.Logger().Info(msg)
+		equipment.Logger().Info(msg)
+
+		return entry.Brief()
+	})
+}
+
+//EndMaintenance 虚拟设备退出维护模式
+func EndMaintenance(equipmentID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		equipment, err := app.Store().GetEquipment(equipmentID)
+		if err != nil {
+			return err
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		if !app.Allow(admin, equipment, resource.Ctrl) {
+			return lang.ErrNoPermission
+		}
+
+		if err = shelve.Remove(shelve.Equipment, equipment.GetID()); err != nil {
+			return err
+		}
+
+		msg := lang.UserEndMaintenanceOk.Str(admin.Name(), equipment.Title())
+		log.WithField("src", logStore.SystemLog).Info(msg)
+		admin.Logger().Info(msg)
+		equipment.Logger().Info(msg)
+
+		return lang.Ok
+	})
+}
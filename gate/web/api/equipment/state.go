@@ -1,6 +1,8 @@
 package equipment
 
 import (
+	"strings"
+
 	"github.com/asaskevich/govalidator"
 	"github.com/kataras/iris"
 	"github.com/kataras/iris/hero"
@@ -210,6 +212,7 @@ func UpdateState(stateID int64, ctx iris.Context) hero.Result {
 				Enable   bool    `json:"enable"`
 				DeadBand float32 `json:"deadband"`
 				Delay    int     `json:"delay"`
+				Priority *string `json:"priority"`
 				Entries  struct {
 					HF *float32 `json:"hf"`
 					HH *float32 `json:"hh"`
@@ -247,6 +250,15 @@ func UpdateState(stateID int64, ctx iris.Context) hero.Result {
 			state.SetAlarmDeadBand(form.Alarm.DeadBand)
 			state.SetAlarmDelay(form.Alarm.Delay)
 
+			//优先级为空时使用系统的设置
+			if form.Alarm.Priority != nil {
+				name := strings.ToLower(*form.Alarm.Priority)
+				if _, ok := alarm.ParsePriority(name); name != "" && !ok {
+					return lang.ErrInvalidAlarmPriority.Error(name)
+				}
+				state.SetAlarmPriority(name)
+			}
+
 			if form.Alarm.Entries.HF != nil {
 				state.SetAlarmEntry(alarm.HF, *form.Alarm.Entries.HF)
 				state.EnableAlarmEntry(alarm.HF)
@@ -69,6 +69,18 @@ func Delete(ctx iris.Context) hero.Result {
 	})
 }
 
+//NotifyList 通知的发送记录
+func NotifyList(ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		admin := app.Store().MustGetUserFromContext(ctx)
+		orgID := admin.OrganizationID()
+		if app.IsDefaultAdminUser(admin) && ctx.URLParamExists("org") {
+			orgID = ctx.URLParamInt64Default("org", admin.OrganizationID())
+		}
+		return GetLogList(ctx, orgID, logStore.NotifyLog)
+	})
+}
+
 func GetLogList(ctx iris.Context, orgID int64, src string) interface{} {
 	level := ctx.URLParam("level")
 	start := ctx.URLParamInt64Default("start", 0)
@@ -0,0 +1,206 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kataras/iris"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/edge"
+	"github.com/maritimusj/centrum/gate/web/metrics"
+	"github.com/maritimusj/centrum/global"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+//检查依赖服务的超时时间
+const checkTimeout = 3 * time.Second
+
+var (
+	devicesDesc = prometheus.NewDesc(
+		"gate_devices", "Number of devices by status.", []string{"status", "title"}, nil)
+	unconfirmedAlarmsDesc = prometheus.NewDesc(
+		"gate_alarms_unconfirmed", "Number of unconfirmed alarms.", nil, nil)
+	logQueueDesc = prometheus.NewDesc(
+		"gate_log_queue_length", "Number of log entries waiting to be written.", nil, nil)
+)
+
+func init() {
+	_ = metrics.Register(&collector{})
+}
+
+//collector 采集时统计设备状态、未确认警报和日志队列
+type collector struct{}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- devicesDesc
+	ch <- unconfirmedAlarmsDesc
+	ch <- logQueueDesc
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	s := app.Store()
+	if s == nil {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(logQueueDesc, prometheus.GaugeValue, float64(app.LogDBStore.QueueLength()))
+
+	devices, _, err := s.GetDeviceList()
+	if err != nil {
+		log.Errorln("[metrics]", err)
+	} else {
+		type status struct {
+			title string
+			total int
+		}
+
+		stats := map[int]*status{}
+		for _, device := range devices {
+			index, title, _ := global.GetDeviceStatus(device)
+			if v, ok := stats[index]; ok {
+				v.total++
+			} else {
+				stats[index] = &status{title: title, total: 1}
+			}
+		}
+
+		for index, v := range stats {
+			ch <- prometheus.MustNewConstMetric(devicesDesc, prometheus.GaugeValue, float64(v.total), strconv.Itoa(index), v.title)
+		}
+	}
+
+	_, total, err := s.GetLastUnconfirmedAlarm()
+	if err != nil {
+		total = 0
+	}
+	ch <- prometheus.MustNewConstMetric(unconfirmedAlarmsDesc, prometheus.GaugeValue, float64(total))
+}
+
+//Observe 统计API请求的用时，路由没有名称时使用路由路径
+func Observe(ctx iris.Context) {
+	begin := time.Now()
+
+	ctx.Next()
+
+	route := ctx.GetCurrentRoute()
+	if route == nil {
+		return
+	}
+
+	name := route.Name()
+	if name == "" {
+		name = route.Path()
+	}
+
+	metrics.APIRequestDuration.
+		WithLabelValues(name, ctx.Method(), strconv.Itoa(ctx.GetStatusCode())).
+		Observe(time.Now().Sub(begin).Seconds())
+}
+
+//Metrics Prometheus统计数据
+func Metrics() iris.Handler {
+	return iris.FromStd(metrics.Handler())
+}
+
+//Healthz 进程和数据库是否正常
+func Healthz(ctx iris.Context) {
+	checks := map[string]interface{}{
+		"db": checkDB(),
+	}
+	reply(ctx, checks)
+}
+
+//Readyz 数据库、InfluxDB和全部edge都可以访问时才能提供服务
+func Readyz(ctx iris.Context) {
+	checks := map[string]interface{}{
+		"db":       checkDB(),
+		"influxdb": checkInfluxDB(),
+		"edges":    checkEdges(),
+	}
+	reply(ctx, checks)
+}
+
+func reply(ctx iris.Context, checks map[string]interface{}) {
+	ok := true
+	for _, v := range checks {
+		if !isOk(v) {
+			ok = false
+			break
+		}
+	}
+
+	status := "ok"
+	if !ok {
+		status = "fail"
+		ctx.StatusCode(http.StatusServiceUnavailable)
+	}
+
+	_, _ = ctx.JSON(iris.Map{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+func isOk(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		return v == "ok"
+	case map[string]string:
+		for _, x := range v {
+			if x != "ok" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func result(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
+
+func checkDB() string {
+	if app.DB == nil {
+		return "not initialized"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	return result(app.DB.Ping(ctx))
+}
+
+func checkInfluxDB() string {
+	return result(app.StatsDB.Ping(checkTimeout))
+}
+
+func checkEdges() map[string]string {
+	var (
+		checks = map[string]string{}
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+	)
+
+	for _, url := range edge.URLs() {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+
+			err := edge.Ping(url, checkTimeout)
+
+			mu.Lock()
+			checks[url] = result(err)
+			mu.Unlock()
+		}(url)
+	}
+
+	wg.Wait()
+	return checks
+}
@@ -0,0 +1,125 @@
+package my
+
+import (
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/hero"
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/response"
+	"github.com/maritimusj/centrum/gate/web/status"
+)
+
+//MessageList 当前用户的站内消息，unread参数存在时只列出未读消息
+func MessageList(ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var (
+			s  = app.Store()
+			my = s.MustGetUserFromContext(ctx)
+
+			page     = ctx.URLParamInt64Default("page", 1)
+			pageSize = ctx.URLParamInt64Default("pagesize", app.Config.DefaultPageSize())
+
+			params = []helper.OptionFN{
+				helper.Page(page, pageSize),
+				helper.User(my.GetID()),
+			}
+		)
+
+		if ctx.URLParamExists("unread") {
+			params = append(params, helper.Status(status.Unread))
+		}
+
+		messages, total, err := s.GetMessageList(params...)
+		if err != nil {
+			return err
+		}
+
+		_, unread, err := s.GetMessageList(helper.User(my.GetID()), helper.Status(status.Unread), helper.Limit(1))
+		if err != nil {
+			return err
+		}
+
+		var result = make([]model.Map, 0, len(messages))
+		for _, message := range messages {
+			result = append(result, message.Brief())
+		}
+
+		return iris.Map{
+			"total":  total,
+			"unread": unread,
+			"list":   result,
+		}
+	})
+}
+
+func getMessage(ctx iris.Context, messageID int64) (model.Message, error) {
+	s := app.Store()
+	message, err := s.GetMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	my := s.MustGetUserFromContext(ctx)
+	if message.UserID() != my.GetID() {
+		return nil, lang.ErrMessageNotFound.Error()
+	}
+	return message, nil
+}
+
+func MessageDetail(messageID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		message, err := getMessage(ctx, messageID)
+		if err != nil {
+			return err
+		}
+		return message.Detail()
+	})
+}
+
+//ReadMessage 消息标记为已读
+func ReadMessage(messageID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		message, err := getMessage(ctx, messageID)
+		if err != nil {
+			return err
+		}
+
+		message.MarkRead()
+		if err = message.Save(); err != nil {
+			return err
+		}
+		return lang.Ok
+	})
+}
+
+//ReadAllMessages 全部消息标记为已读
+func ReadAllMessages(ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		s := app.Store()
+		my := s.MustGetUserFromContext(ctx)
+
+		total, err := s.MarkAllMessagesRead(my.GetID())
+		if err != nil {
+			return err
+		}
+		return iris.Map{
+			"total": total,
+		}
+	})
+}
+
+func DeleteMessage(messageID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		message, err := getMessage(ctx, messageID)
+		if err != nil {
+			return err
+		}
+
+		if err = message.Destroy(); err != nil {
+			return err
+		}
+		return lang.Ok
+	})
+}
@@ -0,0 +1,115 @@
+package push
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/hero"
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/model"
+	pushDef "github.com/maritimusj/centrum/gate/web/push"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	"github.com/maritimusj/centrum/gate/web/response"
+	log "github.com/sirupsen/logrus"
+)
+
+//心跳间隔，防止代理服务器关闭空闲的连接
+const heartbeatInterval = 15 * time.Second
+
+//Stream 以Server-Sent Events推送订阅的设备、虚拟设备的实时数据和状态，新的警报和站内消息，
+//参数：devices=1,2&equipments=3&alarm&inbox
+func Stream(ctx iris.Context) hero.Result {
+	s := app.Store()
+	admin := s.MustGetUserFromContext(ctx)
+
+	var (
+		sub pushDef.Subscription
+		err error
+	)
+
+	sub.Devices, err = parseIDs(ctx.URLParam("devices"), func(id int64) (model.Resource, error) {
+		return s.GetDevice(id)
+	}, admin)
+	if err != nil {
+		return response.Wrap(err)
+	}
+
+	sub.Equipments, err = parseIDs(ctx.URLParam("equipments"), func(id int64) (model.Resource, error) {
+		return s.GetEquipment(id)
+	}, admin)
+	if err != nil {
+		return response.Wrap(err)
+	}
+
+	sub.Alarm = ctx.URLParamExists("alarm")
+	sub.Inbox = ctx.URLParamExists("inbox")
+
+	if len(sub.Devices) == 0 && len(sub.Equipments) == 0 && !sub.Alarm && !sub.Inbox {
+		return response.Wrap(lang.ErrInvalidRequestData)
+	}
+
+	w := ctx.ResponseWriter()
+	ctx.ContentType("text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.StatusCode(iris.StatusOK)
+	w.Flush()
+
+	subscriber := pushDef.Subscribe(admin, sub)
+	defer pushDef.Unsubscribe(subscriber)
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	done := ctx.Request().Context().Done()
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return nil
+			}
+		case event := <-subscriber.Events():
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				log.Errorln("[push] ", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, data); err != nil {
+				return nil
+			}
+		}
+		w.Flush()
+	}
+}
+
+//parseIDs 解析逗号分隔的资源ID，并检查用户是否能查看
+func parseIDs(str string, get func(id int64) (model.Resource, error), admin model.User) ([]int64, error) {
+	var result []int64
+	for _, v := range strings.Split(str, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(v, 10, 0)
+		if err != nil {
+			return nil, lang.ErrInvalidRequestData.Error()
+		}
+		res, err := get(id)
+		if err != nil {
+			return nil, err
+		}
+		if !app.Allow(admin, res, resource.View) {
+			return nil, lang.ErrNoPermission.Error()
+		}
+		result = append(result, id)
+	}
+	return result, nil
+}
@@ -0,0 +1,114 @@
+package push
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/hero"
+	"github.com/maritimusj/centrum/gate/config"
+	"github.com/maritimusj/centrum/gate/web/app"
+	pushDef "github.com/maritimusj/centrum/gate/web/push"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestStream(t *testing.T) {
+	app.Ctx = context.Background()
+	if err := app.InitDB(map[string]interface{}{
+		"connStr": filepath.Join(t.TempDir(), "test.db"),
+		"initDB":  true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	app.Config = config.New(app.Store())
+	if err := app.Config.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := app.Store()
+	org, err := s.CreateOrganization("test", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.CreateUser(org, app.Config.DefaultUserName(), []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := iris.New()
+	a.Use(func(ctx iris.Context) {
+		ctx.Values().Set("__userID__", user.GetID())
+		ctx.Next()
+	})
+	a.Get("/push", hero.Handler(Stream))
+	if err := a.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(a)
+	defer srv.Close()
+
+	//没有订阅任何内容
+	resp, err := http.Get(srv.URL + "/push")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatal("empty subscription should be rejected")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/push?inbox", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatal("invalid content type:", resp.Header.Get("Content-Type"))
+	}
+
+	for pushDef.Count() == 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("subscriber not registered")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	msg, err := s.CreateMessage(user.GetID(), "info", "title", "hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pushDef.OnMessage(msg)
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	if err != nil || line != "event: "+pushDef.Message+"\n" {
+		t.Fatalf("unexpected event: %q, %v", line, err)
+	}
+	line, err = reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "data: {") || !strings.Contains(line, "hello") {
+		t.Fatalf("unexpected data: %q, %v", line, err)
+	}
+
+	//连接断开后取消订阅
+	cancel()
+	for i := 0; pushDef.Count() > 0; i++ {
+		if i > 100 {
+			t.Fatal("subscriber not removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
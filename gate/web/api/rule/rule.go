@@ -0,0 +1,259 @@
+package rule
+
+import (
+	"encoding/json"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/hero"
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/logStore"
+	"github.com/maritimusj/centrum/gate/web/api/log"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/response"
+	"github.com/maritimusj/centrum/gate/web/rule"
+	"github.com/tidwall/gjson"
+
+	log2 "github.com/sirupsen/logrus"
+)
+
+//规则配置中可以修改的项
+var paramKeys = []string{"trigger", "conditions", "actions", "dryRun", "cooldown"}
+
+//getRule 获取规则，只能管理本组织的规则
+func getRule(admin model.User, ruleID int64) (model.Rule, error) {
+	r, err := app.Store().GetRule(ruleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !app.IsDefaultAdminUser(admin) && r.OrganizationID() != admin.OrganizationID() {
+		return nil, lang.ErrNoPermission.Error()
+	}
+
+	return r, nil
+}
+
+//checkParams 检查规则配置和当前用户对规则引用点位的权限
+func checkParams(admin model.User, params map[string]interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return lang.ErrInvalidRequestData.Error()
+	}
+
+	conf, err := rule.Parse(gjson.ParseBytes(data))
+	if err != nil {
+		return err
+	}
+
+	return rule.CheckPerm(admin, conf)
+}
+
+func List(ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var (
+			s = app.Store()
+
+			params []helper.OptionFN
+			orgID  int64
+
+			admin = s.MustGetUserFromContext(ctx)
+		)
+
+		if app.IsDefaultAdminUser(admin) {
+			if ctx.URLParamExists("org") {
+				orgID = ctx.URLParamInt64Default("org", 0)
+			}
+		} else {
+			orgID = admin.OrganizationID()
+		}
+		if orgID > 0 {
+			params = append(params, helper.Organization(orgID))
+		}
+
+		var (
+			page     = ctx.URLParamInt64Default("page", 1)
+			pageSize = ctx.URLParamInt64Default("pagesize", app.Config.DefaultPageSize())
+		)
+
+		params = append(params, helper.Page(page, pageSize))
+
+		keyword := ctx.URLParam("keyword")
+		if keyword != "" {
+			params = append(params, helper.Keyword(keyword))
+		}
+
+		rules, total, err := s.GetRuleList(params...)
+		if err != nil {
+			return err
+		}
+
+		result := make([]model.Map, 0, len(rules))
+		for _, r := range rules {
+			result = append(result, r.Brief())
+		}
+
+		return iris.Map{
+			"total": total,
+			"list":  result,
+		}
+	})
+}
+
+func Create(ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var form struct {
+			OrgID  int64                  `json:"org"`
+			Title  string                 `json:"title" valid:"required"`
+			Enable *bool                  `json:"enable"`
+			Params map[string]interface{} `json:"params"`
+		}
+
+		if err := ctx.ReadJSON(&form); err != nil {
+			return lang.ErrInvalidRequestData
+		}
+
+		if _, err := govalidator.ValidateStruct(&form); err != nil {
+			return lang.ErrInvalidRequestData
+		}
+
+		s := app.Store()
+		admin := s.MustGetUserFromContext(ctx)
+
+		if err := checkParams(admin, form.Params); err != nil {
+			return err
+		}
+
+		var org interface{}
+		if app.IsDefaultAdminUser(admin) {
+			if form.OrgID > 0 {
+				org = form.OrgID
+			} else {
+				org = app.Config.DefaultOrganization()
+			}
+		} else {
+			org = admin.OrganizationID()
+		}
+
+		r, err := s.CreateRule(org, admin.GetID(), form.Title, form.Params)
+		if err != nil {
+			return err
+		}
+
+		if form.Enable != nil && !*form.Enable {
+			r.Disable()
+			if err = r.Save(); err != nil {
+				return err
+			}
+		}
+
+		rule.Reload(r)
+
+		log2.WithField("src", logStore.SystemLog).Info(lang.UserCreateRuleOk.Str(admin.Name(), r.Title()))
+		r.Logger().Info(lang.UserCreateRuleOk.Str(admin.Name(), r.Title()))
+
+		return r.Simple()
+	})
+}
+
+func Detail(ruleID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		admin := app.Store().MustGetUserFromContext(ctx)
+		r, err := getRule(admin, ruleID)
+		if err != nil {
+			return err
+		}
+
+		return r.Detail()
+	})
+}
+
+func Update(ruleID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var form struct {
+			Title  *string                `json:"title"`
+			Enable *bool                  `json:"enable"`
+			Params map[string]interface{} `json:"params"`
+		}
+
+		if err := ctx.ReadJSON(&form); err != nil {
+			return lang.ErrInvalidRequestData
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+		r, err := getRule(admin, ruleID)
+		if err != nil {
+			return err
+		}
+
+		//修改配置时需要提交完整的配置
+		if form.Params != nil {
+			if err := checkParams(admin, form.Params); err != nil {
+				return err
+			}
+			for _, key := range paramKeys {
+				if err := r.SetOption(key, form.Params[key]); err != nil {
+					return lang.InternalError(err)
+				}
+			}
+		}
+
+		if form.Title != nil {
+			r.SetTitle(*form.Title)
+		}
+
+		if form.Enable != nil {
+			if *form.Enable {
+				r.Enable()
+			} else {
+				r.Disable()
+			}
+		}
+
+		if err = r.Save(); err != nil {
+			return err
+		}
+
+		rule.Reload(r)
+
+		log2.WithField("src", logStore.SystemLog).Info(lang.UserUpdateRuleOk.Str(admin.Name(), r.Title()))
+		r.Logger().Info(lang.UserUpdateRuleOk.Str(admin.Name(), r.Title()))
+
+		return lang.Ok
+	})
+}
+
+func Delete(ruleID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		admin := app.Store().MustGetUserFromContext(ctx)
+		r, err := getRule(admin, ruleID)
+		if err != nil {
+			return err
+		}
+
+		title := r.Title()
+		if err = r.Destroy(); err != nil {
+			return err
+		}
+
+		rule.Remove(ruleID)
+
+		log2.WithField("src", logStore.SystemLog).Info(lang.UserDeleteRuleOk.Str(admin.Name(), title))
+		return lang.Ok
+	})
+}
+
+//LogList 规则的执行记录
+func LogList(ruleID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		admin := app.Store().MustGetUserFromContext(ctx)
+		r, err := getRule(admin, ruleID)
+		if err != nil {
+			return err
+		}
+
+		return log.GetLogList(ctx, r.OrganizationID(), r.UID())
+	})
+}
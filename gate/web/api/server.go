@@ -25,13 +25,17 @@ import (
 	"github.com/maritimusj/centrum/gate/web/api/equipment"
 	"github.com/maritimusj/centrum/gate/web/api/group"
 	logStore "github.com/maritimusj/centrum/gate/web/api/log"
+	"github.com/maritimusj/centrum/gate/web/api/monitor"
 	"github.com/maritimusj/centrum/gate/web/api/my"
 	"github.com/maritimusj/centrum/gate/web/api/organization"
+	"github.com/maritimusj/centrum/gate/web/api/push"
 	"github.com/maritimusj/centrum/gate/web/api/resource"
 	"github.com/maritimusj/centrum/gate/web/api/role"
+	"github.com/maritimusj/centrum/gate/web/api/rule"
 	"github.com/maritimusj/centrum/gate/web/api/statistics"
 	"github.com/maritimusj/centrum/gate/web/api/user"
 	"github.com/maritimusj/centrum/gate/web/api/web"
+	"github.com/maritimusj/centrum/gate/web/api/webhook"
 	"github.com/maritimusj/centrum/gate/web/app"
 	"github.com/maritimusj/centrum/gate/web/perm"
 	resourceDef "github.com/maritimusj/centrum/gate/web/resource"
@@ -88,6 +92,14 @@ func (server *server) Start(ctx context.Context, webDir string, _ *cfg.Config) {
 		AllowCredentials: true,
 	})
 
+	//API请求统计
+	server.app.UseGlobal(monitor.Observe)
+
+	//监控和健康检查
+	server.app.Get("/metrics", monitor.Metrics())
+	server.app.Get("/healthz", monitor.Healthz)
+	server.app.Get("/readyz", monitor.Readyz)
+
 	//后台
 	server.app.StaticWeb("/", webDir)
 
@@ -104,6 +116,8 @@ func (server *server) Start(ctx context.Context, webDir string, _ *cfg.Config) {
 		//edge 回调
 		p.PartyFunc("/edge", func(p router.Party) {
 			_ = global.Params.Set("callbackURL", fmt.Sprintf("http://localhost:%d%s", app.Config.APIPort(), p.GetRelPath()))
+			//edge注册和上报负载
+			p.Post("/register", hero.Handler(edge.Register))
 			p.Post("/{id:int64}", hero.Handler(edge.Feedback))
 		})
 
@@ -118,6 +132,12 @@ func (server *server) Start(ctx context.Context, webDir string, _ *cfg.Config) {
 				p.Put("/webview", hero.Handler(config.UpdateWebView)).Name = resourceDef.ConfigWebUpdate
 				p.Get("/stream", hero.Handler(config.StreamView)).Name = resourceDef.ConfigStreamDetail
 				p.Put("/stream", hero.Handler(config.UpdateStreamView)).Name = resourceDef.ConfigStreamUpdate
+				p.Get("/alarm/routing", hero.Handler(config.AlarmRouting)).Name = resourceDef.ConfigBaseDetail
+				p.Put("/alarm/routing", hero.Handler(config.UpdateAlarmRouting)).Name = resourceDef.ConfigBaseUpdate
+				p.Get("/alarm/priority", hero.Handler(config.AlarmPriorities)).Name = resourceDef.ConfigBaseDetail
+				p.Put("/alarm/priority", hero.Handler(config.UpdateAlarmPriorities)).Name = resourceDef.ConfigBaseUpdate
+				p.Get("/inbox", hero.Handler(config.InboxRetention)).Name = resourceDef.ConfigBaseDetail
+				p.Put("/inbox", hero.Handler(config.UpdateInboxRetention)).Name = resourceDef.ConfigBaseUpdate
 			})
 
 			//我的
@@ -125,6 +145,13 @@ func (server *server) Start(ctx context.Context, webDir string, _ *cfg.Config) {
 				p.Get("/profile", hero.Handler(my.Detail)).Name = resourceDef.MyProfileDetail
 				p.Put("/profile", hero.Handler(my.Update)).Name = resourceDef.MyProfileUpdate
 
+				//站内消息
+				p.Get("/message", hero.Handler(my.MessageList)).Name = resourceDef.MyProfileDetail
+				p.Put("/message", hero.Handler(my.ReadAllMessages)).Name = resourceDef.MyProfileUpdate
+				p.Get("/message/{id:int64}", hero.Handler(my.MessageDetail)).Name = resourceDef.MyProfileDetail
+				p.Put("/message/{id:int64}", hero.Handler(my.ReadMessage)).Name = resourceDef.MyProfileUpdate
+				p.Delete("/message/{id:int64}", hero.Handler(my.DeleteMessage)).Name = resourceDef.MyProfileUpdate
+
 				//请求当前用户对于某个资源的权限情况
 				p.Get("/perm/{class:string}", hero.Handler(my.Perm)).Name = resourceDef.MyPerm
 				p.Post("/perm/{class:string}", hero.Handler(my.MultiPerm)).Name = resourceDef.MyPermMulti
@@ -133,8 +160,12 @@ func (server *server) Start(ctx context.Context, webDir string, _ *cfg.Config) {
 			//系统简讯
 			p.PartyFunc("/brief", func(p router.Party) {
 				p.Get("/", hero.Handler(brief.Simple)).Name = resourceDef.SysBrief
+				p.Get("/edges", hero.Handler(edge.Edges)).Name = resourceDef.SysBrief
 			})
 
+			//实时推送
+			p.Get("/push", hero.Handler(push.Stream)).Name = resourceDef.SysBrief
+
 			//资源
 			p.PartyFunc("/resource", func(p router.Party) {
 				p.Get("/{groupID:int}/", hero.Handler(resource.List)).Name = resourceDef.ResourceDetail
@@ -191,6 +222,10 @@ func (server *server) Start(ctx context.Context, webDir string, _ *cfg.Config) {
 				p.Post("/status", hero.Handler(device.MultiStatus)).Name = resourceDef.DeviceList
 				p.Post("/", hero.Handler(device.Create)).Name = resourceDef.DeviceCreate
 
+				//扫描网络中的控制器
+				p.Post("/discover", hero.Handler(device.Discover)).Name = resourceDef.DeviceCreate
+				p.Post("/discover/create", hero.Handler(device.CreateFromDiscovered)).Name = resourceDef.DeviceCreate
+
 				p.Get("/{id:int64}", hero.Handler(device.Detail)).Name = resourceDef.DeviceDetail
 				p.Put("/{id:int64}", hero.Handler(device.Update)).Name = resourceDef.DeviceUpdate
 				p.Delete("/{id:int64}", hero.Handler(device.Delete)).Name = resourceDef.DeviceDelete
@@ -209,8 +244,18 @@ func (server *server) Start(ctx context.Context, webDir string, _ *cfg.Config) {
 				//实时状态
 				p.Get("/{id:int64}/reset", hero.Handler(device.Reset)).Name = resourceDef.DeviceStatus
 				p.Get("/{id:int64}/status", hero.Handler(device.Status)).Name = resourceDef.DeviceStatus
+				p.Put("/{id:int64}/identity", hero.Handler(device.AcceptIdentity)).Name = resourceDef.DeviceCtrl
+				p.Get("/{id:int64}/diagnostics", hero.Handler(device.Diagnostics)).Name = resourceDef.DeviceStatus
+				p.Post("/{id:int64}/diagnostics/capture", hero.Handler(device.Capture)).Name = resourceDef.DeviceCtrl
+				p.Put("/{id:int64}/edge", hero.Handler(device.PinEdge)).Name = resourceDef.DeviceUpdate
+				p.Delete("/{id:int64}/edge", hero.Handler(device.UnpinEdge)).Name = resourceDef.DeviceUpdate
+
+				//维护模式
+				p.Put("/{id:int64}/maintenance", hero.Handler(device.StartMaintenance)).Name = resourceDef.DeviceCtrl
+				p.Delete("/{id:int64}/maintenance", hero.Handler(device.EndMaintenance)).Name = resourceDef.DeviceCtrl
 				p.Get("/{id:int64}/data", hero.Handler(device.Data)).Name = resourceDef.DeviceData
 				p.Put("/{id:int64}/{tagName:string}", hero.Handler(device.Ctrl)).Name = resourceDef.DeviceCtrl
+				p.Post("/{id:int64}/{tagName:string}/pulse", hero.Handler(device.Pulse)).Name = resourceDef.DeviceCtrl
 				p.Get("/{id:int64}/{tagName:string}", hero.Handler(device.GetCHValue)).Name = resourceDef.DeviceCHValue
 
 				//导出报表
@@ -225,6 +270,14 @@ func (server *server) Start(ctx context.Context, webDir string, _ *cfg.Config) {
 
 				//历史趋势
 				p.Post("/{id:int64}/statistics", hero.Handler(statistics.Measure)).Name = resourceDef.DeviceStatistics
+
+				//累计点位
+				p.Post("/{id:int64}/total", hero.Handler(statistics.Total)).Name = resourceDef.DeviceStatistics
+				p.Delete("/{id:int64}/total", hero.Handler(device.ResetTotal)).Name = resourceDef.DeviceCtrl
+
+				//搁置点位
+				p.Put("/{id:int64}/shelve", hero.Handler(device.Shelve)).Name = resourceDef.DeviceCtrl
+				p.Delete("/{id:int64}/shelve", hero.Handler(device.Unshelve)).Name = resourceDef.DeviceCtrl
 			})
 
 			//自定义设备
@@ -249,6 +302,10 @@ func (server *server) Start(ctx context.Context, webDir string, _ *cfg.Config) {
 
 				//实时状态
 				p.Get("/{id:int64}/status", hero.Handler(equipment.Status)).Name = resourceDef.EquipmentStatus
+
+				//维护模式
+				p.Put("/{id:int64}/maintenance", hero.Handler(equipment.StartMaintenance)).Name = resourceDef.EquipmentCtrl
+				p.Delete("/{id:int64}/maintenance", hero.Handler(equipment.EndMaintenance)).Name = resourceDef.EquipmentCtrl
 				p.Get("/{id:int64}/data", hero.Handler(equipment.Data)).Name = resourceDef.EquipmentData
 				p.Put("/{id:int64}/{stateID:int64}", hero.Handler(equipment.Ctrl)).Name = resourceDef.EquipmentCtrl
 				p.Get("/{id:int64}/{stateID:int64}", hero.Handler(equipment.GetCHValue)).Name = resourceDef.EquipmentCHValue
@@ -267,6 +324,8 @@ func (server *server) Start(ctx context.Context, webDir string, _ *cfg.Config) {
 			//警报
 			p.PartyFunc("/alarm", func(p router.Party) {
 				p.Get("/", hero.Handler(alarm.List)).Name = resourceDef.AlarmList
+				p.Get("/shelve", hero.Handler(alarm.Shelves)).Name = resourceDef.AlarmList
+				p.Get("/kpi", hero.Handler(alarm.KPI)).Name = resourceDef.AlarmList
 				p.Put("/{id:int64}", hero.Handler(alarm.Confirm)).Name = resourceDef.AlarmConfirm
 				p.Get("/{id:int64}", hero.Handler(alarm.Detail)).Name = resourceDef.AlarmDetail
 				p.Delete("/{id:int64}", hero.Handler(alarm.Delete)).Name = resourceDef.AlarmDelete
@@ -291,6 +350,33 @@ func (server *server) Start(ctx context.Context, webDir string, _ *cfg.Config) {
 				p.Delete("/{id:int64}", hero.Handler(comment.Delete)).Name = resourceDef.CommentDelete
 			})
 
+			//自动控制规则
+			p.PartyFunc("/rule", func(p router.Party) {
+				p.Get("/", hero.Handler(rule.List)).Name = resourceDef.RuleList
+				p.Post("/", hero.Handler(rule.Create)).Name = resourceDef.RuleCreate
+				p.Get("/{id:int64}", hero.Handler(rule.Detail)).Name = resourceDef.RuleDetail
+				p.Put("/{id:int64}", hero.Handler(rule.Update)).Name = resourceDef.RuleUpdate
+				p.Delete("/{id:int64}", hero.Handler(rule.Delete)).Name = resourceDef.RuleDelete
+
+				//执行记录
+				p.Get("/{id:int64}/log", hero.Handler(rule.LogList)).Name = resourceDef.RuleLogList
+			})
+
+			//webhook
+			p.PartyFunc("/webhook", func(p router.Party) {
+				p.Get("/", hero.Handler(webhook.List)).Name = resourceDef.WebhookList
+				p.Get("/events", hero.Handler(webhook.Events)).Name = resourceDef.WebhookList
+				p.Post("/", hero.Handler(webhook.Create)).Name = resourceDef.WebhookCreate
+				p.Get("/{id:int64}", hero.Handler(webhook.Detail)).Name = resourceDef.WebhookDetail
+				p.Put("/{id:int64}", hero.Handler(webhook.Update)).Name = resourceDef.WebhookUpdate
+				p.Delete("/{id:int64}", hero.Handler(webhook.Delete)).Name = resourceDef.WebhookDelete
+
+				//投递记录
+				p.Get("/{id:int64}/delivery", hero.Handler(webhook.DeliveryList)).Name = resourceDef.WebhookDeliveryList
+				p.Get("/delivery/{id:int64}", hero.Handler(webhook.DeliveryDetail)).Name = resourceDef.WebhookDeliveryList
+				p.Post("/delivery/{id:int64}", hero.Handler(webhook.Redeliver)).Name = resourceDef.WebhookUpdate
+			})
+
 			//日志等级
 			p.Get("/log/level", hero.Handler(logStore.Level)).Name = resourceDef.SysBrief
 			//系统日志
@@ -298,6 +384,8 @@ func (server *server) Start(ctx context.Context, webDir string, _ *cfg.Config) {
 				p.Get("/", hero.Handler(logStore.List)).Name = resourceDef.LogList
 				p.Delete("/", hero.Handler(logStore.Delete)).Name = resourceDef.LogDelete
 			})
+			//通知发送记录
+			p.Get("/notifylog", hero.Handler(logStore.NotifyList)).Name = resourceDef.LogList
 		})
 	})
 
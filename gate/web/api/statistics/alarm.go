@@ -0,0 +1,307 @@
+package statistics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/hero"
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/statistics"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/dispatch"
+	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	"github.com/maritimusj/centrum/gate/web/response"
+	log "github.com/sirupsen/logrus"
+)
+
+type alarmMeasure struct {
+	measure model.Measure
+	device  model.Device
+
+	count  int
+	levels map[string]int
+	stats  *statistics.AlarmStats
+}
+
+const (
+	//警报趋势统计的最短时间段和最多的时间段数量
+	minTrendInterval = time.Minute
+	maxTrendBuckets  = 1000
+)
+
+//Alarm 物理设备或者虚拟设备的警报历史，按点位统计警报次数、持续时间和第一次、最后一次出现的时间，
+//并按时间段统计各等级的警报次数，默认统计最近7天
+func Alarm(ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var form struct {
+			Start     *time.Time `json:"start"`
+			End       *time.Time `json:"end"`
+			Device    *int64     `json:"device"`
+			Equipment *int64     `json:"equipment"`
+			//统计的时间段，单位为秒
+			Interval int64 `json:"interval"`
+		}
+
+		if err := ctx.ReadJSON(&form); err != nil {
+			return lang.ErrInvalidRequestData
+		}
+
+		var (
+			s     = app.Store()
+			admin = s.MustGetUserFromContext(ctx)
+
+			params   []helper.OptionFN
+			measures []model.Measure
+		)
+
+		switch {
+		case form.Device != nil:
+			device, err := s.GetDevice(*form.Device)
+			if err != nil {
+				return err
+			}
+			if !app.Allow(admin, device, resource.View) {
+				return lang.ErrNoPermission
+			}
+			measures, _, err = s.GetMeasureList(helper.Device(device.GetID()))
+			if err != nil {
+				return err
+			}
+			params = append(params, helper.Device(device.GetID()))
+
+		case form.Equipment != nil:
+			equipment, err := s.GetEquipment(*form.Equipment)
+			if err != nil {
+				return err
+			}
+			if !app.Allow(admin, equipment, resource.View) {
+				return lang.ErrNoPermission
+			}
+			states, _, err := s.GetStateList(helper.Equipment(equipment.GetID()))
+			if err != nil {
+				return err
+			}
+			for _, state := range states {
+				if measure := state.Measure(); measure != nil {
+					measures = append(measures, measure)
+				}
+			}
+			params = append(params, helper.Equipment(equipment.GetID()))
+
+		default:
+			return lang.ErrInvalidRequestData
+		}
+
+		if !app.IsDefaultAdminUser(admin) {
+			params = append(params, helper.DefaultEffect(app.Config.DefaultEffect()))
+			params = append(params, helper.User(admin.GetID()))
+		}
+
+		end := time.Now()
+		if form.End != nil {
+			end = *form.End
+		}
+
+		start := end.AddDate(0, 0, -7)
+		if form.Start != nil {
+			start = *form.Start
+		}
+
+		if !end.After(start) {
+			return lang.ErrInvalidRequestData
+		}
+
+		begin, interval, size, ok := trendBuckets(start, end, form.Interval)
+		if !ok {
+			return lang.ErrInvalidRequestData
+		}
+
+		var (
+			list    = map[int64]*alarmMeasure{}
+			devices = map[int64]model.Device{}
+			tags    = map[int64][]string{}
+		)
+
+		for _, measure := range measures {
+			if !app.Allow(admin, measure, resource.View) {
+				continue
+			}
+			device := measure.Device()
+			if device == nil {
+				continue
+			}
+			list[measure.GetID()] = &alarmMeasure{
+				measure: measure,
+				device:  device,
+				levels:  map[string]int{},
+			}
+			devices[device.GetID()] = device
+			tags[device.GetID()] = append(tags[device.GetID()], measure.TagName())
+		}
+
+		//警报的次数从警报记录中统计
+		alarms, _, err := s.GetAlarmList(&start, &end, params...)
+		if err != nil {
+			return err
+		}
+
+		trend := make([]map[string]int, size)
+		for _, alarm := range alarms {
+			level := alarm.GetOption("tags.alarm").String()
+			if level == dispatch.FloodAlarm {
+				continue
+			}
+
+			entry, ok := list[alarm.MeasureID()]
+			if !ok {
+				continue
+			}
+			entry.count++
+			entry.levels[level]++
+
+			i := int(alarm.CreatedAt().Sub(begin) / interval)
+			if i >= 0 && i < len(trend) {
+				if trend[i] == nil {
+					trend[i] = map[string]int{}
+				}
+				trend[i][level]++
+			}
+		}
+
+		//警报的持续时间从采集的历史数据中统计
+		for deviceID, device := range devices {
+			org, err := device.Organization()
+			if err != nil {
+				continue
+			}
+
+			seconds := device.GetOption("params.interval").Int()
+			if seconds < 1 {
+				seconds = 1
+			}
+
+			stats, err := app.StatsDB.GetAlarmStats(org.Name(), deviceID, start, end, time.Duration(seconds)*time.Second, tags[deviceID]...)
+			if err != nil {
+				if err != lang.ErrNoStatisticsData.Error() {
+					log.Errorln("[statistics] get alarm stats: ", err)
+				}
+				continue
+			}
+
+			for _, entry := range list {
+				if entry.device.GetID() == deviceID {
+					entry.stats = stats[entry.measure.TagName()]
+				}
+			}
+		}
+
+		entries := make([]*alarmMeasure, 0, len(list))
+		for _, entry := range list {
+			if entry.count > 0 || entry.stats != nil {
+				entries = append(entries, entry)
+			}
+		}
+
+		sort.Slice(entries, func(i, j int) bool {
+			a, b := entries[i], entries[j]
+			return a.count > b.count || (a.count == b.count && a.measure.GetID() < b.measure.GetID())
+		})
+
+		result := make([]iris.Map, 0, len(entries))
+		for _, entry := range entries {
+			levels := iris.Map{}
+			for level, n := range entry.levels {
+				levels[level] = iris.Map{
+					"count":    n,
+					"duration": 0,
+				}
+			}
+
+			data := iris.Map{
+				"measure":  entry.measure.Brief(),
+				"device":   entry.device.Brief(),
+				"count":    entry.count,
+				"duration": 0,
+			}
+
+			if entry.stats != nil {
+				for level, d := range entry.stats.Duration {
+					v, ok := levels[level].(iris.Map)
+					if !ok {
+						v = iris.Map{"count": 0}
+						levels[level] = v
+					}
+					v["duration"] = d.Seconds()
+				}
+				data["duration"] = entry.stats.Total().Seconds()
+				data["first"] = entry.stats.First.Format(lang.DatetimeFormatterStr.Str())
+				data["last"] = entry.stats.Last.Format(lang.DatetimeFormatterStr.Str())
+			}
+
+			data["levels"] = levels
+			result = append(result, data)
+		}
+
+		buckets := make([]iris.Map, 0, len(trend))
+		for i, levels := range trend {
+			var total int
+			for _, n := range levels {
+				total += n
+			}
+			if levels == nil {
+				levels = map[string]int{}
+			}
+			buckets = append(buckets, iris.Map{
+				"time":   begin.Add(time.Duration(i) * interval).Format(lang.DatetimeFormatterStr.Str()),
+				"total":  total,
+				"levels": levels,
+			})
+		}
+
+		return iris.Map{
+			"start":    start.Format(lang.DatetimeFormatterStr.Str()),
+			"end":      end.Format(lang.DatetimeFormatterStr.Str()),
+			"interval": int64(interval.Seconds()),
+			"measures": result,
+			"trend":    buckets,
+		}
+	})
+}
+
+//trendBuckets 警报趋势的起始时间、时间段和时间段数量，seconds为0时按时间范围选择按小时或者按天统计
+func trendBuckets(start, end time.Time, seconds int64) (time.Time, time.Duration, int64, bool) {
+	interval := time.Duration(seconds) * time.Second
+	if interval <= 0 {
+		if end.Sub(start) > 48*time.Hour {
+			interval = 24 * time.Hour
+		} else {
+			interval = time.Hour
+		}
+	}
+
+	if interval < minTrendInterval {
+		return time.Time{}, 0, 0, false
+	}
+
+	//按天或者更长的时间段统计时，从本地时间的零点开始
+	begin := start
+	if interval >= 24*time.Hour {
+		begin = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	}
+
+	size := int64((end.Sub(begin) + interval - 1) / interval)
+	if size > maxTrendBuckets {
+		if seconds > 0 {
+			return time.Time{}, 0, 0, false
+		}
+		//默认按天统计，时间范围太长时加大时间段
+		days := (size + maxTrendBuckets - 1) / maxTrendBuckets
+		interval = time.Duration(days) * 24 * time.Hour
+		size = int64((end.Sub(begin) + interval - 1) / interval)
+	}
+
+	return begin, interval, size, true
+}
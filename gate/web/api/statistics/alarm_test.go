@@ -0,0 +1,42 @@
+package statistics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrendBuckets(t *testing.T) {
+	start := time.Date(2020, 5, 1, 10, 30, 0, 0, time.Local)
+
+	//两天以内默认按小时统计
+	begin, interval, size, ok := trendBuckets(start, start.Add(6*time.Hour), 0)
+	if !ok || !begin.Equal(start) || interval != time.Hour || size != 6 {
+		t.Fatalf("unexpected hourly buckets: %v, %v, %d, %v", begin, interval, size, ok)
+	}
+
+	//超过两天默认按天统计，从零点开始
+	begin, interval, size, ok = trendBuckets(start, start.AddDate(0, 0, 7), 0)
+	if !ok || !begin.Equal(time.Date(2020, 5, 1, 0, 0, 0, 0, time.Local)) || interval != 24*time.Hour || size != 8 {
+		t.Fatalf("unexpected daily buckets: %v, %v, %d, %v", begin, interval, size, ok)
+	}
+
+	//时间范围太长时加大默认的时间段
+	_, interval, size, ok = trendBuckets(start, start.AddDate(10, 0, 0), 0)
+	if !ok || size > maxTrendBuckets || interval%(24*time.Hour) != 0 || interval <= 24*time.Hour {
+		t.Fatalf("unexpected buckets for long range: %v, %d, %v", interval, size, ok)
+	}
+
+	//指定的时间段
+	_, interval, size, ok = trendBuckets(start, start.Add(maxTrendBuckets*time.Minute), 60)
+	if !ok || interval != time.Minute || size != maxTrendBuckets {
+		t.Fatalf("unexpected buckets: %v, %d, %v", interval, size, ok)
+	}
+
+	//时间段太短或者数量太多
+	if _, _, _, ok = trendBuckets(start, start.Add(time.Hour), 30); ok {
+		t.Fatal("interval shorter than a minute should be rejected")
+	}
+	if _, _, _, ok = trendBuckets(start, start.Add((maxTrendBuckets+1)*time.Minute), 60); ok {
+		t.Fatal("too many buckets should be rejected")
+	}
+}
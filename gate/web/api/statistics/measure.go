@@ -153,20 +153,3 @@ func State(stateID int64, ctx iris.Context) hero.Result {
 		return result
 	})
 }
-
-func Alarm(ctx iris.Context) hero.Result {
-	return response.Wrap(func() interface{} {
-		var form struct {
-			Start     *time.Time `json:"start"`
-			End       *time.Time `json:"end"`
-			Device    *int64     `json:"device"`
-			Equipment *int64     `json:"equipment"`
-		}
-
-		if err := ctx.ReadJSON(&form); err != nil {
-			return lang.ErrInvalidRequestData
-		}
-
-		panic("not finished")
-	})
-}
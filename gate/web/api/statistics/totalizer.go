@@ -0,0 +1,81 @@
+package statistics
+
+import (
+	"time"
+
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/hero"
+	"github.com/maritimusj/centrum/gate/lang"
+	statsDB "github.com/maritimusj/centrum/gate/statistics"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	"github.com/maritimusj/centrum/gate/web/response"
+)
+
+//Total 累计点位按天或者按月的增量
+func Total(measureID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var form struct {
+			Start  *time.Time `json:"start"`
+			End    *time.Time `json:"end"`
+			Period string     `json:"period"`
+		}
+
+		if err := ctx.ReadJSON(&form); err != nil {
+			return lang.ErrInvalidRequestData
+		}
+
+		if form.Period == "" {
+			form.Period = statsDB.PeriodDay
+		} else if form.Period != statsDB.PeriodDay && form.Period != statsDB.PeriodMonth {
+			return lang.ErrInvalidRequestData
+		}
+
+		s := app.Store()
+		measure, err := s.GetMeasure(measureID)
+		if err != nil {
+			return err
+		}
+
+		admin := s.MustGetUserFromContext(ctx)
+		if !app.Allow(admin, measure, resource.View) {
+			return lang.ErrNoPermission
+		}
+
+		device := measure.Device()
+		if device == nil {
+			return lang.ErrDeviceNotFound
+		}
+
+		org, _ := device.Organization()
+
+		var (
+			start time.Time
+			end   time.Time
+			now   = time.Now()
+		)
+
+		if form.Start != nil {
+			start = *form.Start
+		} else if form.Period == statsDB.PeriodMonth {
+			//默认从今年一月开始
+			start = time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.Local)
+		} else {
+			//默认从本月一号开始
+			start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+		}
+
+		if form.End != nil {
+			end = *form.End
+		} else {
+			end = now
+		}
+
+		result, err := app.StatsDB.GetTotalizerStats(org.Name(), device.GetID(), measure.TagName(), start, end, form.Period)
+		if err != nil {
+			return err
+		}
+
+		return result
+	})
+}
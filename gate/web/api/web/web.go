@@ -6,8 +6,6 @@ import (
 
 	"github.com/maritimusj/centrum/gate/Getui"
 
-	"github.com/maritimusj/centrum/global"
-
 	"github.com/dgrijalva/jwt-go"
 	"github.com/kataras/iris"
 	"github.com/kataras/iris/hero"
@@ -26,7 +24,12 @@ func RequireToken(p iris.Party) {
 			return app.Config.JwtTokenKey(), nil
 		},
 		Extractor: func(ctx iris.Context) (string, error) {
-			return ctx.GetHeader("token"), nil
+			token := ctx.GetHeader("token")
+			//EventSource不能设置请求头，推送的连接从参数中读取token
+			if token == "" && strings.Contains(ctx.GetHeader("Accept"), "text/event-stream") {
+				token = ctx.URLParam("token")
+			}
+			return token, nil
 		},
 		SigningMethod: jwt.SigningMethodHS512,
 	})
@@ -126,9 +129,6 @@ func Login(ctx iris.Context) hero.Result {
 			}
 		}
 
-		//注册用户，接收消息
-		global.Create(token, user.GetID())
-
 		return iris.Map{
 			"token": token,
 		}
@@ -0,0 +1,334 @@
+package webhook
+
+import (
+	"github.com/asaskevich/govalidator"
+	"github.com/kataras/iris"
+	"github.com/kataras/iris/hero"
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/logStore"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/response"
+	"github.com/maritimusj/centrum/gate/web/status"
+	webhookDef "github.com/maritimusj/centrum/gate/web/webhook"
+	log "github.com/sirupsen/logrus"
+)
+
+var deliveryStatus = map[string]int64{
+	"pending":   status.Pending,
+	"delivered": status.Delivered,
+	"failed":    status.Failed,
+}
+
+//getWebhook 获取webhook，只能管理本组织的webhook
+func getWebhook(admin model.User, webhookID int64) (model.Webhook, error) {
+	w, err := app.Store().GetWebhook(webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !app.IsDefaultAdminUser(admin) && w.OrganizationID() != admin.OrganizationID() {
+		return nil, lang.ErrNoPermission.Error()
+	}
+
+	return w, nil
+}
+
+//getDelivery 获取投递记录，只能查看本组织webhook的投递
+func getDelivery(admin model.User, deliveryID int64) (model.Delivery, error) {
+	d, err := app.Store().GetDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := getWebhook(admin, d.WebhookID()); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+//Events 可以订阅的事件
+func Events() hero.Result {
+	return response.Wrap(func() interface{} {
+		return webhookDef.Events
+	})
+}
+
+func List(ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var (
+			s     = app.Store()
+			admin = s.MustGetUserFromContext(ctx)
+
+			page     = ctx.URLParamInt64Default("page", 1)
+			pageSize = ctx.URLParamInt64Default("pagesize", app.Config.DefaultPageSize())
+			params   = []helper.OptionFN{helper.Page(page, pageSize)}
+			orgID    int64
+		)
+
+		if app.IsDefaultAdminUser(admin) {
+			if ctx.URLParamExists("org") {
+				orgID = ctx.URLParamInt64Default("org", 0)
+			}
+		} else {
+			orgID = admin.OrganizationID()
+		}
+		if orgID > 0 {
+			params = append(params, helper.Organization(orgID))
+		}
+
+		keyword := ctx.URLParam("keyword")
+		if keyword != "" {
+			params = append(params, helper.Keyword(keyword))
+		}
+
+		webhooks, total, err := s.GetWebhookList(params...)
+		if err != nil {
+			return err
+		}
+
+		result := make([]model.Map, 0, len(webhooks))
+		for _, w := range webhooks {
+			result = append(result, w.Brief())
+		}
+
+		return iris.Map{
+			"total": total,
+			"list":  result,
+		}
+	})
+}
+
+func Create(ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var form struct {
+			OrgID  int64    `json:"org"`
+			Title  string   `json:"title" valid:"required"`
+			URL    string   `json:"url" valid:"required"`
+			Secret string   `json:"secret"`
+			Events []string `json:"events"`
+			Enable *bool    `json:"enable"`
+		}
+
+		if err := ctx.ReadJSON(&form); err != nil {
+			return lang.ErrInvalidRequestData
+		}
+
+		if _, err := govalidator.ValidateStruct(&form); err != nil {
+			return lang.ErrInvalidRequestData
+		}
+
+		if err := webhookDef.Check(form.URL, form.Events); err != nil {
+			return err
+		}
+
+		s := app.Store()
+		admin := s.MustGetUserFromContext(ctx)
+
+		var org interface{}
+		if app.IsDefaultAdminUser(admin) {
+			if form.OrgID > 0 {
+				org = form.OrgID
+			} else {
+				org = app.Config.DefaultOrganization()
+			}
+		} else {
+			org = admin.OrganizationID()
+		}
+
+		w, err := s.CreateWebhook(org, admin.GetID(), form.Title, map[string]interface{}{
+			"url":    form.URL,
+			"secret": form.Secret,
+			"events": form.Events,
+		})
+		if err != nil {
+			return err
+		}
+
+		if form.Enable != nil && !*form.Enable {
+			w.Disable()
+			if err = w.Save(); err != nil {
+				return err
+			}
+		}
+
+		log.WithField("src", logStore.SystemLog).Info(lang.UserCreateWebhookOk.Str(admin.Name(), w.Title()))
+		return w.Simple()
+	})
+}
+
+func Detail(webhookID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		admin := app.Store().MustGetUserFromContext(ctx)
+		w, err := getWebhook(admin, webhookID)
+		if err != nil {
+			return err
+		}
+		return w.Detail()
+	})
+}
+
+//Update 没有提交secret时不修改密钥，提交空字符串时取消签名
+func Update(webhookID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		var form struct {
+			Title  *string   `json:"title"`
+			URL    *string   `json:"url"`
+			Secret *string   `json:"secret"`
+			Events *[]string `json:"events"`
+			Enable *bool     `json:"enable"`
+		}
+
+		if err := ctx.ReadJSON(&form); err != nil {
+			return lang.ErrInvalidRequestData
+		}
+
+		admin := app.Store().MustGetUserFromContext(ctx)
+
+		w, err := getWebhook(admin, webhookID)
+		if err != nil {
+			return err
+		}
+
+		url, events := w.URL(), w.Events()
+		if form.URL != nil {
+			url = *form.URL
+		}
+		if form.Events != nil {
+			events = *form.Events
+		}
+		if err := webhookDef.Check(url, events); err != nil {
+			return err
+		}
+
+		if form.Title != nil {
+			w.SetTitle(*form.Title)
+		}
+
+		if form.URL != nil {
+			if err := w.SetOption("url", url); err != nil {
+				return lang.InternalError(err)
+			}
+		}
+
+		if form.Events != nil {
+			if err := w.SetOption("events", events); err != nil {
+				return lang.InternalError(err)
+			}
+		}
+
+		if form.Secret != nil {
+			if err := w.SetOption("secret", *form.Secret); err != nil {
+				return lang.InternalError(err)
+			}
+		}
+
+		if form.Enable != nil {
+			if *form.Enable {
+				w.Enable()
+			} else {
+				w.Disable()
+			}
+		}
+
+		if err = w.Save(); err != nil {
+			return err
+		}
+
+		log.WithField("src", logStore.SystemLog).Info(lang.UserUpdateWebhookOk.Str(admin.Name(), w.Title()))
+		return lang.Ok
+	})
+}
+
+func Delete(webhookID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		admin := app.Store().MustGetUserFromContext(ctx)
+
+		w, err := getWebhook(admin, webhookID)
+		if err != nil {
+			return err
+		}
+
+		title := w.Title()
+		if err = w.Destroy(); err != nil {
+			return err
+		}
+
+		log.WithField("src", logStore.SystemLog).Info(lang.UserDeleteWebhookOk.Str(admin.Name(), title))
+		return lang.Ok
+	})
+}
+
+//DeliveryList 投递记录，可以按状态(pending, delivered, failed)和事件过滤
+func DeliveryList(webhookID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		s := app.Store()
+		admin := s.MustGetUserFromContext(ctx)
+		if _, err := getWebhook(admin, webhookID); err != nil {
+			return err
+		}
+
+		var (
+			page     = ctx.URLParamInt64Default("page", 1)
+			pageSize = ctx.URLParamInt64Default("pagesize", app.Config.DefaultPageSize())
+			params   = []helper.OptionFN{helper.Webhook(webhookID), helper.Page(page, pageSize)}
+		)
+
+		if ctx.URLParamExists("status") {
+			v, ok := deliveryStatus[ctx.URLParam("status")]
+			if !ok {
+				return lang.ErrInvalidRequestData
+			}
+			params = append(params, helper.Status(v))
+		}
+
+		if name := ctx.URLParam("event"); name != "" {
+			params = append(params, helper.Name(name))
+		}
+
+		deliveries, total, err := s.GetDeliveryList(params...)
+		if err != nil {
+			return err
+		}
+
+		result := make([]model.Map, 0, len(deliveries))
+		for _, d := range deliveries {
+			result = append(result, d.Brief())
+		}
+
+		return iris.Map{
+			"total": total,
+			"list":  result,
+		}
+	})
+}
+
+//DeliveryDetail 投递的内容和最后一次发送的响应
+func DeliveryDetail(deliveryID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		admin := app.Store().MustGetUserFromContext(ctx)
+		d, err := getDelivery(admin, deliveryID)
+		if err != nil {
+			return err
+		}
+		return d.Detail()
+	})
+}
+
+//Redeliver 重新投递，用于调试或者对方系统恢复后补发
+func Redeliver(deliveryID int64, ctx iris.Context) hero.Result {
+	return response.Wrap(func() interface{} {
+		admin := app.Store().MustGetUserFromContext(ctx)
+		d, err := getDelivery(admin, deliveryID)
+		if err != nil {
+			return err
+		}
+
+		if err = webhookDef.Redeliver(d); err != nil {
+			return err
+		}
+		return lang.Ok
+	})
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/maritimusj/centrum/gate/lang"
 	"github.com/maritimusj/centrum/gate/logStore/bolt"
 	"github.com/maritimusj/centrum/gate/statistics"
+	"github.com/maritimusj/centrum/gate/web/alarm"
 	"github.com/maritimusj/centrum/gate/web/db"
 	"github.com/maritimusj/centrum/gate/web/db/mysql"
 	"github.com/maritimusj/centrum/gate/web/edge"
@@ -117,6 +118,28 @@ func InitDB(params map[string]interface{}) error {
 		}
 	}
 
+	if _, err = conn.Exec(upgradeDBSQL); err != nil {
+		return lang.InternalError(err)
+	}
+
+	for _, c := range upgradeDBColumns {
+		var n int
+		err = conn.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name=?", c.table, c.column).Scan(&n)
+		if err != nil {
+			return lang.InternalError(err)
+		}
+		if n == 0 {
+			if _, err = conn.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition)); err != nil {
+				return lang.InternalError(err)
+			}
+			if c.backfill != nil {
+				if err = c.backfill(conn); err != nil {
+					return lang.InternalError(err)
+				}
+			}
+		}
+	}
+
 	DB = conn
 	s = mysqlStore.Attach(Ctx, DB)
 	return nil
@@ -515,6 +538,57 @@ func ResetDefaultAdminUser() error {
 	return nil
 }
 
+//AlarmPriority 点位警报的优先级，点位关联的虚拟点位可以单独设置优先级
+func AlarmPriority(measure model.Measure, level string) int {
+	var list []int
+	states, _, err := s.GetStateList(helper.Measure(measure.GetID()))
+	if err == nil {
+		for _, state := range states {
+			if p, ok := alarm.ParsePriority(state.AlarmPriority()); ok {
+				list = append(list, p)
+			}
+		}
+	}
+	return alarm.Priority(level, measure.GetID(), list...)
+}
+
+//LoadAlarmPriorities 加载警报优先级设置
+func LoadAlarmPriorities() error {
+	p, err := alarm.ParsePriorities([]byte(Config.AlarmPriorities()))
+	if err != nil {
+		return err
+	}
+	alarm.SetPriorities(p)
+	return nil
+}
+
+//AssignPermissionsToUsers 将新创建的点位授权给对设备有相应权限的用户
+//暂时采用循环遍历用户来判断
+func AssignPermissionsToUsers(device model.Device, measure model.Measure) error {
+	if Config.DefaultEffect() == resource.Allow {
+		return nil
+	}
+
+	users, _, err := Store().GetUserList()
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if IsDefaultAdminUser(user) {
+			continue
+		}
+
+		if Allow(user, device, resource.Ctrl) {
+			_ = SetAllow(user, measure, resource.View, resource.Ctrl)
+		} else if Allow(user, device, resource.View) {
+			_ = SetAllow(user, measure, resource.View)
+		}
+	}
+
+	return nil
+}
+
 func SetAllow(user model.User, res model.Resource, actions ...resource.Action) error {
 	if IsDefaultAdminUser(user) {
 		return nil
@@ -1,5 +1,14 @@
 package app
 
+import (
+	"strings"
+
+	"github.com/maritimusj/centrum/gate/web/alarm"
+	"github.com/maritimusj/centrum/gate/web/db"
+	"github.com/maritimusj/centrum/gate/web/status"
+	"github.com/tidwall/gjson"
+)
+
 const initDBSQL = `
 --
 -- 由SQLiteStudio v3.2.1 产生的文件 周一 10月 28 14:01:33 2019
@@ -10,7 +19,7 @@ PRAGMA foreign_keys = off;
 BEGIN TRANSACTION;
 
 -- 表：alarms
-CREATE TABLE alarms (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, status INTEGER NOT NULL DEFAULT 0, org_id INTEGER NOT NULL DEFAULT 0, device_id INTEGER NOT NULL DEFAULT 0, measure_id INTEGER NOT NULL DEFAULT 0, extra BLOB NOT NULL, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL);
+CREATE TABLE alarms (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, status INTEGER NOT NULL DEFAULT 0, org_id INTEGER NOT NULL DEFAULT 0, device_id INTEGER NOT NULL DEFAULT 0, measure_id INTEGER NOT NULL DEFAULT 0, priority INTEGER NOT NULL DEFAULT 0, extra BLOB NOT NULL, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL, cleared_at DATETIME);
 
 -- 表：api_resources
 CREATE TABLE "api_resources" ("id"  INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, "name"  TEXT(64) NOT NULL, "title"  TEXT(128) NOT NULL,"desc"  TEXT(255) NOT NULL);
@@ -60,6 +69,18 @@ CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, org_id INTEGE
 -- 表：comments
 CREATE TABLE comments (id INTEGER PRIMARY KEY AUTOINCREMENT, ref_id INT NOT NULL, user_id NOT NULL, parent_id INT NOT NULL DEFAULT (0), extra BLOB NOT NULL, created_at DATETIME NOT NULL);
 
+-- 表：rules
+CREATE TABLE rules (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, org_id INTEGER NOT NULL DEFAULT 0, user_id INTEGER NOT NULL DEFAULT 0, enable INTEGER NOT NULL DEFAULT 0, title TEXT (128) NOT NULL, extra BLOB NOT NULL, created_at DATETIME NOT NULL);
+
+-- 表：messages
+CREATE TABLE messages (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, user_id INTEGER NOT NULL DEFAULT 0, status INTEGER NOT NULL DEFAULT 0, level TEXT (16) NOT NULL, title TEXT (128) NOT NULL, content TEXT (512) NOT NULL, extra BLOB NOT NULL, created_at DATETIME NOT NULL);
+
+-- 表：webhooks
+CREATE TABLE webhooks (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, org_id INTEGER NOT NULL DEFAULT 0, user_id INTEGER NOT NULL DEFAULT 0, enable INTEGER NOT NULL DEFAULT 0, title TEXT (128) NOT NULL, extra BLOB NOT NULL, created_at DATETIME NOT NULL);
+
+-- 表：deliveries
+CREATE TABLE deliveries (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, webhook_id INTEGER NOT NULL DEFAULT 0, status INTEGER NOT NULL DEFAULT 0, attempts INTEGER NOT NULL DEFAULT 0, code INTEGER NOT NULL DEFAULT 0, event TEXT (64) NOT NULL, payload BLOB NOT NULL, response TEXT NOT NULL, next_at DATETIME NOT NULL, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL);
+
 -- 索引：device
 CREATE INDEX device ON measures ("device_id" ASC);
 
@@ -92,7 +113,88 @@ CREATE UNIQUE INDEX user_namex ON users ("name" ASC);
 -- 索引：ref_idx
 CREATE INDEX ref_idx ON comments (ref_id, parent_id);
 
+-- 索引：user_msgx
+CREATE INDEX user_msgx ON messages ("user_id" ASC, "status" ASC);
+
+-- 索引：webhook_deliveryx
+CREATE INDEX webhook_deliveryx ON deliveries ("webhook_id" ASC, "status" ASC);
+
 COMMIT TRANSACTION;
 PRAGMA foreign_keys = on;
 
 `
+
+//旧版本创建的数据库缺少的表，每次启动时检查
+const upgradeDBSQL = `
+CREATE TABLE IF NOT EXISTS rules (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, org_id INTEGER NOT NULL DEFAULT 0, user_id INTEGER NOT NULL DEFAULT 0, enable INTEGER NOT NULL DEFAULT 0, title TEXT (128) NOT NULL, extra BLOB NOT NULL, created_at DATETIME NOT NULL);
+CREATE TABLE IF NOT EXISTS messages (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, user_id INTEGER NOT NULL DEFAULT 0, status INTEGER NOT NULL DEFAULT 0, level TEXT (16) NOT NULL, title TEXT (128) NOT NULL, content TEXT (512) NOT NULL, extra BLOB NOT NULL, created_at DATETIME NOT NULL);
+CREATE INDEX IF NOT EXISTS user_msgx ON messages ("user_id" ASC, "status" ASC);
+CREATE TABLE IF NOT EXISTS webhooks (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, org_id INTEGER NOT NULL DEFAULT 0, user_id INTEGER NOT NULL DEFAULT 0, enable INTEGER NOT NULL DEFAULT 0, title TEXT (128) NOT NULL, extra BLOB NOT NULL, created_at DATETIME NOT NULL);
+CREATE TABLE IF NOT EXISTS deliveries (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, webhook_id INTEGER NOT NULL DEFAULT 0, status INTEGER NOT NULL DEFAULT 0, attempts INTEGER NOT NULL DEFAULT 0, code INTEGER NOT NULL DEFAULT 0, event TEXT (64) NOT NULL, payload BLOB NOT NULL, response TEXT NOT NULL, next_at DATETIME NOT NULL, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL);
+CREATE INDEX IF NOT EXISTS webhook_deliveryx ON deliveries ("webhook_id" ASC, "status" ASC);
+`
+
+//旧版本创建的数据库缺少的字段，每次启动时检查，添加字段后执行backfill补全已有的数据
+var upgradeDBColumns = []struct {
+	table      string
+	column     string
+	definition string
+	backfill   func(conn db.DB) error
+}{
+	{"alarms", "priority", "INTEGER NOT NULL DEFAULT 0", backfillAlarmPriority},
+	//旧版本的警报按最后一次上报的时间作为消失的时间，仍然存在的警报再次上报时会清除
+	{"alarms", "cleared_at", "DATETIME", execSQL("UPDATE alarms SET cleared_at=updated_at")},
+	{"webhooks", "org_id", "INTEGER NOT NULL DEFAULT 0", execSQL("UPDATE webhooks SET org_id=IFNULL((SELECT u.org_id FROM users u WHERE u.id=webhooks.user_id), 0) WHERE org_id=0")},
+}
+
+func execSQL(query string) func(conn db.DB) error {
+	return func(conn db.DB) error {
+		_, err := conn.Exec(query)
+		return err
+	}
+}
+
+//backfillAlarmPriority 旧版本的警报按警报等级设置默认的优先级，优先级的过滤、排序和统计才能包含这些警报
+func backfillAlarmPriority(conn db.DB) error {
+	rows, err := conn.Query("SELECT id, extra FROM alarms WHERE priority=?", status.PriorityNone)
+	if err != nil {
+		return err
+	}
+
+	var (
+		id    int64
+		extra []byte
+		list  = map[int][]interface{}{}
+	)
+
+	for rows.Next() {
+		if err = rows.Scan(&id, &extra); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		priority := alarm.DefaultPriority(gjson.GetBytes(extra, "tags.alarm").String())
+		list[priority] = append(list[priority], id)
+	}
+	_ = rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	const batch = 500
+	for priority, ids := range list {
+		for len(ids) > 0 {
+			n := len(ids)
+			if n > batch {
+				n = batch
+			}
+			query := "UPDATE alarms SET priority=? WHERE id IN (?" + strings.Repeat(",?", n-1) + ")"
+			if _, err = conn.Exec(query, append([]interface{}{priority}, ids[:n]...)...); err != nil {
+				return err
+			}
+			ids = ids[n:]
+		}
+	}
+
+	return nil
+}
@@ -23,4 +23,8 @@ type Cache interface {
 	LoadApiResource(interface{}) (model.ApiResource, error)
 	LoadAlarm(interface{}) (model.Alarm, error)
 	LoadComment(interface{}) (model.Comment, error)
+	LoadRule(interface{}) (model.Rule, error)
+	LoadMessage(interface{}) (model.Message, error)
+	LoadWebhook(interface{}) (model.Webhook, error)
+	LoadDelivery(interface{}) (model.Delivery, error)
 }
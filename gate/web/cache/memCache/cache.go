@@ -24,6 +24,10 @@ const (
 	prefixApiResource = "9."
 	prefixAlarm       = "A."
 	prefixComment     = "B."
+	prefixRule        = "C."
+	prefixMessage     = "D."
+	prefixWebhook     = "E."
+	prefixDelivery    = "F."
 )
 
 type cache struct {
@@ -63,6 +67,14 @@ func getKeys(obj interface{}) []string {
 		pref = prefixAlarm
 	case model.Comment:
 		pref = prefixComment
+	case model.Rule:
+		pref = prefixRule
+	case model.Message:
+		pref = prefixMessage
+	case model.Webhook:
+		pref = prefixWebhook
+	case model.Delivery:
+		pref = prefixDelivery
 	}
 
 	keys := make([]string, 0)
@@ -248,3 +260,39 @@ func (c *cache) LoadComment(comment interface{}) (model.Comment, error) {
 	}
 	return nil, lang.ErrCommentNotFound.Error()
 }
+
+func (c *cache) LoadRule(rule interface{}) (model.Rule, error) {
+	if v, ok := c.client.Get(prefixRule + c.getUID(rule)); ok {
+		if u, ok := v.(model.Rule); ok {
+			return u, nil
+		}
+	}
+	return nil, lang.ErrCacheNotFound.Error()
+}
+
+func (c *cache) LoadMessage(message interface{}) (model.Message, error) {
+	if v, ok := c.client.Get(prefixMessage + c.getUID(message)); ok {
+		if u, ok := v.(model.Message); ok {
+			return u, nil
+		}
+	}
+	return nil, lang.ErrCacheNotFound.Error()
+}
+
+func (c *cache) LoadWebhook(webhook interface{}) (model.Webhook, error) {
+	if v, ok := c.client.Get(prefixWebhook + c.getUID(webhook)); ok {
+		if u, ok := v.(model.Webhook); ok {
+			return u, nil
+		}
+	}
+	return nil, lang.ErrCacheNotFound.Error()
+}
+
+func (c *cache) LoadDelivery(delivery interface{}) (model.Delivery, error) {
+	if v, ok := c.client.Get(prefixDelivery + c.getUID(delivery)); ok {
+		if u, ok := v.(model.Delivery); ok {
+			return u, nil
+		}
+	}
+	return nil, lang.ErrCacheNotFound.Error()
+}
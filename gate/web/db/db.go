@@ -1,12 +1,14 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 )
 
 type WithTransaction interface {
 	DB
 	TransactionDo(fn func(db DB) interface{}) interface{}
+	Ping(ctx context.Context) error
 }
 
 type DB interface {
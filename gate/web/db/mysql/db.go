@@ -7,6 +7,7 @@ import (
 
 	"github.com/maritimusj/centrum/gate/lang"
 	"github.com/maritimusj/centrum/gate/web/db"
+	"github.com/maritimusj/centrum/gate/web/metrics"
 )
 
 type mysqlDB struct {
@@ -23,8 +24,14 @@ func (m *mysqlDB) TransactionDo(fn func(db db.DB) interface{}) interface{} {
 	if err != nil {
 		return lang.InternalError(err)
 	}
+
+	var (
+		begin  = time.Now()
+		status = "rollback"
+	)
 	defer func() {
 		_ = tx.Rollback()
+		metrics.DBTransactionDuration.WithLabelValues(status).Observe(time.Now().Sub(begin).Seconds())
 	}()
 
 	result := fn(tx)
@@ -42,6 +49,7 @@ func (m *mysqlDB) TransactionDo(fn func(db db.DB) interface{}) interface{} {
 		return lang.InternalError(err)
 	}
 
+	status = "commit"
 	return result
 }
 
@@ -66,6 +74,11 @@ func Open(ctx context.Context, option map[string]interface{}) (db.WithTransactio
 	return nil, lang.ErrInvalidDBConnStr.Error()
 }
 
+//Ping 检查数据库连接
+func (m *mysqlDB) Ping(ctx context.Context) error {
+	return m.db.PingContext(ctx)
+}
+
 func (m *mysqlDB) Close() {
 	if m != nil && m.db != nil {
 		_ = m.db.Close()
@@ -0,0 +1,184 @@
+package dispatch
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/web/alarm"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	"github.com/maritimusj/centrum/json_rpc"
+	"github.com/maritimusj/centrum/util/calendar"
+)
+
+//Routing 警报通知设置，按顺序匹配路由，第一个匹配的路由决定接收人和渠道，
+//没有匹配的路由时，Fallback为true则通知能查看点位的全部用户
+type Routing struct {
+	Routes   []*Route `json:"routes"`
+	Fallback bool     `json:"fallback"`
+}
+
+//Route 警报路由，条件为空时不限制，Users和Roles都为空时通知能查看点位的全部用户
+type Route struct {
+	Title string `json:"title"`
+
+	//设备分组
+	Groups []int64 `json:"groups"`
+	//虚拟设备
+	Equipments []int64 `json:"equipments"`
+	//点位类型：AI, AO, DI, DO
+	Kinds []string `json:"kinds"`
+	//警报等级：HF, HH, HI, LO, LL, LF
+	Levels []string `json:"levels"`
+	//警报优先级：critical, high, medium, low, advisory
+	Priorities []string `json:"priorities"`
+	//生效的班次
+	Shifts []Shift `json:"shifts"`
+
+	Recipients
+
+	//升级：警报没有被确认时依次通知下一级
+	Escalation []*Tier `json:"escalation"`
+	//通知最后一级后，每隔Repeat分钟重复通知最后一级，0表示不重复
+	Repeat int `json:"repeat"`
+
+	shifts *calendar.Calendar
+}
+
+//Shift 班次，Weekdays为空时每天有效，0为星期日，End小于Start时表示跨越午夜
+type Shift struct {
+	Weekdays []int  `json:"weekdays"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+}
+
+//Recipients 接收人和通知渠道，渠道为空时使用全部渠道
+type Recipients struct {
+	Users    []int64  `json:"users"`
+	Roles    []int64  `json:"roles"`
+	Channels []string `json:"channels"`
+}
+
+//Tier 升级的一级，上一次通知After分钟后还没有确认时通知
+type Tier struct {
+	After int `json:"after"`
+	Recipients
+}
+
+var levels = map[string]bool{"HF": true, "HH": true, "HI": true, "LO": true, "LL": true, "LF": true}
+var kinds = map[string]resource.MeasureKind{"AI": resource.AI, "AO": resource.AO, "DI": resource.DI, "DO": resource.DO}
+
+//Parse 解析并检查警报通知设置
+func Parse(data []byte) (*Routing, error) {
+	routing := &Routing{
+		Fallback: true,
+	}
+
+	if len(data) == 0 {
+		return routing, nil
+	}
+
+	if err := json.Unmarshal(data, routing); err != nil {
+		return nil, lang.ErrInvalidAlarmRouting.Error(err)
+	}
+
+	for _, route := range routing.Routes {
+		for i, level := range route.Levels {
+			route.Levels[i] = strings.ToUpper(level)
+			if !levels[route.Levels[i]] {
+				return nil, lang.ErrInvalidAlarmRouting.Error(level)
+			}
+		}
+
+		for i, name := range route.Priorities {
+			route.Priorities[i] = strings.ToLower(name)
+			if _, ok := alarm.ParsePriority(name); !ok {
+				return nil, lang.ErrInvalidAlarmRouting.Error(name)
+			}
+		}
+
+		for i, kind := range route.Kinds {
+			route.Kinds[i] = strings.ToUpper(kind)
+			if _, ok := kinds[route.Kinds[i]]; !ok {
+				return nil, lang.ErrInvalidAlarmRouting.Error(kind)
+			}
+		}
+
+		for _, tier := range route.Escalation {
+			if tier.After <= 0 || (len(tier.Users) == 0 && len(tier.Roles) == 0) {
+				return nil, lang.ErrInvalidAlarmRouting.Error(route.Title)
+			}
+		}
+
+		if route.Repeat < 0 {
+			return nil, lang.ErrInvalidAlarmRouting.Error(route.Title)
+		}
+
+		if len(route.Shifts) > 0 {
+			windows := make([]json_rpc.TimeWindow, 0, len(route.Shifts))
+			for _, s := range route.Shifts {
+				windows = append(windows, json_rpc.TimeWindow{
+					Weekdays: s.Weekdays,
+					Start:    s.Start,
+					End:      s.End,
+				})
+			}
+
+			shifts, err := calendar.Compile(windows, nil)
+			if err != nil {
+				return nil, lang.ErrInvalidAlarmRouting.Error(err)
+			}
+			route.shifts = shifts
+		}
+	}
+
+	return routing, nil
+}
+
+//onDuty 指定时间是否在班次内
+func (route *Route) onDuty(now time.Time) bool {
+	return route.shifts == nil || route.shifts.Contains(now)
+}
+
+//tier 第n级接收人，0为路由本身的接收人
+func (route *Route) tier(n int) (*Recipients, int) {
+	if n == 0 {
+		return &route.Recipients, 0
+	}
+	if n <= len(route.Escalation) {
+		tier := route.Escalation[n-1]
+		return &tier.Recipients, tier.After
+	}
+	if route.Repeat > 0 && len(route.Escalation) > 0 {
+		return &route.Escalation[len(route.Escalation)-1].Recipients, route.Repeat
+	}
+	return nil, 0
+}
+
+func contains(list []int64, id int64) bool {
+	for _, v := range list {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKind(list []string, kind resource.MeasureKind) bool {
+	for _, v := range list {
+		if kinds[v] == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func containsStr(list []string, str string) bool {
+	for _, v := range list {
+		if v == str {
+			return true
+		}
+	}
+	return false
+}
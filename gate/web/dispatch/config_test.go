@@ -0,0 +1,74 @@
+package dispatch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	routing, err := Parse(nil)
+	if err != nil || !routing.Fallback || len(routing.Routes) != 0 {
+		t.Fatal("empty routing should fall back to all users")
+	}
+
+	invalid := []string{
+		`{"routes":[{"levels":["XX"]}]}`,
+		`{"routes":[{"kinds":["BI"]}]}`,
+		`{"routes":[{"escalation":[{"after":0,"users":[1]}]}]}`,
+		`{"routes":[{"escalation":[{"after":5}]}]}`,
+		`{"routes":[{"shifts":[{"start":"25:00","end":"08:00"}]}]}`,
+		`{"routes":[`,
+	}
+	for _, s := range invalid {
+		if _, err := Parse([]byte(s)); err == nil {
+			t.Fatalf("expected error: %s", s)
+		}
+	}
+
+	routing, err = Parse([]byte(`{
+		"fallback": false,
+		"routes": [{
+			"title": "night",
+			"levels": ["hh", "ll"],
+			"kinds": ["ai"],
+			"shifts": [{"start": "20:00", "end": "08:00"}],
+			"users": [1],
+			"escalation": [{"after": 5, "users": [2]}, {"after": 10, "roles": [3]}],
+			"repeat": 30
+		}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	route := routing.Routes[0]
+	if routing.Fallback || route.Levels[0] != "HH" || route.Kinds[0] != "AI" {
+		t.Fatal("unexpected routing")
+	}
+
+	if !route.onDuty(time.Date(2020, 1, 1, 23, 0, 0, 0, time.Local)) || route.onDuty(time.Date(2020, 1, 1, 12, 0, 0, 0, time.Local)) {
+		t.Fatal("unexpected shift")
+	}
+
+	tiers := []struct {
+		users []int64
+		roles []int64
+		after int
+	}{
+		{[]int64{1}, nil, 0},
+		{[]int64{2}, nil, 5},
+		{nil, []int64{3}, 10},
+		{nil, []int64{3}, 30},
+	}
+	for i, tier := range tiers {
+		r, after := route.tier(i)
+		if r == nil || after != tier.after || len(r.Users) != len(tier.users) || len(r.Roles) != len(tier.roles) {
+			t.Fatalf("unexpected tier %d", i)
+		}
+	}
+
+	route.Repeat = 0
+	if r, _ := route.tier(3); r != nil {
+		t.Fatal("expected no more tiers")
+	}
+}
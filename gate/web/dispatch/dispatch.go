@@ -0,0 +1,305 @@
+package dispatch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	edgeLang "github.com/maritimusj/centrum/edge/lang"
+	"github.com/maritimusj/centrum/gate/event"
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/logStore"
+	alarmDef "github.com/maritimusj/centrum/gate/web/alarm"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/inbox"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/notify"
+	"github.com/maritimusj/centrum/gate/web/push"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	"github.com/maritimusj/centrum/gate/web/shelve"
+	"github.com/maritimusj/centrum/gate/web/status"
+	"github.com/maritimusj/centrum/global"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	//检查未确认警报的间隔
+	checkInterval = time.Minute
+
+	//警报泛滥时的汇总警报
+	FloodAlarm = "FLOOD"
+)
+
+var (
+	routing = &Routing{Fallback: true}
+	mu      sync.RWMutex
+)
+
+//Load 重新加载警报通知设置
+func Load() error {
+	r, err := Parse([]byte(app.Config.AlarmRouting()))
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	routing = r
+	mu.Unlock()
+	return nil
+}
+
+//Start 加载设置并定时检查未确认的警报，按路由设置升级通知
+func Start(ctx context.Context) {
+	if err := Load(); err != nil {
+		log.Errorln("[dispatch] load routing: ", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				escalate(time.Now())
+			}
+		}
+	}()
+}
+
+//OnAlarm 新警报产生时，按匹配的路由通知第一级接收人
+func OnAlarm(alarm model.Alarm) error {
+	measure, err := alarm.Measure()
+	if err != nil {
+		return err
+	}
+
+	device, err := alarm.Device()
+	if err != nil {
+		return err
+	}
+
+	//推送给订阅了警报的客户端和webhook
+	push.OnAlarm(alarm)
+	app.Event.Publish(event.AlarmRaised, alarm.GetID())
+
+	msg := message(lang.AlarmNotifyTitle.Str(), alarm, device, measure)
+
+	//能查看点位的用户都会在站内消息中收到警报
+	inbox.Post(measure, inbox.LevelError, device.Title(), msg.Content, msg.Data)
+
+	route := match(alarm, device, measure)
+	if route == nil {
+		mu.RLock()
+		fallback := routing.Fallback
+		mu.RUnlock()
+
+		if fallback {
+			send(&Recipients{}, measure, msg)
+		}
+		return nil
+	}
+
+	send(&route.Recipients, measure, msg)
+
+	if len(route.Escalation) > 0 {
+		_ = alarm.SetOption("escalation.tier", 0)
+		_ = alarm.SetOption("escalation.at", time.Now().Unix())
+		return alarm.Save()
+	}
+	return nil
+}
+
+//match 查找警报匹配的第一个路由，班次按警报产生的时间判断
+func match(alarm model.Alarm, device model.Device, measure model.Measure) *Route {
+	mu.RLock()
+	routes := routing.Routes
+	mu.RUnlock()
+
+	for _, route := range routes {
+		if !route.onDuty(alarm.CreatedAt()) {
+			continue
+		}
+		if len(route.Levels) > 0 && !containsStr(route.Levels, alarm.GetOption("tags.alarm").String()) {
+			continue
+		}
+		if len(route.Priorities) > 0 && !containsStr(route.Priorities, alarmDef.PriorityName(alarm.Priority())) {
+			continue
+		}
+		if len(route.Kinds) > 0 && !containsKind(route.Kinds, resource.ParseMeasureKind(measure.TagName())) {
+			continue
+		}
+		if len(route.Groups) > 0 && !inGroups(device, route.Groups) {
+			continue
+		}
+		if len(route.Equipments) > 0 && !inEquipments(measure, route.Equipments) {
+			continue
+		}
+		return route
+	}
+	return nil
+}
+
+func inGroups(device model.Device, groups []int64) bool {
+	list, err := device.Groups()
+	if err != nil {
+		return false
+	}
+	for _, group := range list {
+		if contains(groups, group.GetID()) {
+			return true
+		}
+	}
+	return false
+}
+
+func inEquipments(measure model.Measure, equipments []int64) bool {
+	for _, id := range equipments {
+		states, _, err := app.Store().GetStateList(helper.Equipment(id))
+		if err != nil {
+			continue
+		}
+		for _, state := range states {
+			if m := state.Measure(); m != nil && m.GetID() == measure.GetID() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//users 接收人列表，只包括能查看点位的用户，没有指定用户和角色时为能查看点位的全部用户
+func users(r *Recipients, measure model.Measure) []model.User {
+	var list []model.User
+	if len(r.Users) == 0 && len(r.Roles) == 0 {
+		all, _, err := app.Store().GetUserList()
+		if err != nil {
+			log.Errorln("[dispatch] get user list: ", err)
+			return nil
+		}
+		list = all
+	} else {
+		for _, id := range r.Users {
+			if user, err := app.Store().GetUser(id); err == nil {
+				list = append(list, user)
+			}
+		}
+		for _, id := range r.Roles {
+			role, err := app.Store().GetRole(id)
+			if err != nil {
+				continue
+			}
+			if members, _, err := role.GetUserList(); err == nil {
+				list = append(list, members...)
+			}
+		}
+	}
+
+	result := make([]model.User, 0, len(list))
+	added := map[int64]bool{}
+	for _, user := range list {
+		if added[user.GetID()] {
+			continue
+		}
+		added[user.GetID()] = true
+		if app.Allow(user, measure, resource.View) {
+			result = append(result, user)
+		}
+	}
+	return result
+}
+
+func send(r *Recipients, measure model.Measure, msg *notify.Message) {
+	for _, user := range users(r, measure) {
+		notify.Send(user, msg, r.Channels...)
+	}
+}
+
+func message(title string, alarm model.Alarm, device model.Device, measure model.Measure) *notify.Message {
+	val := alarm.GetOption("fields.val").String() + alarm.GetOption("tags.unit").String()
+	content := lang.AlarmNotifyDetail.Str(device.Title(), measure.Title(), alarm.GetOption("tags.alarm").String(), val)
+	if alarm.GetOption("tags.alarm").String() == FloodAlarm {
+		content = lang.AlarmFloodDetail.Str(alarm.GetOption("tags.scope").String(), alarm.GetOption("fields.count").Int())
+	}
+
+	return &notify.Message{
+		Title:   title,
+		Content: content,
+		Data: map[string]interface{}{
+			"alarm":   alarm.GetID(),
+			"device":  device.GetID(),
+			"measure": measure.GetID(),
+			"tag":     measure.TagName(),
+			"kind":    alarm.GetOption("tags.alarm").String(),
+			"val":     alarm.GetOption("fields.val").Value(),
+		},
+	}
+}
+
+//escalate 未确认的警报超过设置的时间后通知下一级，确认后不再升级
+func escalate(now time.Time) {
+	alarms, _, err := app.Store().GetAlarmList(nil, nil, helper.Status(status.Unconfirmed))
+	if err != nil {
+		if err != lang.ErrAlarmNotFound.Error() {
+			log.Errorln("[dispatch] get alarm list: ", err)
+		}
+		return
+	}
+
+	for _, alarm := range alarms {
+		if !alarm.GetOption("escalation").Exists() {
+			continue
+		}
+
+		measure, err := alarm.Measure()
+		if err != nil {
+			continue
+		}
+		device, err := alarm.Device()
+		if err != nil {
+			continue
+		}
+
+		//搁置、维护或者设备断开期间不升级
+		if index, _, _ := global.GetDeviceStatus(device); index == int(edgeLang.Disconnected) || shelve.Suppressed(device, measure) != nil {
+			continue
+		}
+
+		route := match(alarm, device, measure)
+		if route == nil {
+			continue
+		}
+
+		next := int(alarm.GetOption("escalation.tier").Int()) + 1
+		r, after := route.tier(next)
+		if r == nil {
+			continue
+		}
+
+		at := time.Unix(alarm.GetOption("escalation.at").Int(), 0)
+		if now.Sub(at) < time.Duration(after)*time.Minute {
+			continue
+		}
+
+		if next > len(route.Escalation) {
+			next = len(route.Escalation)
+		}
+
+		_ = alarm.SetOption("escalation.tier", next)
+		_ = alarm.SetOption("escalation.at", now.Unix())
+		if err := alarm.Save(); err != nil {
+			log.Errorln("[dispatch] save alarm: ", err)
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"org": device.OrganizationID(),
+			"src": logStore.SystemLog,
+		}).Warningln(lang.AlarmEscalated.Str(alarm.GetID(), next))
+
+		go send(r, measure, message(lang.AlarmEscalationTitle.Str(), alarm, device, measure))
+	}
+}
@@ -0,0 +1,317 @@
+package edge
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/rpc/v2/json"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/web/metrics"
+	. "github.com/maritimusj/centrum/json_rpc"
+	"github.com/maritimusj/centrum/util"
+	log "github.com/sirupsen/logrus"
+)
+
+//Options 调用edge的超时、重试和熔断设置
+type Options struct {
+	//每次请求的超时
+	Timeout time.Duration
+	//幂等调用失败后的重试次数，重试间隔从Backoff开始每次加倍
+	Retries int
+	Backoff time.Duration
+	//连续失败BreakerFailures次后暂停访问这个edge，BreakerCooldown后允许一次试探请求
+	BreakerFailures int
+	BreakerCooldown time.Duration
+}
+
+var DefaultOptions = Options{
+	Timeout:         10 * time.Second,
+	Retries:         2,
+	Backoff:         200 * time.Millisecond,
+	BreakerFailures: 5,
+	BreakerCooldown: 30 * time.Second,
+}
+
+var (
+	//幂等的调用，失败时可以重试，值为true时相同的并发请求共用一次调用结果
+	idempotent = map[string]bool{
+		"Edge.Ping":            false,
+		"Edge.GetLoad":         false,
+		"Edge.GetBaseInfo":     true,
+		"Edge.GetValue":        true,
+		"Edge.GetRealtimeData": true,
+		"Edge.GetDiagnostics":  true,
+		"Edge.Active":          false,
+		"Edge.Reset":           false,
+		"Edge.Remove":          false,
+	}
+
+	defaultClient = newClient(DefaultOptions)
+)
+
+//SetOptions 在启动前设置调用edge的超时、重试和熔断
+func SetOptions(opts Options) {
+	defaultClient = newClient(opts)
+}
+
+//breaker 单个edge的熔断状态
+type breaker struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+	mu        sync.Mutex
+}
+
+//allow 熔断期间拒绝请求，冷却后只放行一个试探请求
+func (b *breaker) allow(threshold int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if threshold <= 0 || b.failures < threshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+}
+
+//failure 记录一次失败，开始熔断时返回true
+func (b *breaker) failure(threshold int, cooldown time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if threshold <= 0 || b.failures < threshold {
+		return false
+	}
+
+	opened := b.failures == threshold || b.probing
+	b.probing = false
+	b.openUntil = time.Now().Add(cooldown)
+	return opened
+}
+
+//flight 相同的并发请求共用的调用
+type flight struct {
+	wg   sync.WaitGroup
+	data []byte
+	err  error
+}
+
+type client struct {
+	opts     Options
+	http     *http.Client
+	prefix   string
+	seq      uint64
+	breakers map[string]*breaker
+	flights  map[string]*flight
+	mu       sync.Mutex
+}
+
+func newClient(opts Options) *client {
+	return &client{
+		opts: opts,
+		http: &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   5 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 16,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		prefix:   util.RandStr(6, util.RandAll),
+		breakers: map[string]*breaker{},
+		flights:  map[string]*flight{},
+	}
+}
+
+func (c *client) breaker(url string) *breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.breakers[url]
+	if !ok {
+		b = &breaker{}
+		c.breakers[url] = b
+	}
+	return b
+}
+
+func (c *client) requestID() string {
+	return fmt.Sprintf("%s-%d", c.prefix, atomic.AddUint64(&c.seq, 1))
+}
+
+//Invoke 调用edge，edge返回的错误会原样返回给调用者
+func (c *client) Invoke(url, cmd string, request interface{}) (*Result, error) {
+	return c.call(url, cmd, request, c.opts.Timeout, false)
+}
+
+//probe 健康检查，不受熔断限制，成功后恢复对这个edge的访问
+func (c *client) probe(url, cmd string, timeout time.Duration) (*Result, error) {
+	return c.call(url, cmd, nil, timeout, true)
+}
+
+func (c *client) call(url, cmd string, request interface{}, timeout time.Duration, probe bool) (result *Result, err error) {
+	begin := time.Now()
+	defer func() {
+		metrics.EdgeRPCDuration.WithLabelValues(url, cmd).Observe(time.Now().Sub(begin).Seconds())
+		if err != nil {
+			metrics.EdgeRPCFailures.WithLabelValues(url, cmd).Inc()
+		}
+	}()
+
+	b := c.breaker(url)
+	if !probe && !b.allow(c.opts.BreakerFailures) {
+		return nil, lang.ErrEdgeUnavailable.Error(url)
+	}
+
+	message, err := json.EncodeClientRequest(cmd, request)
+	if err != nil {
+		log.Errorln("[invoke]: ", err)
+		return nil, lang.ErrEdgeInvokeFail.Error(9)
+	}
+
+	var data []byte
+	if shared, ok := idempotent[cmd]; ok && shared {
+		params, _ := jsoniter.Marshal(request)
+		data, err = c.share(url+"|"+cmd+"|"+string(params), func() ([]byte, error) {
+			return c.post(url, cmd, message, timeout, true)
+		})
+	} else {
+		data, err = c.post(url, cmd, message, timeout, ok && !probe)
+	}
+
+	if err != nil {
+		if b.failure(c.opts.BreakerFailures, c.opts.BreakerCooldown) && !probe {
+			log.Warningf("[invoke] %s failed %d times, pause for %s", url, c.opts.BreakerFailures, c.opts.BreakerCooldown)
+		}
+		return nil, lang.ErrEdgeInvokeFail.Error(10)
+	}
+
+	b.success()
+
+	var reply Result
+	if err = json.DecodeClientResponse(bytes.NewReader(data), &reply); err != nil {
+		if e, ok := err.(*json.Error); ok {
+			return nil, errors.New(e.Error())
+		}
+		log.Errorf("[invoke] %s %s: %s, result: %s", url, cmd, err, string(data))
+		return nil, lang.ErrEdgeInvokeFail.Error(11)
+	}
+
+	return &reply, nil
+}
+
+//share 相同的并发请求只调用一次
+func (c *client) share(key string, fn func() ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	if f, ok := c.flights[key]; ok {
+		c.mu.Unlock()
+		f.wg.Wait()
+		return f.data, f.err
+	}
+
+	f := &flight{}
+	f.wg.Add(1)
+	c.flights[key] = f
+	c.mu.Unlock()
+
+	f.data, f.err = fn()
+	f.wg.Done()
+
+	c.mu.Lock()
+	delete(c.flights, key)
+	c.mu.Unlock()
+
+	return f.data, f.err
+}
+
+//post 发送请求，幂等的调用在网络错误或者edge返回5xx时重试
+func (c *client) post(url, cmd string, message []byte, timeout time.Duration, retry bool) (data []byte, err error) {
+	attempts := 1
+	if retry && c.opts.Retries > 0 {
+		attempts += c.opts.Retries
+	}
+
+	backoff := c.opts.Backoff
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		id := c.requestID()
+		begin := time.Now()
+
+		data, err = c.do(url, id, message, timeout)
+		if err == nil {
+			log.Debugf("[invoke] %s %s %s, elapsed: %s", id, url, cmd, time.Now().Sub(begin))
+			return data, nil
+		}
+
+		log.Errorf("[invoke] %s %s %s: %s", id, url, cmd, err)
+	}
+
+	return nil, err
+}
+
+func (c *client) do(url, id string, message []byte, timeout time.Duration) ([]byte, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(message))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(RequestIDHeader, id)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	//读取全部数据，连接才能复用
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, errors.New(resp.Status)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,107 @@
+package edge
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/rpc/v2"
+	"github.com/gorilla/rpc/v2/json"
+	. "github.com/maritimusj/centrum/json_rpc"
+)
+
+type testService struct {
+	calls int32
+}
+
+func (s *testService) GetValue(r *http.Request, ch *CH, result *Result) error {
+	atomic.AddInt32(&s.calls, 1)
+	if r.Header.Get(RequestIDHeader) == "" {
+		return errors.New("no request id")
+	}
+	if ch.Tag == "" {
+		return errors.New("invalid tag")
+	}
+	result.Data = ch.Tag
+	return nil
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, *testService) {
+	service := &testService{}
+
+	server := rpc.NewServer()
+	server.RegisterCodec(json.NewCodec(), "application/json")
+	if err := server.RegisterService(service, "Edge"); err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(server), service
+}
+
+func TestClientInvoke(t *testing.T) {
+	ts, service := newTestServer(t)
+	defer ts.Close()
+
+	c := newClient(DefaultOptions)
+
+	result, err := c.Invoke(ts.URL, "Edge.GetValue", &CH{UID: "1", Tag: "AI-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Data != "AI-1" {
+		t.Fatalf("expected AI-1, got %v", result.Data)
+	}
+
+	//edge返回的错误原样返回，不重试
+	_, err = c.Invoke(ts.URL, "Edge.GetValue", &CH{UID: "1"})
+	if err == nil || err.Error() != "invalid tag" {
+		t.Fatalf("expected invalid tag, got %v", err)
+	}
+	if n := atomic.LoadInt32(&service.calls); n != 2 {
+		t.Fatalf("expected 2 calls, got %d", n)
+	}
+}
+
+func TestClientBreaker(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	c := newClient(Options{
+		Timeout:         time.Second,
+		Retries:         2,
+		Backoff:         time.Millisecond,
+		BreakerFailures: 2,
+		BreakerCooldown: time.Hour,
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Invoke(ts.URL, "Edge.GetValue", &CH{UID: "1", Tag: "AI-1"}); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	//每次调用重试2次
+	if n := atomic.LoadInt32(&calls); n != 6 {
+		t.Fatalf("expected 6 requests, got %d", n)
+	}
+
+	//熔断后不再访问edge
+	if _, err := c.Invoke(ts.URL, "Edge.GetValue", &CH{UID: "1", Tag: "AI-1"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if n := atomic.LoadInt32(&calls); n != 6 {
+		t.Fatalf("expected 6 requests, got %d", n)
+	}
+
+	//不幂等的调用不重试
+	c.breaker(ts.URL).success()
+	_, _ = c.Invoke(ts.URL, "Edge.SetValue", nil)
+	if n := atomic.LoadInt32(&calls); n != 7 {
+		t.Fatalf("expected 7 requests, got %d", n)
+	}
+}
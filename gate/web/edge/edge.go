@@ -1,10 +1,6 @@
 package edge
 
 import (
-	"bytes"
-	"io/ioutil"
-	"net/http"
-	"strings"
 	"sync"
 	"time"
 
@@ -12,92 +8,83 @@ import (
 
 	"github.com/maritimusj/centrum/gate/lang"
 
-	log "github.com/sirupsen/logrus"
-
-	"github.com/gorilla/rpc/v2/json"
 	. "github.com/maritimusj/centrum/json_rpc"
 )
 
-type balanceCacheEntry struct {
-	data interface{}
-	exp  time.Time
-	sync.Mutex
-}
+type Balance struct {
+	url   string
+	total int
 
-func (e *balanceCacheEntry) CloneData() interface{} {
-	e.Lock()
-	defer e.Unlock()
+	//配置文件中的edge，不会被移除
+	static   bool
+	alive    bool
+	failures int
+	load     *Load
+	lastSeen time.Time
 
-	v, err := jsoniter.MarshalToString(e.data)
-	if err != nil {
-		return nil
-	}
+	//故障时转移到其它edge的设备，恢复后需要从这个edge上移除
+	orphans map[string]struct{}
 
-	var result interface{}
-	err = jsoniter.Unmarshal([]byte(v), &result)
-	if err != nil {
-		return nil
-	}
-	return result
+	mu sync.RWMutex
 }
 
-func (e *balanceCacheEntry) IsExpired() bool {
-	return e == nil || time.Now().Sub(e.exp) > time.Second
-}
-
-type Balance struct {
-	url   string
-	total int
-	cache map[string]*balanceCacheEntry
-	mu    sync.RWMutex
+func newBalance(url string, static bool) *Balance {
+	return &Balance{
+		url:     url,
+		static:  static,
+		alive:   true,
+		orphans: map[string]struct{}{},
+	}
 }
 
-func (b *Balance) DeltaTotal(delta int) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+func (b *Balance) IsAlive() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 
-	b.total += delta
+	return b.alive
 }
 
-func (b *Balance) Remove(uid string) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+//score 负载评分，设备数量按CPU使用率放大，每秒的平均采集用时相当于一个设备
+func (b *Balance) score() float64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 
-	for path, _ := range b.cache {
-		if strings.HasPrefix(path, uid) {
-			delete(b.cache, path)
-		}
+	adapters := b.total
+	if b.load != nil && b.load.Adapters > adapters {
+		adapters = b.load.Adapters
 	}
-}
 
-func (b *Balance) Set(uid string, key string, value interface{}) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	path := uid + "." + key
-	if e, ok := b.cache[path]; ok {
-		e.data = value
-		e.exp = time.Now()
-	} else {
-		b.cache[path] = &balanceCacheEntry{
-			data: value,
-			exp:  time.Now(),
-		}
+	score := float64(adapters)
+	if b.load != nil {
+		score = score*(1+b.load.CPU/100) + b.load.PollLatency/1000
 	}
+	return score
 }
 
-func (b *Balance) Get(uid string, key string) *balanceCacheEntry {
-	b.mu.RLock()
-	defer b.mu.RUnlock()
+func (b *Balance) DeltaTotal(delta int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-	v, _ := b.cache[uid+"."+key]
-	return v
+	b.total += delta
 }
 
 type EdgesMap struct {
 	edges   []*Balance
 	devices map[string]*Balance
-	mu      sync.RWMutex
+	//设备固定使用的edge
+	pins map[string]string
+	//设备最后一次激活的配置，用于故障转移
+	confs map[string]*Conf
+	mu    sync.RWMutex
+}
+
+func (e *EdgesMap) find(url string) *Balance {
+	for _, b := range e.edges {
+		if b.url == url {
+			return b
+		}
+	}
+	return nil
 }
 
 func (e *EdgesMap) GetBalanceByDeviceUID(uid string) *Balance {
@@ -115,17 +102,26 @@ func (e *EdgesMap) AddDevice(uid string) *Balance {
 	var balance *Balance
 	if v, ok := e.devices[uid]; ok {
 		balance = v
+	} else if url, ok := e.pins[uid]; ok {
+		//固定的edge不可用时等待恢复
+		if b := e.find(url); b != nil && b.IsAlive() {
+			balance = b
+		}
 	} else {
+		var score float64
 		for _, b := range e.edges {
-			if balance == nil || balance.total > b.total {
-				balance = b
+			if !b.IsAlive() {
+				continue
+			}
+			if x := b.score(); balance == nil || x < score {
+				balance, score = b, x
 			}
 		}
 	}
 
 	if balance != nil {
 		if _, ok := e.devices[uid]; !ok {
-			balance.total += 1
+			balance.DeltaTotal(1)
 			e.devices[uid] = balance
 		}
 	}
@@ -133,66 +129,68 @@ func (e *EdgesMap) AddDevice(uid string) *Balance {
 	return balance
 }
 
+//RemoveDevice 取消设备和edge的关联，返回原来的edge
+func (e *EdgesMap) RemoveDevice(uid string) *Balance {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.confs, uid)
+	delete(e.pins, uid)
+
+	b, ok := e.devices[uid]
+	if ok {
+		delete(e.devices, uid)
+		b.DeltaTotal(-1)
+	}
+	return b
+}
+
 var (
 	defaultEdgesMap = &EdgesMap{
 		edges:   []*Balance{},
 		devices: map[string]*Balance{},
+		pins:    map[string]string{},
+		confs:   map[string]*Conf{},
 	}
 )
 
-//Add 增加一个edge URL
+//Add 增加一个配置文件中的edge URL
 func Add(url string) {
 	defaultEdgesMap.mu.Lock()
 	defer defaultEdgesMap.mu.Unlock()
 
-	defaultEdgesMap.edges = append(defaultEdgesMap.edges, &Balance{
-		url:   url,
-		total: 0,
-		cache: map[string]*balanceCacheEntry{},
-	})
+	if defaultEdgesMap.find(url) == nil {
+		defaultEdgesMap.edges = append(defaultEdgesMap.edges, newBalance(url, true))
+	}
 }
 
+//Invoke 调用edge，超时、重试和熔断见Options
 func Invoke(url, cmd string, request interface{}) (*Result, error) {
-	message, err := json.EncodeClientRequest(cmd, request)
-	if err != nil {
-		log.Errorln("[invoke]: ", err)
-		return nil, lang.ErrEdgeInvokeFail.Error(9)
-	}
+	return defaultClient.Invoke(url, cmd, request)
+}
 
-	resp, err := http.Post(url, "application/json", bytes.NewReader(message))
+//Ping 检查edge是否可以访问
+func Ping(url string, timeout time.Duration) error {
+	result, err := defaultClient.probe(url, "Edge.Ping", timeout)
 	if err != nil {
-		log.Errorln("[invoke]: ", err)
-		return nil, lang.ErrEdgeInvokeFail.Error(10)
+		return err
 	}
+	if v, ok := result.Data.(string); !ok || v != "pong" {
+		return lang.ErrEdgeInvokeFail.Error(14)
+	}
+	return nil
+}
 
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	var reply Result
-
-	if log.IsLevelEnabled(log.TraceLevel) {
-		data, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			log.Errorf("[invoke] %s, result: %s", err, string(data))
-			//return nil, lang.ErrEdgeInvokeFail, 11.Error()
-		}
-
-		err = json.DecodeClientResponse(bytes.NewReader(data), &reply)
-		if err != nil {
-			log.Errorln("[invoke]: ", err)
-			//return nil, lang.ErrEdgeInvokeFail, 12.Error()
-		}
+//URLs 全部edge的URL
+func URLs() []string {
+	defaultEdgesMap.mu.RLock()
+	defer defaultEdgesMap.mu.RUnlock()
 
-	} else {
-		err = json.DecodeClientResponse(resp.Body, &reply)
-		if err != nil {
-			log.Errorln("[invoke]: ", err)
-			//return nil, lang.ErrEdgeInvokeFail, 13.Error()
-		}
+	urls := make([]string, 0, len(defaultEdgesMap.edges))
+	for _, b := range defaultEdgesMap.edges {
+		urls = append(urls, b.url)
 	}
-
-	return &reply, nil
+	return urls
 }
 
 //Restart 重启指定的edge
@@ -207,20 +205,12 @@ func GetBaseInfo(uid string) (map[string]interface{}, error) {
 		return map[string]interface{}{}, lang.ErrDeviceNotExistsOrActive.Error()
 	}
 
-	if e := balance.Get(uid, "baseInfo"); !e.IsExpired() {
-		if baseInfoData, ok := e.data.(map[string]interface{}); ok {
-			return baseInfoData, nil
-		}
-	}
-
 	result, err := Invoke(balance.url, "Edge.GetBaseInfo", uid)
 	if err != nil {
 		return map[string]interface{}{}, err
 	}
 
 	data, _ := result.Data.(map[string]interface{})
-	balance.Set(uid, "baseInfo", data)
-
 	return data, nil
 }
 
@@ -228,25 +218,24 @@ func GetBaseInfo(uid string) (map[string]interface{}, error) {
 func Reset(uid string) {
 	balance := defaultEdgesMap.GetBalanceByDeviceUID(uid)
 	if balance != nil {
-		if e := balance.Get(uid, "reset"); e.IsExpired() {
-			_, _ = Invoke(balance.url, "Edge.Reset", uid)
-			balance.Set(uid, "reset", true)
-		}
+		_, _ = Invoke(balance.url, "Edge.Reset", uid)
 	}
 }
 
 //Remove 移除一个设备
 func Remove(uid string) {
-	balance := defaultEdgesMap.GetBalanceByDeviceUID(uid)
+	balance := defaultEdgesMap.RemoveDevice(uid)
 	if balance != nil {
 		_, _ = Invoke(balance.url, "Edge.Remove", uid)
-		balance.DeltaTotal(-1)
-		balance.Remove(uid)
 	}
 }
 
-//Active 激活设备
+//Active 激活设备，edge故障时使用保存的配置在其它edge上重新激活
 func Active(conf *Conf) error {
+	defaultEdgesMap.mu.Lock()
+	defaultEdgesMap.confs[conf.UID] = conf
+	defaultEdgesMap.mu.Unlock()
+
 	balance := defaultEdgesMap.AddDevice(conf.UID)
 	if balance != nil {
 		_, err := Invoke(balance.url, "Edge.Active", conf)
@@ -273,14 +262,41 @@ func SetValue(uid string, tag string, val interface{}) error {
 	return lang.ErrDeviceNotExistsOrActive.Error()
 }
 
+//SetPulse 脉冲输出，开启DO指定的秒数后由edge关闭
+func SetPulse(uid string, tag string, seconds int) error {
+	balance := defaultEdgesMap.GetBalanceByDeviceUID(uid)
+	if balance != nil {
+		_, err := Invoke(balance.url, "Edge.Pulse", &Pulse{
+			CH: CH{
+				UID: uid,
+				Tag: tag,
+			},
+			Seconds: seconds,
+		})
+		return err
+	}
+
+	return lang.ErrDeviceNotExistsOrActive.Error()
+}
+
+//ResetTotalizer 清零设备指定的累计点位，tag为空时清零全部累计点位
+func ResetTotalizer(uid string, tag string) error {
+	balance := defaultEdgesMap.GetBalanceByDeviceUID(uid)
+	if balance != nil {
+		_, err := Invoke(balance.url, "Edge.ResetTotalizer", &CH{
+			UID: uid,
+			Tag: tag,
+		})
+		return err
+	}
+
+	return lang.ErrDeviceNotExistsOrActive.Error()
+}
+
 //GetValue 获取设备指定点位的值
 func GetValue(uid string, tag string) (map[string]interface{}, error) {
 	balance := defaultEdgesMap.GetBalanceByDeviceUID(uid)
 	if balance != nil {
-		if e := balance.Get(uid, tag); !e.IsExpired() {
-			return e.data.(map[string]interface{}), nil
-		}
-
 		result, err := Invoke(balance.url, "Edge.GetValue", &CH{
 			UID: uid,
 			Tag: tag,
@@ -290,9 +306,6 @@ func GetValue(uid string, tag string) (map[string]interface{}, error) {
 		}
 
 		data, _ := result.Data.(map[string]interface{})
-
-		balance.Set(uid, tag, data)
-
 		return data, nil
 	}
 
@@ -303,20 +316,80 @@ func GetValue(uid string, tag string) (map[string]interface{}, error) {
 func GetRealtimeData(uid string) (interface{}, error) {
 	balance := defaultEdgesMap.GetBalanceByDeviceUID(uid)
 	if balance != nil {
-		if e := balance.Get(uid, "realtimeData"); !e.IsExpired() {
-			return e.CloneData().(interface{}), nil
+		result, err := Invoke(balance.url, "Edge.GetRealtimeData", uid)
+		if err != nil {
+			return nil, err
 		}
 
-		result, err := Invoke(balance.url, "Edge.GetRealtimeData", uid)
+		return result.Data, nil
+	}
+
+	return []interface{}{}, lang.ErrDeviceNotExistsOrActive.Error()
+}
+
+//GetDiagnostics 获取设备的通讯诊断数据
+func GetDiagnostics(uid string) (interface{}, error) {
+	balance := defaultEdgesMap.GetBalanceByDeviceUID(uid)
+	if balance != nil {
+		result, err := Invoke(balance.url, "Edge.GetDiagnostics", uid)
 		if err != nil {
 			return nil, err
 		}
+		return result.Data, nil
+	}
 
-		data, _ := result.Data.(interface{})
-		balance.Set(uid, "realtimeData", data)
+	return nil, lang.ErrDeviceNotExistsOrActive.Error()
+}
 
-		return data, nil
+//StartCapture 抓取设备接下来num次请求和应答的原始数据
+func StartCapture(uid string, num int) error {
+	balance := defaultEdgesMap.GetBalanceByDeviceUID(uid)
+	if balance != nil {
+		_, err := Invoke(balance.url, "Edge.Capture", &Capture{
+			UID: uid,
+			Num: num,
+		})
+		return err
 	}
 
-	return []interface{}{}, lang.ErrDeviceNotExistsOrActive.Error()
+	return lang.ErrDeviceNotExistsOrActive.Error()
+}
+
+//Discover 使用全部edge扫描网络中的控制器
+func Discover(conf *ScanConf) ([]*Discovered, error) {
+	urls := URLs()
+	if len(urls) == 0 {
+		return nil, lang.ErrNoEdgeAvailable.Error()
+	}
+
+	var (
+		result = make([]*Discovered, 0)
+		exists = map[string]struct{}{}
+	)
+
+	for _, url := range urls {
+		reply, err := Invoke(url, "Edge.Discover", conf)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := jsoniter.Marshal(reply.Data)
+		if err != nil {
+			return nil, lang.InternalError(err)
+		}
+
+		var list []*Discovered
+		if err := jsoniter.Unmarshal(data, &list); err != nil {
+			return nil, lang.InternalError(err)
+		}
+
+		for _, v := range list {
+			if _, ok := exists[v.Address]; !ok {
+				exists[v.Address] = struct{}{}
+				result = append(result, v)
+			}
+		}
+	}
+
+	return result, nil
 }
@@ -0,0 +1,50 @@
+package edge
+
+import (
+	"testing"
+
+	. "github.com/maritimusj/centrum/json_rpc"
+)
+
+func TestAddDevice(t *testing.T) {
+	var (
+		a = newBalance("http://a/rpc", true)
+		b = newBalance("http://b/rpc", true)
+		c = newBalance("http://c/rpc", false)
+	)
+
+	e := &EdgesMap{
+		edges:   []*Balance{a, b, c},
+		devices: map[string]*Balance{},
+		pins:    map[string]string{},
+		confs:   map[string]*Conf{},
+	}
+
+	//a的设备少但是CPU占用高
+	a.load = &Load{Adapters: 2, CPU: 100}
+	b.load = &Load{Adapters: 3, CPU: 0}
+	c.alive = false
+
+	if x := e.AddDevice("1"); x != b {
+		t.Fatalf("expected %s, got %s", b.url, x.url)
+	}
+	//已经分配的设备不会改变
+	if x := e.AddDevice("1"); x != b || b.total != 1 {
+		t.Fatal("device should stay on the same edge")
+	}
+
+	//固定到无响应的edge时等待恢复
+	e.pins["2"] = c.url
+	if x := e.AddDevice("2"); x != nil {
+		t.Fatalf("expected nil, got %s", x.url)
+	}
+
+	c.alive = true
+	if x := e.AddDevice("2"); x != c {
+		t.Fatal("pinned device should use the pinned edge")
+	}
+
+	if x := e.RemoveDevice("1"); x != b || b.total != 0 {
+		t.Fatal("device should be removed")
+	}
+}
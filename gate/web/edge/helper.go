@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/maritimusj/centrum/gate/config"
+	"github.com/maritimusj/centrum/gate/lang"
 
 	"github.com/maritimusj/centrum/gate/web/model"
 	"github.com/maritimusj/centrum/global"
@@ -18,6 +19,9 @@ func ActiveDevice(device model.Device, config *config.Config) error {
 		return err
 	}
 
+	//固定使用的edge
+	SetPin(strconv.FormatInt(device.GetID(), 10), device.GetOption("params.edge").Str)
+
 	influxDBConfig := config.InfluxDBConfig()
 	conf := &json_rpc.Conf{
 		UID:              strconv.FormatInt(device.GetID(), 10),
@@ -29,6 +33,70 @@ func ActiveDevice(device model.Device, config *config.Config) error {
 		InfluxDBPassword: influxDBConfig["password"],
 		CallbackURL:      fmt.Sprintf("%s/%d", global.Params.MustGet("callbackURL"), device.GetID()),
 		LogLevel:         "error",
+		Driver:           device.GetOption("params.driver").Str,
+		Options:          device.GetOption("params.options").Raw,
+	}
+
+	//虚拟点位：[{"tag": "AI-DP", "title": "压差", "unit": "kPa", "expr": "[AI-1] - [AI-2]"}]
+	for _, v := range device.GetOption("params.virtual").Array() {
+		conf.Virtual = append(conf.Virtual, json_rpc.VirtualCH{
+			Tag:   v.Get("tag").Str,
+			Title: v.Get("title").Str,
+			Unit:  v.Get("unit").Str,
+			Expr:  v.Get("expr").Str,
+		})
+	}
+
+	//累计点位：[{"tag": "AI-FT", "title": "累计流量", "unit": "m3", "source": "AI-1", "kind": "flow"}]
+	for _, v := range device.GetOption("params.totalizers").Array() {
+		conf.Totalizers = append(conf.Totalizers, json_rpc.TotalizerCH{
+			Tag:    v.Get("tag").Str,
+			Title:  v.Get("title").Str,
+			Unit:   v.Get("unit").Str,
+			Source: v.Get("source").Str,
+			Kind:   v.Get("kind").Str,
+			Scale:  v.Get("scale").Float(),
+			MaxGap: int(v.Get("maxGap").Int()),
+		})
+	}
+
+	//DO定时：[{"tag": "DO-1", "kind": "calendar", "windows": [{"weekdays": [1, 2, 3, 4, 5], "start": "08:00", "end": "17:30"}], "holidays": ["2020-10-01"]}]
+	//或者：[{"tag": "DO-2", "kind": "cycle", "onTime": 600, "offTime": 1800}]
+	for _, v := range device.GetOption("params.schedules").Array() {
+		schedule := json_rpc.Schedule{
+			Tag:     v.Get("tag").Str,
+			Kind:    v.Get("kind").Str,
+			OnTime:  int(v.Get("onTime").Int()),
+			OffTime: int(v.Get("offTime").Int()),
+		}
+		for _, w := range v.Get("windows").Array() {
+			window := json_rpc.TimeWindow{
+				Start: w.Get("start").Str,
+				End:   w.Get("end").Str,
+			}
+			for _, d := range w.Get("weekdays").Array() {
+				window.Weekdays = append(window.Weekdays, int(d.Int()))
+			}
+			schedule.Windows = append(schedule.Windows, window)
+		}
+		for _, d := range v.Get("holidays").Array() {
+			schedule.Holidays = append(schedule.Holidays, d.Str)
+		}
+		conf.Schedules = append(conf.Schedules, schedule)
+	}
+
+	//首次连接时edge上报的设备身份
+	if identity := device.GetOption("params.identity"); identity.Exists() {
+		conf.Identity = &json_rpc.Identity{
+			Model:   identity.Get("model").Str,
+			Version: identity.Get("version").Str,
+			MAC:     identity.Get("mac").Str,
+			AI:      int(identity.Get("ai").Int()),
+			AO:      int(identity.Get("ao").Int()),
+			DI:      int(identity.Get("di").Int()),
+			DO:      int(identity.Get("do").Int()),
+			VO:      int(identity.Get("vo").Int()),
+		}
 	}
 
 	return Active(conf)
@@ -53,3 +121,47 @@ func SetCHValue(device model.Device, chTagName string, v interface{}) error {
 func GetCHValue(device model.Device, chTagName string) (map[string]interface{}, error) {
 	return GetValue(strconv.FormatInt(device.GetID(), 10), chTagName)
 }
+
+//GetCHFloatValue 读取点位的数值，开关量转换为0或1
+func GetCHFloatValue(device model.Device, chTagName string) (float64, error) {
+	data, err := GetCHValue(device, chTagName)
+	if err != nil {
+		return 0, err
+	}
+
+	switch v := data["value"].(type) {
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	return 0, lang.ErrInvalidCHValue.Error(chTagName)
+}
+
+func ResetTotalizerValue(device model.Device, chTagName string) error {
+	return ResetTotalizer(strconv.FormatInt(device.GetID(), 10), chTagName)
+}
+
+func PulseCH(device model.Device, chTagName string, seconds int) error {
+	return SetPulse(strconv.FormatInt(device.GetID(), 10), chTagName, seconds)
+}
+
+func GetDeviceDiagnostics(device model.Device) (interface{}, error) {
+	return GetDiagnostics(strconv.FormatInt(device.GetID(), 10))
+}
+
+func CaptureFrames(device model.Device, num int) error {
+	return StartCapture(strconv.FormatInt(device.GetID(), 10), num)
+}
+
+func PinDevice(device model.Device, url string) error {
+	return Pin(strconv.FormatInt(device.GetID(), 10), url)
+}
+
+func UnpinDevice(device model.Device) {
+	SetPin(strconv.FormatInt(device.GetID(), 10), "")
+}
@@ -0,0 +1,299 @@
+package edge
+
+import (
+	"context"
+	"crypto/subtle"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/logStore"
+	. "github.com/maritimusj/centrum/json_rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	//健康检查间隔和超时
+	checkInterval = 10 * time.Second
+	checkTimeout  = 3 * time.Second
+	//连续失败次数达到后转移设备
+	maxFailures = 3
+)
+
+var (
+	//edge注册时需要提供的令牌，没有设置时拒绝全部注册
+	registerToken string
+)
+
+func SetRegisterToken(token string) {
+	registerToken = token
+}
+
+//Start 定时检查全部edge，转移无响应edge上的设备
+func Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				checkAll()
+			}
+		}
+	}()
+}
+
+//Register edge注册或者上报负载
+func Register(reg *Registration) error {
+	if registerToken == "" || subtle.ConstantTimeCompare([]byte(reg.Token), []byte(registerToken)) != 1 {
+		return lang.ErrInvalidEdgeToken.Error()
+	}
+
+	if reg.URL == "" {
+		return lang.ErrInvalidRequestData.Error()
+	}
+
+	e := defaultEdgesMap
+
+	e.mu.Lock()
+	b := e.find(reg.URL)
+	isNew := b == nil
+	if isNew {
+		b = newBalance(reg.URL, false)
+		e.edges = append(e.edges, b)
+	}
+	e.mu.Unlock()
+
+	if isNew {
+		log.WithField("src", logStore.SystemLog).Infoln(lang.EdgeRegistered.Str(reg.URL))
+	}
+
+	b.online(reg.Load)
+	return nil
+}
+
+//SetPin 记录设备固定使用的edge，url为空时取消固定
+func SetPin(uid, url string) {
+	e := defaultEdgesMap
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if url == "" {
+		delete(e.pins, uid)
+	} else {
+		e.pins[uid] = url
+	}
+}
+
+//Pin 把设备固定到指定的edge，已经在其它edge上运行的设备会转移过去
+func Pin(uid, url string) error {
+	e := defaultEdgesMap
+
+	e.mu.Lock()
+	b := e.find(url)
+	if b == nil {
+		e.mu.Unlock()
+		return lang.ErrEdgeNotFound.Error(url)
+	}
+
+	e.pins[uid] = url
+
+	old, ok := e.devices[uid]
+	conf := e.confs[uid]
+	if ok && old != b {
+		delete(e.devices, uid)
+		old.DeltaTotal(-1)
+	}
+	e.mu.Unlock()
+
+	if ok && old != b {
+		_, _ = Invoke(old.url, "Edge.Remove", uid)
+		if conf != nil {
+			return Active(conf)
+		}
+	}
+	return nil
+}
+
+//Edges 全部edge的状态和负载
+func Edges() []map[string]interface{} {
+	e := defaultEdgesMap
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	pinned := map[string]int{}
+	for _, url := range e.pins {
+		pinned[url]++
+	}
+
+	result := make([]map[string]interface{}, 0, len(e.edges))
+	for _, b := range e.edges {
+		b.mu.RLock()
+		entry := map[string]interface{}{
+			"url":     b.url,
+			"static":  b.static,
+			"alive":   b.alive,
+			"devices": b.total,
+			"pinned":  pinned[b.url],
+			"load":    b.load,
+		}
+		if !b.lastSeen.IsZero() {
+			entry["last_seen"] = b.lastSeen.Format(lang.DatetimeFormatterStr.Str())
+		}
+		b.mu.RUnlock()
+
+		result = append(result, entry)
+	}
+	return result
+}
+
+//online 更新负载，从故障中恢复时移除已经转移到其它edge的设备
+func (b *Balance) online(load *Load) {
+	b.mu.Lock()
+	revived := !b.alive
+	b.alive = true
+	b.failures = 0
+	b.load = load
+	b.lastSeen = time.Now()
+
+	orphans := b.orphans
+	b.orphans = map[string]struct{}{}
+	b.mu.Unlock()
+
+	if revived {
+		log.WithField("src", logStore.SystemLog).Infoln(lang.EdgeOnline.Str(b.url))
+	}
+
+	for uid := range orphans {
+		if defaultEdgesMap.GetBalanceByDeviceUID(uid) != b {
+			_, _ = Invoke(b.url, "Edge.Remove", uid)
+		}
+	}
+}
+
+//offline 记录一次失败，连续失败时返回true
+func (b *Balance) offline() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.alive && b.failures >= maxFailures {
+		b.alive = false
+		return true
+	}
+	return false
+}
+
+func getLoad(url string) (*Load, error) {
+	result, err := defaultClient.probe(url, "Edge.GetLoad", checkTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Data == nil {
+		return nil, lang.ErrEdgeInvokeFail.Error(15)
+	}
+
+	data, err := jsoniter.Marshal(result.Data)
+	if err != nil {
+		return nil, lang.InternalError(err)
+	}
+
+	var load Load
+	if err = jsoniter.Unmarshal(data, &load); err != nil {
+		return nil, lang.InternalError(err)
+	}
+	return &load, nil
+}
+
+func checkAll() {
+	e := defaultEdgesMap
+
+	e.mu.RLock()
+	edges := append([]*Balance{}, e.edges...)
+	e.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, b := range edges {
+		wg.Add(1)
+		go func(b *Balance) {
+			defer wg.Done()
+
+			load, err := getLoad(b.url)
+			if err == nil {
+				b.online(load)
+				return
+			}
+
+			if b.offline() {
+				log.WithField("src", logStore.SystemLog).Warningln(lang.EdgeOffline.Str(b.url, err))
+				failover(b)
+			}
+		}(b)
+	}
+	wg.Wait()
+
+	reassign()
+}
+
+//failover 取消无响应edge上的设备关联，固定到这个edge的设备等待恢复
+func failover(b *Balance) {
+	e := defaultEdgesMap
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for uid, x := range e.devices {
+		if x != b {
+			continue
+		}
+		if _, ok := e.pins[uid]; ok {
+			continue
+		}
+
+		delete(e.devices, uid)
+		b.DeltaTotal(-1)
+
+		b.mu.Lock()
+		b.orphans[uid] = struct{}{}
+		b.mu.Unlock()
+	}
+}
+
+//reassign 重新激活没有关联edge的设备
+func reassign() {
+	e := defaultEdgesMap
+
+	e.mu.RLock()
+	var confs []*Conf
+	for uid, conf := range e.confs {
+		if _, ok := e.devices[uid]; ok {
+			continue
+		}
+		//固定的edge不可用时等待恢复
+		if url, ok := e.pins[uid]; ok {
+			if b := e.find(url); b == nil || !b.IsAlive() {
+				continue
+			}
+		}
+		confs = append(confs, conf)
+	}
+	e.mu.RUnlock()
+
+	for _, conf := range confs {
+		if err := Active(conf); err != nil {
+			log.WithField("src", logStore.SystemLog).Warningln(lang.DeviceFailoverFailed.Str(conf.UID, err))
+			continue
+		}
+
+		if b := defaultEdgesMap.GetBalanceByDeviceUID(conf.UID); b != nil {
+			log.WithField("src", logStore.SystemLog).Infoln(lang.DeviceFailover.Str(conf.UID, b.url))
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package helper
 
 import (
+	"time"
+
 	resource2 "github.com/maritimusj/centrum/gate/web/resource"
 )
 
@@ -18,9 +20,19 @@ type Option struct {
 	MeasureID   int64
 	EquipmentID int64
 	StateID     int64
+	WebhookID   int64
 
 	Status *int64
 
+	//排除的设备
+	ExcludeDeviceIDs []int64
+	//警报优先级
+	Priorities []int
+	//指定时间还没有消失的警报
+	ClearedAfter *time.Time
+	//还没有消失的警报
+	Uncleared bool
+
 	Name          string
 	Keyword       string
 	DefaultEffect resource2.Effect
@@ -106,6 +118,30 @@ func Device(deviceID int64) OptionFN {
 	}
 }
 
+func ExcludeDevices(deviceIDs ...int64) OptionFN {
+	return func(i *Option) {
+		i.ExcludeDeviceIDs = append(i.ExcludeDeviceIDs, deviceIDs...)
+	}
+}
+
+func Priority(priorities ...int) OptionFN {
+	return func(i *Option) {
+		i.Priorities = append(i.Priorities, priorities...)
+	}
+}
+
+func ClearedAfter(t time.Time) OptionFN {
+	return func(i *Option) {
+		i.ClearedAfter = &t
+	}
+}
+
+func Uncleared() OptionFN {
+	return func(i *Option) {
+		i.Uncleared = true
+	}
+}
+
 func Measure(measureID int64) OptionFN {
 	return func(i *Option) {
 		i.MeasureID = measureID
@@ -124,6 +160,12 @@ func State(stateID int64) OptionFN {
 	}
 }
 
+func Webhook(webhookID int64) OptionFN {
+	return func(i *Option) {
+		i.WebhookID = webhookID
+	}
+}
+
 func Name(name string) OptionFN {
 	return func(i *Option) {
 		i.Name = name
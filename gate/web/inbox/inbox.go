@@ -0,0 +1,146 @@
+package inbox
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/push"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	"github.com/maritimusj/centrum/gate/web/status"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	LevelSuccess = "success"
+	LevelWarning = "warning"
+	LevelError   = "error"
+
+	//清理过期消息的间隔
+	cleanInterval = time.Hour
+
+	//简讯中只弹出最近的新消息
+	popupMax    = 6
+	popupWithin = 3 * time.Minute
+)
+
+//Retention 消息保留的天数和每个用户最多保留的消息数量
+type Retention struct {
+	Days int `json:"days"`
+	Max  int `json:"max"`
+}
+
+var DefaultRetention = Retention{
+	Days: 30,
+	Max:  500,
+}
+
+var (
+	//每个用户已经弹出的最后一条消息
+	popped = map[int64]int64{}
+	mu     sync.Mutex
+)
+
+//ParseRetention 解析并检查消息保留设置，没有设置时使用默认值
+func ParseRetention(data []byte) (*Retention, error) {
+	r := DefaultRetention
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, lang.ErrInvalidInboxRetention.Error()
+		}
+	}
+	if r.Days <= 0 || r.Max <= 0 {
+		return nil, lang.ErrInvalidInboxRetention.Error()
+	}
+	return &r, nil
+}
+
+//Start 定时清理过期的消息
+func Start(ctx context.Context) {
+	go func() {
+		clean()
+
+		ticker := time.NewTicker(cleanInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				clean()
+			}
+		}
+	}()
+}
+
+func clean() {
+	r, err := ParseRetention([]byte(app.Config.InboxRetention()))
+	if err != nil {
+		log.Errorln("[inbox] retention: ", err)
+		r = &DefaultRetention
+	}
+
+	n, err := app.Store().RemoveExpiredMessages(time.Now().AddDate(0, 0, -r.Days), r.Max)
+	if err != nil {
+		log.Errorln("[inbox] remove expired messages: ", err)
+	} else if n > 0 {
+		log.Debugf("[inbox] %d messages removed", n)
+	}
+}
+
+//Post 发送消息给能查看资源的所有用户
+func Post(res model.Resource, level, title, content string, data map[string]interface{}) {
+	s := app.Store()
+	users, _, err := s.GetUserList()
+	if err != nil {
+		log.Errorln("[inbox] get user list: ", err)
+		return
+	}
+
+	for _, user := range users {
+		if !user.IsEnabled() || !app.Allow(user, res, resource.View) {
+			continue
+		}
+		msg, err := s.CreateMessage(user.GetID(), level, title, content, data)
+		if err != nil {
+			log.Errorln("[inbox] create message: ", err)
+			continue
+		}
+		push.OnMessage(msg)
+	}
+}
+
+//Popup 用户最近的还没有弹出过的未读消息
+func Popup(user model.User) []model.Message {
+	messages, _, err := app.Store().GetMessageList(helper.User(user.GetID()), helper.Status(status.Unread), helper.Limit(popupMax))
+	if err != nil {
+		return nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var (
+		last   = popped[user.GetID()]
+		result []model.Message
+	)
+
+	for i := len(messages) - 1; i >= 0; i-- {
+		m := messages[i]
+		if m.GetID() > last && time.Since(m.CreatedAt()) < popupWithin {
+			result = append(result, m)
+		}
+	}
+
+	if len(messages) > 0 && messages[0].GetID() > last {
+		popped[user.GetID()] = messages[0].GetID()
+	}
+
+	return result
+}
@@ -0,0 +1,21 @@
+package inbox
+
+import "testing"
+
+func TestParseRetention(t *testing.T) {
+	r, err := ParseRetention(nil)
+	if err != nil || *r != DefaultRetention {
+		t.Fatalf("default retention expected, got %v, %v", r, err)
+	}
+
+	r, err = ParseRetention([]byte(`{"days":7}`))
+	if err != nil || r.Days != 7 || r.Max != DefaultRetention.Max {
+		t.Fatalf("unexpected retention: %v, %v", r, err)
+	}
+
+	for _, data := range []string{`{"days":0}`, `{"max":-1}`, `[]`} {
+		if _, err := ParseRetention([]byte(data)); err == nil {
+			t.Fatalf("%s should be rejected", data)
+		}
+	}
+}
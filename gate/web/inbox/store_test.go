@@ -0,0 +1,186 @@
+package inbox
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maritimusj/centrum/gate/config"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	"github.com/maritimusj/centrum/gate/web/status"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func initStore(t *testing.T) {
+	app.Ctx = context.Background()
+	if err := app.InitDB(map[string]interface{}{
+		"connStr": filepath.Join(t.TempDir(), "test.db"),
+		"initDB":  true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	app.Config = config.New(app.Store())
+	if err := app.Config.Load(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createUser(t *testing.T, org model.Organization, name string) model.User {
+	s := app.Store()
+	role, err := s.CreateRole(org, name, name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.CreateUser(org, name, []byte(name), role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func messages(t *testing.T, user model.User, options ...helper.OptionFN) []model.Message {
+	list, _, err := app.Store().GetMessageList(append(options, helper.User(user.GetID()))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func TestPost(t *testing.T) {
+	initStore(t)
+
+	s := app.Store()
+	org, err := s.CreateOrganization("test", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	device, err := s.CreateDevice(org, "device", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viewer := createUser(t, org, "viewer")
+	if err := viewer.SetAllow(device, resource.View); err != nil {
+		t.Fatal(err)
+	}
+	other := createUser(t, org, "other")
+	disabled := createUser(t, org, "disabled")
+	if err := disabled.SetAllow(device, resource.View); err != nil {
+		t.Fatal(err)
+	}
+	disabled.Disable()
+	if err := disabled.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	Post(device, LevelWarning, "title", "content", map[string]interface{}{"device": device.GetID()})
+
+	list := messages(t, viewer)
+	if len(list) != 1 {
+		t.Fatalf("viewer should receive 1 message, got %d", len(list))
+	}
+	if m := list[0]; m.Level() != LevelWarning || m.Title() != "title" || m.Content() != "content" || m.IsRead() {
+		t.Fatalf("unexpected message: %v", m.Simple())
+	}
+	if n := len(messages(t, other)); n != 0 {
+		t.Fatalf("user without permission should not receive messages, got %d", n)
+	}
+	if n := len(messages(t, disabled)); n != 0 {
+		t.Fatalf("disabled user should not receive messages, got %d", n)
+	}
+}
+
+func TestRead(t *testing.T) {
+	initStore(t)
+
+	s := app.Store()
+	org, err := s.CreateOrganization("test", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := createUser(t, org, "user")
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.CreateMessage(user.GetID(), LevelSuccess, "title", "content", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list := messages(t, user, helper.Status(status.Unread))
+	if len(list) != 3 {
+		t.Fatalf("expected 3 unread messages, got %d", len(list))
+	}
+
+	list[0].MarkRead()
+	if err := list[0].Save(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(messages(t, user, helper.Status(status.Unread))); n != 2 {
+		t.Fatalf("expected 2 unread messages, got %d", n)
+	}
+	if n := len(messages(t, user, helper.Status(status.Read))); n != 1 {
+		t.Fatalf("expected 1 read message, got %d", n)
+	}
+
+	n, err := s.MarkAllMessagesRead(user.GetID())
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 messages marked read, got %d, %v", n, err)
+	}
+	if n := len(messages(t, user, helper.Status(status.Unread))); n != 0 {
+		t.Fatalf("expected no unread messages, got %d", n)
+	}
+}
+
+func TestClean(t *testing.T) {
+	initStore(t)
+
+	s := app.Store()
+	org, err := s.CreateOrganization("test", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := createUser(t, org, "a")
+	b := createUser(t, org, "b")
+
+	for i := 0; i < 4; i++ {
+		if _, err := s.CreateMessage(a.GetID(), LevelSuccess, "title", "content", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.CreateMessage(b.GetID(), LevelSuccess, "title", "content", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	//每个用户只保留最新的消息
+	if err := app.Config.SaveInboxRetention(Retention{Days: 1, Max: 2}); err != nil {
+		t.Fatal(err)
+	}
+	clean()
+
+	list := messages(t, a)
+	if len(list) != 2 {
+		t.Fatalf("expected 2 messages kept, got %d", len(list))
+	}
+	for _, m := range list {
+		if m.GetID() <= 2 {
+			t.Fatalf("older message %d should be removed", m.GetID())
+		}
+	}
+	if n := len(messages(t, b)); n != 1 {
+		t.Fatalf("other users' messages should be kept, got %d", n)
+	}
+
+	//过期的消息
+	n, err := s.RemoveExpiredMessages(time.Now().Add(time.Minute), 0)
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 expired messages removed, got %d, %v", n, err)
+	}
+	if _, err := s.GetMessage(list[0].GetID()); err == nil {
+		t.Fatal("removed message should not be found")
+	}
+}
@@ -0,0 +1,421 @@
+package interlock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/edge"
+	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/model"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	//指定点位存在还没有消失的警报
+	KindAlarm = "alarm"
+	//指定点位的值满足条件
+	KindValue = "value"
+	//两次启动之间的最小停止时间
+	KindMinOffTime = "minOffTime"
+	//一段时间内的最大启动次数
+	KindMaxStarts = "maxStarts"
+
+	WhenOn  = "on"
+	WhenOff = "off"
+	WhenAny = "any"
+
+	defaultStartsPeriod = 3600
+
+	//读取DO实际状态的间隔
+	watchInterval = 5 * time.Second
+	//gate控制后edge的状态可能还没有更新，这段时间内忽略读取到的状态
+	settleTime = 2 * watchInterval
+)
+
+//Rule 联锁条件，保存在设备的params.interlocks中：
+//[{"tag": "DO-2", "kind": "alarm", "measure": "AI-3", "title": "液位低低报警时禁止启动"},
+// {"tag": "DO-2", "kind": "value", "measure": "AI-3", "op": "<", "value": 1.2},
+// {"tag": "DO-2", "kind": "minOffTime", "seconds": 300},
+// {"tag": "DO-2", "kind": "maxStarts", "count": 6, "seconds": 3600}]
+type Rule struct {
+	Tag     string  `json:"tag"`
+	When    string  `json:"when,omitempty"`
+	Kind    string  `json:"kind"`
+	Measure string  `json:"measure,omitempty"`
+	Op      string  `json:"op,omitempty"`
+	Value   float64 `json:"value,omitempty"`
+	Seconds int     `json:"seconds,omitempty"`
+	Count   int     `json:"count,omitempty"`
+	Title   string  `json:"title,omitempty"`
+}
+
+func isOp(op string) bool {
+	switch op {
+	case ">", ">=", "<", "<=", "==", "!=":
+		return true
+	}
+	return false
+}
+
+//Validate 检查联锁条件，maxStarts没有指定时间段时使用默认的一小时
+func Validate(rules []*Rule) error {
+	for _, rule := range rules {
+		if rule == nil || rule.Tag == "" {
+			return lang.ErrInvalidInterlock.Error("tag")
+		}
+
+		switch rule.When {
+		case "", WhenOn, WhenOff, WhenAny:
+		default:
+			return lang.ErrInvalidInterlock.Error(rule.When)
+		}
+
+		switch rule.Kind {
+		case KindAlarm:
+			if rule.Measure == "" {
+				return lang.ErrInvalidInterlock.Error(rule.Tag)
+			}
+		case KindValue:
+			if rule.Measure == "" || !isOp(rule.Op) {
+				return lang.ErrInvalidInterlock.Error(rule.Tag)
+			}
+		case KindMinOffTime:
+			if rule.Seconds <= 0 {
+				return lang.ErrInvalidInterlock.Error(rule.Tag)
+			}
+		case KindMaxStarts:
+			if rule.Seconds == 0 {
+				rule.Seconds = defaultStartsPeriod
+			}
+			if rule.Count <= 0 || rule.Seconds <= 0 {
+				return lang.ErrInvalidInterlock.Error(rule.Tag)
+			}
+		default:
+			return lang.ErrInvalidInterlock.Error(rule.Kind)
+		}
+	}
+	return nil
+}
+
+//Parse 解析并检查设备的联锁条件
+func Parse(data gjson.Result) ([]*Rule, error) {
+	var rules []*Rule
+	if data.Exists() {
+		if err := json.Unmarshal([]byte(data.Raw), &rules); err != nil {
+			return nil, lang.ErrInvalidInterlock.Error(err)
+		}
+	}
+	if err := Validate(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (rule *Rule) match(on bool) bool {
+	switch rule.Kind {
+	case KindMinOffTime, KindMaxStarts:
+		return on
+	}
+
+	switch rule.When {
+	case WhenAny:
+		return true
+	case WhenOff:
+		return !on
+	default:
+		return on
+	}
+}
+
+//history DO的开关记录，用于检查停止时间和启动次数，包括gate的控制和从edge读取到的实际状态变化
+//记录只保存在内存中，gate重启后从第一次读取到的状态重新开始记录，重启前的启动次数和停止时间不再计入
+type history struct {
+	on      bool
+	lastOff time.Time
+	starts  []time.Time
+	//最近一次通过gate控制的时间
+	recorded time.Time
+}
+
+var (
+	histories = map[string]*history{}
+	mu        sync.Mutex
+
+	//每个DO一个锁，检查联锁条件、控制和记录作为一个整体执行
+	outputs   = map[string]*sync.Mutex{}
+	outputsMu sync.Mutex
+)
+
+func key(device model.Device, tagName string) string {
+	return fmt.Sprintf("%d.%s", device.GetID(), strings.ToUpper(tagName))
+}
+
+//Check 检查控制操作是否满足设备的联锁条件，不满足时返回拒绝的原因，联锁条件配置错误时拒绝所有控制
+func Check(device model.Device, tagName string, on bool) error {
+	rules, err := Parse(device.GetOption("params.interlocks"))
+	if err != nil {
+		return lang.ErrInterlockBlocked.Error(lang.InterlockInvalid.Str())
+	}
+
+	for _, rule := range rules {
+		if !strings.EqualFold(rule.Tag, tagName) || !rule.match(on) {
+			continue
+		}
+
+		if reason := check(device, tagName, rule); reason != "" {
+			if rule.Title != "" {
+				reason = rule.Title
+			}
+			return lang.ErrInterlockBlocked.Error(reason)
+		}
+	}
+	return nil
+}
+
+//check 返回不满足条件的原因，满足时返回空字符串
+func check(device model.Device, tagName string, rule *Rule) string {
+	switch rule.Kind {
+	case KindAlarm:
+		measure, err := app.Store().GetMeasureFromTagName(device.GetID(), rule.Measure)
+		if err != nil {
+			return lang.InterlockNoValue.Str(rule.Measure)
+		}
+		_, total, err := app.Store().GetLastAlarm(helper.Device(device.GetID()), helper.Measure(measure.GetID()), helper.Uncleared())
+		if err != nil {
+			if err == lang.ErrAlarmNotFound.Error() {
+				return ""
+			}
+			return lang.InterlockNoValue.Str(rule.Measure)
+		}
+		if total > 0 {
+			return lang.InterlockAlarm.Str(measure.Title())
+		}
+
+	case KindValue:
+		if !isOp(rule.Op) {
+			return lang.InterlockInvalid.Str()
+		}
+
+		x, err := edge.GetCHFloatValue(device, rule.Measure)
+		if err != nil {
+			return lang.InterlockNoValue.Str(rule.Measure)
+		}
+
+		if Compare(x, rule.Op, rule.Value) {
+			return lang.InterlockValue.Str(rule.Measure, rule.Op, rule.Value)
+		}
+
+	case KindMinOffTime:
+		mu.Lock()
+		defer mu.Unlock()
+
+		if h, ok := histories[key(device, tagName)]; ok && !h.on && !h.lastOff.IsZero() {
+			if time.Now().Sub(h.lastOff) < time.Duration(rule.Seconds)*time.Second {
+				return lang.InterlockMinOffTime.Str(rule.Seconds)
+			}
+		}
+
+	case KindMaxStarts:
+		seconds := rule.Seconds
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if h, ok := histories[key(device, tagName)]; ok && !h.on {
+			since := time.Now().Add(-time.Duration(seconds) * time.Second)
+			n := 0
+			for _, t := range h.starts {
+				if t.After(since) {
+					n++
+				}
+			}
+			if n >= rule.Count {
+				return lang.InterlockMaxStarts.Str(seconds, rule.Count)
+			}
+		}
+
+	default:
+		return lang.InterlockInvalid.Str()
+	}
+
+	return ""
+}
+
+//Compare 按运算符比较两个数值
+func Compare(x float64, op string, v float64) bool {
+	switch op {
+	case ">":
+		return x > v
+	case ">=":
+		return x >= v
+	case "<":
+		return x < v
+	case "<=":
+		return x <= v
+	case "==":
+		return x == v
+	case "!=":
+		return x != v
+	}
+	return false
+}
+
+//Record 记录一次成功的开关操作
+func Record(device model.Device, tagName string, on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	h := get(device, tagName)
+	h.recorded = time.Now()
+	h.change(on, h.recorded)
+}
+
+//Observe 记录从edge读取到的DO实际状态，现场手动或者edge脉冲关闭等不经过gate的变化也会被记录
+func Observe(device model.Device, tagName string, on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	k := key(device, tagName)
+	h, ok := histories[k]
+	if !ok {
+		//第一次读取到的状态不知道是什么时候变化的，不计入启动次数和停止时间
+		histories[k] = &history{on: on}
+		return
+	}
+
+	now := time.Now()
+	if h.on == on || now.Sub(h.recorded) < settleTime {
+		return
+	}
+	h.change(on, now)
+}
+
+func get(device model.Device, tagName string) *history {
+	k := key(device, tagName)
+	h, ok := histories[k]
+	if !ok {
+		h = &history{}
+		histories[k] = h
+	}
+	return h
+}
+
+func (h *history) change(on bool, now time.Time) {
+	if on {
+		if !h.on {
+			//只保留一天内的启动记录
+			since := now.Add(-24 * time.Hour)
+			starts := h.starts[:0]
+			for _, t := range h.starts {
+				if t.After(since) {
+					starts = append(starts, t)
+				}
+			}
+			h.starts = append(starts, now)
+		}
+	} else if h.on || h.lastOff.IsZero() {
+		h.lastOff = now
+	}
+	h.on = on
+}
+
+//Start 定时读取有停止时间或者启动次数条件的DO的实际状态
+func Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				watch()
+			}
+		}
+	}()
+}
+
+func watch() {
+	devices, _, err := app.Store().GetDeviceList()
+	if err != nil {
+		log.Errorln("[interlock] load devices: ", err)
+		return
+	}
+
+	for _, device := range devices {
+		rules, err := Parse(device.GetOption("params.interlocks"))
+		if err != nil {
+			continue
+		}
+
+		tags := map[string]struct{}{}
+		for _, rule := range rules {
+			if rule.Kind == KindMinOffTime || rule.Kind == KindMaxStarts {
+				tags[strings.ToUpper(rule.Tag)] = struct{}{}
+			}
+		}
+
+		for tag := range tags {
+			x, err := edge.GetCHFloatValue(device, tag)
+			if err != nil {
+				continue
+			}
+			Observe(device, tag, x != 0)
+		}
+	}
+}
+
+//lock 锁定DO，返回解锁函数
+func lock(device model.Device, tagName string) func() {
+	k := key(device, tagName)
+
+	outputsMu.Lock()
+	l, ok := outputs[k]
+	if !ok {
+		l = &sync.Mutex{}
+		outputs[k] = l
+	}
+	outputsMu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+//Do 检查联锁条件后执行控制，成功后记录开关状态，允许和拒绝的操作都记录到设备日志，同一个DO的操作依次执行
+func Do(user model.User, device model.Device, measure model.Measure, on bool, fn func() error) error {
+	state := lang.SwitchOff.Str()
+	if on {
+		state = lang.SwitchOn.Str()
+	}
+
+	unlock := lock(device, measure.TagName())
+	defer unlock()
+
+	if err := Check(device, measure.TagName(), on); err != nil {
+		device.Logger().Warningln(lang.DeviceCtrlBlocked.Str(user.Name(), measure.Title(), state, err.Error()))
+		return err
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	Record(device, measure.TagName(), on)
+	device.Logger().Infoln(lang.DeviceCtrlOk.Str(user.Name(), measure.Title(), state))
+	return nil
+}
+
+//SetCHValue 检查联锁条件后控制设备点位
+func SetCHValue(user model.User, device model.Device, measure model.Measure, on bool) error {
+	return Do(user, device, measure, on, func() error {
+		return edge.SetCHValue(device, measure.TagName(), on)
+	})
+}
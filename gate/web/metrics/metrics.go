@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gate"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	//API请求，按路由名称统计
+	APIRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Duration of API requests by route name.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+
+	//调用edge的用时和失败次数
+	EdgeRPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "edge_rpc_duration_seconds",
+		Help:      "Duration of RPC calls to edges.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"edge", "method"})
+
+	EdgeRPCFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "edge_rpc_failures_total",
+		Help:      "Number of failed RPC calls to edges.",
+	}, []string{"edge", "method"})
+
+	//数据库事务用时，result为commit, rollback
+	DBTransactionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_transaction_duration_seconds",
+		Help:      "Duration of sqlite transactions.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"result"})
+)
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		APIRequestDuration,
+		EdgeRPCDuration,
+		EdgeRPCFailures,
+		DBTransactionDuration,
+	)
+}
+
+//Register 注册其它的统计数据
+func Register(c prometheus.Collector) error {
+	return registry.Register(c)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -14,8 +14,16 @@ type Alarm interface {
 	Measure() (Measure, error)
 
 	Status() (int, string)
+
+	//警报优先级，数值越大越紧急
+	Priority() int
+	SetPriority(priority int)
 	Confirm(map[string]interface{}) error
 
 	UpdatedAt() time.Time
 	Updated()
+
+	//警报消失的时间，没有消失时为零值
+	ClearedAt() time.Time
+	Cleared(at time.Time)
 }
@@ -0,0 +1,18 @@
+package model
+
+//用户的站内消息
+type Message interface {
+	DBEntry
+	OptionEntry
+	Profile
+
+	UserID() int64
+	User() (User, error)
+
+	Level() string
+	Title() string
+	Content() string
+
+	IsRead() bool
+	MarkRead()
+}
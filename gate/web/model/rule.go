@@ -0,0 +1,20 @@
+package model
+
+//自动控制规则
+type Rule interface {
+	DBEntry
+	EnableEntry
+	OptionEntry
+	LogEntry
+	Profile
+
+	OrganizationID() int64
+	Organization() (Organization, error)
+
+	//规则的创建者，执行时使用创建者的权限
+	UserID() int64
+	User() (User, error)
+
+	Title() string
+	SetTitle(title string)
+}
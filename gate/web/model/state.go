@@ -34,4 +34,7 @@ type State interface {
 	SetAlarmEntry(name string, value float32)
 	EnableAlarmEntry(name string)
 	DisableAlarmEntry(name string)
+
+	AlarmPriority() string
+	SetAlarmPriority(name string)
 }
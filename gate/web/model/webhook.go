@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+//系统事件的webhook订阅
+type Webhook interface {
+	DBEntry
+	EnableEntry
+	OptionEntry
+	Profile
+
+	//所属组织，只接收本组织的事件
+	OrganizationID() int64
+	Organization() (Organization, error)
+
+	//创建者
+	UserID() int64
+	User() (User, error)
+
+	Title() string
+	SetTitle(title string)
+
+	URL() string
+	Secret() string
+	//订阅的事件，为空时订阅全部事件
+	Events() []string
+}
+
+//webhook的一次事件投递
+type Delivery interface {
+	DBEntry
+	Profile
+
+	WebhookID() int64
+	Webhook() (Webhook, error)
+
+	Event() string
+	Payload() []byte
+
+	Status() int
+	Attempts() int
+	//下一次尝试发送的时间
+	NextAt() time.Time
+	UpdatedAt() time.Time
+
+	//Attempted 记录一次发送的结果，status为发送后的状态
+	Attempted(status int, code int, response string, next time.Time)
+	//Retry 重新投递，清除已经尝试的次数
+	Retry()
+}
@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"github.com/spf13/viper"
+)
+
+//Init 根据配置文件启用通知渠道，个推始终启用
+func Init() {
+	Register(&Push{})
+
+	viper.SetDefault("notify.smtp.port", 25)
+
+	if host := viper.GetString("notify.smtp.host"); host != "" {
+		Register(&SMTP{
+			Host:     host,
+			Port:     viper.GetInt("notify.smtp.port"),
+			Username: viper.GetString("notify.smtp.username"),
+			Password: viper.GetString("notify.smtp.password"),
+			From:     viper.GetString("notify.smtp.from"),
+		})
+	}
+
+	if url := viper.GetString("notify.webhook.url"); url != "" {
+		Register(&Webhook{
+			URL:    url,
+			Secret: viper.GetString("notify.webhook.secret"),
+		})
+	}
+
+	if url := viper.GetString("notify.sms.url"); url != "" {
+		Register(&SMS{
+			URL:  url,
+			Key:  viper.GetString("notify.sms.key"),
+			Sign: viper.GetString("notify.sms.sign"),
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package notify
+
+import (
+	"github.com/maritimusj/centrum/gate/Getui"
+	"github.com/maritimusj/centrum/gate/properties"
+	"github.com/maritimusj/centrum/gate/web/model"
+)
+
+//Push 个推推送到用户登录过的App
+type Push struct{}
+
+func (p *Push) Name() string {
+	return "getui"
+}
+
+func (p *Push) Address(user model.User) string {
+	return user.Name()
+}
+
+func (p *Push) Send(user model.User, msg *Message) error {
+	//用户没有登录过客户端
+	if len(properties.LoadAllString("user", user.Name())) == 0 {
+		return ErrNoAddress
+	}
+	return Getui.SendTo(user, msg.Title, msg.Content)
+}
@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/logStore"
+	"github.com/maritimusj/centrum/gate/web/model"
+	log "github.com/sirupsen/logrus"
+)
+
+//每次发送的超时
+const sendTimeout = 10 * time.Second
+
+//ErrNoAddress 用户没有设置这个渠道需要的联系方式，不发送也不记录
+var ErrNoAddress = errors.New("no address")
+
+//Message 通知内容，Data为附加数据，webhook等渠道会原样发送
+type Message struct {
+	Title   string                 `json:"title"`
+	Content string                 `json:"content"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+//Notifier 通知渠道
+type Notifier interface {
+	Name() string
+	//Address 用户在这个渠道的联系方式，没有时返回空字符串
+	Address(user model.User) string
+	Send(user model.User, msg *Message) error
+}
+
+var (
+	notifiers []Notifier
+	mu        sync.RWMutex
+)
+
+//Register 增加一个通知渠道，相同名称的渠道会被替换
+func Register(n Notifier) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i, x := range notifiers {
+		if x.Name() == n.Name() {
+			notifiers[i] = n
+			return
+		}
+	}
+	notifiers = append(notifiers, n)
+}
+
+//Names 已启用的通知渠道
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(notifiers))
+	for _, n := range notifiers {
+		names = append(names, n.Name())
+	}
+	return names
+}
+
+func get(names ...string) []Notifier {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if len(names) == 0 {
+		return append([]Notifier{}, notifiers...)
+	}
+
+	var result []Notifier
+	for _, n := range notifiers {
+		for _, name := range names {
+			if n.Name() == name {
+				result = append(result, n)
+				break
+			}
+		}
+	}
+	return result
+}
+
+//Send 通过指定的渠道向用户发送通知，没有指定渠道时使用全部渠道，每次发送的结果记录到通知日志
+func Send(user model.User, msg *Message, channels ...string) {
+	var wg sync.WaitGroup
+	for _, n := range get(channels...) {
+		wg.Add(1)
+		go func(n Notifier) {
+			defer wg.Done()
+
+			err := n.Send(user, msg)
+			if err != ErrNoAddress {
+				record(user, n, msg, err)
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+//record 记录发送结果
+func record(user model.User, n Notifier, msg *Message, err error) {
+	logger := log.WithFields(log.Fields{
+		"org":     user.OrganizationID(),
+		"src":     logStore.NotifyLog,
+		"channel": n.Name(),
+		"user":    user.Name(),
+		"address": n.Address(user),
+		"title":   msg.Title,
+	})
+
+	if err != nil {
+		logger.Warningln(lang.NotifyFailed.Str(n.Name(), user.Name(), err))
+	} else {
+		logger.Infoln(lang.NotifyDelivered.Str(n.Name(), user.Name()))
+	}
+}
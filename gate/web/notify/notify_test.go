@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maritimusj/centrum/gate/web/model"
+)
+
+type testUser struct {
+	model.User
+	name   string
+	mobile string
+	email  string
+}
+
+func (u *testUser) Name() string   { return u.name }
+func (u *testUser) Title() string  { return u.name }
+func (u *testUser) Mobile() string { return u.mobile }
+func (u *testUser) Email() string  { return u.email }
+
+var (
+	user = &testUser{name: "test", mobile: "13800000000", email: "test@example.com"}
+	msg  = &Message{Title: "警报通知", Content: "AI-1 HH", Data: map[string]interface{}{"alarm": 1}}
+)
+
+func TestWebhook(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if Sign("secret", r.Header.Get(TimestampHeader), body) != r.Header.Get(SignatureHeader) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var data struct {
+			Content string `json:"content"`
+			User    struct {
+				Name string `json:"name"`
+			} `json:"user"`
+		}
+		if err := json.Unmarshal(body, &data); err != nil || data.Content != msg.Content || data.User.Name != user.name {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	if err := (&Webhook{URL: ts.URL, Secret: "secret"}).Send(user, msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Webhook{URL: ts.URL, Secret: "wrong"}).Send(user, msg); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestSMS(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&data)
+		if r.Header.Get("Authorization") != "Bearer key" || data["mobile"] != user.mobile || data["content"] != "【centrum】AI-1 HH" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	if err := (&SMS{URL: ts.URL, Key: "key", Sign: "centrum"}).Send(user, msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&SMS{URL: ts.URL}).Send(&testUser{name: "x"}, msg); err != ErrNoAddress {
+		t.Fatalf("expected ErrNoAddress, got %v", err)
+	}
+}
+
+func TestSMTP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) {
+			_, _ = conn.Write([]byte(s + "\r\n"))
+		}
+
+		reply("220 localhost ESMTP")
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 go ahead")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				reply("250 ok")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 bye")
+				received <- data.String()
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	addr := l.Addr().(*net.TCPAddr)
+	s := &SMTP{Host: "127.0.0.1", Port: addr.Port, From: "centrum@example.com"}
+	if err := s.Send(user, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if data := <-received; !strings.Contains(data, "To: test@example.com") || !strings.Contains(data, msg.Content) {
+		t.Fatalf("unexpected mail: %s", data)
+	}
+}
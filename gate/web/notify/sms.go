@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/web/model"
+)
+
+//SMS 通过短信网关的HTTP接口发送短信，请求内容为 {"mobile": "...", "content": "..."}，
+//Key不为空时通过Authorization: Bearer <Key>认证，Sign为短信签名，添加在内容前面
+type SMS struct {
+	URL  string
+	Key  string
+	Sign string
+}
+
+func (s *SMS) Name() string {
+	return "sms"
+}
+
+func (s *SMS) Address(user model.User) string {
+	return user.Mobile()
+}
+
+func (s *SMS) Send(user model.User, msg *Message) error {
+	mobile := user.Mobile()
+	if mobile == "" {
+		return ErrNoAddress
+	}
+
+	content := msg.Content
+	if s.Sign != "" {
+		content = "【" + s.Sign + "】" + content
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"mobile":  mobile,
+		"content": content,
+	})
+	if err != nil {
+		return lang.InternalError(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if s.Key != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Key)
+	}
+
+	return post(req)
+}
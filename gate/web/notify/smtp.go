@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/maritimusj/centrum/gate/web/model"
+)
+
+//SMTP 邮件通知，用户名为空时不进行认证，服务器支持时使用STARTTLS
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (s *SMTP) Name() string {
+	return "email"
+}
+
+func (s *SMTP) Address(user model.User) string {
+	return user.Email()
+}
+
+func (s *SMTP) Send(user model.User, msg *Message) error {
+	to := user.Email()
+	if to == "" {
+		return ErrNoAddress
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(s.Host, strconv.Itoa(s.Port)), sendTimeout)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_ = conn.SetDeadline(time.Now().Add(sendTimeout))
+
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = c.Close()
+	}()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: s.Host}); err != nil {
+			return err
+		}
+	}
+
+	if s.Username != "" {
+		if err = c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err = c.Mail(s.From); err != nil {
+		return err
+	}
+	if err = c.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(s.message(to, msg)); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+func (s *SMTP) message(to string, msg *Message) []byte {
+	var b bytes.Buffer
+	_, _ = fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	_, _ = fmt.Fprintf(&b, "To: %s\r\n", to)
+	_, _ = fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", msg.Title))
+	_, _ = fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Content)
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/web/model"
+)
+
+const (
+	TimestampHeader = "X-Centrum-Timestamp"
+	SignatureHeader = "X-Centrum-Signature"
+)
+
+//Sign 使用HMAC-SHA256签名，签名内容为 时间戳 + "." + 请求内容
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+//Webhook 把通知POST到指定的URL，设置了Secret时附带签名
+type Webhook struct {
+	URL    string
+	Secret string
+}
+
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+func (w *Webhook) Address(user model.User) string {
+	return w.URL
+}
+
+func (w *Webhook) Send(user model.User, msg *Message) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"user": map[string]interface{}{
+			"name":   user.Name(),
+			"title":  user.Title(),
+			"mobile": user.Mobile(),
+			"email":  user.Email(),
+		},
+		"title":   msg.Title,
+		"content": msg.Content,
+		"data":    msg.Data,
+		"time":    time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return lang.InternalError(err)
+	}
+
+	return Post(w.URL, w.Secret, body)
+}
+
+//Post 把JSON内容POST到指定的URL，设置了secret时附带签名
+func Post(url, secret string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+	}
+
+	return post(req)
+}
+
+//post 发送请求，返回2xx以外的状态时失败
+func post(req *http.Request) error {
+	client := http.Client{Timeout: sendTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("%s: %s", resp.Status, string(data))
+	}
+
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,421 @@
+package push
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/edge"
+	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DeviceData    = "device.data"
+	DeviceStatus  = "device.status"
+	DevicePerf    = "device.perf"
+	EquipmentData = "equipment.data"
+	Alarm         = "alarm"
+	Message       = "message"
+
+	//每个订阅者等待发送的事件上限，客户端接收过慢时丢弃新的事件
+	queueSize = 128
+
+	//实时数据的轮询间隔，每个设备按自己的采集间隔读取，不会比这个更频繁
+	pollInterval = time.Second
+)
+
+//Event 推送给客户端的事件
+type Event struct {
+	Name string
+	Data interface{}
+}
+
+//Subscription 客户端订阅的内容
+type Subscription struct {
+	Devices    []int64
+	Equipments []int64
+	//能查看的全部新警报
+	Alarm bool
+	//自己的站内消息
+	Inbox bool
+}
+
+//Subscriber 一个客户端连接
+type Subscriber struct {
+	user       model.User
+	devices    map[int64]bool
+	equipments map[int64]bool
+	alarm      bool
+	inbox      bool
+
+	//下一次轮询时先推送订阅设备的全部数据
+	fresh bool
+
+	ch chan *Event
+}
+
+var (
+	subscribers = map[*Subscriber]struct{}{}
+	mu          sync.RWMutex
+
+	//上一次轮询到的实时数据和读取时间，只在轮询的goroutine中使用
+	last   = map[int64]map[string]map[string]interface{}{}
+	polled = map[int64]time.Time{}
+)
+
+//Subscribe 添加订阅者，连接断开时需要调用Unsubscribe
+func Subscribe(user model.User, sub Subscription) *Subscriber {
+	subscriber := &Subscriber{
+		user:       user,
+		devices:    map[int64]bool{},
+		equipments: map[int64]bool{},
+		alarm:      sub.Alarm,
+		inbox:      sub.Inbox,
+		fresh:      true,
+		ch:         make(chan *Event, queueSize),
+	}
+	for _, id := range sub.Devices {
+		subscriber.devices[id] = true
+	}
+	for _, id := range sub.Equipments {
+		subscriber.equipments[id] = true
+	}
+
+	mu.Lock()
+	subscribers[subscriber] = struct{}{}
+	mu.Unlock()
+
+	return subscriber
+}
+
+func Unsubscribe(subscriber *Subscriber) {
+	mu.Lock()
+	delete(subscribers, subscriber)
+	mu.Unlock()
+}
+
+//Count 当前的订阅者数量
+func Count() int {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(subscribers)
+}
+
+func (subscriber *Subscriber) Events() <-chan *Event {
+	return subscriber.ch
+}
+
+func (subscriber *Subscriber) send(name string, data interface{}) {
+	select {
+	case subscriber.ch <- &Event{Name: name, Data: data}:
+	default:
+		log.Debugf("[push] user %d: event %s dropped", subscriber.user.GetID(), name)
+	}
+}
+
+func each(fn func(subscriber *Subscriber)) {
+	mu.RLock()
+	list := make([]*Subscriber, 0, len(subscribers))
+	for subscriber := range subscribers {
+		list = append(list, subscriber)
+	}
+	mu.RUnlock()
+
+	for _, subscriber := range list {
+		fn(subscriber)
+	}
+}
+
+//OnDeviceStatus 设备状态变化
+func OnDeviceStatus(device model.Device, index int, title string) {
+	each(func(subscriber *Subscriber) {
+		if subscriber.devices[device.GetID()] && app.Allow(subscriber.user, device, resource.View) {
+			subscriber.send(DeviceStatus, map[string]interface{}{
+				"id":    device.GetID(),
+				"index": index,
+				"title": title,
+			})
+		}
+	})
+}
+
+//OnDevicePerf 设备通讯性能变化
+func OnDevicePerf(device model.Device, perf map[string]interface{}) {
+	each(func(subscriber *Subscriber) {
+		if subscriber.devices[device.GetID()] && app.Allow(subscriber.user, device, resource.View) {
+			subscriber.send(DevicePerf, map[string]interface{}{
+				"id":   device.GetID(),
+				"perf": perf,
+			})
+		}
+	})
+}
+
+//OnAlarm 新的警报，订阅了警报或者警报所在设备的用户能查看点位时推送
+func OnAlarm(alarm model.Alarm) {
+	measure, err := alarm.Measure()
+	if err != nil {
+		return
+	}
+
+	each(func(subscriber *Subscriber) {
+		if (subscriber.alarm || subscriber.devices[alarm.DeviceID()]) && app.Allow(subscriber.user, measure, resource.View) {
+			subscriber.send(Alarm, alarm.Brief())
+		}
+	})
+}
+
+//OnMessage 新的站内消息，只推送给消息的接收人
+func OnMessage(message model.Message) {
+	each(func(subscriber *Subscriber) {
+		if subscriber.inbox && subscriber.user.GetID() == message.UserID() {
+			subscriber.send(Message, message.Simple())
+		}
+	})
+}
+
+//Start 定时读取订阅的设备的实时数据，edge只在采集时更新数据，所以每个设备按采集间隔读取，
+//多个订阅者共用一次读取，只推送变化的数据
+func Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				poll()
+			}
+		}
+	}()
+}
+
+type equipmentState struct {
+	state    model.State
+	deviceID int64
+	tagName  string
+}
+
+func poll() {
+	var (
+		s = app.Store()
+
+		list       []*Subscriber
+		devices    = map[int64]model.Device{}
+		equipments = map[int64][]equipmentState{}
+	)
+
+	each(func(subscriber *Subscriber) {
+		list = append(list, subscriber)
+	})
+
+	for _, subscriber := range list {
+		for id := range subscriber.devices {
+			if _, ok := devices[id]; ok {
+				continue
+			}
+			if device, err := s.GetDevice(id); err == nil {
+				devices[id] = device
+			}
+		}
+
+		for id := range subscriber.equipments {
+			if _, ok := equipments[id]; ok {
+				continue
+			}
+			states, _, err := s.GetStateList(helper.Equipment(id))
+			if err != nil {
+				continue
+			}
+			for _, state := range states {
+				measure := state.Measure()
+				if measure == nil {
+					continue
+				}
+				device := measure.Device()
+				if device == nil {
+					continue
+				}
+				devices[device.GetID()] = device
+				equipments[id] = append(equipments[id], equipmentState{
+					state:    state,
+					deviceID: device.GetID(),
+					tagName:  measure.TagName(),
+				})
+			}
+		}
+	}
+
+	now := time.Now()
+	due := map[int64]model.Device{}
+	for id, device := range devices {
+		if now.Sub(polled[id]) >= interval(device) {
+			due[id] = device
+		}
+	}
+
+	fetched := fetch(due)
+
+	//没有到读取时间或者读取失败的设备使用上一次的数据，只推送给新的订阅者
+	current := map[int64]map[string]map[string]interface{}{}
+	changed := map[int64]map[string]bool{}
+	for id := range devices {
+		if data, ok := fetched[id]; ok {
+			current[id] = data
+			changed[id] = changes(last[id], data)
+		} else if data, ok := last[id]; ok {
+			current[id] = data
+		}
+	}
+
+	for id := range polled {
+		if _, ok := devices[id]; !ok {
+			delete(polled, id)
+		}
+	}
+	for id := range due {
+		polled[id] = now
+	}
+	last = current
+
+	for _, subscriber := range list {
+		for id := range subscriber.devices {
+			device, ok := devices[id]
+			if !ok || !app.Allow(subscriber.user, device, resource.View) {
+				continue
+			}
+
+			var result []interface{}
+			for tagName, entry := range current[id] {
+				if !subscriber.fresh && !changed[id][tagName] {
+					continue
+				}
+				measure, err := s.GetMeasureFromTagName(id, tagName)
+				if err != nil || !app.Allow(subscriber.user, measure, resource.View) {
+					continue
+				}
+				result = append(result, with(entry, map[string]interface{}{
+					"id": measure.GetID(),
+					"perm": map[string]bool{
+						"view": true,
+						"ctrl": app.Allow(subscriber.user, measure, resource.Ctrl),
+					},
+				}))
+			}
+
+			if len(result) > 0 {
+				subscriber.send(DeviceData, map[string]interface{}{
+					"id":   id,
+					"data": result,
+				})
+			}
+		}
+
+		for id := range subscriber.equipments {
+			var result []interface{}
+			for _, es := range equipments[id] {
+				entry, ok := current[es.deviceID][es.tagName]
+				if !ok || (!subscriber.fresh && !changed[es.deviceID][es.tagName]) {
+					continue
+				}
+				if !app.Allow(subscriber.user, es.state, resource.View) {
+					continue
+				}
+				result = append(result, with(entry, map[string]interface{}{
+					"id":    es.state.GetID(),
+					"title": es.state.Title(),
+					"perm": map[string]bool{
+						"view": true,
+						"ctrl": app.Allow(subscriber.user, es.state, resource.Ctrl),
+					},
+				}))
+			}
+
+			if len(result) > 0 {
+				subscriber.send(EquipmentData, map[string]interface{}{
+					"id":   id,
+					"data": result,
+				})
+			}
+		}
+
+		subscriber.fresh = false
+	}
+}
+
+//interval 设备的采集间隔
+func interval(device model.Device) time.Duration {
+	if d := time.Duration(device.GetOption("params.interval").Int()) * time.Second; d > pollInterval {
+		return d
+	}
+	return pollInterval
+}
+
+//fetch 同时读取多个设备的实时数据，按点位的tag整理
+func fetch(devices map[int64]model.Device) map[int64]map[string]map[string]interface{} {
+	var (
+		result = map[int64]map[string]map[string]interface{}{}
+		wg     sync.WaitGroup
+		lock   sync.Mutex
+	)
+
+	for id, device := range devices {
+		wg.Add(1)
+		go func(id int64, device model.Device) {
+			defer wg.Done()
+
+			data, err := edge.GetRealTimeData(device)
+			if err != nil {
+				return
+			}
+
+			arr, _ := data.([]interface{})
+			entries := make(map[string]map[string]interface{}, len(arr))
+			for _, v := range arr {
+				if entry, ok := v.(map[string]interface{}); ok {
+					if tagName, ok := entry["tag"].(string); ok {
+						entries[tagName] = entry
+					}
+				}
+			}
+
+			lock.Lock()
+			result[id] = entries
+			lock.Unlock()
+		}(id, device)
+	}
+
+	wg.Wait()
+	return result
+}
+
+//changes 和上一次相比数值或者警报状态发生变化的点位
+func changes(prev, data map[string]map[string]interface{}) map[string]bool {
+	result := map[string]bool{}
+	for tagName, entry := range data {
+		old, ok := prev[tagName]
+		if !ok || !reflect.DeepEqual(old["value"], entry["value"]) || !reflect.DeepEqual(old["alarm"], entry["alarm"]) {
+			result[tagName] = true
+		}
+	}
+	return result
+}
+
+//with 复制数据并添加字段，同一份数据会推送给多个订阅者
+func with(entry map[string]interface{}, fields map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(entry)+len(fields))
+	for k, v := range entry {
+		result[k] = v
+	}
+	for k, v := range fields {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,26 @@
+package push
+
+import "testing"
+
+func TestChanges(t *testing.T) {
+	prev := map[string]map[string]interface{}{
+		"AI-1": {"tag": "AI-1", "value": 1.5, "alarm": ""},
+		"AI-2": {"tag": "AI-2", "value": 2.0, "alarm": ""},
+		"DI-1": {"tag": "DI-1", "value": false},
+	}
+	data := map[string]map[string]interface{}{
+		"AI-1": {"tag": "AI-1", "value": 1.5, "alarm": ""},
+		"AI-2": {"tag": "AI-2", "value": 2.0, "alarm": "HI"},
+		"DI-1": {"tag": "DI-1", "value": true},
+		"DO-1": {"tag": "DO-1", "value": false},
+	}
+
+	result := changes(prev, data)
+	if len(result) != 3 || result["AI-1"] || !result["AI-2"] || !result["DI-1"] || !result["DO-1"] {
+		t.Fatalf("unexpected changes: %v", result)
+	}
+
+	if result := changes(nil, data); len(result) != len(data) {
+		t.Fatalf("all entries should be changed without previous data, got %v", result)
+	}
+}
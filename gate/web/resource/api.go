@@ -97,6 +97,20 @@ const (
 	CommentCreate = "comment.create"
 	CommentDelete = "comment.delete"
 
+	RuleList    = "rule.list"
+	RuleCreate  = "rule.create"
+	RuleDetail  = "rule.detail"
+	RuleUpdate  = "rule.update"
+	RuleDelete  = "rule.delete"
+	RuleLogList = "rule.log.list"
+
+	WebhookList         = "webhook.list"
+	WebhookCreate       = "webhook.create"
+	WebhookDetail       = "webhook.detail"
+	WebhookUpdate       = "webhook.update"
+	WebhookDelete       = "webhook.delete"
+	WebhookDeliveryList = "webhook.delivery.list"
+
 	LogList   = "log.list"
 	LogDelete = "log.delete"
 
@@ -199,6 +213,13 @@ var (
 		CommentCreate,
 		CommentDelete,
 
+		RuleList,
+		RuleCreate,
+		RuleDetail,
+		RuleUpdate,
+		RuleDelete,
+		RuleLogList,
+
 		LogList,
 		LogDelete,
 
@@ -212,6 +233,13 @@ var (
 		OrganizationDetail,
 		OrganizationUpdate,
 		OrganizationDelete,
+
+		WebhookList,
+		WebhookCreate,
+		WebhookDetail,
+		WebhookUpdate,
+		WebhookDelete,
+		WebhookDeliveryList,
 	)
 )
 
@@ -0,0 +1,227 @@
+package rule
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/dispatch"
+	"github.com/maritimusj/centrum/gate/web/edge"
+	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/interlock"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/notify"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	"github.com/maritimusj/centrum/gate/web/shelve"
+	log "github.com/sirupsen/logrus"
+)
+
+//fire 规则被触发，检查条件后执行动作，执行结果记录到规则日志中
+func fire(ruleID int64, trigger *Trigger) {
+	rule, err := app.Store().GetRule(ruleID)
+	if err != nil {
+		Remove(ruleID)
+		return
+	}
+
+	mu.Lock()
+	e, ok := entries[ruleID]
+	if !ok || !rule.IsEnabled() {
+		mu.Unlock()
+		return
+	}
+	conf := e.conf
+	if conf.Cooldown > 0 && time.Since(e.lastRun) < time.Duration(conf.Cooldown)*time.Second {
+		mu.Unlock()
+		return
+	}
+	e.lastRun = time.Now()
+	mu.Unlock()
+
+	reason := trigger.String()
+
+	user, err := rule.User()
+	if err == nil {
+		//创建者的权限可能已经改变，每次执行前都要重新检查
+		err = CheckPerm(user, conf)
+	}
+	if err != nil {
+		rule.Logger().Warningln(lang.RuleFailed.Str(rule.Title(), reason, err))
+		return
+	}
+
+	for _, c := range conf.Conditions {
+		device, err := app.Store().GetDevice(c.Device)
+		if err != nil {
+			return
+		}
+		x, err := edge.GetCHFloatValue(device, c.Tag)
+		if err != nil || !interlock.Compare(x, c.Op, c.Value) {
+			return
+		}
+	}
+
+	actions := make([]string, 0, len(conf.Actions))
+	for _, a := range conf.Actions {
+		actions = append(actions, a.String())
+	}
+
+	logger := rule.Logger().WithField("actions", actions)
+	if conf.DryRun {
+		logger.Infoln(lang.RuleDryRun.Str(rule.Title(), reason))
+		return
+	}
+
+	for i := range conf.Actions {
+		if err := execute(user, rule, &conf.Actions[i], reason); err != nil {
+			logger.Warningln(lang.RuleFailed.Str(rule.Title(), reason, err))
+			return
+		}
+	}
+
+	logger.Infoln(lang.RuleExecuted.Str(rule.Title(), reason))
+}
+
+func execute(user model.User, rule model.Rule, action *Action, reason string) error {
+	switch action.Kind {
+	case ActionCtrl:
+		device, measure, err := getMeasure(user, action.Device, action.Tag, resource.Ctrl)
+		if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(strings.ToUpper(action.Tag), "DO-") {
+			return interlock.SetCHValue(user, device, measure, action.Value != 0)
+		}
+
+		if err := edge.SetCHValue(device, measure.TagName(), action.Value); err != nil {
+			return err
+		}
+		device.Logger().Infoln(lang.DeviceCtrlOk.Str(user.Name(), measure.Title(), strconv.FormatFloat(action.Value, 'f', -1, 64)))
+
+	case ActionAlarm:
+		device, measure, err := getMeasure(user, action.Device, action.Tag, resource.View)
+		if err != nil {
+			return err
+		}
+
+		//点位被搁置或者设备处于维护模式时不产生警报
+		if entry := shelve.Suppressed(device, measure); entry != nil {
+			return nil
+		}
+
+		//已经有未确认的报警时不再重复创建
+		_, total, err := app.Store().GetLastUnconfirmedAlarm(helper.Device(device.GetID()), helper.Measure(measure.GetID()))
+		if err != nil && err != lang.ErrAlarmNotFound.Error() {
+			return err
+		}
+		if total > 0 {
+			return nil
+		}
+
+		message := action.Message
+		if message == "" {
+			message = rule.Title()
+		}
+
+		fields := map[string]interface{}{}
+		if x, err := edge.GetCHFloatValue(device, measure.TagName()); err == nil {
+			fields["val"] = x
+		}
+
+		alarm, err := app.Store().CreateAlarm(device, measure.GetID(), app.AlarmPriority(measure, message), map[string]interface{}{
+			"name": measure.Title(),
+			"tags": map[string]interface{}{
+				"tag":   measure.TagName(),
+				"alarm": message,
+				"rule":  rule.GetID(),
+			},
+			"fields": fields,
+			"time":   time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		//和edge上报的警报一样推送、通知并触发警报规则
+		go func() {
+			_ = dispatch.OnAlarm(alarm)
+		}()
+		OnAlarm(device, measure)
+		return nil
+
+	case ActionNotify:
+		message := action.Message
+		if message == "" {
+			message = reason
+		}
+		return notifyUsers(action, rule, message)
+
+	case ActionWebhook:
+		return callWebhook(action, rule, reason)
+	}
+
+	return nil
+}
+
+//notifyUsers 通知指定的用户，没有指定用户时通知能查看设备的所有用户，没有指定渠道时使用全部渠道
+func notifyUsers(action *Action, rule model.Rule, message string) error {
+	var users []model.User
+	if len(action.Users) > 0 {
+		for _, id := range action.Users {
+			user, err := app.Store().GetUser(id)
+			if err != nil {
+				log.Debugln("[rule] notify: ", err)
+				continue
+			}
+			users = append(users, user)
+		}
+	} else {
+		device, err := app.Store().GetDevice(action.Device)
+		if err != nil {
+			return err
+		}
+
+		list, _, err := app.Store().GetUserList(helper.Organization(device.OrganizationID()))
+		if err != nil {
+			return err
+		}
+
+		for _, user := range list {
+			if app.Allow(user, device, resource.View) {
+				users = append(users, user)
+			}
+		}
+	}
+
+	msg := &notify.Message{
+		Title:   rule.Title(),
+		Content: message,
+		Data: map[string]interface{}{
+			"rule": rule.GetID(),
+		},
+	}
+	for _, user := range users {
+		notify.Send(user, msg, action.Channels...)
+	}
+	return nil
+}
+
+//callWebhook 使用和通知相同的方式发送，设置了secret时附带签名
+func callWebhook(action *Action, rule model.Rule, reason string) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"rule": map[string]interface{}{
+			"id":    rule.GetID(),
+			"title": rule.Title(),
+		},
+		"trigger": reason,
+		"time":    time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		return lang.InternalError(err)
+	}
+
+	return notify.Post(action.URL, action.Secret, data)
+}
@@ -0,0 +1,260 @@
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	"github.com/maritimusj/centrum/gate/web/webhook"
+	"github.com/robfig/cron/v3"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	//点位数值满足条件
+	TriggerThreshold = "threshold"
+	//点位报警产生或者解除
+	TriggerAlarm = "alarm"
+	//设备连接状态变化
+	TriggerStatus = "status"
+	//定时执行
+	TriggerCron = "cron"
+
+	AlarmRaised  = "raised"
+	AlarmCleared = "cleared"
+
+	StatusConnected    = "connected"
+	StatusDisconnected = "disconnected"
+
+	//控制DO/AO点位
+	ActionCtrl = "ctrl"
+	//产生一条报警
+	ActionAlarm = "alarm"
+	//推送通知
+	ActionNotify = "notify"
+	//调用webhook
+	ActionWebhook = "webhook"
+)
+
+type Trigger struct {
+	Kind   string  `json:"kind"`
+	Device int64   `json:"device"`
+	Tag    string  `json:"tag"`
+	Op     string  `json:"op"`
+	Value  float64 `json:"value"`
+	Event  string  `json:"event"`
+	Status string  `json:"status"`
+	Spec   string  `json:"spec"`
+}
+
+func (t *Trigger) String() string {
+	switch t.Kind {
+	case TriggerThreshold:
+		return fmt.Sprintf("%s %s %s %v", t.Kind, t.Tag, t.Op, t.Value)
+	case TriggerAlarm:
+		return fmt.Sprintf("%s %s %s", t.Kind, t.Tag, t.Event)
+	case TriggerStatus:
+		return fmt.Sprintf("%s %s", t.Kind, t.Status)
+	case TriggerCron:
+		return fmt.Sprintf("%s %s", t.Kind, t.Spec)
+	}
+	return t.Kind
+}
+
+//Condition 触发后还需要满足的条件，全部满足才执行动作
+type Condition struct {
+	Device int64   `json:"device"`
+	Tag    string  `json:"tag"`
+	Op     string  `json:"op"`
+	Value  float64 `json:"value"`
+}
+
+type Action struct {
+	Kind    string  `json:"kind"`
+	Device  int64   `json:"device"`
+	Tag     string  `json:"tag"`
+	Value   float64 `json:"value"`
+	Users   []int64 `json:"users"`
+	Message string  `json:"message"`
+	URL     string  `json:"url"`
+	//设置后webhook请求附带签名
+	Secret string `json:"secret"`
+	//通知渠道，为空时使用全部渠道
+	Channels []string `json:"channels"`
+}
+
+func (a *Action) String() string {
+	switch a.Kind {
+	case ActionCtrl:
+		return fmt.Sprintf("%s %d.%s=%v", a.Kind, a.Device, a.Tag, a.Value)
+	case ActionAlarm:
+		return fmt.Sprintf("%s %d.%s", a.Kind, a.Device, a.Tag)
+	case ActionWebhook:
+		return fmt.Sprintf("%s %s", a.Kind, a.URL)
+	}
+	return a.Kind
+}
+
+//Config 规则配置，保存在规则的extra中：
+//{"trigger": {"kind": "threshold", "device": 1, "tag": "AI-1", "op": ">", "value": 3.5},
+// "conditions": [{"device": 1, "tag": "AI-2", "op": "<", "value": 10}],
+// "actions": [{"kind": "ctrl", "device": 1, "tag": "DO-1", "value": 1}],
+// "dryRun": false, "cooldown": 60}
+type Config struct {
+	Trigger    Trigger     `json:"trigger"`
+	Conditions []Condition `json:"conditions"`
+	Actions    []Action    `json:"actions"`
+	DryRun     bool        `json:"dryRun"`
+	Cooldown   int         `json:"cooldown"`
+}
+
+func isOp(op string) bool {
+	switch op {
+	case ">", ">=", "<", "<=", "==", "!=":
+		return true
+	}
+	return false
+}
+
+//Parse 解析并检查规则配置
+func Parse(data gjson.Result) (*Config, error) {
+	var conf Config
+	if err := json.Unmarshal([]byte(data.Raw), &conf); err != nil {
+		return nil, lang.ErrInvalidRule.Error(err)
+	}
+
+	t := &conf.Trigger
+	switch t.Kind {
+	case TriggerThreshold:
+		if t.Device == 0 || t.Tag == "" || !isOp(t.Op) {
+			return nil, lang.ErrInvalidRule.Error(t)
+		}
+	case TriggerAlarm:
+		if t.Device == 0 || t.Tag == "" || (t.Event != AlarmRaised && t.Event != AlarmCleared) {
+			return nil, lang.ErrInvalidRule.Error(t)
+		}
+	case TriggerStatus:
+		if t.Device == 0 || (t.Status != StatusConnected && t.Status != StatusDisconnected) {
+			return nil, lang.ErrInvalidRule.Error(t)
+		}
+	case TriggerCron:
+		if _, err := cron.ParseStandard(t.Spec); err != nil {
+			return nil, lang.ErrInvalidRule.Error(err)
+		}
+	default:
+		return nil, lang.ErrInvalidRule.Error(t)
+	}
+
+	for _, c := range conf.Conditions {
+		if c.Device == 0 || c.Tag == "" || !isOp(c.Op) {
+			return nil, lang.ErrInvalidRule.Error(c.Tag)
+		}
+	}
+
+	if len(conf.Actions) == 0 {
+		return nil, lang.ErrInvalidRule.Error("actions")
+	}
+
+	for _, a := range conf.Actions {
+		switch a.Kind {
+		case ActionCtrl:
+			tag := strings.ToUpper(a.Tag)
+			if a.Device == 0 || (!strings.HasPrefix(tag, "DO-") && !strings.HasPrefix(tag, "AO-")) {
+				return nil, lang.ErrInvalidRule.Error(&a)
+			}
+		case ActionAlarm:
+			if a.Device == 0 || a.Tag == "" {
+				return nil, lang.ErrInvalidRule.Error(&a)
+			}
+		case ActionNotify:
+			if a.Device == 0 && len(a.Users) == 0 {
+				return nil, lang.ErrInvalidRule.Error(&a)
+			}
+		case ActionWebhook:
+			if err := webhook.Check(a.URL, nil); err != nil {
+				return nil, lang.ErrInvalidRule.Error(a.URL)
+			}
+		default:
+			return nil, lang.ErrInvalidRule.Error(&a)
+		}
+	}
+
+	return &conf, nil
+}
+
+//getMeasure 获取规则引用的点位，并检查用户是否有相应的权限
+func getMeasure(user model.User, deviceID int64, tag string, action resource.Action) (model.Device, model.Measure, error) {
+	device, err := app.Store().GetDevice(deviceID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if device.OrganizationID() != user.OrganizationID() && !app.IsDefaultAdminUser(user) {
+		return nil, nil, lang.ErrNoPermission.Error()
+	}
+
+	measure, err := app.Store().GetMeasureFromTagName(device.GetID(), tag)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !app.Allow(user, measure, action) {
+		return nil, nil, lang.ErrNoPermission.Error()
+	}
+
+	return device, measure, nil
+}
+
+//CheckPerm 检查用户对规则引用的设备和点位是否有足够的权限，保存和执行规则时都会检查
+func CheckPerm(user model.User, conf *Config) error {
+	t := &conf.Trigger
+	switch t.Kind {
+	case TriggerThreshold, TriggerAlarm:
+		if _, _, err := getMeasure(user, t.Device, t.Tag, resource.View); err != nil {
+			return err
+		}
+	case TriggerStatus:
+		device, err := app.Store().GetDevice(t.Device)
+		if err != nil {
+			return err
+		}
+		if !app.Allow(user, device, resource.View) {
+			return lang.ErrNoPermission.Error()
+		}
+	}
+
+	for _, c := range conf.Conditions {
+		if _, _, err := getMeasure(user, c.Device, c.Tag, resource.View); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range conf.Actions {
+		switch a.Kind {
+		case ActionCtrl:
+			if _, _, err := getMeasure(user, a.Device, a.Tag, resource.Ctrl); err != nil {
+				return err
+			}
+		case ActionAlarm:
+			if _, _, err := getMeasure(user, a.Device, a.Tag, resource.View); err != nil {
+				return err
+			}
+		case ActionNotify:
+			if a.Device > 0 {
+				device, err := app.Store().GetDevice(a.Device)
+				if err != nil {
+					return err
+				}
+				if !app.Allow(user, device, resource.View) {
+					return lang.ErrNoPermission.Error()
+				}
+			}
+		}
+	}
+
+	return nil
+}
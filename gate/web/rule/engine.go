@@ -0,0 +1,235 @@
+package rule
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	edgeLang "github.com/maritimusj/centrum/edge/lang"
+	gateEvent "github.com/maritimusj/centrum/gate/event"
+	"github.com/maritimusj/centrum/gate/web/alarm"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/edge"
+	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/interlock"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/status"
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	//检查数值条件的间隔
+	pollInterval = 5 * time.Second
+)
+
+type entry struct {
+	id     int64
+	conf   *Config
+	cronID cron.EntryID
+
+	//上一次检查时数值条件是否满足，只在条件从不满足变为满足时触发
+	matched bool
+	lastRun time.Time
+}
+
+var (
+	entries   = map[int64]*entry{}
+	scheduler = cron.New()
+	mu        sync.Mutex
+)
+
+//Start 加载所有启用的规则，开始定时任务和数值条件检查
+func Start(ctx context.Context) {
+	rules, _, err := app.Store().GetRuleList(helper.Status(int64(status.Enable)))
+	if err != nil {
+		log.Errorln("[rule] load rules: ", err)
+	}
+
+	for _, rule := range rules {
+		Reload(rule)
+	}
+
+	scheduler.Start()
+
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				scheduler.Stop()
+				return
+			case <-ticker.C:
+				checkThresholds()
+				checkClearedAlarms()
+			}
+		}
+	}()
+}
+
+//Reload 规则创建或者修改后重新加载，禁用的规则只会被移除
+func Reload(rule model.Rule) {
+	Remove(rule.GetID())
+
+	if !rule.IsEnabled() {
+		return
+	}
+
+	conf, err := Parse(rule.GetOption("@this"))
+	if err != nil {
+		rule.Logger().Warningln(err)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	e := &entry{
+		id:   rule.GetID(),
+		conf: conf,
+	}
+
+	if conf.Trigger.Kind == TriggerCron {
+		e.cronID, err = scheduler.AddFunc(conf.Trigger.Spec, func() {
+			fire(e.id, &conf.Trigger)
+		})
+		if err != nil {
+			rule.Logger().Warningln(err)
+			return
+		}
+	}
+
+	entries[e.id] = e
+}
+
+//Remove 停止一个规则
+func Remove(ruleID int64) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if e, ok := entries[ruleID]; ok {
+		if e.cronID > 0 {
+			scheduler.Remove(e.cronID)
+		}
+		delete(entries, ruleID)
+	}
+}
+
+//find 查找符合条件的规则
+func find(fn func(e *entry) bool) []*entry {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var result []*entry
+	for _, e := range entries {
+		if fn(e) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+//OnAlarm 点位上产生了新的警报，或者已经消失的警报再次出现
+func OnAlarm(device model.Device, measure model.Measure) {
+	onAlarmEvent(device.GetID(), measure.TagName(), AlarmRaised)
+}
+
+func onAlarmEvent(deviceID int64, tag string, event string) {
+	for _, e := range find(func(e *entry) bool {
+		t := &e.conf.Trigger
+		return t.Kind == TriggerAlarm && t.Device == deviceID && strings.EqualFold(t.Tag, tag) && t.Event == event
+	}) {
+		go fire(e.id, &e.conf.Trigger)
+	}
+}
+
+//checkClearedAlarms 从警报记录中找出超过ClearTimeout没有再上报的点位，记录消失时间，gate重启后也能继续判断
+func checkClearedAlarms() {
+	alarms, _, err := app.Store().GetAlarmList(nil, nil, helper.Uncleared())
+	if err != nil {
+		log.Errorln("[rule] load uncleared alarms: ", err)
+		return
+	}
+
+	//同一个点位可能有多条还没有消失的警报（确认后再次上报会产生新的警报），以最后一次上报为准
+	lastSeen := map[int64]model.Alarm{}
+	for _, a := range alarms {
+		if last, ok := lastSeen[a.MeasureID()]; !ok || a.UpdatedAt().After(last.UpdatedAt()) {
+			lastSeen[a.MeasureID()] = a
+		}
+	}
+
+	for _, a := range lastSeen {
+		device, err := a.Device()
+		if err != nil {
+			continue
+		}
+
+		if time.Since(a.UpdatedAt()) <= alarm.ClearTimeout(device) {
+			continue
+		}
+
+		n, err := app.Store().ClearAlarms(a.DeviceID(), a.MeasureID(), a.UpdatedAt())
+		if err != nil {
+			log.Errorln("[rule] clear alarms: ", err)
+			continue
+		}
+
+		if n > 0 {
+			if measure, err := a.Measure(); err == nil {
+				onAlarmEvent(a.DeviceID(), measure.TagName(), AlarmCleared)
+			}
+			app.Event.Publish(gateEvent.AlarmCleared, a.DeviceID(), a.MeasureID(), a.UpdatedAt())
+		}
+	}
+}
+
+//OnDeviceStatus 设备连接状态变化
+func OnDeviceStatus(device model.Device, org, index int) {
+	var event string
+	if index == int(edgeLang.Connected) && org != int(edgeLang.Connected) {
+		event = StatusConnected
+	} else if index == int(edgeLang.Disconnected) && org == int(edgeLang.Connected) {
+		event = StatusDisconnected
+	} else {
+		return
+	}
+
+	for _, e := range find(func(e *entry) bool {
+		t := &e.conf.Trigger
+		return t.Kind == TriggerStatus && t.Device == device.GetID() && t.Status == event
+	}) {
+		go fire(e.id, &e.conf.Trigger)
+	}
+}
+
+func checkThresholds() {
+	for _, e := range find(func(e *entry) bool {
+		return e.conf.Trigger.Kind == TriggerThreshold
+	}) {
+		t := &e.conf.Trigger
+		device, err := app.Store().GetDevice(t.Device)
+		if err != nil {
+			continue
+		}
+
+		x, err := edge.GetCHFloatValue(device, t.Tag)
+		if err != nil {
+			continue
+		}
+
+		matched := interlock.Compare(x, t.Op, t.Value)
+
+		mu.Lock()
+		fired := matched && !e.matched
+		e.matched = matched
+		mu.Unlock()
+
+		if fired {
+			go fire(e.id, t)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package shelve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/logStore"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/model"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	//搁置点位
+	Measure = "measure"
+	//物理设备和虚拟设备的维护模式
+	Device    = "device"
+	Equipment = "equipment"
+
+	//最长的搁置或者维护时间，到期后自动恢复
+	MaxDuration = 7 * 24 * time.Hour
+
+	//检查到期的间隔
+	checkInterval = 30 * time.Second
+)
+
+//Entry 搁置或者维护记录
+type Entry struct {
+	Class  string    `json:"class"`
+	ID     int64     `json:"id"`
+	OrgID  int64     `json:"org"`
+	Title  string    `json:"title"`
+	Reason string    `json:"reason"`
+	User   string    `json:"user"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+	//警报反复出现时自动搁置
+	Chattering bool `json:"chattering"`
+}
+
+func (e *Entry) key() string {
+	return key(e.Class, e.ID)
+}
+
+//Brief 列表中显示的标记
+func (e *Entry) Brief() model.Map {
+	return model.Map{
+		"class":      e.Class,
+		"id":         e.ID,
+		"title":      e.Title,
+		"reason":     e.Reason,
+		"user":       e.User,
+		"since":      e.Since.Format(lang.DatetimeFormatterStr.Str()),
+		"until":      e.Until.Format(lang.DatetimeFormatterStr.Str()),
+		"chattering": e.Chattering,
+	}
+}
+
+var (
+	entries = map[string]*Entry{}
+	mu      sync.RWMutex
+)
+
+func key(class string, id int64) string {
+	return fmt.Sprintf("%s:%d", class, id)
+}
+
+//Load 加载保存的搁置和维护记录
+func Load() error {
+	list := make([]*Entry, 0)
+	if data := app.Config.AlarmShelves(); data != "" {
+		if err := json.Unmarshal([]byte(data), &list); err != nil {
+			return err
+		}
+	}
+
+	mu.Lock()
+	entries = map[string]*Entry{}
+	for _, e := range list {
+		entries[e.key()] = e
+	}
+	mu.Unlock()
+	return nil
+}
+
+//Start 加载记录并定时移除到期的搁置和维护
+func Start(ctx context.Context) {
+	if err := Load(); err != nil {
+		log.Errorln("[shelve] load: ", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				expire(time.Now())
+			}
+		}
+	}()
+}
+
+//save 保存全部记录，调用时需要持有锁
+func save() error {
+	list := make([]*Entry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e)
+	}
+	return app.Config.SaveAlarmShelves(list)
+}
+
+func expire(now time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var expired []*Entry
+	for k, e := range entries {
+		if !now.Before(e.Until) {
+			delete(entries, k)
+			expired = append(expired, e)
+		}
+	}
+
+	if len(expired) == 0 {
+		return
+	}
+
+	if err := save(); err != nil {
+		log.Errorln("[shelve] save: ", err)
+	}
+
+	for _, e := range expired {
+		log.WithFields(log.Fields{
+			"org": e.OrgID,
+			"src": logStore.SystemLog,
+		}).Infoln(lang.ShelveExpired.Str(e.Title))
+	}
+}
+
+//Set 搁置点位或者设置维护模式，已有的记录会被替换
+func Set(class string, id int64, orgID int64, title string, user model.User, minutes int, reason string) (*Entry, error) {
+	duration := time.Duration(minutes) * time.Minute
+	if duration <= 0 || duration > MaxDuration {
+		return nil, lang.ErrInvalidShelveDuration.Error(int(MaxDuration / time.Minute))
+	}
+
+	now := time.Now()
+	e := &Entry{
+		Class:  class,
+		ID:     id,
+		OrgID:  orgID,
+		Title:  title,
+		Reason: reason,
+		User:   user.Name(),
+		Since:  now,
+		Until:  now.Add(duration),
+	}
+
+	return e, add(e)
+}
+
+//Chattering 自动搁置警报反复出现的点位，已经搁置的点位不处理
+func Chattering(measure model.Measure, duration time.Duration) (*Entry, error) {
+	if e := Get(Measure, measure.GetID()); e != nil {
+		return e, nil
+	}
+
+	now := time.Now()
+	e := &Entry{
+		Class:      Measure,
+		ID:         measure.GetID(),
+		OrgID:      measure.OrganizationID(),
+		Title:      measure.Title(),
+		Reason:     lang.AlarmChatteringReason.Str(),
+		Since:      now,
+		Until:      now.Add(duration),
+		Chattering: true,
+	}
+
+	return e, add(e)
+}
+
+func add(e *Entry) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	entries[e.key()] = e
+	return save()
+}
+
+//Remove 取消搁置或者维护模式
+func Remove(class string, id int64) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	k := key(class, id)
+	if _, ok := entries[k]; !ok {
+		return lang.ErrShelveNotFound.Error()
+	}
+
+	delete(entries, k)
+	return save()
+}
+
+//Get 有效的搁置或者维护记录，没有时返回nil
+func Get(class string, id int64) *Entry {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if e, ok := entries[key(class, id)]; ok && time.Now().Before(e.Until) {
+		return e
+	}
+	return nil
+}
+
+//List 指定组织有效的搁置和维护记录，orgID为0时返回全部
+func List(orgID int64) []*Entry {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	now := time.Now()
+	list := make([]*Entry, 0, len(entries))
+	for _, e := range entries {
+		if now.Before(e.Until) && (orgID == 0 || e.OrgID == orgID) {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+//Suppressed 点位被搁置，或者所属的物理设备、虚拟设备处于维护模式时返回对应的记录
+func Suppressed(device model.Device, measure model.Measure) *Entry {
+	if e := Get(Measure, measure.GetID()); e != nil {
+		return e
+	}
+	if e := Get(Device, device.GetID()); e != nil {
+		return e
+	}
+
+	for _, e := range List(device.OrganizationID()) {
+		if e.Class != Equipment {
+			continue
+		}
+		states, _, err := app.Store().GetStateList(helper.Equipment(e.ID))
+		if err != nil {
+			continue
+		}
+		for _, state := range states {
+			if m := state.Measure(); m != nil && m.GetID() == measure.GetID() {
+				return e
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package shelve
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maritimusj/centrum/gate/config"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func initStore(t *testing.T) {
+	app.Ctx = context.Background()
+	if err := app.InitDB(map[string]interface{}{
+		"connStr": filepath.Join(t.TempDir(), "test.db"),
+		"initDB":  true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	app.Config = config.New(app.Store())
+	if err := app.Config.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	entries = map[string]*Entry{}
+	mu.Unlock()
+}
+
+func TestExpire(t *testing.T) {
+	initStore(t)
+
+	s := app.Store()
+	org, err := s.CreateOrganization("test", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.CreateUser(org, "user", []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, minutes := range []int{0, -1, int(MaxDuration/time.Minute) + 1} {
+		if _, err := Set(Measure, 1, org.GetID(), "AI-1", user, minutes, ""); err == nil {
+			t.Fatalf("%d minutes should be rejected", minutes)
+		}
+	}
+
+	e, err := Set(Measure, 1, org.GetID(), "AI-1", user, 10, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Get(Measure, 1) != e || len(List(org.GetID())) != 1 || len(List(org.GetID()+1)) != 0 {
+		t.Fatal("measure should be shelved")
+	}
+
+	//到期前不移除，到期后移除并保存
+	expire(e.Until.Add(-time.Second))
+	if Get(Measure, 1) == nil {
+		t.Fatal("shelve should not expire before until")
+	}
+	expire(e.Until)
+	if Get(Measure, 1) != nil || len(List(0)) != 0 {
+		t.Fatal("shelve should expire")
+	}
+
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(List(0)) != 0 {
+		t.Fatal("expired shelve should not be saved")
+	}
+
+	if err := Remove(Measure, 1); err == nil {
+		t.Fatal("removing an expired shelve should fail")
+	}
+}
+
+func TestSuppressed(t *testing.T) {
+	initStore(t)
+
+	s := app.Store()
+	org, err := s.CreateOrganization("test", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.CreateUser(org, "user", []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	device, err := s.CreateDevice(org, "device", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m1, err := device.CreateMeasure("AI-1", "AI-1", resource.AI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2, err := device.CreateMeasure("AI-2", "AI-2", resource.AI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equipment, err := s.CreateEquipment(org, "equipment", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := equipment.CreateState("state", "", m2, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if Suppressed(device, m1) != nil || Suppressed(device, m2) != nil {
+		t.Fatal("nothing should be suppressed")
+	}
+
+	//搁置点位只影响这个点位
+	if _, err := Set(Measure, m1.GetID(), org.GetID(), m1.Title(), user, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+	if e := Suppressed(device, m1); e == nil || e.Class != Measure {
+		t.Fatal("shelved measure should be suppressed")
+	}
+	if Suppressed(device, m2) != nil {
+		t.Fatal("other measures should not be suppressed")
+	}
+	if err := Remove(Measure, m1.GetID()); err != nil {
+		t.Fatal(err)
+	}
+
+	//虚拟设备维护时只影响关联的点位
+	if _, err := Set(Equipment, equipment.GetID(), org.GetID(), equipment.Title(), user, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+	if Suppressed(device, m1) != nil {
+		t.Fatal("measure not in equipment should not be suppressed")
+	}
+	if e := Suppressed(device, m2); e == nil || e.Class != Equipment {
+		t.Fatal("measure in equipment under maintenance should be suppressed")
+	}
+	if err := Remove(Equipment, equipment.GetID()); err != nil {
+		t.Fatal(err)
+	}
+
+	//物理设备维护时影响全部点位，到期后恢复
+	e, err := Set(Device, device.GetID(), org.GetID(), device.Title(), user, 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Suppressed(device, m1) == nil || Suppressed(device, m2) == nil {
+		t.Fatal("all measures of device under maintenance should be suppressed")
+	}
+	expire(e.Until)
+	if Suppressed(device, m1) != nil || Suppressed(device, m2) != nil {
+		t.Fatal("maintenance should expire")
+	}
+}
@@ -10,3 +10,26 @@ const (
 	Unconfirmed = iota
 	Confirmed
 )
+
+//message status
+const (
+	Unread = iota
+	Read
+)
+
+//webhook delivery status
+const (
+	Pending = iota
+	Delivered
+	Failed
+)
+
+//alarm priority，数值越大越紧急，0表示未设置
+const (
+	PriorityNone = iota
+	PriorityAdvisory
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityCritical
+)
@@ -1,10 +1,12 @@
 package mysqlStore
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/kataras/iris"
 	"github.com/maritimusj/centrum/gate/lang"
+	alarmDef "github.com/maritimusj/centrum/gate/web/alarm"
 	"github.com/maritimusj/centrum/gate/web/dirty"
 	"github.com/maritimusj/centrum/gate/web/model"
 	"github.com/maritimusj/centrum/gate/web/status"
@@ -19,10 +21,12 @@ type Alarm struct {
 	status    int
 	deviceID  int64
 	measureID int64
+	priority  int
 
 	extra     []byte
 	createdAt time.Time
 	updatedAt time.Time
+	clearedAt sql.NullTime
 
 	dirty *dirty.Dirty
 	store *mysqlStore
@@ -52,6 +56,23 @@ func (alarm *Alarm) Status() (int, string) {
 	return alarm.status, lang.AlarmStatusDesc(alarm.status)
 }
 
+//Priority 警报优先级，旧版本的警报没有保存优先级，按警报等级得到默认的优先级
+func (alarm *Alarm) Priority() int {
+	if alarm.priority == status.PriorityNone {
+		return alarmDef.DefaultPriority(alarm.GetOption("tags.alarm").String())
+	}
+	return alarm.priority
+}
+
+func (alarm *Alarm) SetPriority(priority int) {
+	if alarm.priority != priority {
+		alarm.priority = priority
+		alarm.dirty.Set("priority", func() interface{} {
+			return alarm.priority
+		})
+	}
+}
+
 func (alarm *Alarm) Confirm(data map[string]interface{}) error {
 	if err := alarm.SetOption("confirm", data); err != nil {
 		return err
@@ -79,11 +100,33 @@ func (alarm *Alarm) UpdatedAt() time.Time {
 	return alarm.updatedAt
 }
 
+//Updated 警报再次上报，已经记录了消失时间的警报重新变为存在
 func (alarm *Alarm) Updated() {
 	alarm.updatedAt = time.Now()
 	alarm.dirty.Set("updated_at", func() interface{} {
 		return alarm.updatedAt
 	})
+
+	if alarm.clearedAt.Valid {
+		alarm.clearedAt = sql.NullTime{}
+		alarm.dirty.Set("cleared_at", func() interface{} {
+			return nil
+		})
+	}
+}
+
+func (alarm *Alarm) ClearedAt() time.Time {
+	if alarm.clearedAt.Valid {
+		return alarm.clearedAt.Time
+	}
+	return time.Time{}
+}
+
+func (alarm *Alarm) Cleared(at time.Time) {
+	alarm.clearedAt = sql.NullTime{Time: at, Valid: true}
+	alarm.dirty.Set("cleared_at", func() interface{} {
+		return alarm.clearedAt.Time
+	})
 }
 
 func (alarm *Alarm) Save() error {
@@ -126,11 +169,13 @@ func (alarm *Alarm) Simple() model.Map {
 	device, _ := alarm.Device()
 	measure, _ := alarm.Measure()
 	return model.Map{
-		"id":          alarm.GetID(),
-		"status":      alarm.status,
-		"status_desc": lang.AlarmStatusDesc(alarm.status),
-		"device":      device.Brief(),
-		"measure":     measure.Brief(),
+		"id":            alarm.GetID(),
+		"status":        alarm.status,
+		"status_desc":   lang.AlarmStatusDesc(alarm.status),
+		"priority":      alarm.Priority(),
+		"priority_desc": lang.AlarmPriorityDesc(alarm.Priority()),
+		"device":        device.Brief(),
+		"measure":       measure.Brief(),
 		"raw": iris.Map{
 			"alarm": alarm.GetOption("tags.alarm").String(),
 			"val":   alarm.GetOption("fields.val").String(),
@@ -156,28 +201,36 @@ func (alarm *Alarm) Brief() model.Map {
 	}
 
 	return model.Map{
-		"id":          alarm.GetID(),
-		"status":      alarm.status,
-		"status_desc": lang.AlarmStatusDesc(alarm.status),
-		"device":      deviceBrief(),
-		"measure":     measureBrief(),
-		"raw":         alarm.Option(),
-		"created_at":  alarm.createdAt.Format(lang.DatetimeFormatterStr.Str()),
-		"updated_at":  alarm.updatedAt.Format(lang.DatetimeFormatterStr.Str()),
+		"id":            alarm.GetID(),
+		"status":        alarm.status,
+		"status_desc":   lang.AlarmStatusDesc(alarm.status),
+		"priority":      alarm.Priority(),
+		"priority_desc": lang.AlarmPriorityDesc(alarm.Priority()),
+		"device":        deviceBrief(),
+		"measure":       measureBrief(),
+		"raw":           alarm.Option(),
+		"created_at":    alarm.createdAt.Format(lang.DatetimeFormatterStr.Str()),
+		"updated_at":    alarm.updatedAt.Format(lang.DatetimeFormatterStr.Str()),
 	}
 }
 
 func (alarm *Alarm) Detail() model.Map {
 	device, _ := alarm.Device()
 	measure, _ := alarm.Measure()
-	return model.Map{
-		"id":          alarm.GetID(),
-		"status":      alarm.status,
-		"status_desc": lang.AlarmStatusDesc(alarm.status),
-		"device":      device.Brief(),
-		"measure":     measure.Brief(),
-		"raw":         alarm.Option(),
-		"created_at":  alarm.createdAt.Format(lang.DatetimeFormatterStr.Str()),
-		"updated_at":  alarm.updatedAt.Format(lang.DatetimeFormatterStr.Str()),
+	detail := model.Map{
+		"id":            alarm.GetID(),
+		"status":        alarm.status,
+		"status_desc":   lang.AlarmStatusDesc(alarm.status),
+		"priority":      alarm.Priority(),
+		"priority_desc": lang.AlarmPriorityDesc(alarm.Priority()),
+		"device":        device.Brief(),
+		"measure":       measure.Brief(),
+		"raw":           alarm.Option(),
+		"created_at":    alarm.createdAt.Format(lang.DatetimeFormatterStr.Str()),
+		"updated_at":    alarm.updatedAt.Format(lang.DatetimeFormatterStr.Str()),
+	}
+	if alarm.clearedAt.Valid {
+		detail["cleared_at"] = alarm.clearedAt.Time.Format(lang.DatetimeFormatterStr.Str())
 	}
+	return detail
 }
@@ -0,0 +1,171 @@
+package mysqlStore
+
+import (
+	"time"
+
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/web/dirty"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/status"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+type Message struct {
+	id     int64
+	userID int64
+	status int
+
+	level   string
+	title   string
+	content string
+
+	extra     []byte
+	createdAt time.Time
+
+	dirty *dirty.Dirty
+	store *mysqlStore
+}
+
+func NewMessage(s *mysqlStore, id int64) *Message {
+	return &Message{
+		id:    id,
+		dirty: dirty.New(),
+		store: s,
+	}
+}
+
+func (m *Message) GetID() int64 {
+	return m.id
+}
+
+func (m *Message) UserID() int64 {
+	return m.userID
+}
+
+func (m *Message) User() (model.User, error) {
+	return m.store.GetUser(m.userID)
+}
+
+func (m *Message) Level() string {
+	return m.level
+}
+
+func (m *Message) Title() string {
+	return m.title
+}
+
+func (m *Message) Content() string {
+	return m.content
+}
+
+func (m *Message) IsRead() bool {
+	return m.status == status.Read
+}
+
+func (m *Message) MarkRead() {
+	if m.status != status.Read {
+		m.status = status.Read
+		m.dirty.Set("status", func() interface{} {
+			return m.status
+		})
+	}
+}
+
+func (m *Message) CreatedAt() time.Time {
+	return m.createdAt
+}
+
+func (m *Message) Save() error {
+	if m.dirty.Any() {
+		err := SaveData(m.store.db, TbMessages, m.dirty.Data(true), "id=?", m.id)
+		if err != nil {
+			return lang.InternalError(err)
+		}
+	}
+	return nil
+}
+
+func (m *Message) Destroy() error {
+	if m == nil {
+		return lang.ErrMessageNotFound.Error()
+	}
+	return m.store.RemoveMessage(m.id)
+}
+
+func (m *Message) Option() map[string]interface{} {
+	if v, ok := gjson.ParseBytes(m.extra).Value().(map[string]interface{}); ok {
+		return v
+	}
+	return map[string]interface{}{}
+}
+
+func (m *Message) GetOption(path string) gjson.Result {
+	if m != nil {
+		return gjson.GetBytes(m.extra, path)
+	}
+	return gjson.Result{}
+}
+
+func (m *Message) SetOption(path string, value interface{}) error {
+	if m != nil {
+		data, err := sjson.SetBytes(m.extra, path, value)
+		if err != nil {
+			return err
+		}
+
+		m.extra = data
+		m.dirty.Set("extra", func() interface{} {
+			return m.extra
+		})
+
+		return nil
+	}
+
+	return lang.ErrMessageNotFound.Error()
+}
+
+func (m *Message) Simple() model.Map {
+	if m == nil {
+		return model.Map{}
+	}
+
+	return model.Map{
+		"id":         m.id,
+		"level":      m.level,
+		"title":      m.title,
+		"message":    m.content,
+		"created_at": m.createdAt.Format(lang.DatetimeFormatterStr.Str()),
+	}
+}
+
+func (m *Message) Brief() model.Map {
+	if m == nil {
+		return model.Map{}
+	}
+
+	return model.Map{
+		"id":         m.id,
+		"level":      m.level,
+		"title":      m.title,
+		"message":    m.content,
+		"read":       m.IsRead(),
+		"created_at": m.createdAt.Format(lang.DatetimeFormatterStr.Str()),
+	}
+}
+
+func (m *Message) Detail() model.Map {
+	if m == nil {
+		return model.Map{}
+	}
+
+	return model.Map{
+		"id":         m.id,
+		"level":      m.level,
+		"title":      m.title,
+		"message":    m.content,
+		"read":       m.IsRead(),
+		"data":       m.Option(),
+		"created_at": m.createdAt.Format(lang.DatetimeFormatterStr.Str()),
+	}
+}
@@ -0,0 +1,204 @@
+package mysqlStore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/web/dirty"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/status"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+type Rule struct {
+	id     int64
+	orgID  int64
+	userID int64
+
+	enable    int8
+	title     string
+	extra     []byte
+	createdAt time.Time
+
+	dirty *dirty.Dirty
+	store *mysqlStore
+}
+
+func NewRule(s *mysqlStore, id int64) *Rule {
+	return &Rule{
+		id:    id,
+		dirty: dirty.New(),
+		store: s,
+	}
+}
+
+func (r *Rule) GetID() int64 {
+	return r.id
+}
+
+func (r *Rule) OrganizationID() int64 {
+	return r.orgID
+}
+
+func (r *Rule) Organization() (model.Organization, error) {
+	return r.store.GetOrganization(r.orgID)
+}
+
+func (r *Rule) UserID() int64 {
+	return r.userID
+}
+
+func (r *Rule) User() (model.User, error) {
+	return r.store.GetUser(r.userID)
+}
+
+func (r *Rule) UID() string {
+	return fmt.Sprintf("rule:%d", r.id)
+}
+
+func (r *Rule) Logger() *log.Entry {
+	return log.WithFields(log.Fields{
+		"org": r.OrganizationID(),
+		"src": r.UID(),
+	})
+}
+
+func (r *Rule) CreatedAt() time.Time {
+	return r.createdAt
+}
+
+func (r *Rule) Enable() {
+	if r.enable != status.Enable {
+		r.enable = status.Enable
+		r.dirty.Set("enable", func() interface{} {
+			return r.enable
+		})
+	}
+}
+
+func (r *Rule) Disable() {
+	if r.enable != status.Disable {
+		r.enable = status.Disable
+		r.dirty.Set("enable", func() interface{} {
+			return r.enable
+		})
+	}
+}
+
+func (r *Rule) IsEnabled() bool {
+	return r.enable == status.Enable
+}
+
+func (r *Rule) Title() string {
+	return r.title
+}
+
+func (r *Rule) SetTitle(title string) {
+	if r.title != title {
+		r.title = title
+		r.dirty.Set("title", func() interface{} {
+			return r.title
+		})
+	}
+}
+
+func (r *Rule) Save() error {
+	if r != nil {
+		if r.dirty.Any() {
+			err := SaveData(r.store.db, TbRules, r.dirty.Data(true), "id=?", r.id)
+			if err != nil {
+				return lang.InternalError(err)
+			}
+		}
+		return nil
+	}
+	return lang.ErrRuleNotFound.Error()
+}
+
+func (r *Rule) Destroy() error {
+	if r == nil {
+		return lang.ErrRuleNotFound.Error()
+	}
+	return r.store.RemoveRule(r.id)
+}
+
+func (r *Rule) Option() map[string]interface{} {
+	if v, ok := gjson.ParseBytes(r.extra).Value().(map[string]interface{}); ok {
+		return v
+	}
+	return map[string]interface{}{}
+}
+
+func (r *Rule) GetOption(path string) gjson.Result {
+	if r != nil {
+		return gjson.GetBytes(r.extra, path)
+	}
+	return gjson.Result{}
+}
+
+func (r *Rule) SetOption(path string, value interface{}) error {
+	if r != nil {
+		data, err := sjson.SetBytes(r.extra, path, value)
+		if err != nil {
+			return err
+		}
+
+		r.extra = data
+		r.dirty.Set("extra", func() interface{} {
+			return r.extra
+		})
+
+		return nil
+	}
+
+	return lang.ErrRuleNotFound.Error()
+}
+
+func (r *Rule) Simple() model.Map {
+	if r == nil {
+		return model.Map{}
+	}
+	return model.Map{
+		"id":     r.id,
+		"enable": r.IsEnabled(),
+		"title":  r.title,
+	}
+}
+
+func (r *Rule) Brief() model.Map {
+	if r == nil {
+		return model.Map{}
+	}
+	brief := model.Map{
+		"id":         r.id,
+		"enable":     r.IsEnabled(),
+		"title":      r.title,
+		"dryRun":     r.GetOption("dryRun").Bool(),
+		"trigger":    r.GetOption("trigger").Value(),
+		"created_at": r.createdAt.Format(lang.DatetimeFormatterStr.Str()),
+	}
+	if user, err := r.User(); err == nil {
+		brief["user"] = user.Simple()
+	}
+	return brief
+}
+
+func (r *Rule) Detail() model.Map {
+	if r == nil {
+		return model.Map{}
+	}
+	detail := model.Map{
+		"id":         r.id,
+		"enable":     r.IsEnabled(),
+		"title":      r.title,
+		"params":     r.Option(),
+		"created_at": r.createdAt.Format(lang.DatetimeFormatterStr.Str()),
+	}
+	if user, err := r.User(); err == nil {
+		detail["user"] = user.Simple()
+	}
+	return detail
+}
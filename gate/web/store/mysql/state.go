@@ -273,6 +273,15 @@ func (s *State) DisableAlarmEntry(name string) {
 	_ = s.SetOption("__alarm."+name+".enable", false)
 }
 
+//AlarmPriority 关联点位警报的优先级名称，没有设置时返回空字符串
+func (s *State) AlarmPriority() string {
+	return s.GetOption("__alarm.priority").String()
+}
+
+func (s *State) SetAlarmPriority(name string) {
+	_ = s.SetOption("__alarm.priority", name)
+}
+
 func (s *State) Simple() model.Map {
 	if s == nil {
 		return model.Map{}
@@ -312,6 +321,7 @@ func (s *State) Detail() model.Map {
 			"deadband": s.AlarmDeadBand(),
 			"delay":    s.AlarmDelaySecond(),
 			"entries":  s.GetAlarmEntries(),
+			"priority": s.AlarmPriority(),
 		},
 		"created_at": s.createdAt.Format(lang.DatetimeFormatterStr.Str()),
 	}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/kataras/iris"
@@ -42,6 +43,10 @@ const (
 	TbApiResources    = "`api_resources`"
 	TbAlarms          = "`alarms`"
 	TbComments        = "`comments`"
+	TbRules           = "`rules`"
+	TbMessages        = "`messages`"
+	TbWebhooks        = "`webhooks`"
+	TbDeliveries      = "`deliveries`"
 )
 
 type mysqlStore struct {
@@ -91,6 +96,7 @@ func (s *mysqlStore) EraseAllData() error {
 		"DELETE FROM " + TbEquipmentGroups,
 		"DELETE FROM " + TbApiResources,
 		"DELETE FROM " + TbAlarms,
+		"DELETE FROM " + TbRules,
 		"UPDATE `sqlite_sequence` SET seq = 0",
 	}
 	for _, st := range statements {
@@ -2125,6 +2131,11 @@ WHERE p.role_id IN (SELECT role_id FROM %s WHERE user_id=%d)
 		params = append(params, option.EquipmentID)
 	}
 
+	if option.MeasureID > 0 {
+		where += " AND s.measure_id=?"
+		params = append(params, option.MeasureID)
+	}
+
 	if option.Keyword != "" {
 		where += " AND s.title LIKE ?"
 		keyword := "%" + option.Keyword + "%"
@@ -2196,9 +2207,11 @@ func (s *mysqlStore) loadAlarm(id int64) (model.Alarm, error) {
 		"status":     &alarm.status,
 		"device_id":  &alarm.deviceID,
 		"measure_id": &alarm.measureID,
+		"priority":   &alarm.priority,
 		"extra":      &alarm.extra,
 		"created_at": &alarm.createdAt,
 		"updated_at": &alarm.updatedAt,
+		"cleared_at": &alarm.clearedAt,
 	}, "id=?", id)
 	if err != nil {
 		if err != sql.ErrNoRows {
@@ -2237,7 +2250,7 @@ func (s *mysqlStore) GetAlarm(alarmID int64) (model.Alarm, error) {
 	return result.(model.Alarm), nil
 }
 
-func (s *mysqlStore) CreateAlarm(device model.Device, measureID int64, data map[string]interface{}) (model.Alarm, error) {
+func (s *mysqlStore) CreateAlarm(device model.Device, measureID int64, priority int, data map[string]interface{}) (model.Alarm, error) {
 	result := <-synchronized.Do(TbAlarms, func() interface{} {
 		extra, err := json.Marshal(data)
 		if err != nil {
@@ -2250,6 +2263,7 @@ func (s *mysqlStore) CreateAlarm(device model.Device, measureID int64, data map[
 			"status":     status.Unconfirmed,
 			"device_id":  device.GetID(),
 			"measure_id": measureID,
+			"priority":   priority,
 			"extra":      extra,
 			"created_at": now,
 			"updated_at": now,
@@ -2307,6 +2321,10 @@ func (s *mysqlStore) GetLastAlarm(options ...helper.OptionFN) (model.Alarm, int6
 		params = append(params, *option.Status)
 	}
 
+	if option.Uncleared {
+		fromSQL += " AND cleared_at IS NULL"
+	}
+
 	var total int64
 	if err := s.db.QueryRow("SELECT COUNT(*) "+fromSQL, params...).Scan(&total); err != nil {
 		return nil, 0, lang.InternalError(err)
@@ -2339,6 +2357,13 @@ func (s *mysqlStore) GetLastUnconfirmedAlarm(options ...helper.OptionFN) (model.
 	return s.GetLastAlarm(options...)
 }
 
+//ClearAlarms 记录点位上还没有消失的警报的消失时间
+func (s *mysqlStore) ClearAlarms(deviceID, measureID int64, at time.Time) (int64, error) {
+	return s.updateEntries(TbAlarms, func(id int64) {
+		s.cache.Remove(&Alarm{id: id})
+	}, "UPDATE "+TbAlarms+" SET cleared_at=?", []interface{}{at}, "device_id=? AND measure_id=? AND cleared_at IS NULL AND created_at<=?", deviceID, measureID, at)
+}
+
 func (s *mysqlStore) GetAlarmList(start, end *time.Time, options ...helper.OptionFN) ([]model.Alarm, int64, error) {
 	option := parseOption(options...)
 
@@ -2377,16 +2402,45 @@ WHERE p.role_id IN (SELECT role_id FROM %s WHERE user_id=%d)
 		params = append(params, option.DeviceID)
 	}
 
+	if len(option.Priorities) > 0 {
+		where += " AND a.priority IN (?" + strings.Repeat(",?", len(option.Priorities)-1) + ")"
+		for _, p := range option.Priorities {
+			params = append(params, p)
+		}
+	}
+
+	if len(option.ExcludeDeviceIDs) > 0 {
+		where += " AND a.device_id NOT IN (?" + strings.Repeat(",?", len(option.ExcludeDeviceIDs)-1) + ")"
+		for _, id := range option.ExcludeDeviceIDs {
+			params = append(params, id)
+		}
+	}
+
 	if option.EquipmentID > 0 {
 		where += fmt.Sprintf(" AND a.measure_id IN (SELECT measure_id FROM %s WHERE equipment_id=?)", TbStates)
 		params = append(params, option.EquipmentID)
 	}
 
+	//分组中的设备，以及分组中虚拟设备关联的点位
+	if option.GroupID != nil {
+		where += fmt.Sprintf(" AND (a.device_id IN (SELECT device_id FROM %s WHERE group_id=?) OR a.measure_id IN (SELECT s.measure_id FROM %s s INNER JOIN %s g ON s.equipment_id=g.equipment_id WHERE g.group_id=?))", TbDeviceGroups, TbStates, TbEquipmentGroups)
+		params = append(params, *option.GroupID, *option.GroupID)
+	}
+
 	if option.MeasureID > 0 {
 		where += " AND a.measure_id=?"
 		params = append(params, option.MeasureID)
 	}
 
+	if option.ClearedAfter != nil {
+		where += " AND (a.cleared_at IS NULL OR a.cleared_at>?)"
+		params = append(params, *option.ClearedAfter)
+	}
+
+	if option.Uncleared {
+		where += " AND a.cleared_at IS NULL"
+	}
+
 	if start != nil {
 		where += " AND a.created_at>=?"
 		params = append(params, *start)
@@ -2406,7 +2460,11 @@ WHERE p.role_id IN (SELECT role_id FROM %s WHERE user_id=%d)
 		return []model.Alarm{}, 0, nil
 	}
 
-	where += " ORDER BY a.updated_at DESC"
+	if option.OrderBy != "" {
+		where += " ORDER BY " + option.OrderBy
+	} else {
+		where += " ORDER BY a.updated_at DESC"
+	}
 
 	if option.Limit > 0 {
 		where += " LIMIT ?"
@@ -2420,7 +2478,7 @@ WHERE p.role_id IN (SELECT role_id FROM %s WHERE user_id=%d)
 
 	log.Trace("SELECT DISTINCT a.id " + from + where)
 
-	rows, err := s.db.Query("SELECT DISTINCT a.id,a.updated_at "+from+where, params...)
+	rows, err := s.db.Query("SELECT DISTINCT a.id,a.updated_at,a.priority "+from+where, params...)
 	if err != nil {
 		return nil, 0, lang.InternalError(err)
 	}
@@ -2429,13 +2487,14 @@ WHERE p.role_id IN (SELECT role_id FROM %s WHERE user_id=%d)
 	}()
 
 	var (
-		ids     []int64
-		alarmID int64
-		updated time.Time
+		ids      []int64
+		alarmID  int64
+		updated  time.Time
+		priority int
 	)
 
 	for rows.Next() {
-		err = rows.Scan(&alarmID, &updated)
+		err = rows.Scan(&alarmID, &updated, &priority)
 		if err != nil {
 			if err != sql.ErrNoRows {
 				return nil, 0, lang.InternalError(err)
@@ -2936,3 +2995,789 @@ func (s *mysqlStore) InitDefaultRoles(org interface{}) error {
 
 	return nil
 }
+
+func (s *mysqlStore) loadRule(id int64) (model.Rule, error) {
+	var rule = NewRule(s, id)
+	err := LoadData(s.db, TbRules, map[string]interface{}{
+		"org_id":     &rule.orgID,
+		"user_id":    &rule.userID,
+		"enable":     &rule.enable,
+		"title":      &rule.title,
+		"extra":      &rule.extra,
+		"created_at": &rule.createdAt,
+	}, "id=?", id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return nil, lang.InternalError(err)
+		}
+		return nil, lang.ErrRuleNotFound.Error()
+	}
+	return rule, nil
+}
+
+func (s *mysqlStore) GetRule(ruleID int64) (model.Rule, error) {
+	result := <-synchronized.Do(TbRules, func() interface{} {
+		if rule, err := s.cache.LoadRule(ruleID); err != nil {
+			if err != lang.ErrCacheNotFound.Error() {
+				return err
+			}
+		} else {
+			return rule
+		}
+
+		rule, err := s.loadRule(ruleID)
+		if err != nil {
+			return err
+		}
+
+		err = s.cache.Save(rule)
+		if err != nil {
+			return err
+		}
+		return rule
+	})
+
+	if err, ok := result.(error); ok {
+		return nil, err
+	}
+	return result.(model.Rule), nil
+}
+
+func (s *mysqlStore) CreateRule(org interface{}, userID int64, title string, data interface{}) (model.Rule, error) {
+	result := <-synchronized.Do(TbRules, func() interface{} {
+		orgID, err := s.getOrganizationID(org)
+		if err != nil {
+			return err
+		}
+
+		extra, err := json.Marshal(util.If(data != nil, data, map[string]interface{}{}))
+		if err != nil {
+			return lang.InternalError(err)
+		}
+
+		ruleID, err := CreateData(s.db, TbRules, map[string]interface{}{
+			"org_id":     orgID,
+			"user_id":    userID,
+			"enable":     status.Enable,
+			"title":      title,
+			"extra":      extra,
+			"created_at": time.Now(),
+		})
+		if err != nil {
+			return lang.InternalError(err)
+		}
+
+		rule, err := s.loadRule(ruleID)
+		if err != nil {
+			return err
+		}
+
+		err = s.cache.Save(rule)
+		if err != nil {
+			return err
+		}
+		return rule
+	})
+
+	if err, ok := result.(error); ok {
+		return nil, err
+	}
+	return result.(model.Rule), nil
+}
+
+func (s *mysqlStore) RemoveRule(ruleID int64) error {
+	err := RemoveData(s.db, TbRules, "id=?", ruleID)
+	if err != nil {
+		return lang.InternalError(err)
+	}
+
+	s.cache.Remove(&Rule{id: ruleID})
+	return nil
+}
+
+func (s *mysqlStore) GetRuleList(options ...helper.OptionFN) ([]model.Rule, int64, error) {
+	option := parseOption(options...)
+
+	var (
+		from  = "FROM " + TbRules + " r"
+		where = " WHERE 1"
+
+		params []interface{}
+	)
+
+	if option.OrgID > 0 {
+		where += " AND r.org_id=?"
+		params = append(params, option.OrgID)
+	}
+
+	if option.UserID != nil {
+		where += " AND r.user_id=?"
+		params = append(params, *option.UserID)
+	}
+
+	if option.Status != nil {
+		where += " AND r.enable=?"
+		params = append(params, *option.Status)
+	}
+
+	if option.Keyword != "" {
+		where += " AND r.title LIKE ?"
+		params = append(params, "%"+option.Keyword+"%")
+	}
+
+	var total int64
+	if err := s.db.QueryRow("SELECT COUNT(r.id) "+from+where, params...).Scan(&total); err != nil {
+		return nil, 0, lang.InternalError(err)
+	}
+
+	if total == 0 {
+		return []model.Rule{}, 0, nil
+	}
+
+	where += " ORDER BY r.id ASC"
+
+	if option.Limit > 0 {
+		where += " LIMIT ?"
+		params = append(params, option.Limit)
+	}
+
+	if option.Offset > 0 {
+		where += " OFFSET ?"
+		params = append(params, option.Offset)
+	}
+
+	rows, err := s.db.Query("SELECT r.id "+from+where, params...)
+	if err != nil {
+		return nil, 0, lang.InternalError(err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var (
+		ids    []int64
+		ruleID int64
+	)
+
+	for rows.Next() {
+		if err = rows.Scan(&ruleID); err != nil {
+			return nil, 0, lang.InternalError(err)
+		}
+		ids = append(ids, ruleID)
+	}
+
+	result := make([]model.Rule, 0, len(ids))
+	for _, id := range ids {
+		rule, err := s.GetRule(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = append(result, rule)
+	}
+
+	return result, total, nil
+}
+
+func (s *mysqlStore) loadMessage(id int64) (model.Message, error) {
+	var message = NewMessage(s, id)
+	err := LoadData(s.db, TbMessages, map[string]interface{}{
+		"user_id":    &message.userID,
+		"status":     &message.status,
+		"level":      &message.level,
+		"title":      &message.title,
+		"content":    &message.content,
+		"extra":      &message.extra,
+		"created_at": &message.createdAt,
+	}, "id=?", id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return nil, lang.InternalError(err)
+		}
+		return nil, lang.ErrMessageNotFound.Error()
+	}
+	return message, nil
+}
+
+func (s *mysqlStore) GetMessage(messageID int64) (model.Message, error) {
+	result := <-synchronized.Do(TbMessages, func() interface{} {
+		if message, err := s.cache.LoadMessage(messageID); err != nil {
+			if err != lang.ErrCacheNotFound.Error() {
+				return err
+			}
+		} else {
+			return message
+		}
+
+		message, err := s.loadMessage(messageID)
+		if err != nil {
+			return err
+		}
+
+		err = s.cache.Save(message)
+		if err != nil {
+			return err
+		}
+		return message
+	})
+
+	if err, ok := result.(error); ok {
+		return nil, err
+	}
+	return result.(model.Message), nil
+}
+
+func (s *mysqlStore) CreateMessage(userID int64, level, title, content string, data interface{}) (model.Message, error) {
+	result := <-synchronized.Do(TbMessages, func() interface{} {
+		extra, err := json.Marshal(util.If(data != nil, data, map[string]interface{}{}))
+		if err != nil {
+			return lang.InternalError(err)
+		}
+
+		messageID, err := CreateData(s.db, TbMessages, map[string]interface{}{
+			"user_id":    userID,
+			"status":     status.Unread,
+			"level":      level,
+			"title":      title,
+			"content":    content,
+			"extra":      extra,
+			"created_at": time.Now(),
+		})
+		if err != nil {
+			return lang.InternalError(err)
+		}
+
+		message, err := s.loadMessage(messageID)
+		if err != nil {
+			return err
+		}
+
+		err = s.cache.Save(message)
+		if err != nil {
+			return err
+		}
+		return message
+	})
+
+	if err, ok := result.(error); ok {
+		return nil, err
+	}
+	return result.(model.Message), nil
+}
+
+func (s *mysqlStore) RemoveMessage(messageID int64) error {
+	err := RemoveData(s.db, TbMessages, "id=?", messageID)
+	if err != nil {
+		return lang.InternalError(err)
+	}
+
+	s.cache.Remove(&Message{id: messageID})
+	return nil
+}
+
+func (s *mysqlStore) GetMessageList(options ...helper.OptionFN) ([]model.Message, int64, error) {
+	option := parseOption(options...)
+
+	var (
+		from  = "FROM " + TbMessages + " m"
+		where = " WHERE 1"
+
+		params []interface{}
+	)
+
+	if option.UserID != nil {
+		where += " AND m.user_id=?"
+		params = append(params, *option.UserID)
+	}
+
+	if option.Status != nil {
+		where += " AND m.status=?"
+		params = append(params, *option.Status)
+	}
+
+	var total int64
+	if err := s.db.QueryRow("SELECT COUNT(m.id) "+from+where, params...).Scan(&total); err != nil {
+		return nil, 0, lang.InternalError(err)
+	}
+
+	if total == 0 {
+		return []model.Message{}, 0, nil
+	}
+
+	where += " ORDER BY m.id DESC"
+
+	if option.Limit > 0 {
+		where += " LIMIT ?"
+		params = append(params, option.Limit)
+	}
+
+	if option.Offset > 0 {
+		where += " OFFSET ?"
+		params = append(params, option.Offset)
+	}
+
+	rows, err := s.db.Query("SELECT m.id "+from+where, params...)
+	if err != nil {
+		return nil, 0, lang.InternalError(err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var (
+		ids       []int64
+		messageID int64
+	)
+
+	for rows.Next() {
+		if err = rows.Scan(&messageID); err != nil {
+			return nil, 0, lang.InternalError(err)
+		}
+		ids = append(ids, messageID)
+	}
+
+	result := make([]model.Message, 0, len(ids))
+	for _, id := range ids {
+		message, err := s.GetMessage(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = append(result, message)
+	}
+
+	return result, total, nil
+}
+
+//MarkAllMessagesRead 用户的全部消息标记为已读，返回更新的消息数量
+func (s *mysqlStore) MarkAllMessagesRead(userID int64) (int64, error) {
+	return s.updateEntries(TbMessages, func(id int64) {
+		s.cache.Remove(&Message{id: id})
+	}, "UPDATE "+TbMessages+" SET status=?", []interface{}{status.Read}, "user_id=? AND status=?", userID, status.Unread)
+}
+
+func (s *mysqlStore) RemoveExpiredMessages(before time.Time, keep int) (int64, error) {
+	where := "created_at<?"
+	params := []interface{}{before}
+	if keep > 0 {
+		where += fmt.Sprintf(" OR id NOT IN (SELECT id FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY id DESC) AS n FROM %s) WHERE n<=?)", TbMessages)
+		params = append(params, keep)
+	}
+
+	return s.updateEntries(TbMessages, func(id int64) {
+		s.cache.Remove(&Message{id: id})
+	}, "DELETE FROM "+TbMessages, nil, where, params...)
+}
+
+//updateEntries 批量更新或者删除数据，并移除缓存中对应的数据
+func (s *mysqlStore) updateEntries(tbName string, uncache func(id int64), SQL string, values []interface{}, where string, params ...interface{}) (int64, error) {
+	result := <-synchronized.Do(tbName, func() interface{} {
+		rows, err := s.db.Query("SELECT id FROM "+tbName+" WHERE "+where, params...)
+		if err != nil {
+			return lang.InternalError(err)
+		}
+
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err = rows.Scan(&id); err != nil {
+				_ = rows.Close()
+				return lang.InternalError(err)
+			}
+			ids = append(ids, id)
+		}
+		_ = rows.Close()
+
+		if len(ids) == 0 {
+			return int64(0)
+		}
+
+		if _, err = s.db.Exec(SQL+" WHERE "+where, append(values, params...)...); err != nil {
+			return lang.InternalError(err)
+		}
+
+		for _, id := range ids {
+			uncache(id)
+		}
+		return int64(len(ids))
+	})
+
+	if err, ok := result.(error); ok {
+		return 0, err
+	}
+	return result.(int64), nil
+}
+
+func (s *mysqlStore) loadWebhook(id int64) (model.Webhook, error) {
+	var webhook = NewWebhook(s, id)
+	err := LoadData(s.db, TbWebhooks, map[string]interface{}{
+		"org_id":     &webhook.orgID,
+		"user_id":    &webhook.userID,
+		"enable":     &webhook.enable,
+		"title":      &webhook.title,
+		"extra":      &webhook.extra,
+		"created_at": &webhook.createdAt,
+	}, "id=?", id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return nil, lang.InternalError(err)
+		}
+		return nil, lang.ErrWebhookNotFound.Error()
+	}
+	return webhook, nil
+}
+
+func (s *mysqlStore) GetWebhook(webhookID int64) (model.Webhook, error) {
+	result := <-synchronized.Do(TbWebhooks, func() interface{} {
+		if webhook, err := s.cache.LoadWebhook(webhookID); err != nil {
+			if err != lang.ErrCacheNotFound.Error() {
+				return err
+			}
+		} else {
+			return webhook
+		}
+
+		webhook, err := s.loadWebhook(webhookID)
+		if err != nil {
+			return err
+		}
+
+		err = s.cache.Save(webhook)
+		if err != nil {
+			return err
+		}
+		return webhook
+	})
+
+	if err, ok := result.(error); ok {
+		return nil, err
+	}
+	return result.(model.Webhook), nil
+}
+
+func (s *mysqlStore) CreateWebhook(org interface{}, userID int64, title string, data interface{}) (model.Webhook, error) {
+	result := <-synchronized.Do(TbWebhooks, func() interface{} {
+		orgID, err := s.getOrganizationID(org)
+		if err != nil {
+			return err
+		}
+
+		extra, err := json.Marshal(util.If(data != nil, data, map[string]interface{}{}))
+		if err != nil {
+			return lang.InternalError(err)
+		}
+
+		webhookID, err := CreateData(s.db, TbWebhooks, map[string]interface{}{
+			"org_id":     orgID,
+			"user_id":    userID,
+			"enable":     status.Enable,
+			"title":      title,
+			"extra":      extra,
+			"created_at": time.Now(),
+		})
+		if err != nil {
+			return lang.InternalError(err)
+		}
+
+		webhook, err := s.loadWebhook(webhookID)
+		if err != nil {
+			return err
+		}
+
+		err = s.cache.Save(webhook)
+		if err != nil {
+			return err
+		}
+		return webhook
+	})
+
+	if err, ok := result.(error); ok {
+		return nil, err
+	}
+	return result.(model.Webhook), nil
+}
+
+func (s *mysqlStore) RemoveWebhook(webhookID int64) error {
+	_, err := s.updateEntries(TbDeliveries, func(id int64) {
+		s.cache.Remove(&Delivery{id: id})
+	}, "DELETE FROM "+TbDeliveries, nil, "webhook_id=?", webhookID)
+	if err != nil {
+		return err
+	}
+
+	err = RemoveData(s.db, TbWebhooks, "id=?", webhookID)
+	if err != nil {
+		return lang.InternalError(err)
+	}
+
+	s.cache.Remove(&Webhook{id: webhookID})
+	return nil
+}
+
+func (s *mysqlStore) GetWebhookList(options ...helper.OptionFN) ([]model.Webhook, int64, error) {
+	option := parseOption(options...)
+
+	var (
+		from  = "FROM " + TbWebhooks + " w"
+		where = " WHERE 1"
+
+		params []interface{}
+	)
+
+	if option.OrgID > 0 {
+		where += " AND w.org_id=?"
+		params = append(params, option.OrgID)
+	}
+
+	if option.UserID != nil {
+		where += " AND w.user_id=?"
+		params = append(params, *option.UserID)
+	}
+
+	if option.Status != nil {
+		where += " AND w.enable=?"
+		params = append(params, *option.Status)
+	}
+
+	if option.Keyword != "" {
+		where += " AND w.title LIKE ?"
+		params = append(params, "%"+option.Keyword+"%")
+	}
+
+	var total int64
+	if err := s.db.QueryRow("SELECT COUNT(w.id) "+from+where, params...).Scan(&total); err != nil {
+		return nil, 0, lang.InternalError(err)
+	}
+
+	if total == 0 {
+		return []model.Webhook{}, 0, nil
+	}
+
+	where += " ORDER BY w.id ASC"
+
+	if option.Limit > 0 {
+		where += " LIMIT ?"
+		params = append(params, option.Limit)
+	}
+
+	if option.Offset > 0 {
+		where += " OFFSET ?"
+		params = append(params, option.Offset)
+	}
+
+	rows, err := s.db.Query("SELECT w.id "+from+where, params...)
+	if err != nil {
+		return nil, 0, lang.InternalError(err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var (
+		ids       []int64
+		webhookID int64
+	)
+
+	for rows.Next() {
+		if err = rows.Scan(&webhookID); err != nil {
+			return nil, 0, lang.InternalError(err)
+		}
+		ids = append(ids, webhookID)
+	}
+
+	result := make([]model.Webhook, 0, len(ids))
+	for _, id := range ids {
+		webhook, err := s.GetWebhook(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = append(result, webhook)
+	}
+
+	return result, total, nil
+}
+
+func (s *mysqlStore) loadDelivery(id int64) (model.Delivery, error) {
+	var delivery = NewDelivery(s, id)
+	err := LoadData(s.db, TbDeliveries, map[string]interface{}{
+		"webhook_id": &delivery.webhookID,
+		"status":     &delivery.status,
+		"attempts":   &delivery.attempts,
+		"code":       &delivery.code,
+		"event":      &delivery.event,
+		"payload":    &delivery.payload,
+		"response":   &delivery.response,
+		"next_at":    &delivery.nextAt,
+		"created_at": &delivery.createdAt,
+		"updated_at": &delivery.updatedAt,
+	}, "id=?", id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return nil, lang.InternalError(err)
+		}
+		return nil, lang.ErrDeliveryNotFound.Error()
+	}
+	return delivery, nil
+}
+
+func (s *mysqlStore) GetDelivery(deliveryID int64) (model.Delivery, error) {
+	result := <-synchronized.Do(TbDeliveries, func() interface{} {
+		if delivery, err := s.cache.LoadDelivery(deliveryID); err != nil {
+			if err != lang.ErrCacheNotFound.Error() {
+				return err
+			}
+		} else {
+			return delivery
+		}
+
+		delivery, err := s.loadDelivery(deliveryID)
+		if err != nil {
+			return err
+		}
+
+		err = s.cache.Save(delivery)
+		if err != nil {
+			return err
+		}
+		return delivery
+	})
+
+	if err, ok := result.(error); ok {
+		return nil, err
+	}
+	return result.(model.Delivery), nil
+}
+
+func (s *mysqlStore) CreateDelivery(webhookID int64, event string, payload []byte) (model.Delivery, error) {
+	result := <-synchronized.Do(TbDeliveries, func() interface{} {
+		now := time.Now()
+		deliveryID, err := CreateData(s.db, TbDeliveries, map[string]interface{}{
+			"webhook_id": webhookID,
+			"status":     status.Pending,
+			"attempts":   0,
+			"code":       0,
+			"event":      event,
+			"payload":    payload,
+			"response":   "",
+			"next_at":    now,
+			"created_at": now,
+			"updated_at": now,
+		})
+		if err != nil {
+			return lang.InternalError(err)
+		}
+
+		delivery, err := s.loadDelivery(deliveryID)
+		if err != nil {
+			return err
+		}
+
+		err = s.cache.Save(delivery)
+		if err != nil {
+			return err
+		}
+		return delivery
+	})
+
+	if err, ok := result.(error); ok {
+		return nil, err
+	}
+	return result.(model.Delivery), nil
+}
+
+func (s *mysqlStore) RemoveDelivery(deliveryID int64) error {
+	err := RemoveData(s.db, TbDeliveries, "id=?", deliveryID)
+	if err != nil {
+		return lang.InternalError(err)
+	}
+
+	s.cache.Remove(&Delivery{id: deliveryID})
+	return nil
+}
+
+func (s *mysqlStore) GetDeliveryList(options ...helper.OptionFN) ([]model.Delivery, int64, error) {
+	option := parseOption(options...)
+
+	var (
+		from  = "FROM " + TbDeliveries + " d"
+		where = " WHERE 1"
+
+		params []interface{}
+	)
+
+	if option.WebhookID > 0 {
+		where += " AND d.webhook_id=?"
+		params = append(params, option.WebhookID)
+	}
+
+	if option.Status != nil {
+		where += " AND d.status=?"
+		params = append(params, *option.Status)
+	}
+
+	if option.Name != "" {
+		where += " AND d.event=?"
+		params = append(params, option.Name)
+	}
+
+	var total int64
+	if err := s.db.QueryRow("SELECT COUNT(d.id) "+from+where, params...).Scan(&total); err != nil {
+		return nil, 0, lang.InternalError(err)
+	}
+
+	if total == 0 {
+		return []model.Delivery{}, 0, nil
+	}
+
+	//等待发送的投递按下一次发送的时间排序
+	if option.OrderBy == "next" {
+		where += " ORDER BY d.next_at ASC, d.id ASC"
+	} else {
+		where += " ORDER BY d.id DESC"
+	}
+
+	if option.Limit > 0 {
+		where += " LIMIT ?"
+		params = append(params, option.Limit)
+	}
+
+	if option.Offset > 0 {
+		where += " OFFSET ?"
+		params = append(params, option.Offset)
+	}
+
+	rows, err := s.db.Query("SELECT d.id "+from+where, params...)
+	if err != nil {
+		return nil, 0, lang.InternalError(err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var (
+		ids        []int64
+		deliveryID int64
+	)
+
+	for rows.Next() {
+		if err = rows.Scan(&deliveryID); err != nil {
+			return nil, 0, lang.InternalError(err)
+		}
+		ids = append(ids, deliveryID)
+	}
+
+	result := make([]model.Delivery, 0, len(ids))
+	for _, id := range ids {
+		delivery, err := s.GetDelivery(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		result = append(result, delivery)
+	}
+
+	return result, total, nil
+}
+
+func (s *mysqlStore) RemoveExpiredDeliveries(before time.Time) (int64, error) {
+	return s.updateEntries(TbDeliveries, func(id int64) {
+		s.cache.Remove(&Delivery{id: id})
+	}, "DELETE FROM "+TbDeliveries, nil, "status<>? AND updated_at<?", status.Pending, before)
+}
@@ -0,0 +1,388 @@
+package mysqlStore
+
+import (
+	"time"
+
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/web/dirty"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/status"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+type Webhook struct {
+	id     int64
+	orgID  int64
+	userID int64
+
+	enable    int8
+	title     string
+	extra     []byte
+	createdAt time.Time
+
+	dirty *dirty.Dirty
+	store *mysqlStore
+}
+
+func NewWebhook(s *mysqlStore, id int64) *Webhook {
+	return &Webhook{
+		id:    id,
+		dirty: dirty.New(),
+		store: s,
+	}
+}
+
+func (w *Webhook) GetID() int64 {
+	return w.id
+}
+
+func (w *Webhook) OrganizationID() int64 {
+	return w.orgID
+}
+
+func (w *Webhook) Organization() (model.Organization, error) {
+	return w.store.GetOrganization(w.orgID)
+}
+
+func (w *Webhook) UserID() int64 {
+	return w.userID
+}
+
+func (w *Webhook) User() (model.User, error) {
+	return w.store.GetUser(w.userID)
+}
+
+func (w *Webhook) CreatedAt() time.Time {
+	return w.createdAt
+}
+
+func (w *Webhook) Enable() {
+	if w.enable != status.Enable {
+		w.enable = status.Enable
+		w.dirty.Set("enable", func() interface{} {
+			return w.enable
+		})
+	}
+}
+
+func (w *Webhook) Disable() {
+	if w.enable != status.Disable {
+		w.enable = status.Disable
+		w.dirty.Set("enable", func() interface{} {
+			return w.enable
+		})
+	}
+}
+
+func (w *Webhook) IsEnabled() bool {
+	return w.enable == status.Enable
+}
+
+func (w *Webhook) Title() string {
+	return w.title
+}
+
+func (w *Webhook) SetTitle(title string) {
+	if w.title != title {
+		w.title = title
+		w.dirty.Set("title", func() interface{} {
+			return w.title
+		})
+	}
+}
+
+func (w *Webhook) URL() string {
+	return w.GetOption("url").String()
+}
+
+func (w *Webhook) Secret() string {
+	return w.GetOption("secret").String()
+}
+
+func (w *Webhook) Events() []string {
+	var events []string
+	for _, v := range w.GetOption("events").Array() {
+		events = append(events, v.String())
+	}
+	return events
+}
+
+func (w *Webhook) Save() error {
+	if w != nil {
+		if w.dirty.Any() {
+			err := SaveData(w.store.db, TbWebhooks, w.dirty.Data(true), "id=?", w.id)
+			if err != nil {
+				return lang.InternalError(err)
+			}
+		}
+		return nil
+	}
+	return lang.ErrWebhookNotFound.Error()
+}
+
+func (w *Webhook) Destroy() error {
+	if w == nil {
+		return lang.ErrWebhookNotFound.Error()
+	}
+	return w.store.RemoveWebhook(w.id)
+}
+
+func (w *Webhook) Option() map[string]interface{} {
+	if v, ok := gjson.ParseBytes(w.extra).Value().(map[string]interface{}); ok {
+		return v
+	}
+	return map[string]interface{}{}
+}
+
+func (w *Webhook) GetOption(path string) gjson.Result {
+	if w != nil {
+		return gjson.GetBytes(w.extra, path)
+	}
+	return gjson.Result{}
+}
+
+func (w *Webhook) SetOption(path string, value interface{}) error {
+	if w != nil {
+		data, err := sjson.SetBytes(w.extra, path, value)
+		if err != nil {
+			return err
+		}
+
+		w.extra = data
+		w.dirty.Set("extra", func() interface{} {
+			return w.extra
+		})
+
+		return nil
+	}
+
+	return lang.ErrWebhookNotFound.Error()
+}
+
+func (w *Webhook) Simple() model.Map {
+	if w == nil {
+		return model.Map{}
+	}
+	return model.Map{
+		"id":     w.id,
+		"enable": w.IsEnabled(),
+		"title":  w.title,
+	}
+}
+
+func (w *Webhook) Brief() model.Map {
+	if w == nil {
+		return model.Map{}
+	}
+	brief := model.Map{
+		"id":         w.id,
+		"enable":     w.IsEnabled(),
+		"title":      w.title,
+		"url":        w.URL(),
+		"events":     w.GetOption("events").Value(),
+		"created_at": w.createdAt.Format(lang.DatetimeFormatterStr.Str()),
+	}
+	if user, err := w.User(); err == nil {
+		brief["user"] = user.Simple()
+	}
+	return brief
+}
+
+//Detail 不返回签名密钥，只返回是否设置了密钥
+func (w *Webhook) Detail() model.Map {
+	if w == nil {
+		return model.Map{}
+	}
+	detail := model.Map{
+		"id":         w.id,
+		"enable":     w.IsEnabled(),
+		"title":      w.title,
+		"url":        w.URL(),
+		"signed":     w.Secret() != "",
+		"events":     w.GetOption("events").Value(),
+		"created_at": w.createdAt.Format(lang.DatetimeFormatterStr.Str()),
+	}
+	if user, err := w.User(); err == nil {
+		detail["user"] = user.Simple()
+	}
+	return detail
+}
+
+type Delivery struct {
+	id        int64
+	webhookID int64
+
+	status   int
+	attempts int
+	code     int
+
+	event    string
+	payload  []byte
+	response string
+
+	nextAt    time.Time
+	createdAt time.Time
+	updatedAt time.Time
+
+	dirty *dirty.Dirty
+	store *mysqlStore
+}
+
+func NewDelivery(s *mysqlStore, id int64) *Delivery {
+	return &Delivery{
+		id:    id,
+		dirty: dirty.New(),
+		store: s,
+	}
+}
+
+func (d *Delivery) GetID() int64 {
+	return d.id
+}
+
+func (d *Delivery) WebhookID() int64 {
+	return d.webhookID
+}
+
+func (d *Delivery) Webhook() (model.Webhook, error) {
+	return d.store.GetWebhook(d.webhookID)
+}
+
+func (d *Delivery) Event() string {
+	return d.event
+}
+
+func (d *Delivery) Payload() []byte {
+	return d.payload
+}
+
+func (d *Delivery) Status() int {
+	return d.status
+}
+
+func (d *Delivery) Attempts() int {
+	return d.attempts
+}
+
+func (d *Delivery) NextAt() time.Time {
+	return d.nextAt
+}
+
+func (d *Delivery) CreatedAt() time.Time {
+	return d.createdAt
+}
+
+func (d *Delivery) UpdatedAt() time.Time {
+	return d.updatedAt
+}
+
+func (d *Delivery) Attempted(status int, code int, response string, next time.Time) {
+	d.status = status
+	d.attempts++
+	d.code = code
+	d.response = response
+	d.nextAt = next
+	d.updatedAt = time.Now()
+
+	d.dirty.Set("status", func() interface{} {
+		return d.status
+	})
+	d.dirty.Set("attempts", func() interface{} {
+		return d.attempts
+	})
+	d.dirty.Set("code", func() interface{} {
+		return d.code
+	})
+	d.dirty.Set("response", func() interface{} {
+		return d.response
+	})
+	d.dirty.Set("next_at", func() interface{} {
+		return d.nextAt
+	})
+	d.dirty.Set("updated_at", func() interface{} {
+		return d.updatedAt
+	})
+}
+
+func (d *Delivery) Retry() {
+	d.status = status.Pending
+	d.attempts = 0
+	d.nextAt = time.Now()
+
+	d.dirty.Set("status", func() interface{} {
+		return d.status
+	})
+	d.dirty.Set("attempts", func() interface{} {
+		return d.attempts
+	})
+	d.dirty.Set("next_at", func() interface{} {
+		return d.nextAt
+	})
+}
+
+func (d *Delivery) Save() error {
+	if d.dirty.Any() {
+		err := SaveData(d.store.db, TbDeliveries, d.dirty.Data(true), "id=?", d.id)
+		if err != nil {
+			return lang.InternalError(err)
+		}
+	}
+	return nil
+}
+
+func (d *Delivery) Destroy() error {
+	if d == nil {
+		return lang.ErrDeliveryNotFound.Error()
+	}
+	return d.store.RemoveDelivery(d.id)
+}
+
+func (d *Delivery) statusDesc() string {
+	switch d.status {
+	case status.Delivered:
+		return "delivered"
+	case status.Failed:
+		return "failed"
+	default:
+		return "pending"
+	}
+}
+
+func (d *Delivery) Simple() model.Map {
+	if d == nil {
+		return model.Map{}
+	}
+	return model.Map{
+		"id":     d.id,
+		"event":  d.event,
+		"status": d.statusDesc(),
+	}
+}
+
+func (d *Delivery) Brief() model.Map {
+	if d == nil {
+		return model.Map{}
+	}
+	return model.Map{
+		"id":         d.id,
+		"webhook_id": d.webhookID,
+		"event":      d.event,
+		"status":     d.statusDesc(),
+		"attempts":   d.attempts,
+		"code":       d.code,
+		"next_at":    d.nextAt.Format(lang.DatetimeFormatterStr.Str()),
+		"created_at": d.createdAt.Format(lang.DatetimeFormatterStr.Str()),
+		"updated_at": d.updatedAt.Format(lang.DatetimeFormatterStr.Str()),
+	}
+}
+
+func (d *Delivery) Detail() model.Map {
+	if d == nil {
+		return model.Map{}
+	}
+	detail := d.Brief()
+	detail["payload"] = gjson.ParseBytes(d.payload).Value()
+	detail["response"] = d.response
+	return detail
+}
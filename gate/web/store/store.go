@@ -77,8 +77,9 @@ type Store interface {
 
 	GetAlarm(alarmID int64) (model.Alarm, error)
 	GetLastAlarm(options ...helper.OptionFN) (model.Alarm, int64, error)
-	CreateAlarm(device model.Device, measureID int64, data map[string]interface{}) (model.Alarm, error)
+	CreateAlarm(device model.Device, measureID int64, priority int, data map[string]interface{}) (model.Alarm, error)
 	RemoveAlarm(alarmID int64) error
+	ClearAlarms(deviceID, measureID int64, at time.Time) (int64, error)
 	GetAlarmList(start, end *time.Time, options ...helper.OptionFN) ([]model.Alarm, int64, error)
 	GetLastUnconfirmedAlarm(options ...helper.OptionFN) (model.Alarm, int64, error)
 
@@ -87,6 +88,32 @@ type Store interface {
 	RemoveComment(commentID int64) error
 	GetCommentList(alarm model.Alarm, lastID int64, options ...helper.OptionFN) ([]model.Comment, int64, error)
 
+	GetRule(ruleID int64) (model.Rule, error)
+	CreateRule(org interface{}, userID int64, title string, data interface{}) (model.Rule, error)
+	RemoveRule(ruleID int64) error
+	GetRuleList(options ...helper.OptionFN) ([]model.Rule, int64, error)
+
+	GetMessage(messageID int64) (model.Message, error)
+	CreateMessage(userID int64, level, title, content string, data interface{}) (model.Message, error)
+	RemoveMessage(messageID int64) error
+	GetMessageList(options ...helper.OptionFN) ([]model.Message, int64, error)
+	MarkAllMessagesRead(userID int64) (int64, error)
+	//删除指定时间以前的消息，每个用户最多保留keep条消息
+	RemoveExpiredMessages(before time.Time, keep int) (int64, error)
+
+	GetWebhook(webhookID int64) (model.Webhook, error)
+	CreateWebhook(org interface{}, userID int64, title string, data interface{}) (model.Webhook, error)
+	//删除webhook和它的投递记录
+	RemoveWebhook(webhookID int64) error
+	GetWebhookList(options ...helper.OptionFN) ([]model.Webhook, int64, error)
+
+	GetDelivery(deliveryID int64) (model.Delivery, error)
+	CreateDelivery(webhookID int64, event string, payload []byte) (model.Delivery, error)
+	RemoveDelivery(deliveryID int64) error
+	GetDeliveryList(options ...helper.OptionFN) ([]model.Delivery, int64, error)
+	//删除指定时间以前已经结束的投递记录
+	RemoveExpiredDeliveries(before time.Time) (int64, error)
+
 	GetResourceGroupList() []interface{}
 	GetResourceList(class resource.Class, options ...helper.OptionFN) ([]model.Resource, int64, error)
 	GetResource(class resource.Class, resourceID int64) (model.Resource, error)
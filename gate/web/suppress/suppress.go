@@ -0,0 +1,497 @@
+package suppress
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	edgeLang "github.com/maritimusj/centrum/edge/lang"
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/logStore"
+	"github.com/maritimusj/centrum/gate/web/alarm"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/dispatch"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/resource"
+	"github.com/maritimusj/centrum/gate/web/shelve"
+	"github.com/maritimusj/centrum/gate/web/status"
+	log "github.com/sirupsen/logrus"
+)
+
+//Options 警报泛滥和反复出现的判断条件
+type Options struct {
+	//FloodWindow内单个设备或者整个站点新出现的警报超过指定数量时，合并为一个汇总警报
+	FloodDevice int
+	FloodSite   int
+	FloodWindow time.Duration
+	//ChatterWindow内同一个点位的警报出现ChatterCount次时，自动搁置ChatterShelve
+	ChatterCount  int
+	ChatterWindow time.Duration
+	ChatterShelve time.Duration
+}
+
+const (
+	//FloodTag 保存汇总警报的设备点位
+	FloodTag = "FLOOD"
+
+	//检查泛滥是否结束和清理过期记录的间隔
+	tickInterval = 10 * time.Second
+)
+
+var DefaultOptions = Options{
+	FloodDevice:   10,
+	FloodSite:     50,
+	FloodWindow:   time.Minute,
+	ChatterCount:  5,
+	ChatterWindow: 10 * time.Minute,
+	ChatterShelve: 30 * time.Minute,
+}
+
+type point struct {
+	lastSeen time.Time
+	//设备的警报消失时间
+	timeout time.Duration
+	//最近警报出现的时间
+	raised []time.Time
+}
+
+type flood struct {
+	//窗口内新出现警报的点位和出现的时间，持续上报的警报不更新时间
+	raised map[int64]time.Time
+	//是否处于泛滥状态，泛滥期间合并的点位和警报数量
+	active bool
+	merged map[int64]bool
+	count  int
+
+	//汇总警报的ID和范围，用于确认后结束泛滥状态
+	summaryID int64
+	title     string
+	org       int64
+
+	//汇总警报，保存时使用单独的锁
+	summary model.Alarm
+	saved   int
+	mu      sync.Mutex
+}
+
+func newFlood() *flood {
+	return &flood{
+		raised: map[int64]time.Time{},
+		merged: map[int64]bool{},
+	}
+}
+
+//save 保存汇总警报中合并的警报数量
+func (f *flood) save(count int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.summary == nil || count <= f.saved {
+		return
+	}
+
+	_ = f.summary.SetOption("fields.count", count)
+	f.summary.Updated()
+	if err := f.summary.Save(); err != nil {
+		log.Errorln("[suppress] save summary alarm: ", err)
+		return
+	}
+	f.saved = count
+}
+
+var (
+	options = DefaultOptions
+
+	points       = map[int64]*point{}
+	floods       = map[string]*flood{}
+	disconnected = map[int64]bool{}
+	//泛滥结束时被合并的点位，再次上报时重新判断，单独产生警报
+	released = map[int64]bool{}
+	mu       sync.Mutex
+)
+
+func SetOptions(o Options) {
+	mu.Lock()
+	options = o
+	mu.Unlock()
+}
+
+//Observe 每次收到点位的警报时调用，返回警报是否是新出现的，
+//警报反复出现达到设置的次数时自动搁置点位
+func Observe(device model.Device, measure model.Measure) bool {
+	now := time.Now()
+
+	mu.Lock()
+	p, ok := points[measure.GetID()]
+	if !ok {
+		p = &point{}
+		points[measure.GetID()] = p
+	}
+
+	//超过设备的警报消失时间没有上报，再次上报时是新出现的警报
+	p.timeout = alarm.ClearTimeout(device)
+	raised := now.Sub(p.lastSeen) > p.timeout
+	p.lastSeen = now
+
+	var chattering bool
+	if raised {
+		p.raised = append(within(p.raised, now, options.ChatterWindow), now)
+		if options.ChatterCount > 0 && len(p.raised) >= options.ChatterCount {
+			p.raised = nil
+			chattering = true
+		}
+	}
+
+	duration := options.ChatterShelve
+	mu.Unlock()
+
+	if chattering {
+		if _, err := shelve.Chattering(measure, duration); err != nil {
+			log.Errorln("[suppress] shelve: ", err)
+		} else {
+			log.WithFields(log.Fields{
+				"org": measure.OrganizationID(),
+				"src": logStore.SystemLog,
+			}).Warningln(lang.AlarmChattering.Str(measure.Title(), int(duration/time.Minute)))
+		}
+	}
+
+	return raised
+}
+
+func within(list []time.Time, now time.Time, window time.Duration) []time.Time {
+	for len(list) > 0 && now.Sub(list[0]) > window {
+		list = list[1:]
+	}
+	return list
+}
+
+//OnDeviceStatus 记录设备的连接状态
+func OnDeviceStatus(device model.Device, index int) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if index == int(edgeLang.Disconnected) {
+		disconnected[device.GetID()] = true
+	} else {
+		delete(disconnected, device.GetID())
+	}
+}
+
+//Disconnected 设备是否处于断开状态，断开期间设备点位的警报是连带警报
+func Disconnected(device model.Device) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return disconnected[device.GetID()]
+}
+
+//DisconnectedDevices 处于断开状态的设备
+func DisconnectedDevices() []int64 {
+	mu.Lock()
+	defer mu.Unlock()
+
+	list := make([]int64, 0, len(disconnected))
+	for id := range disconnected {
+		list = append(list, id)
+	}
+	return list
+}
+
+//Start 定时结束已经平息的警报泛滥，清理不再上报的点位记录
+func Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				tick(now)
+			}
+		}
+	}()
+}
+
+func tick(now time.Time) {
+	var finished []ended
+
+	mu.Lock()
+	//警报已经消失并且没有需要判断反复出现的记录
+	for id, p := range points {
+		if now.Sub(p.lastSeen) > p.timeout && len(within(p.raised, now, options.ChatterWindow)) == 0 {
+			delete(points, id)
+			delete(released, id)
+		}
+	}
+
+	for key, f := range floods {
+		for mid, t := range f.raised {
+			if now.Sub(t) > options.FloodWindow {
+				delete(f.raised, mid)
+			}
+		}
+
+		limit := options.FloodSite
+		if strings.HasPrefix(key, "device:") {
+			limit = options.FloodDevice
+		}
+
+		if f.active && len(f.raised) <= limit {
+			finished = append(finished, end(key, f))
+		} else if !f.active && len(f.raised) == 0 {
+			delete(floods, key)
+		}
+	}
+	mu.Unlock()
+
+	for _, e := range finished {
+		e.finish()
+	}
+}
+
+//Flood 新警报产生前调用，设备或者站点处于警报泛滥状态时返回true，
+//这时警报被合并到汇总警报，不再单独创建和通知。
+//新出现警报的数量降到阈值以下或者汇总警报被确认时泛滥结束，合并的警报再次上报时单独产生
+func Flood(device model.Device, measure model.Measure) bool {
+	now := time.Now()
+	id := measure.GetID()
+
+	mu.Lock()
+	window := options.FloodWindow
+	scopes := []struct {
+		key   string
+		limit int
+	}{
+		{fmt.Sprintf("device:%d", device.GetID()), options.FloodDevice},
+		{fmt.Sprintf("org:%d", device.OrganizationID()), options.FloodSite},
+	}
+
+	var (
+		started  *flood
+		scope    string
+		updated  []*flood
+		counts   []int
+		finished []ended
+		flooding bool
+		total    int
+	)
+
+	for _, s := range scopes {
+		if s.limit <= 0 {
+			continue
+		}
+
+		f, ok := floods[s.key]
+		if !ok {
+			f = newFlood()
+			floods[s.key] = f
+		}
+
+		for mid, t := range f.raised {
+			if now.Sub(t) > window {
+				delete(f.raised, mid)
+			}
+		}
+
+		if f.active && len(f.raised) <= s.limit {
+			finished = append(finished, end(s.key, f))
+			f = floods[s.key]
+		}
+
+		if f.active {
+			//泛滥期间被合并的警报会持续上报，不重复计数
+			if !f.merged[id] {
+				if _, exists := f.raised[id]; !exists {
+					f.raised[id] = now
+				}
+				f.merged[id] = true
+				f.count++
+				updated = append(updated, f)
+				counts = append(counts, f.count)
+			}
+			flooding = true
+			continue
+		}
+
+		//泛滥结束时被合并的警报不是新出现的警报
+		if _, exists := f.raised[id]; !exists && !released[id] {
+			f.raised[id] = now
+		}
+
+		//同一时间只产生一个汇总警报
+		if !flooding && len(f.raised) > s.limit {
+			f.active = true
+			f.merged[id] = true
+			f.count = len(f.raised)
+			started, scope, total, flooding = f, s.key, f.count, true
+		}
+	}
+
+	if !flooding {
+		delete(released, id)
+	}
+	mu.Unlock()
+
+	for _, e := range finished {
+		e.finish()
+	}
+
+	if started != nil {
+		title := device.Title()
+		if scope != fmt.Sprintf("device:%d", device.GetID()) {
+			if org, err := device.Organization(); err == nil {
+				title = org.Title()
+			}
+		}
+
+		summary, err := createSummary(device, title, total)
+		if err != nil {
+			log.Errorln("[suppress] create summary alarm: ", err)
+		} else {
+			mu.Lock()
+			started.summaryID = summary.GetID()
+			started.title = title
+			started.org = device.OrganizationID()
+			mu.Unlock()
+
+			started.mu.Lock()
+			started.summary = summary
+			started.saved = int(summary.GetOption("fields.count").Int())
+			started.mu.Unlock()
+		}
+	}
+
+	for i, f := range updated {
+		f.save(counts[i])
+	}
+
+	return flooding
+}
+
+//OnConfirmed 汇总警报被确认时结束对应的泛滥状态
+func OnConfirmed(alarm model.Alarm) {
+	var list []ended
+
+	mu.Lock()
+	for key, f := range floods {
+		if f.active && f.summaryID == alarm.GetID() {
+			list = append(list, end(key, f))
+		}
+	}
+	mu.Unlock()
+
+	for _, e := range list {
+		e.finish()
+	}
+}
+
+//ended 已经结束的泛滥状态
+type ended struct {
+	summary   model.Alarm
+	summaryID int64
+	title     string
+	org       int64
+	count     int
+}
+
+//finish 记录汇总警报消失的时间和日志
+func (e ended) finish() {
+	if e.summaryID == 0 {
+		return
+	}
+
+	if e.summary != nil {
+		e.summary.Cleared(time.Now())
+		if err := e.summary.Save(); err != nil {
+			log.Errorln("[suppress] save summary alarm: ", err)
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"org": e.org,
+		"src": logStore.SystemLog,
+	}).Infoln(lang.AlarmFloodEnded.Str(e.title, e.count))
+}
+
+//end 结束泛滥状态，合并的点位等待重新判断，调用时需要持有mu
+func end(key string, f *flood) ended {
+	for id := range f.merged {
+		released[id] = true
+	}
+
+	//重新开始统计新出现的警报，合并数量仍然保存到原来的汇总警报
+	floods[key] = newFlood()
+
+	f.mu.Lock()
+	summary := f.summary
+	f.mu.Unlock()
+
+	return ended{
+		summary:   summary,
+		summaryID: f.summaryID,
+		title:     f.title,
+		org:       f.org,
+		count:     f.count,
+	}
+}
+
+//floodMeasure 设备上用于保存汇总警报的点位，不存在时创建，
+//汇总警报不占用引发泛滥的点位，避免混入点位自己的警报记录和统计
+func floodMeasure(device model.Device) (model.Measure, error) {
+	s := app.Store()
+	measure, err := s.GetMeasureFromTagName(device.GetID(), FloodTag)
+	if err == nil {
+		return measure, nil
+	}
+	if err != lang.ErrMeasureNotFound.Error() {
+		return nil, err
+	}
+
+	measure, err = s.CreateMeasure(device.GetID(), lang.Str(lang.AlarmFloodMeasure), FloodTag, resource.AllKind)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := app.AssignPermissionsToUsers(device, measure); err != nil {
+		log.Errorln("[suppress] assign permissions: ", err)
+	}
+	return measure, nil
+}
+
+func createSummary(device model.Device, title string, count int) (model.Alarm, error) {
+	measure, err := floodMeasure(device)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := app.Store().CreateAlarm(device, measure.GetID(), status.PriorityHigh, map[string]interface{}{
+		"name": "flood",
+		"tags": map[string]interface{}{
+			"tag":   measure.TagName(),
+			"alarm": dispatch.FloodAlarm,
+			"scope": title,
+		},
+		"fields": map[string]interface{}{
+			"count": count,
+		},
+		"time": time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"org": device.OrganizationID(),
+		"src": logStore.SystemLog,
+	}).Warningln(lang.AlarmFloodDetail.Str(title, count))
+
+	go func() {
+		_ = dispatch.OnAlarm(summary)
+	}()
+	return summary, nil
+}
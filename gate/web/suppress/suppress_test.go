@@ -0,0 +1,232 @@
+package suppress
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	edgeLang "github.com/maritimusj/centrum/edge/lang"
+	"github.com/maritimusj/centrum/gate/web/alarm"
+	"github.com/maritimusj/centrum/gate/web/model"
+)
+
+type testMeasure struct {
+	model.Measure
+	id int64
+}
+
+func (m *testMeasure) GetID() int64 { return m.id }
+
+func TestObserve(t *testing.T) {
+	SetOptions(Options{ChatterWindow: time.Minute})
+	defer SetOptions(DefaultOptions)
+
+	m := &testMeasure{id: 1}
+	if !Observe(nil, m) {
+		t.Fatal("first alarm should be raised")
+	}
+	if Observe(nil, m) {
+		t.Fatal("repeated report should not be raised")
+	}
+
+	mu.Lock()
+	points[m.id].lastSeen = time.Now().Add(-alarm.MinClearTimeout - time.Second)
+	mu.Unlock()
+
+	if !Observe(nil, m) {
+		t.Fatal("alarm should be raised again after cleared")
+	}
+	if n := len(points[m.id].raised); n != 2 {
+		t.Fatalf("expected 2 raises, got %d", n)
+	}
+}
+
+type testDevice struct {
+	model.Device
+	id       int64
+	interval int
+}
+
+func (d *testDevice) GetID() int64 { return d.id }
+
+func (d *testDevice) OrganizationID() int64 { return 1 }
+
+func (d *testDevice) GetOption(path string) gjson.Result {
+	return gjson.Parse(fmt.Sprintf(`{"params":{"interval":%d}}`, d.interval)).Get(path)
+}
+
+func TestObserveSlowDevice(t *testing.T) {
+	SetOptions(Options{ChatterWindow: time.Hour})
+	defer SetOptions(DefaultOptions)
+
+	d := &testDevice{interval: 60}
+	m := &testMeasure{id: 2}
+	if !Observe(d, m) {
+		t.Fatal("first alarm should be raised")
+	}
+
+	//采集间隔60秒的设备，每次上报相隔一个周期，不是新出现的警报
+	mu.Lock()
+	points[m.id].lastSeen = time.Now().Add(-time.Minute)
+	mu.Unlock()
+
+	if Observe(d, m) {
+		t.Fatal("report within the poll interval should not be raised")
+	}
+
+	mu.Lock()
+	points[m.id].lastSeen = time.Now().Add(-4 * time.Minute)
+	mu.Unlock()
+
+	if !Observe(d, m) {
+		t.Fatal("alarm should be raised again after cleared")
+	}
+}
+
+func TestWithin(t *testing.T) {
+	now := time.Now()
+	list := []time.Time{now.Add(-3 * time.Minute), now.Add(-2 * time.Minute), now.Add(-30 * time.Second)}
+	if n := len(within(list, now, time.Minute)); n != 1 {
+		t.Fatalf("expected 1, got %d", n)
+	}
+	if n := len(within(list, now, time.Hour)); n != 3 {
+		t.Fatalf("expected 3, got %d", n)
+	}
+}
+
+type testAlarm struct {
+	model.Alarm
+	id int64
+}
+
+func (a *testAlarm) GetID() int64 { return a.id }
+
+//startFlood 设置设备处于泛滥状态，合并了measures中的点位
+func startFlood(d *testDevice, summaryID int64, raisedAt time.Time, measures ...int64) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	f := newFlood()
+	f.active = true
+	f.summaryID = summaryID
+	for _, id := range measures {
+		f.raised[id] = raisedAt
+		f.merged[id] = true
+	}
+	f.count = len(measures)
+	floods[fmt.Sprintf("device:%d", d.id)] = f
+}
+
+func TestFloodEnd(t *testing.T) {
+	SetOptions(Options{FloodDevice: 2, FloodWindow: time.Minute})
+	defer SetOptions(DefaultOptions)
+
+	d := &testDevice{id: 10}
+	startFlood(d, 100, time.Now(), 1, 2, 3)
+
+	//泛滥期间合并的警报持续上报
+	if !Flood(d, &testMeasure{id: 1}) {
+		t.Fatal("merged alarm should be collapsed during flood")
+	}
+
+	//窗口内没有新出现的警报，泛滥结束，合并的警报单独产生
+	startFlood(d, 100, time.Now().Add(-2*time.Minute), 1, 2, 3)
+	for _, id := range []int64{1, 2, 3} {
+		if Flood(d, &testMeasure{id: id}) {
+			t.Fatalf("alarm %d should be raised after flood ended", id)
+		}
+	}
+
+	mu.Lock()
+	n := len(floods["device:10"].raised)
+	mu.Unlock()
+	if n != 0 {
+		t.Fatalf("released alarms should not be counted as new, got %d", n)
+	}
+}
+
+func TestFloodConfirmed(t *testing.T) {
+	SetOptions(Options{FloodDevice: 2, FloodWindow: time.Minute})
+	defer SetOptions(DefaultOptions)
+
+	d := &testDevice{id: 11}
+	startFlood(d, 200, time.Now(), 4, 5, 6)
+
+	OnConfirmed(&testAlarm{id: 201})
+	if !Flood(d, &testMeasure{id: 4}) {
+		t.Fatal("confirming another alarm should not end flood")
+	}
+
+	OnConfirmed(&testAlarm{id: 200})
+	if Flood(d, &testMeasure{id: 7}) {
+		t.Fatal("new alarm should be raised after summary confirmed")
+	}
+	if Flood(d, &testMeasure{id: 5}) {
+		t.Fatal("merged alarm should be raised after summary confirmed")
+	}
+}
+
+func TestTick(t *testing.T) {
+	SetOptions(Options{FloodDevice: 2, FloodWindow: time.Minute, ChatterWindow: time.Minute})
+	defer SetOptions(DefaultOptions)
+
+	d := &testDevice{id: 12}
+	Observe(d, &testMeasure{id: 20})
+	Observe(d, &testMeasure{id: 21})
+	startFlood(d, 300, time.Now(), 20, 21, 22)
+
+	//泛滥期间和警报没有消失时都不清理
+	tick(time.Now())
+	mu.Lock()
+	active, n := floods["device:12"].active, len(points)
+	mu.Unlock()
+	if !active || n < 2 {
+		t.Fatal("active flood and live points should be kept")
+	}
+
+	//窗口内没有新的警报，泛滥结束；警报消失后点位的记录被清理
+	tick(time.Now().Add(2 * time.Minute))
+	mu.Lock()
+	active = floods["device:12"].active
+	_, ok := points[20]
+	mu.Unlock()
+	if active {
+		t.Fatal("flood should end without new alarms")
+	}
+	if ok {
+		t.Fatal("cleared point should be pruned")
+	}
+
+	tick(time.Now().Add(2 * time.Minute))
+	mu.Lock()
+	_, ok = floods["device:12"]
+	mu.Unlock()
+	if ok {
+		t.Fatal("idle flood state should be removed")
+	}
+}
+
+func TestDisconnected(t *testing.T) {
+	d := &testDevice{id: 13}
+
+	OnDeviceStatus(d, int(edgeLang.Disconnected))
+	if !Disconnected(d) || !containsID(DisconnectedDevices(), d.id) {
+		t.Fatal("device should be disconnected")
+	}
+
+	OnDeviceStatus(d, int(edgeLang.Connected))
+	if Disconnected(d) || containsID(DisconnectedDevices(), d.id) {
+		t.Fatal("device should be connected")
+	}
+}
+
+func containsID(list []int64, id int64) bool {
+	for _, v := range list {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
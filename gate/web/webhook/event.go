@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/maritimusj/centrum/gate/event"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/model"
+)
+
+//handlers 系统事件的处理函数，参数和app.Event.Publish时的参数一致
+func handlers() map[string]interface{} {
+	return map[string]interface{}{
+		event.UserCreated: func(adminID int64, userID int64) {
+			onUser(event.UserCreated, adminID, userID)
+		},
+		event.UserUpdated: func(adminID int64, userID int64) {
+			onUser(event.UserUpdated, adminID, userID)
+		},
+		event.UserDeleted: func(adminID int64, name string) {
+			Enqueue(event.UserDeleted, orgOf(adminID), map[string]interface{}{
+				"admin": user(adminID),
+				"user": model.Map{
+					"name": name,
+				},
+			})
+		},
+
+		event.DeviceCreated: func(userID int64, deviceID int64) {
+			onDevice(event.DeviceCreated, userID, deviceID)
+		},
+		event.DeviceUpdated: func(userID int64, deviceID int64) {
+			onDevice(event.DeviceUpdated, userID, deviceID)
+		},
+		event.DeviceDeleted: func(userID int64, id int64, uid string, title string) {
+			Enqueue(event.DeviceDeleted, orgOf(userID), map[string]interface{}{
+				"user": user(userID),
+				"device": model.Map{
+					"id":    id,
+					"title": title,
+				},
+			})
+		},
+		event.DeviceStatusChanged: func(deviceID int64, index int, title string) {
+			device, err := app.Store().GetDevice(deviceID)
+			if err != nil {
+				return
+			}
+			Enqueue(event.DeviceStatusChanged, device.OrganizationID(), map[string]interface{}{
+				"device": device.Brief(),
+				"status": model.Map{
+					"index": index,
+					"title": title,
+				},
+			})
+		},
+
+		event.EquipmentCreated: func(userID int64, equipmentID int64) {
+			onEquipment(event.EquipmentCreated, userID, equipmentID)
+		},
+		event.EquipmentUpdated: func(userID int64, equipmentID int64) {
+			onEquipment(event.EquipmentUpdated, userID, equipmentID)
+		},
+		event.EquipmentDeleted: func(userID int64, title string) {
+			Enqueue(event.EquipmentDeleted, orgOf(userID), map[string]interface{}{
+				"user": user(userID),
+				"equipment": model.Map{
+					"title": title,
+				},
+			})
+		},
+
+		event.AlarmRaised: func(alarmID int64) {
+			onAlarm(event.AlarmRaised, nil, alarmID)
+		},
+		event.AlarmConfirmed: func(userID int64, alarmID int64) {
+			onAlarm(event.AlarmConfirmed, &userID, alarmID)
+		},
+		event.AlarmCleared: func(deviceID int64, measureID int64, at time.Time) {
+			s := app.Store()
+			device, err := s.GetDevice(deviceID)
+			if err != nil {
+				return
+			}
+			measure, err := s.GetMeasure(measureID)
+			if err != nil {
+				return
+			}
+			Enqueue(event.AlarmCleared, device.OrganizationID(), map[string]interface{}{
+				"device":     device.Brief(),
+				"measure":    measure.Brief(),
+				"cleared_at": at.Format(time.RFC3339),
+			})
+		},
+	}
+}
+
+//orgOf 删除事件中的资源已经不存在，按操作人所在的组织投递
+func orgOf(userID int64) int64 {
+	if u, err := app.Store().GetUser(userID); err == nil {
+		return u.OrganizationID()
+	}
+	return 0
+}
+
+//user 事件的操作人，用户已经被删除时只有ID
+func user(userID int64) model.Map {
+	if u, err := app.Store().GetUser(userID); err == nil {
+		return u.Simple()
+	}
+	return model.Map{
+		"id": userID,
+	}
+}
+
+func onUser(name string, adminID int64, userID int64) {
+	u, err := app.Store().GetUser(userID)
+	if err != nil {
+		return
+	}
+	Enqueue(name, u.OrganizationID(), map[string]interface{}{
+		"admin": user(adminID),
+		"user":  u.Brief(),
+	})
+}
+
+func onDevice(name string, userID int64, deviceID int64) {
+	device, err := app.Store().GetDevice(deviceID)
+	if err != nil {
+		return
+	}
+	Enqueue(name, device.OrganizationID(), map[string]interface{}{
+		"user":   user(userID),
+		"device": device.Brief(),
+	})
+}
+
+func onEquipment(name string, userID int64, equipmentID int64) {
+	equipment, err := app.Store().GetEquipment(equipmentID)
+	if err != nil {
+		return
+	}
+	Enqueue(name, equipment.OrganizationID(), map[string]interface{}{
+		"user":      user(userID),
+		"equipment": equipment.Brief(),
+	})
+}
+
+func onAlarm(name string, userID *int64, alarmID int64) {
+	alarm, err := app.Store().GetAlarm(alarmID)
+	if err != nil {
+		return
+	}
+	device, err := alarm.Device()
+	if err != nil {
+		return
+	}
+	data := map[string]interface{}{
+		"alarm": alarm.Detail(),
+	}
+	if userID != nil {
+		data["user"] = user(*userID)
+	}
+	Enqueue(name, device.OrganizationID(), data)
+}
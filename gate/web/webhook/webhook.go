@@ -0,0 +1,317 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/maritimusj/centrum/gate/event"
+	"github.com/maritimusj/centrum/gate/lang"
+	"github.com/maritimusj/centrum/gate/logStore"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/notify"
+	"github.com/maritimusj/centrum/gate/web/status"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	EventHeader    = "X-Centrum-Event"
+	DeliveryHeader = "X-Centrum-Delivery"
+
+	//每个投递最多尝试的次数
+	MaxAttempts = 8
+
+	//第一次重试的等待时间，之后每次加倍，最长不超过retryMax
+	retryBase = 30 * time.Second
+	retryMax  = 2 * time.Hour
+
+	//检查等待发送的投递的间隔和每个webhook每次处理的数量
+	checkInterval = 5 * time.Second
+	batchSize     = 100
+
+	//已经结束的投递记录保留的时间
+	retention     = 7 * 24 * time.Hour
+	cleanInterval = time.Hour
+
+	sendTimeout = 10 * time.Second
+	//投递记录中保存的响应内容长度
+	responseLimit = 1024
+)
+
+//Events 可以订阅的事件
+var Events = []string{
+	event.UserCreated,
+	event.UserUpdated,
+	event.UserDeleted,
+
+	event.DeviceCreated,
+	event.DeviceUpdated,
+	event.DeviceDeleted,
+	event.DeviceStatusChanged,
+
+	event.EquipmentCreated,
+	event.EquipmentUpdated,
+	event.EquipmentDeleted,
+
+	event.AlarmRaised,
+	event.AlarmConfirmed,
+	event.AlarmCleared,
+}
+
+var (
+	//有新的投递时立即处理
+	wake = make(chan struct{}, 1)
+)
+
+//Check 检查webhook的地址和订阅的事件
+func Check(rawURL string, events []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return lang.ErrInvalidWebhook.Error(rawURL)
+	}
+
+	for _, name := range events {
+		if !contains(Events, name) {
+			return lang.ErrInvalidWebhook.Error(name)
+		}
+	}
+
+	return nil
+}
+
+//Backoff 第n次发送失败后等待重试的时间
+func Backoff(attempts int) time.Duration {
+	d := retryBase
+	for i := 1; i < attempts && d < retryMax; i++ {
+		d *= 2
+	}
+	if d > retryMax {
+		d = retryMax
+	}
+	return d
+}
+
+//Start 订阅系统事件，定时发送等待中的投递并清理过期的投递记录
+func Start(ctx context.Context) {
+	for name, fn := range handlers() {
+		if err := app.Event.SubscribeAsync(name, fn, false); err != nil {
+			log.Errorln("[webhook] subscribe ", name, ": ", err)
+		}
+	}
+
+	go func() {
+		clean()
+		process()
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		cleaner := time.NewTicker(cleanInterval)
+		defer cleaner.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-cleaner.C:
+				clean()
+			case <-ticker.C:
+				process()
+			case <-wake:
+				process()
+			}
+		}
+	}()
+}
+
+func kick() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+//Enqueue 为事件来源组织中订阅了事件的全部webhook创建投递
+func Enqueue(name string, org int64, data map[string]interface{}) {
+	if org <= 0 {
+		return
+	}
+
+	s := app.Store()
+	webhooks, _, err := s.GetWebhookList(helper.Organization(org), helper.Status(int64(status.Enable)))
+	if err != nil {
+		log.Errorln("[webhook] get webhook list: ", err)
+		return
+	}
+
+	var payload []byte
+	for _, w := range webhooks {
+		if events := w.Events(); len(events) > 0 && !contains(events, name) {
+			continue
+		}
+
+		if payload == nil {
+			payload, err = json.Marshal(map[string]interface{}{
+				"event": name,
+				"time":  time.Now().Format(time.RFC3339),
+				"data":  data,
+			})
+			if err != nil {
+				log.Errorln("[webhook] ", err)
+				return
+			}
+		}
+
+		if _, err := s.CreateDelivery(w.GetID(), name, payload); err != nil {
+			log.Errorln("[webhook] create delivery: ", err)
+		}
+	}
+
+	if payload != nil {
+		kick()
+	}
+}
+
+//Redeliver 重新投递
+func Redeliver(delivery model.Delivery) error {
+	delivery.Retry()
+	if err := delivery.Save(); err != nil {
+		return err
+	}
+	kick()
+	return nil
+}
+
+func clean() {
+	n, err := app.Store().RemoveExpiredDeliveries(time.Now().Add(-retention))
+	if err != nil {
+		log.Errorln("[webhook] remove expired deliveries: ", err)
+	} else if n > 0 {
+		log.Debugf("[webhook] %d deliveries removed", n)
+	}
+}
+
+//process 发送到期的投递，每个webhook单独读取等待中的投递，不同webhook同时发送，同一个webhook按顺序发送，失败后本轮不再发送
+func process() {
+	webhooks, _, err := app.Store().GetWebhookList()
+	if err != nil {
+		log.Errorln("[webhook] get webhook list: ", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, w := range webhooks {
+		wg.Add(1)
+		go func(webhookID int64) {
+			defer wg.Done()
+			processWebhook(webhookID, time.Now())
+		}(w.GetID())
+	}
+	wg.Wait()
+}
+
+//processWebhook 按顺序发送一个webhook最早到期的投递，一个无法访问的地址不会影响其它webhook
+func processWebhook(webhookID int64, now time.Time) {
+	deliveries, _, err := app.Store().GetDeliveryList(helper.Webhook(webhookID), helper.Status(int64(status.Pending)), helper.OrderBy("next"), helper.Limit(batchSize))
+	if err != nil {
+		log.Errorln("[webhook] get delivery list: ", err)
+		return
+	}
+
+	for _, d := range deliveries {
+		if d.NextAt().After(now) || !deliver(d) {
+			return
+		}
+	}
+}
+
+//deliver 发送一次投递并记录结果，返回是否发送成功
+func deliver(d model.Delivery) bool {
+	w, err := d.Webhook()
+	if err != nil || !w.IsEnabled() {
+		//webhook被禁用时不再发送，重新启用后可以手动重新投递
+		reason := lang.ErrWebhookDisabled.Str()
+		if err != nil {
+			reason = err.Error()
+		}
+		d.Attempted(status.Failed, 0, reason, d.NextAt())
+		if err := d.Save(); err != nil {
+			log.Errorln("[webhook] save delivery: ", err)
+		}
+		return false
+	}
+
+	code, response, err := send(w, d)
+
+	switch {
+	case err == nil:
+		d.Attempted(status.Delivered, code, response, d.NextAt())
+	case d.Attempts()+1 >= MaxAttempts:
+		d.Attempted(status.Failed, code, response, d.NextAt())
+		log.WithField("src", logStore.SystemLog).Warningln(lang.WebhookDeliveryFailed.Str(w.Title(), d.Event(), d.Attempts(), err))
+	default:
+		d.Attempted(status.Pending, code, response, time.Now().Add(Backoff(d.Attempts()+1)))
+	}
+
+	if err := d.Save(); err != nil {
+		log.Errorln("[webhook] save delivery: ", err)
+	}
+
+	return err == nil
+}
+
+//send 发送请求，返回状态码和响应内容，2xx以外的状态为失败
+func send(w model.Webhook, d model.Delivery) (int, string, error) {
+	payload := d.Payload()
+
+	req, err := http.NewRequest(http.MethodPost, w.URL(), bytes.NewReader(payload))
+	if err != nil {
+		return 0, err.Error(), err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, d.Event())
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(d.GetID(), 10))
+	if secret := w.Secret(); secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(notify.TimestampHeader, timestamp)
+		req.Header.Set(notify.SignatureHeader, notify.Sign(secret, timestamp, payload))
+	}
+
+	client := http.Client{Timeout: sendTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err.Error(), err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, responseLimit))
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(data), fmt.Errorf("%s", resp.Status)
+	}
+	return resp.StatusCode, string(data), nil
+}
+
+func contains(list []string, str string) bool {
+	for _, v := range list {
+		if v == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/maritimusj/centrum/gate/event"
+	"github.com/maritimusj/centrum/gate/web/app"
+	"github.com/maritimusj/centrum/gate/web/helper"
+	"github.com/maritimusj/centrum/gate/web/model"
+	"github.com/maritimusj/centrum/gate/web/notify"
+	"github.com/maritimusj/centrum/gate/web/status"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  retryBase,
+		2:  2 * retryBase,
+		3:  4 * retryBase,
+		20: retryMax,
+	}
+	for attempts, expected := range cases {
+		if d := Backoff(attempts); d != expected {
+			t.Fatalf("backoff after %d attempts: expected %v, got %v", attempts, expected, d)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check("https://example.com/hook", []string{event.AlarmRaised, event.DeviceDeleted}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check("http://example.com/hook", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, url := range []string{"", "example.com/hook", "ftp://example.com", "http://"} {
+		if err := Check(url, nil); err == nil {
+			t.Fatalf("%q should be rejected", url)
+		}
+	}
+
+	if err := Check("https://example.com/hook", []string{event.ApiServerStarted}); err == nil {
+		t.Fatal("unknown event should be rejected")
+	}
+}
+
+func initStore(t *testing.T) {
+	app.Ctx = context.Background()
+	if err := app.InitDB(map[string]interface{}{
+		"connStr": filepath.Join(t.TempDir(), "test.db"),
+		"initDB":  true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	initStore(t)
+
+	const secret = "secret"
+
+	var (
+		code     int32 = http.StatusInternalServerError
+		requests int32
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		body, _ := ioutil.ReadAll(r.Body)
+		timestamp := r.Header.Get(notify.TimestampHeader)
+		if r.Header.Get(EventHeader) != event.AlarmRaised || r.Header.Get(DeliveryHeader) == "" || timestamp == "" {
+			t.Error("missing headers:", r.Header)
+		}
+		if r.Header.Get(notify.SignatureHeader) != notify.Sign(secret, timestamp, body) {
+			t.Error("invalid signature")
+		}
+
+		w.WriteHeader(int(atomic.LoadInt32(&code)))
+	}))
+	defer srv.Close()
+
+	s := app.Store()
+	org, err := s.CreateOrganization("test", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := s.CreateWebhook(org, 0, "test", map[string]interface{}{
+		"url":    srv.URL,
+		"secret": secret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := func() model.Delivery {
+		list, _, err := s.GetDeliveryList(helper.Webhook(w.GetID()), helper.Limit(1))
+		if err != nil || len(list) == 0 {
+			t.Fatal("delivery not found:", err)
+		}
+		return list[0]
+	}
+
+	Enqueue(event.AlarmRaised, org.GetID(), map[string]interface{}{"alarm": 1})
+
+	//2xx以外的状态等待重试
+	begin := time.Now()
+	processWebhook(w.GetID(), begin)
+
+	d := last()
+	if d.Status() != status.Pending || d.Attempts() != 1 {
+		t.Fatal("expect pending after first failure, got:", d.Status(), d.Attempts())
+	}
+	if next := d.NextAt().Sub(begin); next < Backoff(1) || next > Backoff(1)+time.Minute {
+		t.Fatal("invalid retry time:", next)
+	}
+
+	//还没有到重试时间
+	processWebhook(w.GetID(), time.Now())
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatal("delivery sent before retry time, requests:", n)
+	}
+
+	for i := 1; i < MaxAttempts; i++ {
+		processWebhook(w.GetID(), time.Now().Add(24*time.Hour))
+	}
+
+	d = last()
+	if d.Status() != status.Failed || d.Attempts() != MaxAttempts {
+		t.Fatal("expect failed after max attempts, got:", d.Status(), d.Attempts())
+	}
+	if n := atomic.LoadInt32(&requests); n != MaxAttempts {
+		t.Fatal("invalid requests:", n)
+	}
+
+	atomic.StoreInt32(&code, http.StatusOK)
+	Enqueue(event.AlarmRaised, org.GetID(), map[string]interface{}{"alarm": 2})
+	processWebhook(w.GetID(), time.Now())
+
+	if d = last(); d.Status() != status.Delivered {
+		t.Fatal("expect delivered, got:", d.Status())
+	}
+
+	//禁用的webhook不再发送
+	w.Disable()
+	if err := w.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.CreateDelivery(w.GetID(), event.AlarmRaised, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	processWebhook(w.GetID(), time.Now())
+
+	if d = last(); d.Status() != status.Failed {
+		t.Fatal("expect failed for disabled webhook, got:", d.Status())
+	}
+	if n := atomic.LoadInt32(&requests); n != MaxAttempts+1 {
+		t.Fatal("disabled webhook should not be called, requests:", n)
+	}
+}
@@ -4,6 +4,7 @@ go 1.16
 
 require (
 	github.com/Joker/jade v1.0.0 // indirect
+	github.com/Knetic/govaluate v3.0.0+incompatible
 	github.com/Shopify/goreferrer v0.0.0-20181106222321-ec9c9a553398 // indirect
 	github.com/StackExchange/wmi v0.0.0-20190523213315-cbe66965904d // indirect
 	github.com/ajg/form v1.5.1 // indirect
@@ -19,13 +20,14 @@ require (
 	github.com/flosch/pongo2 v0.0.0-20190707114632-bbf5a6c351f4 // indirect
 	github.com/gavv/monotime v0.0.0-20190418164738-30dba4353424 // indirect
 	github.com/go-ole/go-ole v1.2.4 // indirect
-	github.com/goburrow/serial v0.1.0 // indirect
+	github.com/goburrow/serial v0.1.0
 	github.com/gogf/gf v1.16.6
 	github.com/gomodule/redigo v1.8.8 // indirect
 	github.com/google/go-querystring v1.0.0 // indirect
 	github.com/gorilla/mux v1.8.0
 	github.com/gorilla/rpc v1.2.0
 	github.com/gorilla/schema v1.1.0 // indirect
+	github.com/gosnmp/gosnmp v1.32.0
 	github.com/gqcn/structs v1.1.1 // indirect
 	github.com/grokify/html-strip-tags-go v0.0.0-20190921062105-daaa06bf1aaf // indirect
 	github.com/imkira/go-interpol v1.1.0 // indirect
@@ -50,6 +52,8 @@ require (
 	github.com/microcosm-cc/bluemonday v1.0.2 // indirect
 	github.com/moul/http2curl v1.0.0 // indirect
 	github.com/patrickmn/go-cache v2.1.0+incompatible
+	github.com/prometheus/client_golang v1.11.1
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/ryanuber/columnize v2.1.0+incompatible // indirect
 	github.com/sergi/go-diff v1.0.0 // indirect
 	github.com/shirou/gopsutil v2.20.2+incompatible
@@ -6,6 +6,9 @@ import (
 	"time"
 )
 
+//RequestIDHeader gate调用edge时附带的请求ID，两边的日志中都会记录
+const RequestIDHeader = "X-Request-ID"
+
 type Sink interface {
 	StartInverseServer(conf *InverseConf) error
 	Reset(uid string)
@@ -15,6 +18,12 @@ type Sink interface {
 	SetValue(val *Value) error
 	GetValue(ch *CH) (interface{}, error)
 	GetRealtimeData(uid string) ([]map[string]interface{}, error)
+	ResetTotalizer(ch *CH) error
+	Discover(conf *ScanConf) ([]*Discovered, error)
+	Pulse(pulse *Pulse) error
+	GetDiagnostics(uid string) (interface{}, error)
+	Capture(capture *Capture) error
+	GetLoad() (*Load, error)
 }
 
 type Edge struct {
@@ -26,6 +35,12 @@ type InverseConf struct {
 	Port    int
 }
 
+const (
+	DriverEP6v2  = ""
+	DriverDLT645 = "dlt645"
+	DriverSNMP   = "snmp"
+)
+
 type Conf struct {
 	UID              string
 	Address          string
@@ -36,6 +51,107 @@ type Conf struct {
 	InfluxDBPassword string
 	CallbackURL      string
 	LogLevel         string
+	Driver           string
+	Options          string
+	Virtual          []VirtualCH
+	Totalizers       []TotalizerCH
+	Identity         *Identity
+	Schedules        []Schedule
+}
+
+//Schedule DO定时控制，Kind为cycle(循环开关), calendar(按星期和时间段开关)
+type Schedule struct {
+	Tag      string
+	Kind     string
+	OnTime   int
+	OffTime  int
+	Windows  []TimeWindow
+	Holidays []string
+}
+
+//TimeWindow 开启的时间段，Weekdays为空时每天有效，0为星期日，End小于Start时表示跨越午夜
+type TimeWindow struct {
+	Weekdays []int
+	Start    string
+	End      string
+}
+
+//Pulse 开启DO指定的秒数后关闭
+type Pulse struct {
+	CH
+	Seconds int
+}
+
+//Identity 设备身份，首次连接时记录，以后每次连接时校验
+type Identity struct {
+	Model   string
+	Version string
+	MAC     string
+	AI      int
+	AO      int
+	DI      int
+	DO      int
+	VO      int
+}
+
+//VirtualCH 由表达式计算得到的虚拟点位
+type VirtualCH struct {
+	Tag   string
+	Title string
+	Unit  string
+	Expr  string
+}
+
+//TotalizerCH 累计点位，Kind为flow(流量累计), runtime(运行小时), starts(启动次数)
+type TotalizerCH struct {
+	Tag    string
+	Title  string
+	Unit   string
+	Source string
+	Kind   string
+	Scale  float64
+	MaxGap int
+}
+
+//ScanConf 网络扫描参数，CIDR可以是单个IP，Timeout单位为毫秒
+type ScanConf struct {
+	CIDR    string
+	Ports   []int
+	Timeout int
+}
+
+//Discovered 扫描发现的控制器，Address为ip:port，反向连接的控制器为MAC地址
+type Discovered struct {
+	Address string
+	Model   string
+	Version string
+	Title   string
+	IP      string
+	Mask    string
+	Gateway string
+	MAC     string
+	Remote  string
+	Inverse bool
+}
+
+//Load edge的负载，PollLatency为平均每次采集的用时(毫秒)，CPU为使用率(%)
+type Load struct {
+	Adapters    int
+	PollLatency float64
+	CPU         float64
+}
+
+//Registration edge向gate注册时提交的数据，URL为gate访问edge的rpc地址
+type Registration struct {
+	URL   string
+	Token string
+	Load  *Load
+}
+
+//Capture 抓取设备接下来Num次请求和应答的原始数据
+type Capture struct {
+	UID string
+	Num int
 }
 
 type CH struct {
@@ -208,3 +324,132 @@ func (e *Edge) GetRealtimeData(_ *http.Request, uid *string, result *Result) (er
 	result.Data = data
 	return nil
 }
+
+//ResetTotalizer 累计点位清零，Tag为空时清零设备全部累计点位
+func (e *Edge) ResetTotalizer(_ *http.Request, ch *CH, _ *Result) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			switch v := e.(type) {
+			case error:
+				err = v
+			case string:
+				err = errors.New(v)
+			default:
+				err = errors.New("unknown error")
+			}
+		}
+	}()
+
+	return e.sink.ResetTotalizer(ch)
+}
+
+//Discover 扫描网络中的Modbus TCP设备和等待反向连接的控制器
+func (e *Edge) Discover(_ *http.Request, conf *ScanConf, result *Result) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			switch v := e.(type) {
+			case error:
+				err = v
+			case string:
+				err = errors.New(v)
+			default:
+				err = errors.New("unknown error")
+			}
+		}
+	}()
+
+	data, err := e.sink.Discover(conf)
+	if err != nil {
+		return err
+	}
+	result.Data = data
+	return nil
+}
+
+//Pulse 脉冲输出，由edge负责按时关闭
+func (e *Edge) Pulse(_ *http.Request, pulse *Pulse, _ *Result) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			switch v := e.(type) {
+			case error:
+				err = v
+			case string:
+				err = errors.New(v)
+			default:
+				err = errors.New("unknown error")
+			}
+		}
+	}()
+
+	return e.sink.Pulse(pulse)
+}
+
+//GetDiagnostics 获取设备的通讯诊断数据和抓取的原始帧
+func (e *Edge) GetDiagnostics(_ *http.Request, uid *string, result *Result) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			switch v := e.(type) {
+			case error:
+				err = v
+			case string:
+				err = errors.New(v)
+			default:
+				err = errors.New("unknown error")
+			}
+		}
+	}()
+
+	data, err := e.sink.GetDiagnostics(*uid)
+	if err != nil {
+		return err
+	}
+	result.Data = data
+	return nil
+}
+
+//Capture 开始抓取设备的原始通讯数据
+func (e *Edge) Capture(_ *http.Request, capture *Capture, _ *Result) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			switch v := e.(type) {
+			case error:
+				err = v
+			case string:
+				err = errors.New(v)
+			default:
+				err = errors.New("unknown error")
+			}
+		}
+	}()
+
+	return e.sink.Capture(capture)
+}
+
+//Ping 用于gate检查edge是否可以访问
+func (e *Edge) Ping(_ *http.Request, _ *string, result *Result) error {
+	result.Data = "pong"
+	return nil
+}
+
+//GetLoad 获取edge的负载，同时用于gate的健康检查
+func (e *Edge) GetLoad(_ *http.Request, _ *string, result *Result) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			switch v := e.(type) {
+			case error:
+				err = v
+			case string:
+				err = errors.New(v)
+			default:
+				err = errors.New("unknown error")
+			}
+		}
+	}()
+
+	data, err := e.sink.GetLoad()
+	if err != nil {
+		return err
+	}
+	result.Data = data
+	return nil
+}
@@ -0,0 +1,105 @@
+package calendar
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/maritimusj/centrum/json_rpc"
+)
+
+type window struct {
+	weekdays map[time.Weekday]bool
+	start    int
+	end      int
+}
+
+//contains 判断时间是否在这个时间段内，跨越午夜的时间段按开始那天的星期判断
+func (w *window) contains(now time.Time) bool {
+	minutes := now.Hour()*60 + now.Minute()
+	if w.start <= w.end {
+		return w.allow(now.Weekday()) && minutes >= w.start && minutes < w.end
+	}
+
+	if minutes >= w.start {
+		return w.allow(now.Weekday())
+	}
+
+	if minutes < w.end {
+		return w.allow(now.AddDate(0, 0, -1).Weekday())
+	}
+
+	return false
+}
+
+func (w *window) allow(weekday time.Weekday) bool {
+	return len(w.weekdays) == 0 || w.weekdays[weekday]
+}
+
+//Calendar 按星期和时间段的日历，edge的DO定时和gate的值班班次共用
+type Calendar struct {
+	windows  []*window
+	holidays map[string]bool
+}
+
+//Compile 检查并编译时间段和节假日，节假日格式为"2006-01-02"
+func Compile(windows []json_rpc.TimeWindow, holidays []string) (*Calendar, error) {
+	c := &Calendar{
+		holidays: map[string]bool{},
+	}
+
+	for _, w := range windows {
+		start, err := parseClock(w.Start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time window: %s", err)
+		}
+		end, err := parseClock(w.End)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time window: %s", err)
+		}
+
+		weekdays := map[time.Weekday]bool{}
+		for _, d := range w.Weekdays {
+			if d < 0 || d > 6 {
+				return nil, fmt.Errorf("invalid weekday: %d", d)
+			}
+			weekdays[time.Weekday(d)] = true
+		}
+
+		c.windows = append(c.windows, &window{
+			weekdays: weekdays,
+			start:    start,
+			end:      end,
+		})
+	}
+
+	for _, day := range holidays {
+		if _, err := time.ParseInLocation("2006-01-02", day, time.Local); err != nil {
+			return nil, fmt.Errorf("invalid holiday: %s", day)
+		}
+		c.holidays[day] = true
+	}
+	return c, nil
+}
+
+//Contains 指定时间是否在日历的时间段内，节假日全天不在，跨越午夜的时间段也按当天判断
+func (c *Calendar) Contains(now time.Time) bool {
+	if c.holidays[now.Format("2006-01-02")] {
+		return false
+	}
+
+	for _, w := range c.windows {
+		if w.contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+//parseClock 解析"08:30"格式的时间，返回从零点开始的分钟数
+func parseClock(str string) (int, error) {
+	t, err := time.Parse("15:04", str)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}